@@ -3,20 +3,27 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"unicode"
 
 	_ "github.com/mattn/go-sqlite3"
 
 	"github.com/darkodi/url-shortener/internal/cache"
 	"github.com/darkodi/url-shortener/internal/config"
+	"github.com/darkodi/url-shortener/internal/encoder"
 	"github.com/darkodi/url-shortener/internal/handler"
 	"github.com/darkodi/url-shortener/internal/logger"
+	"github.com/darkodi/url-shortener/internal/metrics"
 	"github.com/darkodi/url-shortener/internal/middleware"
+	"github.com/darkodi/url-shortener/internal/outbound"
 	"github.com/darkodi/url-shortener/internal/repository"
 	"github.com/darkodi/url-shortener/internal/service"
+	"github.com/darkodi/url-shortener/internal/validator"
 )
 
 func main() {
@@ -45,139 +52,589 @@ func main() {
 		Level:       cfg.Log.Level,
 		Format:      cfg.Log.Format,
 		Environment: cfg.Log.Environment,
+		RedactURLs:  cfg.Log.RedactURLs,
 	})
 
 	log.Info("starting url-shortener",
 		"level", cfg.Log.Level,
 		"format", cfg.Log.Format,
 		"environment", cfg.App.Environment)
+
+	listener, err := net.Listen("tcp", ":"+cfg.Server.Port)
+	if err != nil {
+		log.Error("Failed to listen", "port", cfg.Server.Port, "error", err.Error())
+		os.Exit(1)
+	}
+	var adminListener net.Listener
+	if cfg.Server.AdminPort != "" {
+		adminAddr := net.JoinHostPort(cfg.Server.AdminAddr, cfg.Server.AdminPort)
+		adminListener, err = net.Listen("tcp", adminAddr)
+		if err != nil {
+			log.Error("Failed to listen for admin", "addr", adminAddr, "error", err.Error())
+			os.Exit(1)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err = Run(ctx, cfg, log, listener, adminListener)
+	stop()
+	if err != nil {
+		os.Exit(1) // Run has logged the cause
+	}
+}
+
+// Run serves cfg on listener until ctx is cancelled, then shuts down
+// gracefully. With a non-nil admin listener, the admin endpoints, /metrics,
+// and pprof are served there and not on listener. Failures are logged
+// before they are returned.
+func Run(ctx context.Context, cfg *config.Config, log *logger.Logger, listener, admin net.Listener) error {
 	// ============================================================
 	// INITIALIZE LAYERS
 	// ============================================================
 	fmt.Println("🗄️  Connecting to database...")
-	repo, err := repository.NewURLRepository(&cfg.Database)
+	repo, err := repository.New(&cfg.Database)
 	if err != nil {
 		log.Error("Failed to initialize database", "error", err.Error())
-		os.Exit(1)
+		return err
 	}
 
 	// ============================================================
 	// INITIALIZE REDIS CACHE
 	// ============================================================
-	log.Info("connecting to Redis...")
-	redisCache, err := cache.NewRedisCache(&cfg.Redis)
-	if err != nil {
-		log.Error("Failed to connect to Redis", "error", err.Error())
-		os.Exit(1)
+	// Redis is only a cache: without it every lookup goes to the database,
+	// click write-behind stays off, and the shared limits count per instance
+	var linkCache cache.Cache
+	var redisCache *cache.RedisCache
+	if cfg.Redis.CacheBackend == "memory" {
+		log.Info("using in-memory link cache, Redis disabled", "size", cfg.Redis.CacheSize)
+		linkCache = cache.NewMemoryCache(cfg.Redis.CacheSize)
+	} else {
+		log.Info("connecting to Redis...")
+		redisCache, err = cache.NewRedisCache(&cfg.Redis)
+		if err != nil {
+			log.Warn("Redis unavailable, running without cache until restart",
+				"error", err.Error(),
+				"disabled", "click write-behind",
+				"per_instance", "shared rate limits, create and per-link limits",
+			)
+			redisCache = nil
+			linkCache = cache.Noop{}
+		} else {
+			linkCache = redisCache
+			log.Info("Redis connected successfully!")
+		}
 	}
 	defer func() {
-		if err := redisCache.Close(); err != nil {
-			log.Error("Failed to close Redis client", "error", err.Error())
+		if err := linkCache.Close(); err != nil {
+			log.Error("Failed to close cache", "error", err.Error())
 		}
 	}()
-	log.Info("Redis connected successfully!")
 
 	fmt.Println("⚙️  Initializing service...")
-	svc := service.NewURLService(repo, cfg.App.BaseURL, redisCache)
+	sequence, err := encoder.NewSequence(uint64(cfg.App.IDOffset), uint64(cfg.App.IDStride))
+	if err != nil {
+		log.Error("Invalid ID sequence", "offset", cfg.App.IDOffset, "stride", cfg.App.IDStride, "error", err.Error())
+		return err
+	}
+	codeEncoder := encoder.Default
+	if cfg.App.CodeAlphabet != "" {
+		codeEncoder, err = encoder.NewEncoder(cfg.App.CodeAlphabet)
+		if err != nil {
+			log.Error("Invalid code alphabet", "error", err.Error())
+			return err
+		}
+	}
+	var unicodeAliases *unicode.RangeTable // nil keeps aliases ASCII
+	if cfg.App.UnicodeAliases {
+		unicodeAliases, err = validator.ParseRuneRanges(cfg.App.UnicodeAliasRanges)
+		if err != nil {
+			log.Error("Invalid UNICODE_ALIAS_RANGES", "error", err.Error())
+			return err
+		}
+	}
+
+	svc := service.NewURLService(repo, cfg.App.BaseURL, linkCache).
+		WithCacheTTL(cfg.Redis.CacheTTL).
+		WithCacheJitter(cfg.Redis.CacheJitter).
+		WithNegativeCacheTTL(cfg.Redis.NegativeTTL).
+		WithClickEvents(cfg.Analytics.ClickEvents).
+		WithDoNotTrackPolicy(cfg.Analytics.HonorDNT, cfg.Analytics.DNTCountAggregate).
+		WithClickClientIP(cfg.Analytics.StoreClientIP).
+		WithCodeChecksum(cfg.App.CodeChecksum).
+		WithIDSequence(sequence).
+		WithEncoder(codeEncoder).
+		WithCodeMinLength(cfg.App.CodeMinLength).
+		WithCaseFallback(cfg.App.CaseFallback).
+		WithUnicodeAliases(unicodeAliases).
+		WithMaxClickRows(cfg.Analytics.MaxClickRows).
+		WithSignedLinks(cfg.SignedLink.Secret, cfg.SignedLink.TTL).
+		WithReadOnly(cfg.Database.ReadOnly).
+		WithRegionalBaseURLs(cfg.App.RegionBaseURLs, cfg.App.DefaultRegion).
+		WithUniqueURLPerOwner(cfg.App.UniqueURLPerOwner)
+	if cfg.Database.ReadOnly {
+		log.Info("read-only mode: writes are rejected and clicks are not counted")
+	}
+
+	if cfg.App.LegacyCodesFile != "" {
+		legacyCodes, err := service.LoadLegacyCodes(cfg.App.LegacyCodesFile)
+		if err != nil {
+			log.Error("Failed to load legacy codes", "error", err.Error())
+			return err
+		}
+		svc.WithLegacyCodes(legacyCodes)
+		log.Info("legacy code mapping loaded", "entries", len(legacyCodes))
+	}
+
+	// Write-behind click counting keeps redirects off the database;
+	// the same flusher prunes click rows beyond the cap
+	flushCtx, stopFlusher := context.WithCancel(context.Background())
+	defer stopFlusher()
+	flusherDone := make(chan struct{})
+	if cfg.Analytics.ClickWriteBehind && redisCache != nil {
+		svc.WithClickBuffer(redisCache).WithClickFlushBatchSize(cfg.Analytics.ClickFlushBatch)
+		log.Info("click write-behind enabled",
+			"flush_interval", cfg.Analytics.ClickFlushInterval,
+			"flush_batch_size", cfg.Analytics.ClickFlushBatch,
+		)
+	}
+
+	// Failed click increments are retried in the background instead of lost
+	retrierDone := make(chan struct{})
+	if cfg.Analytics.ClickRetry && !cfg.Database.ReadOnly {
+		svc.WithClickRetry(cfg.Analytics.ClickRetryQueueSize, cfg.Analytics.ClickRetryMaxAttempts, cfg.Analytics.ClickRetryBackoff)
+		go func() {
+			svc.RunClickRetrier(flushCtx)
+			close(retrierDone)
+		}()
+	} else {
+		close(retrierDone)
+	}
+
+	// Destinations are probed in the background; resolves only read the results
+	checkerDone := make(chan struct{})
+	if cfg.DestinationCheck.Enabled {
+		checker := service.NewDestinationChecker(service.NewHTTPProber(outbound.NewPublicClient(cfg.Outbound)), cfg.DestinationCheck.TTL)
+		svc.WithDestinationChecker(checker)
+		go func() {
+			checker.Run(flushCtx)
+			close(checkerDone)
+		}()
+		log.Info("destination health checks enabled", "ttl", cfg.DestinationCheck.TTL)
+	} else {
+		close(checkerDone)
+	}
+
+	// Click webhooks are delivered in the background, off the redirect path
+	webhooksDone := make(chan struct{})
+	if cfg.Webhook.Secret != "" && !cfg.Database.ReadOnly {
+		// Per-link endpoints come from whoever created the link, so they
+		// are only reached on public addresses
+		webhooks := service.NewWebhookSender(outbound.NewClient(cfg.Outbound), outbound.NewPublicClient(cfg.Outbound), cfg.Webhook.URL, cfg.Webhook.Secret, cfg.Webhook.QueueSize)
+		svc.WithWebhooks(webhooks)
+		go func() {
+			webhooks.Run(flushCtx)
+			close(webhooksDone)
+		}()
+		log.Info("click webhooks enabled", "queue_size", cfg.Webhook.QueueSize)
+	} else {
+		close(webhooksDone)
+	}
+
+	// Detailed click rows are written in the background, off the redirect path
+	eventWriterDone := make(chan struct{})
+	if cfg.Analytics.ClickEvents && cfg.Analytics.ClickEventQueue > 0 && !cfg.Database.ReadOnly {
+		svc.WithClickEventQueue(cfg.Analytics.ClickEventQueue)
+		go func() {
+			svc.RunClickEventWriter(flushCtx)
+			close(eventWriterDone)
+		}()
+	} else {
+		close(eventWriterDone)
+	}
+
+	runFlusher := cfg.Analytics.ClickWriteBehind || cfg.Analytics.MaxClickRows > 0
+	if runFlusher && !cfg.Database.ReadOnly {
+		go func() {
+			svc.RunClickFlusher(flushCtx, cfg.Analytics.ClickFlushInterval)
+			close(flusherDone)
+		}()
+	} else {
+		close(flusherDone)
+	}
 
 	fmt.Println("🌐 Setting up HTTP handlers...")
-	h := handler.NewURLHandler(svc)
+	urlValidator := validator.NewURLValidator().WithUnicodeAliases(unicodeAliases)
+	if !cfg.App.NormalizeHosts {
+		urlValidator.WithoutHostNormalization()
+	}
+	if cfg.App.StripTrackingParams {
+		urlValidator.WithTrackingParamStripping(cfg.App.TrackingParams...)
+	}
+	if cfg.Log.ValidationRejections {
+		urlValidator.WithRejectionLogger(log)
+	}
+	if cfg.App.AliasDenylistFile != "" {
+		words, err := validator.LoadWordList(cfg.App.AliasDenylistFile)
+		if err != nil {
+			log.Error("Failed to load alias denylist", "error", err.Error())
+			return err
+		}
+		urlValidator.WithDeniedWords(words...)
+		log.Info("alias denylist loaded", "words", len(words))
+	}
+
+	var latency *metrics.LatencyTracker
+	if cfg.Metrics.Latency {
+		latency = metrics.NewLatencyTracker(
+			"urlshortener_request_duration_seconds",
+			"Response time percentiles over recent requests, by route.",
+			cfg.Metrics.LatencySamples,
+			cfg.Metrics.LatencyPercentiles...,
+		)
+		metrics.Register(latency)
+		log.Info("latency percentiles enabled",
+			"samples", cfg.Metrics.LatencySamples,
+			"percentiles", cfg.Metrics.LatencyPercentiles,
+		)
+	}
+
+	h := handler.NewURLHandler(svc).
+		WithValidator(urlValidator).
+		WithMaxPathDepth(cfg.App.MaxPathDepth).
+		WithNoIndex(cfg.App.RobotsNoIndex).
+		WithRedirectBody(cfg.App.RedirectBody).
+		WithRedirectStatus(cfg.App.RedirectStatus).
+		WithConditionalRedirects(cfg.App.ConditionalRedirects).
+		WithTagStatsLimit(cfg.Admin.TagStatsLimit).
+		WithAliasPageLimit(cfg.Admin.AliasesLimit).
+		WithRecentLimit(cfg.Admin.RecentLimit).
+		WithDecodeEndpoint(cfg.App.DecodeEndpoint).
+		WithStatsJSONP(cfg.App.StatsJSONP).
+		WithCacheStatusHeader(cfg.App.CacheStatusHeader).
+		WithClickStep(uint64(cfg.Analytics.APIClickStep), cfg.Analytics.ClickStepHeader, uint64(cfg.Analytics.MaxClickStep)).
+		WithRegionHeader(cfg.App.RegionHeader).
+		WithOwnerHeader(cfg.App.OwnerHeader).
+		WithAPIKeys(cfg.APIKeys.Keys).
+		WithMetrics(cfg.Metrics.Enabled).
+		WithLatency(latency).
+		WithWarmUp(cfg.Server.WarmUp).
+		WithBodyReadTimeout(cfg.Server.BodyReadTimeout)
+	if cfg.Metrics.Links {
+		h.WithLinkMetrics(cfg.Metrics.LinkMax, uint64(cfg.Metrics.LinkMinClicks))
+	}
+	if cfg.LinkLimit.Enabled {
+		// Without Redis each instance counts its own redirects
+		var linkStore handler.LinkLimitStore = middleware.NewMemoryWindowStore()
+		if redisCache != nil {
+			linkStore = redisCache
+		} else {
+			log.Warn("Redis unavailable: per-link redirect limit is counted per instance")
+		}
+		h.WithLinkLimit(linkStore, handler.LinkLimit{
+			Limit:  cfg.LinkLimit.Limit,
+			Window: cfg.LinkLimit.Window,
+
+			RetryAfterHTTPDate: cfg.RateLimit.RetryAfterHTTPDate(),
+		})
+		log.Info("per-link redirect limit enabled",
+			"limit", cfg.LinkLimit.Limit,
+			"window", cfg.LinkLimit.Window,
+		)
+	}
+	if cfg.App.ErrorPages {
+		errorPages, err := handler.LoadErrorPages(cfg.App.ErrorPagesDir)
+		if err != nil {
+			log.Error("Failed to load error pages", "error", err.Error())
+			return err
+		}
+		h.WithErrorPages(errorPages)
+	}
+	h.WithAdminListener(admin != nil)
 	router := h.SetupRoutes()
 
 	// ============================================================
 	// BUILD MIDDLEWARE CHAIN
 	// ============================================================
+	trustedProxies, err := cfg.Server.TrustedProxyPrefixes()
+	if err != nil {
+		log.Error("Invalid TRUSTED_PROXIES", "error", err.Error())
+		return err
+	}
 	middlewares := []middleware.Middleware{
-		middleware.RequestID,
-		middleware.RecoveryWithLogger(log),
-		middleware.LoggingWithLogger(log),
+		middleware.RequestIDWithConfig(middleware.RequestIDConfig{
+			TrustIncoming: cfg.Tracing.TrustRequestID,
+			MaxLength:     cfg.Tracing.RequestIDMaxLength,
+		}),
+		middleware.RealIP(trustedProxies),
+	}
+	// Trace context must run before logging so logs carry the trace ID
+	if cfg.Tracing.Enabled {
+		middlewares = append(middlewares, middleware.TraceContext)
+		log.Info("W3C trace context enabled")
+	}
+	middlewares = append(middlewares, middleware.RecoveryWithLogger(log))
+	if cfg.Log.AccessFormat == "combined" {
+		var accessLog io.Writer = os.Stdout
+		if cfg.Log.AccessFile != "" {
+			f, err := os.OpenFile(cfg.Log.AccessFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+			if err != nil {
+				log.Error("Failed to open access log", "path", cfg.Log.AccessFile, "error", err.Error())
+				return err
+			}
+			defer f.Close()
+			accessLog = f
+		}
+		middlewares = append(middlewares, middleware.CombinedLog(accessLog, cfg.Log.RedactURLs != logger.RedactOff))
+		log.Info("combined access log enabled", "file", cfg.Log.AccessFile)
+	} else {
+		middlewares = append(middlewares, middleware.LoggingWithLogger(log))
+	}
+	if cfg.Admin.Token != "" {
+		middlewares = append(middlewares, middleware.AdminAuth(middleware.DefaultAdminAuthConfig(cfg.Admin.Token)))
+	} else {
+		// Validate only allows this in development
+		log.Warn("ADMIN_TOKEN not set, admin endpoints are unauthenticated (development only)")
+	}
+	if cfg.Database.AllowConsistencyOverride {
+		middlewares = append(middlewares, middleware.Consistency)
+		log.Info("per-request consistency override enabled", "header", middleware.ConsistencyHeader)
 	}
 	// Add rate limiter if enabled
 	if cfg.RateLimit.Enabled {
+		var routeLimits []middleware.RouteLimit
+		for _, route := range cfg.RateLimit.Routes {
+			routeLimits = append(routeLimits, middleware.RouteLimit{
+				PathPrefix: route.PathPrefix,
+				Rate:       route.Rate,
+				Burst:      route.Burst,
+			})
+		}
 		rateLimiter := middleware.NewRateLimiter(
 			middleware.RateLimiterConfig{
 				Rate:     cfg.RateLimit.Rate,
 				Burst:    cfg.RateLimit.Burst,
 				Interval: cfg.RateLimit.Interval,
 				Cleanup:  cfg.RateLimit.Cleanup,
+				Routes:   routeLimits,
+
+				RetryAfterHTTPDate: cfg.RateLimit.RetryAfterHTTPDate(),
 			},
 			log,
 		)
+		// Shared buckets so every instance enforces the same per-IP limit
+		if cfg.RateLimit.Backend == "redis" {
+			if redisCache != nil {
+				rateLimiter.WithStore(redisCache)
+			} else {
+				log.Warn("Redis unavailable: rate limit buckets are kept per instance")
+			}
+		}
 		middlewares = append(middlewares, rateLimiter.Middleware())
 		log.Info("rate limiter enabled",
 			"rate", cfg.RateLimit.Rate,
 			"burst", cfg.RateLimit.Burst,
+			"backend", cfg.RateLimit.Backend,
+			"routes", len(routeLimits),
+		)
+	}
+
+	// Per-IP create cap, shared across instances through Redis
+	if cfg.CreateLimit.Enabled {
+		// A nil store counts per instance
+		var createStore middleware.WindowStore
+		if redisCache != nil {
+			createStore = redisCache
+		} else {
+			log.Warn("Redis unavailable: create limit is counted per instance")
+		}
+		createLimiter := middleware.NewCreateLimiter(
+			middleware.CreateLimiterConfig{
+				Limit:  cfg.CreateLimit.Limit,
+				Window: cfg.CreateLimit.Window,
+
+				RetryAfterHTTPDate: cfg.RateLimit.RetryAfterHTTPDate(),
+			},
+			createStore,
+			log,
+		)
+		middlewares = append(middlewares, createLimiter.Middleware())
+		log.Info("create limiter enabled",
+			"limit", cfg.CreateLimit.Limit,
+			"window", cfg.CreateLimit.Window,
 		)
 	}
 
+	// Inside logging and recovery, so both see the 503 and any handler panic;
+	// outside the route-reading middlewares, which need the request the
+	// router sees
+	if cfg.Server.RequestTimeout > 0 {
+		middlewares = append(middlewares, middleware.Timeout(cfg.Server.RequestTimeout))
+		log.Info("request timeout enabled", "timeout", cfg.Server.RequestTimeout)
+	}
+
+	// Compression runs innermost so logging still sees the final status code
+	if cfg.Gzip.Enabled {
+		middlewares = append(middlewares, middleware.GzipWithConfig(
+			middleware.GzipConfig{
+				MinSize:      cfg.Gzip.MinSize,
+				ContentTypes: cfg.Gzip.ContentTypes,
+			},
+		))
+		log.Info("gzip compression enabled",
+			"min_size", cfg.Gzip.MinSize,
+			"content_types", cfg.Gzip.ContentTypes,
+		)
+	}
+
+	// Request metrics and latency read the matched route from the router,
+	// so they go last
+	if cfg.Metrics.Enabled {
+		requestMetrics := metrics.NewRequestMetrics()
+		metrics.Register(requestMetrics)
+		middlewares = append(middlewares, middleware.Metrics(requestMetrics))
+	}
+	if latency != nil {
+		middlewares = append(middlewares, middleware.Latency(latency))
+	}
+
 	wrappedRouter := middleware.Chain(router, middlewares...)
 
 	// ============================================================
-	// CREATE SERVER WITH CONFIG TIMEOUTS
+	// CREATE SERVERS WITH CONFIG TIMEOUTS
 	// ============================================================
-	addr := ":" + cfg.Server.Port
+	addr := listener.Addr().String()
 	server := &http.Server{
-		Addr:         addr,
 		Handler:      wrappedRouter,
 		ReadTimeout:  cfg.Server.ReadTimeout,
 		WriteTimeout: cfg.Server.WriteTimeout,
 		IdleTimeout:  cfg.Server.IdleTimeout,
 	}
-	// Channel to listen for shutdown signals
-	shutdown := make(chan os.Signal, 1)
-	signal.Notify(shutdown, os.Interrupt, syscall.SIGTERM)
+	servers := []*http.Server{server}
 
-	// Channel to track server errors
-	serverErr := make(chan error, 1)
+	// Channel to track server errors, one slot per server
+	serverErr := make(chan error, 2)
+
+	// The admin listener gets its own short chain: no rate limits or
+	// request timeout, and no write timeout so CPU profiles and traces
+	// can run longer than SERVER_WRITE_TIMEOUT
+	if admin != nil {
+		adminMiddlewares := []middleware.Middleware{
+			middleware.RequestIDWithConfig(middleware.RequestIDConfig{
+				TrustIncoming: cfg.Tracing.TrustRequestID,
+				MaxLength:     cfg.Tracing.RequestIDMaxLength,
+			}),
+			middleware.RealIP(trustedProxies),
+			middleware.RecoveryWithLogger(log),
+			middleware.LoggingWithLogger(log),
+		}
+		if cfg.Admin.Token != "" {
+			authConfig := middleware.DefaultAdminAuthConfig(cfg.Admin.Token)
+			authConfig.Prefixes = append(authConfig.Prefixes, "/debug/pprof/")
+			adminMiddlewares = append(adminMiddlewares, middleware.AdminAuth(authConfig))
+		}
+		adminServer := &http.Server{
+			Handler:     middleware.Chain(h.SetupAdminRoutes(), adminMiddlewares...),
+			ReadTimeout: cfg.Server.ReadTimeout,
+			IdleTimeout: cfg.Server.IdleTimeout,
+		}
+		servers = append(servers, adminServer)
+		go func() {
+			log.Info("admin server starting", "addr", admin.Addr().String())
+			serverErr <- adminServer.Serve(admin)
+		}()
+	}
 
 	// Start server in a goroutine
 	go func() {
 		if cfg.IsDevelopment() {
-			fmt.Printf("🚀 Server starting on http://localhost%s\n", addr)
+			fmt.Printf("🚀 Server starting on http://%s\n", addr)
 			fmt.Println("───────────────────────────────────────")
 			fmt.Println("Endpoints:")
 			fmt.Println("  POST /shorten      - Create short URL")
 			fmt.Println("  GET  /{code}       - Redirect to original")
 			fmt.Println("  GET  /{code}/stats - View statistics")
 			fmt.Println("  GET  /health       - Health check")
+			fmt.Println("  GET  /health/ready - Database and Redis reachability")
+			fmt.Println("  POST /reserve      - Reserve a code without a URL")
+			fmt.Println("  PUT  /{code}       - Activate a reserved code")
+			if admin != nil {
+				fmt.Printf("Admin endpoints, /metrics and /debug/pprof/ on %s\n", admin.Addr())
+			} else {
+				fmt.Println("  GET  /admin/capacity - Creation rate and code space")
+				fmt.Println("  GET  /admin/runtime  - Goroutines, memory, GC, uptime")
+				fmt.Println("  GET  /admin/aliases  - Reserved words and taken codes")
+				fmt.Println("  POST /admin/migrate-codes - Re-encode codes from an old scheme")
+			}
 			fmt.Println("───────────────────────────────────────")
 			fmt.Println("Press Ctrl+C to shutdown gracefully")
 		}
-		log.Info("server starting", "addr", "http://localhost"+addr)
-		serverErr <- server.ListenAndServe()
+		log.Info("server starting", "addr", addr)
+		serverErr <- server.Serve(listener)
 	}()
 
+	// Fill the connection pools while /health still reports starting
+	if cfg.Server.WarmUp {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.WarmUpTimeout)
+			defer cancel()
+			conns := cfg.Database.MaxIdleConns
+			warmers := []handler.Warmer{func(ctx context.Context) error { return repo.WarmUp(ctx, conns) }}
+			if redisCache != nil {
+				warmers = append(warmers, func(ctx context.Context) error { return redisCache.WarmUp(ctx, conns) })
+			}
+			err := h.WarmUp(ctx, warmers...)
+			if err != nil {
+				log.Warn("connection warm-up incomplete", "error", err.Error())
+				return
+			}
+			log.Info("connection pools warmed up", "conns", conns)
+		}()
+	}
+
 	// ============================================================
 	// WAIT FOR SHUTDOWN OR ERROR
 	// ============================================================
+	// A listener failing takes the whole process down, through the same
+	// sequence as a signal so buffered clicks still reach the database
+	var runErr error
 	select {
-	case err := <-serverErr:
-		log.Error("server error", "error", err.Error())
-		os.Exit(1)
+	case runErr = <-serverErr:
+		log.Error("server error", "error", runErr.Error())
+	case <-ctx.Done():
+		log.Info("shutdown signal received")
+	}
 
-	case sig := <-shutdown:
-		log.Info("shutdown signal received", "signal", sig.String())
-		// Create context with timeout for shutdown
-		ctx, cancel := context.WithTimeout(
-			context.Background(),
-			cfg.Server.ShutdownTimeout,
-		)
-		defer cancel()
+	// Create context with timeout for shutdown
+	shutdownCtx, cancel := context.WithTimeout(
+		context.Background(),
+		cfg.Server.ShutdownTimeout,
+	)
+	defer cancel()
 
-		// Attempt graceful shutdown
-		if err := server.Shutdown(ctx); err != nil {
+	// Attempt graceful shutdown of every listener
+	for _, s := range servers {
+		if err := s.Shutdown(shutdownCtx); err != nil {
 			log.Error("graceful shutdown failed", "error", err.Error())
 			// force close if graceful shutdown fails
-			if err := server.Close(); err != nil {
+			if err := s.Close(); err != nil {
 				log.Error("forced shutdown failed", "error", err.Error())
 			}
 		}
+	}
 
-		// Close repository (database connection)
-		if err := repo.Close(); err != nil {
-			log.Error("failed to close database", "error", err.Error())
-		}
+	// Flush buffered clicks before the database goes away
+	stopFlusher()
+	<-flusherDone
+	<-retrierDone
+	<-checkerDone
+	<-eventWriterDone
+	<-webhooksDone
 
-		log.Info("server stopped")
+	// Close repository (database connection)
+	if err := repo.Close(); err != nil {
+		log.Error("failed to close database", "error", err.Error())
 	}
+
+	log.Info("server stopped")
+	return runErr
 }
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/darkodi/url-shortener/internal/config"
+	"github.com/darkodi/url-shortener/internal/logger"
+)
+
+// testConfig loads a config that needs neither a database server nor Redis
+func testConfig(t *testing.T) (*config.Config, *logger.Logger) {
+	t.Helper()
+	t.Setenv("DB_DRIVER", "memory")
+	t.Setenv("CACHE_BACKEND", "memory")
+	t.Setenv("METRICS_ENABLED", "true")
+	t.Setenv("RATE_LIMIT_ENABLED", "false")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	return cfg, logger.New(logger.Config{Level: "error", Format: "text", Output: io.Discard})
+}
+
+func listen(t *testing.T) net.Listener {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	return l
+}
+
+// serve runs the server on fresh listeners until the test ends
+func serve(t *testing.T, cfg *config.Config, log *logger.Logger) (public, admin net.Listener) {
+	t.Helper()
+	public, admin = listen(t), listen(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- Run(ctx, cfg, log, public, admin) }()
+	t.Cleanup(func() {
+		cancel()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("Expected clean shutdown, got: %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Error("Run did not return after cancel")
+		}
+	})
+	return public, admin
+}
+
+func TestRun_AdminListener(t *testing.T) {
+	cfg, log := testConfig(t)
+	public, admin := serve(t, cfg, log)
+
+	get := func(l net.Listener, path string) int {
+		t.Helper()
+		resp, err := http.Get("http://" + l.Addr().String() + path)
+		if err != nil {
+			t.Fatalf("GET %s failed: %v", path, err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := get(public, "/health"); code != http.StatusOK {
+		t.Fatalf("Expected public /health to answer 200, got: %d", code)
+	}
+	for _, path := range []string{"/admin/runtime", "/admin/recent", "/metrics", "/debug/pprof/"} {
+		if code := get(admin, path); code != http.StatusOK {
+			t.Errorf("Expected %s served on the admin port, got: %d", path, code)
+		}
+		// The public port treats it like any other unknown short code
+		if code := get(public, path); code == http.StatusOK {
+			t.Errorf("Expected %s absent from the public port, got: %d", path, code)
+		}
+	}
+}
+
+func TestRun_ListenerFailureShutsDown(t *testing.T) {
+	cfg, log := testConfig(t)
+	public, admin := listen(t), listen(t)
+	admin.Close() // Serve fails at once
+
+	done := make(chan error, 1)
+	go func() { done <- Run(context.Background(), cfg, log, public, admin) }()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("Expected the listener error returned")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after a listener failed")
+	}
+
+	// The public server was shut down along with it
+	if _, err := http.Get("http://" + public.Addr().String() + "/health"); err == nil {
+		t.Error("Expected the public listener closed")
+	}
+}
+
+func TestRun_SlowBodyClosesConnection(t *testing.T) {
+	t.Setenv("SERVER_BODY_READ_TIMEOUT", "100ms")
+	t.Setenv("SERVER_READ_TIMEOUT", "10s")
+	t.Setenv("GZIP_ENABLED", "true")
+	cfg, log := testConfig(t)
+	public, _ := serve(t, cfg, log)
+
+	conn, err := net.Dial("tcp", public.Addr().String())
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	// Promise a body and send only part of it
+	fmt.Fprint(conn, "POST /shorten HTTP/1.1\r\nHost: test\r\nContent-Type: application/json\r\nContent-Length: 100\r\n\r\n{\"url\":")
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("Expected a response to the slow body, got: %v", err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusRequestTimeout {
+		t.Errorf("Expected 408, got: %d", resp.StatusCode)
+	}
+
+	// The read deadline reaches the connection through every wrapper, so
+	// the server hangs up instead of waiting out SERVER_READ_TIMEOUT
+	start := time.Now()
+	if _, err := reader.ReadByte(); err != io.EOF {
+		t.Errorf("Expected the connection closed, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the connection closed promptly, took: %s", elapsed)
+	}
+}
+
+func TestRun_ImportStreamsThroughMiddleware(t *testing.T) {
+	t.Setenv("GZIP_ENABLED", "true")
+	t.Setenv("GZIP_MIN_SIZE", "0")
+	t.Setenv("GZIP_CONTENT_TYPES", "application/x-ndjson")
+	cfg, log := testConfig(t)
+	public, _ := serve(t, cfg, log)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	upload, uploadWriter := io.Pipe()
+	defer uploadWriter.Close()
+	context.AfterFunc(ctx, func() { uploadWriter.CloseWithError(ctx.Err()) })
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://"+public.Addr().String()+"/shorten/import", upload)
+	if err != nil {
+		t.Fatalf("NewRequest failed: %v", err)
+	}
+
+	// One full batch goes up, then the upload stays open
+	go func() {
+		for i := 0; i < 100; i++ {
+			fmt.Fprintf(uploadWriter, "{\"url\": \"https://example.com/%d\"}\n", i)
+		}
+	}()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if !resp.Uncompressed {
+		t.Error("Expected the stream gzipped")
+	}
+
+	// Its progress must arrive while the client is still uploading
+	events := json.NewDecoder(resp.Body)
+	var event struct {
+		Progress *struct{ Created int } `json:"progress"`
+		Done     *struct{ Created int } `json:"done"`
+	}
+	if err := events.Decode(&event); err != nil || event.Progress == nil || event.Progress.Created != 100 {
+		t.Fatalf("Expected progress after the first batch, got: %+v, %v", event.Progress, err)
+	}
+
+	// The rest of the upload is still read after that flush
+	go func() {
+		fmt.Fprint(uploadWriter, "{\"url\": \"https://example.com/last\"}\n")
+		uploadWriter.Close()
+	}()
+	for event.Done == nil {
+		event.Progress = nil
+		if err := events.Decode(&event); err != nil {
+			t.Fatalf("Expected the summary, got: %v", err)
+		}
+	}
+	if event.Done.Created != 101 {
+		t.Errorf("Expected 101 links created, got: %d", event.Done.Created)
+	}
+}
@@ -0,0 +1,43 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrDisabled is what Noop reports as its health: there is no cache to
+// reach, and lookups go straight to the database
+var ErrDisabled = errors.New("cache disabled: Redis was unreachable at startup")
+
+// Cache is the link cache the service reads through. Get returns "" and
+// no error for a missing key.
+type Cache interface {
+	Get(ctx context.Context, key string) (string, error)
+	Set(ctx context.Context, key string, value string, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+	Ping(ctx context.Context) error
+	Close() error
+}
+
+// Compile-time checks that every cache satisfies the interface
+var (
+	_ Cache = (*RedisCache)(nil)
+	_ Cache = (*MemoryCache)(nil)
+	_ Cache = Noop{}
+)
+
+// Noop is a cache that holds nothing, for running without Redis: every
+// Get misses and writes are dropped
+type Noop struct{}
+
+func (Noop) Get(ctx context.Context, key string) (string, error) { return "", nil }
+
+func (Noop) Set(ctx context.Context, key string, value string, ttl time.Duration) error { return nil }
+
+func (Noop) Delete(ctx context.Context, key string) error { return nil }
+
+// Ping reports ErrDisabled so readiness can show the degraded mode
+func (Noop) Ping(ctx context.Context) error { return ErrDisabled }
+
+func (Noop) Close() error { return nil }
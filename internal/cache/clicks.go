@@ -0,0 +1,69 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	pendingClicksSet    = "clicks:pending"
+	pendingClicksPrefix = "clicks:pending:"
+)
+
+// drainClicksScript atomically reads and clears every pending counter so a
+// click recorded mid-drain lands in the next flush rather than being lost.
+// Returns a flat {code, count, code, count, ...} list.
+var drainClicksScript = redis.NewScript(`
+local out = {}
+local codes = redis.call('SMEMBERS', KEYS[1])
+for _, code in ipairs(codes) do
+	local key = ARGV[1] .. code
+	local count = redis.call('GET', key)
+	redis.call('DEL', key)
+	redis.call('SREM', KEYS[1], code)
+	if count then
+		table.insert(out, code)
+		table.insert(out, count)
+	end
+end
+return out
+`)
+
+// IncrClicks buffers clicks for a code until the next drain.
+// It satisfies service.ClickBuffer.
+func (r *RedisCache) IncrClicks(ctx context.Context, shortCode string, delta uint64) error {
+	pipe := r.client.TxPipeline()
+	pipe.IncrBy(ctx, pendingClicksPrefix+shortCode, int64(delta))
+	pipe.SAdd(ctx, pendingClicksSet, shortCode)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// PendingClicks returns clicks buffered for a code but not yet flushed
+func (r *RedisCache) PendingClicks(ctx context.Context, shortCode string) (uint64, error) {
+	count, err := r.client.Get(ctx, pendingClicksPrefix+shortCode).Uint64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return count, err
+}
+
+// DrainClicks removes and returns all buffered click counts
+func (r *RedisCache) DrainClicks(ctx context.Context) (map[string]uint64, error) {
+	res, err := drainClicksScript.Run(ctx, r.client, []string{pendingClicksSet}, pendingClicksPrefix).StringSlice()
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	drained := make(map[string]uint64, len(res)/2)
+	for i := 0; i+1 < len(res); i += 2 {
+		count, err := strconv.ParseUint(res[i+1], 10, 64)
+		if err != nil {
+			continue
+		}
+		drained[res[i]] = count
+	}
+	return drained, nil
+}
@@ -0,0 +1,67 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryCache_TTLExpiry(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryCache(10)
+
+	m.Set(ctx, "short", "a", 20*time.Millisecond)
+	m.Set(ctx, "forever", "b", 0)
+	if v, _ := m.Get(ctx, "short"); v != "a" {
+		t.Fatalf("Expected a before expiry, got: %q", v)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+	if v, _ := m.Get(ctx, "short"); v != "" {
+		t.Errorf("Expected short expired, got: %q", v)
+	}
+	if v, _ := m.Get(ctx, "forever"); v != "b" {
+		t.Errorf("Expected a zero TTL kept, got: %q", v)
+	}
+	if n := m.Len(); n != 1 {
+		t.Errorf("Expected the expired entry dropped on read, got %d entries", n)
+	}
+
+	// Setting again restarts the TTL
+	m.Set(ctx, "short", "c", time.Hour)
+	if v, _ := m.Get(ctx, "short"); v != "c" {
+		t.Errorf("Expected c after resetting, got: %q", v)
+	}
+}
+
+func TestMemoryCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryCache(2)
+
+	m.Set(ctx, "a", "1", 0)
+	m.Set(ctx, "b", "2", 0)
+	m.Get(ctx, "a") // a is now more recent than b
+	m.Set(ctx, "c", "3", 0)
+
+	if v, _ := m.Get(ctx, "b"); v != "" {
+		t.Errorf("Expected b evicted as least recently used, got: %q", v)
+	}
+	for key, want := range map[string]string{"a": "1", "c": "3"} {
+		if v, _ := m.Get(ctx, key); v != want {
+			t.Errorf("Expected %s = %s kept, got: %q", key, want, v)
+		}
+	}
+
+	// Overwriting a key refreshes it rather than adding one
+	m.Set(ctx, "a", "4", 0)
+	m.Set(ctx, "d", "5", 0)
+	if n := m.Len(); n != 2 {
+		t.Errorf("Expected 2 entries, got: %d", n)
+	}
+	if v, _ := m.Get(ctx, "c"); v != "" {
+		t.Errorf("Expected c evicted after a was overwritten, got: %q", v)
+	}
+	if v, _ := m.Get(ctx, "a"); v != "4" {
+		t.Errorf("Expected the overwritten a kept, got: %q", v)
+	}
+}
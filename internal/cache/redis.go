@@ -32,6 +32,12 @@ func NewRedisCache(cfg *config.RedisConfig) (*RedisCache, error) {
 	return &RedisCache{client: client}, nil
 }
 
+// NewRedisCacheFromClient wraps an already configured client without
+// checking that Redis is reachable
+func NewRedisCacheFromClient(client *redis.Client) *RedisCache {
+	return &RedisCache{client: client}
+}
+
 func (r *RedisCache) Get(ctx context.Context, key string) (string, error) {
 	val, err := r.client.Get(ctx, key).Result()
 	if err == redis.Nil {
@@ -56,3 +62,19 @@ func (r *RedisCache) Close() error {
 func (r *RedisCache) Ping(ctx context.Context) error {
 	return r.client.Ping(ctx).Err()
 }
+
+// WarmUp sends conns concurrent PINGs, so the client's pool dials that
+// many connections before traffic arrives
+func (r *RedisCache) WarmUp(ctx context.Context, conns int) error {
+	errs := make(chan error, conns)
+	for i := 0; i < conns; i++ {
+		go func() { errs <- r.client.Ping(ctx).Err() }()
+	}
+	var first error
+	for i := 0; i < conns; i++ {
+		if err := <-errs; err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
@@ -0,0 +1,59 @@
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// tokenBucketScript atomically refills and takes one token from a bucket
+// stored as a hash of {tokens, last refill in ms}. A bucket is refilled
+// rate tokens per whole interval elapsed, capped at burst, and expires once
+// it would be full again so idle clients cost nothing.
+// Returns 1 if a token was taken, 0 otherwise.
+var tokenBucketScript = redis.NewScript(`
+local key      = KEYS[1]
+local now      = tonumber(ARGV[1])
+local rate     = tonumber(ARGV[2])
+local burst    = tonumber(ARGV[3])
+local interval = tonumber(ARGV[4])
+
+local bucket = redis.call('HMGET', key, 'tokens', 'last')
+local tokens = tonumber(bucket[1])
+local last   = tonumber(bucket[2])
+if tokens == nil or last == nil then
+	tokens = burst
+	last = now
+else
+	local refills = math.floor((now - last) / interval)
+	if refills > 0 then
+		tokens = math.min(tokens + refills * rate, burst)
+		last = last + refills * interval
+	end
+end
+
+local allowed = 0
+if tokens > 0 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call('HSET', key, 'tokens', tokens, 'last', last)
+redis.call('PEXPIRE', key, math.ceil(burst / math.max(rate, 1)) * interval + interval)
+return allowed
+`)
+
+// Take implements a token bucket shared by all instances.
+// It satisfies middleware.TokenStore.
+func (r *RedisCache) Take(ctx context.Context, key string, rate, burst int, interval time.Duration, now time.Time) (bool, error) {
+	allowed, err := tokenBucketScript.Run(ctx, r.client, []string{key},
+		now.UnixMilli(),
+		rate,
+		burst,
+		max(interval.Milliseconds(), 1),
+	).Int()
+	if err != nil {
+		return false, err
+	}
+	return allowed == 1, nil
+}
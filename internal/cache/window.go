@@ -0,0 +1,65 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// slidingWindowScript atomically trims, checks, and records n events in a
+// sorted set scored by millisecond timestamp. Events are recorded all
+// together or, when they don't all fit, not at all.
+// Returns {allowed (0/1), oldest event score}.
+var slidingWindowScript = redis.NewScript(`
+local key    = KEYS[1]
+local now    = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit  = tonumber(ARGV[3])
+local n      = tonumber(ARGV[5])
+
+redis.call('ZREMRANGEBYSCORE', key, '-inf', now - window)
+
+local allowed = 0
+if redis.call('ZCARD', key) + n <= limit then
+	for i = 1, n do
+		redis.call('ZADD', key, now, ARGV[4] .. '-' .. i)
+	end
+	allowed = 1
+end
+redis.call('PEXPIRE', key, window)
+
+local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+local oldestScore = now
+if oldest[2] then
+	oldestScore = tonumber(oldest[2])
+end
+return {allowed, oldestScore}
+`)
+
+// Allow implements a sliding-window counter shared by all instances
+func (r *RedisCache) Allow(ctx context.Context, key string, limit int, window time.Duration, now time.Time) (bool, time.Time, error) {
+	return r.AllowN(ctx, key, 1, limit, window, now)
+}
+
+// AllowN is Allow for n events at once. It satisfies middleware.WindowStore.
+func (r *RedisCache) AllowN(ctx context.Context, key string, n, limit int, window time.Duration, now time.Time) (bool, time.Time, error) {
+	res, err := slidingWindowScript.Run(ctx, r.client, []string{key},
+		now.UnixMilli(),
+		window.Milliseconds(),
+		limit,
+		fmt.Sprintf("%d-%s", now.UnixNano(), uuid.New().String()[:8]), // unique member prefix per call
+		n,
+	).Int64Slice()
+	if err != nil {
+		return false, time.Time{}, err
+	}
+	if len(res) != 2 {
+		return false, time.Time{}, fmt.Errorf("unexpected sliding window reply: %v", res)
+	}
+
+	resetAt := time.UnixMilli(res[1]).Add(window)
+	return res[0] == 1, resetAt, nil
+}
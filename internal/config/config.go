@@ -1,8 +1,12 @@
 package config
 
 import (
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"net/http"
+	"net/netip"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
@@ -11,27 +15,64 @@ import (
 
 // Config holds all application configuration
 type Config struct {
-	Server    ServerConfig
-	Database  DatabaseConfig
-	App       AppConfig
-	Log       LogConfig
-	RateLimit RateLimitConfig
-	Redis     RedisConfig
+	Server      ServerConfig
+	Database    DatabaseConfig
+	App         AppConfig
+	Log         LogConfig
+	RateLimit   RateLimitConfig
+	Redis       RedisConfig
+	Tracing     TracingConfig
+	Gzip        GzipConfig
+	Analytics   AnalyticsConfig
+	CreateLimit CreateLimitConfig
+	LinkLimit   LinkLimitConfig
+	Admin       AdminConfig
+	SignedLink  SignedLinkConfig
+	Metrics     MetricsConfig
+	Outbound    OutboundConfig
+	APIKeys     APIKeysConfig
+
+	DestinationCheck DestinationCheckConfig
+	Webhook          WebhookConfig
 }
 
 // ServerConfig holds HTTP server settings
 type ServerConfig struct {
 	Port            string
+	AdminPort       string // Serve admin routes, /metrics, and pprof here only; empty keeps them on Port
+	AdminAddr       string // Interface AdminPort binds to; loopback unless set
 	ReadTimeout     time.Duration
 	WriteTimeout    time.Duration
 	IdleTimeout     time.Duration
 	ShutdownTimeout time.Duration
+
+	// Longest a handler may run before the request is answered 503 and
+	// its context cancelled; zero disables the limit
+	RequestTimeout time.Duration
+
+	// Longest POST /shorten may take to send its body, well below
+	// ReadTimeout so slow senders give up their connection early; zero
+	// leaves only ReadTimeout
+	BodyReadTimeout time.Duration
+
+	// Open DB_MAX_IDLE_CONNS database and Redis connections at startup;
+	// /health reports starting until done or WarmUpTimeout passes
+	WarmUp        bool
+	WarmUpTimeout time.Duration
+
+	// Proxies (IPs or CIDRs) whose X-Forwarded-For and X-Real-IP headers
+	// name the client; from anyone else the connection address is used
+	TrustedProxies []string
 }
 
+// maxReplicaWeight bounds each replica weight, keeping one weighted
+// rotation short
+const maxReplicaWeight = 100
+
 // DatabaseConfig holds database settings
 type DatabaseConfig struct {
 	// Common settings
-	Driver       string // "postgres" or "sqlite3"
+	Driver       string // "postgres", "sqlite3", or "memory"
 	MaxOpenConns int
 	MaxIdleConns int
 	ReadTimeout  time.Duration
@@ -50,18 +91,132 @@ type DatabaseConfig struct {
 
 	// for Read replicas
 	ReplicaHosts []string // Replica hostnames
+
+	// Relative share of reads per replica, in ReplicaHosts order; empty
+	// splits reads evenly
+	ReplicaWeights []int
+
+	// How often replicas are pinged; failing ones get no reads until they
+	// answer again. Zero disables the checks.
+	ReplicaCheckInterval time.Duration
+
+	// Honor "X-Consistency: strong" to force reads to the primary
+	AllowConsistencyOverride bool
+
+	// Refuse all writes and skip schema setup. With replicas configured
+	// the primary is never contacted.
+	ReadOnly bool
+
+	// Check the schema on startup instead of creating or migrating it,
+	// for deployments that run migrations separately
+	VerifySchema bool
 }
 
 // AppConfig holds application-specific settings
 type AppConfig struct {
 	BaseURL     string
 	Environment string // "development", "production"
+
+	// Accept http:// base URLs in production, e.g. behind a TLS-terminating
+	// proxy that rewrites links; otherwise production requires https
+	AllowInsecureBaseURL bool
+
+	// Optional JSON file mapping legacy codes to current codes
+	LegacyCodesFile string
+
+	// Max path segments considered by the redirect catch-all
+	MaxPathDepth int
+
+	// Canonicalize IDN hosts and trailing dots before validation
+	NormalizeHosts bool
+
+	// Remove tracking query params (utm_*, fbclid, ...) from URLs on storage
+	StripTrackingParams bool
+	TrackingParams      []string // a trailing * matches a prefix
+
+	// Send X-Robots-Tag: noindex, nofollow on short-link responses
+	RobotsNoIndex bool
+
+	// Append a typo-detecting check character to generated codes
+	CodeChecksum bool
+
+	// Generated codes encode IDOffset + id*IDStride so they don't start at
+	// "0" and consecutive creates aren't adjacent. Changing these after
+	// launch doesn't break existing links, but old codes stop decoding.
+	IDOffset int
+	IDStride int
+
+	// Generated codes are left-padded to at least this many characters
+	CodeMinLength int
+
+	// Custom base62 alphabet for generated codes; empty uses the standard
+	// 0-9a-z-A-Z. Like the sequence, changing it strands old codes until
+	// they are migrated.
+	CodeAlphabet string
+
+	// Retry all-lower/all-upper variants of unknown generated codes
+	CaseFallback bool
+
+	// Echo the destination in the redirect body and a Link header
+	RedirectBody bool
+
+	// 301 or 302 for short-link redirects; defaults to 302 in development
+	// so repeat visits aren't served from the browser cache
+	RedirectStatus int
+
+	// Send Last-Modified on 301 redirects and answer If-Modified-Since with
+	// 304, so crawlers can revalidate without counting a click
+	ConditionalRedirects bool
+
+	// Short domain per region, e.g. "eu=https://eu.sho.rt,us=https://us.sho.rt".
+	// RegionHeader names the request header carrying the caller's region;
+	// DefaultRegion applies when it is missing or unknown, and BaseURL
+	// when that is empty too.
+	RegionBaseURLs map[string]string
+	RegionHeader   string
+	DefaultRegion  string
+
+	// Serve GET /api/decode/{code} (admin auth applies)
+	DecodeEndpoint bool
+
+	// Answer stats requests carrying ?callback= with JSONP
+	StatsJSONP bool
+
+	// Send X-Cache: HIT|MISS on redirects, for debugging the cache
+	CacheStatusHeader bool
+
+	// OwnerHeader names the request header, set by a trusted proxy, that
+	// identifies the account creating a link. With UniqueURLPerOwner an
+	// owner shortening the same URL twice gets their first code back.
+	OwnerHeader       string
+	UniqueURLPerOwner bool
+
+	// Word list (one per line) that custom aliases may not contain
+	AliasDenylistFile string
+
+	// Allow custom aliases with characters from UnicodeAliasRanges, hex
+	// code points and ranges like "1F300-1FAFF,FE0F"
+	UnicodeAliases     bool
+	UnicodeAliasRanges string
+
+	// Render HTML 404/500 pages for browsers; templates in ErrorPagesDir
+	// override the built-in ones
+	ErrorPages    bool
+	ErrorPagesDir string
 }
 
 type LogConfig struct {
 	Level       string
 	Format      string
 	Environment string
+	RedactURLs  string // "off", "query", "full"; see defaultRedactURLs
+
+	ValidationRejections bool // Log a reason-tagged entry per rejected URL
+
+	// Access log: "structured" logs requests with the app logger,
+	// "combined" writes NCSA combined lines to AccessFile (stdout if empty)
+	AccessFormat string
+	AccessFile   string
 }
 
 type RateLimitConfig struct {
@@ -70,6 +225,41 @@ type RateLimitConfig struct {
 	Burst    int           // Max burst
 	Interval time.Duration // Refill interval
 	Cleanup  time.Duration // Cleanup interval
+	Backend  string        // "memory" (per instance) or "redis" (shared)
+
+	// Per-path-prefix overrides from RATE_LIMIT_ROUTES, e.g.
+	// "/shorten=2:5,/api/=5:10" (prefix=rate:burst); longest prefix wins
+	Routes []RouteRateLimit
+
+	RetryAfterFormat string // "seconds" or "http-date"
+}
+
+// defaultRateLimits is the per-IP RATE_LIMIT_RATE for each environment
+// when unset. Production serves real traffic, often several users behind
+// one NAT address, so it starts much higher.
+var defaultRateLimits = map[string]int{
+	"development": 10,
+	"testing":     10,
+	"production":  100,
+}
+
+// defaultRedactURLs is LOG_REDACT_URLS for each environment when unset.
+// Query strings often carry tokens and personal data, so only
+// development logs them by default.
+var defaultRedactURLs = map[string]string{
+	"development": "off",
+	"testing":     "query",
+	"production":  "query",
+}
+
+// defaultBurstFactor scales RATE_LIMIT_BURST from the rate when unset
+const defaultBurstFactor = 2
+
+// RouteRateLimit is one RATE_LIMIT_ROUTES entry
+type RouteRateLimit struct {
+	PathPrefix string
+	Rate       int
+	Burst      int
 }
 
 type RedisConfig struct {
@@ -77,17 +267,188 @@ type RedisConfig struct {
 	Port     string
 	Password string
 	DB       int
+	CacheTTL time.Duration // How long a resolved link stays cached
+
+	// Each cached link's TTL is moved randomly by up to this fraction
+	// either way, so hot links cached together don't expire together
+	CacheJitter float64
+
+	// "redis" (shared) or "memory" (per instance, no Redis at all)
+	CacheBackend string
+	CacheSize    int // Max links held by the memory backend
+
+	// How long an unknown code is cached as not found; zero disables
+	NegativeTTL time.Duration
+}
+
+type TracingConfig struct {
+	Enabled bool // Propagate W3C traceparent/tracestate headers
+
+	// Reuse valid incoming X-Request-ID values up to RequestIDMaxLength
+	// characters; others are replaced with a generated ID
+	TrustRequestID     bool
+	RequestIDMaxLength int
+}
+
+type GzipConfig struct {
+	Enabled      bool
+	MinSize      int      // Minimum body size in bytes before compressing
+	ContentTypes []string // Compressible media types (never add image/png)
+}
+
+type CreateLimitConfig struct {
+	Enabled bool
+	Limit   int           // Links per IP per window
+	Window  time.Duration // Rolling window
+}
+
+type LinkLimitConfig struct {
+	Enabled bool
+	Limit   int           // Redirects per short code per window
+	Window  time.Duration // Sliding window
+}
+
+type MetricsConfig struct {
+	Enabled bool // Serve Prometheus-format counters on /metrics
+
+	// Per-route response time percentiles over the last LatencySamples
+	// requests, on /admin/latency and /metrics
+	Latency            bool
+	LatencySamples     int
+	LatencyPercentiles []float64
+
+	// Per-link click gauges on /admin/metrics/links, for the LinkMax
+	// busiest links with at least LinkMinClicks clicks
+	Links         bool
+	LinkMax       int
+	LinkMinClicks int
+}
+
+// APIKeysConfig holds the keys that authenticate link creation
+type APIKeysConfig struct {
+	// Keys required to create links, mapped to the owner each identifies;
+	// from API_KEYS="key=owner,key=owner". Empty leaves creation open.
+	Keys map[string]string
+}
+
+// DestinationCheckConfig controls background probing of link destinations
+type DestinationCheckConfig struct {
+	// Show a warning page instead of redirecting to destinations that
+	// failed their last probe
+	Enabled bool
+	TTL     time.Duration // how long a probe result is trusted
+}
+
+// WebhookConfig controls click webhooks
+type WebhookConfig struct {
+	URL       string // endpoint that receives a POST per click of links without their own
+	Secret    string // HMAC-SHA256 key for the X-Signature header; empty disables webhooks
+	QueueSize int    // events waiting for delivery; more are dropped
+}
+
+// OutboundConfig applies to the HTTP client shared by outbound calls
+// (URL checks, webhooks)
+type OutboundConfig struct {
+	Timeout       time.Duration // Upper bound on a single outbound request
+	TLSMinVersion string        // "1.2" or "1.3"
+}
+
+// outboundTLSVersions maps TLSMinVersion values to crypto/tls constants
+var outboundTLSVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// MinTLSVersion returns TLSMinVersion as a crypto/tls constant, or 0 if
+// it is not a supported version
+func (o *OutboundConfig) MinTLSVersion() uint16 {
+	return outboundTLSVersions[o.TLSMinVersion]
+}
+
+// TrustedProxyPrefixes parses TrustedProxies, reading a bare IP as a
+// single-address prefix
+func (s *ServerConfig) TrustedProxyPrefixes() ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(s.TrustedProxies))
+	for _, entry := range s.TrustedProxies {
+		if addr, err := netip.ParseAddr(entry); err == nil {
+			addr = addr.Unmap()
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TRUSTED_PROXIES entry: %s", entry)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+type SignedLinkConfig struct {
+	Secret string        // HMAC key for signed links; empty disables them
+	TTL    time.Duration // How long a signed link stays valid
+}
+
+type AdminConfig struct {
+	Token         string // Bearer token for /admin/ endpoints; empty leaves them open
+	TagStatsLimit int    // Max tags returned by /admin/stats/by-tag
+	AliasesLimit  int    // Max taken aliases per /admin/aliases page
+	RecentLimit   int    // Max links returned by /admin/recent
+}
+
+type AnalyticsConfig struct {
+	ClickEvents       bool // Store a detailed row per click
+	ClickEventQueue   int  // Rows waiting for the background writer; more are dropped, 0 writes inline
+	HonorDNT          bool // Skip detailed rows for DNT: 1 requests
+	DNTCountAggregate bool // Still bump click_count for DNT requests
+	StoreClientIP     bool // Keep the visitor's IP on click rows
+
+	ClickWriteBehind   bool          // Buffer click counts in Redis
+	ClickFlushInterval time.Duration // How often buffered counts reach the DB
+	ClickFlushBatch    int           // Codes updated per flush transaction
+	MaxClickRows       int           // Click rows kept per code; 0 keeps all
+
+	ClickRetry            bool          // Retry failed click increments in the background
+	ClickRetryQueueSize   int           // Failed increments waiting at once; more are dropped
+	ClickRetryMaxAttempts int           // Attempts per increment, including the first
+	ClickRetryBackoff     time.Duration // Wait before each retry
+
+	// Click increment per resolve: APIClickStep for POST /api/resolve,
+	// or the value of ClickStepHeader (up to MaxClickStep) when a trusted
+	// proxy sets it. Redirects count 1 otherwise.
+	APIClickStep    int
+	ClickStepHeader string
+	MaxClickStep    int
 }
 
 // Load reads configuration from environment variables
 func Load() (*Config, error) {
+	environment := getEnv("ENVIRONMENT", "development")
+	// An unknown environment is reported by Validate; don't let it zero the rate first
+	defaultRate, ok := defaultRateLimits[environment]
+	if !ok {
+		defaultRate = defaultRateLimits["development"]
+	}
+	defaultRedact, ok := defaultRedactURLs[environment]
+	if !ok {
+		defaultRedact = "query"
+	}
+	rateLimit := getIntEnv("RATE_LIMIT_RATE", defaultRate)
+
 	cfg := &Config{
 		Server: ServerConfig{
 			Port:            getEnv("PORT", "8080"),
+			AdminPort:       getEnv("ADMIN_PORT", ""),
+			AdminAddr:       getEnv("ADMIN_ADDR", "127.0.0.1"),
 			ReadTimeout:     getDurationEnv("SERVER_READ_TIMEOUT", 15*time.Second),
 			WriteTimeout:    getDurationEnv("SERVER_WRITE_TIMEOUT", 15*time.Second),
 			IdleTimeout:     getDurationEnv("SERVER_IDLE_TIMEOUT", 60*time.Second),
 			ShutdownTimeout: getDurationEnv("SERVER_SHUTDOWN_TIMEOUT", 30*time.Second),
+			BodyReadTimeout: getDurationEnv("SERVER_BODY_READ_TIMEOUT", 5*time.Second),
+			RequestTimeout:  getDurationEnv("REQUEST_TIMEOUT", 10*time.Second),
+			WarmUp:          getBoolEnv("WARMUP_ENABLED", false),
+			WarmUpTimeout:   getDurationEnv("WARMUP_TIMEOUT", 10*time.Second),
+			TrustedProxies:  getSliceEnv("TRUSTED_PROXIES", nil),
 		},
 		Database: DatabaseConfig{
 			Driver:       getEnv("DB_DRIVER", "postgres"), // Default to PostgreSQL
@@ -108,29 +469,169 @@ func Load() (*Config, error) {
 			SSLMode:  getEnv("DB_SSLMODE", "disable"),
 
 			// Read replicas
-			ReplicaHosts: getSliceEnv("DB_REPLICA_HOSTS", []string{}),
+			ReplicaHosts:   getSliceEnv("DB_REPLICA_HOSTS", []string{}),
+			ReplicaWeights: getIntSliceEnv("DB_REPLICA_WEIGHTS"),
+
+			ReplicaCheckInterval: getDurationEnv("DB_REPLICA_CHECK_INTERVAL", 5*time.Second),
+
+			AllowConsistencyOverride: getBoolEnv("DB_ALLOW_CONSISTENCY_OVERRIDE", false),
+			ReadOnly:                 getBoolEnv("READ_ONLY", false),
+			VerifySchema:             getBoolEnv("DB_VERIFY_SCHEMA", false),
 		},
 		App: AppConfig{
 			BaseURL:     getEnv("BASE_URL", ""),
-			Environment: getEnv("ENVIRONMENT", "development"),
+			Environment: environment,
+
+			AllowInsecureBaseURL: getBoolEnv("ALLOW_INSECURE_BASE_URL", false),
+
+			LegacyCodesFile:      getEnv("LEGACY_CODES_FILE", ""),
+			MaxPathDepth:         getIntEnv("MAX_PATH_DEPTH", 2),
+			NormalizeHosts:       getBoolEnv("NORMALIZE_HOSTS", true),
+			StripTrackingParams:  getBoolEnv("STRIP_TRACKING_PARAMS", false),
+			TrackingParams:       getSliceEnv("TRACKING_PARAMS", []string{"utm_*", "fbclid", "gclid"}),
+			RobotsNoIndex:        getBoolEnv("ROBOTS_NOINDEX", true),
+			CodeChecksum:         getBoolEnv("CODE_CHECKSUM_ENABLED", false),
+			IDOffset:             getIntEnv("ID_OFFSET", 0),
+			IDStride:             getIntEnv("ID_STRIDE", 1),
+			CodeAlphabet:         getEnv("CODE_ALPHABET", ""),
+			CodeMinLength:        getIntEnv("CODE_MIN_LENGTH", 6),
+			CaseFallback:         getBoolEnv("CODE_CASE_FALLBACK", false),
+			RedirectBody:         getBoolEnv("REDIRECT_BODY", false),
+			RedirectStatus:       getIntEnv("REDIRECT_STATUS", 0),
+			ConditionalRedirects: getBoolEnv("REDIRECT_CONDITIONAL_GET", false),
+			ErrorPages:           getBoolEnv("ERROR_PAGES_ENABLED", false),
+			ErrorPagesDir:        getEnv("ERROR_PAGES_DIR", ""),
+
+			AliasDenylistFile: getEnv("ALIAS_DENYLIST_FILE", ""),
+			UnicodeAliases:    getBoolEnv("UNICODE_ALIASES_ENABLED", false),
+			// Emoji blocks plus the variation selector and joiner in emoji sequences
+			UnicodeAliasRanges: getEnv("UNICODE_ALIAS_RANGES", "1F300-1FAFF,2600-27BF,FE0F,200D"),
+			DecodeEndpoint:     getBoolEnv("DECODE_ENDPOINT_ENABLED", false),
+			StatsJSONP:         getBoolEnv("STATS_JSONP_ENABLED", false),
+			CacheStatusHeader:  getBoolEnv("CACHE_STATUS_HEADER", false),
+			RegionBaseURLs:     getMapEnv("REGION_BASE_URLS"),
+			RegionHeader:       getEnv("REGION_HEADER", "X-Region"),
+			DefaultRegion:      strings.ToLower(getEnv("APP_REGION", "")),
+			OwnerHeader:        getEnv("OWNER_HEADER", ""),
+			UniqueURLPerOwner:  getBoolEnv("UNIQUE_URL_PER_OWNER", false),
 		},
 		Log: LogConfig{
 			Level:       getEnv("LOG_LEVEL", "info"),
 			Format:      getEnv("LOG_FORMAT", "text"),
-			Environment: getEnv("ENVIRONMENT", "development"),
+			Environment: environment,
+			RedactURLs:  getEnv("LOG_REDACT_URLS", defaultRedact),
+
+			ValidationRejections: getBoolEnv("LOG_VALIDATION_REJECTIONS", false),
+			AccessFormat:         getEnv("ACCESS_LOG_FORMAT", "structured"),
+			AccessFile:           getEnv("ACCESS_LOG_FILE", ""),
 		},
 		RateLimit: RateLimitConfig{
 			Enabled:  getBoolEnv("RATE_LIMIT_ENABLED", true),
-			Rate:     getIntEnv("RATE_LIMIT_RATE", 10),
-			Burst:    getIntEnv("RATE_LIMIT_BURST", 20),
+			Rate:     rateLimit,
+			Burst:    getIntEnv("RATE_LIMIT_BURST", rateLimit*defaultBurstFactor),
 			Interval: getDurationEnv("RATE_LIMIT_INTERVAL", time.Second),
 			Cleanup:  getDurationEnv("RATE_LIMIT_CLEANUP", 5*time.Minute),
+			Backend:  getEnv("RATE_LIMIT_BACKEND", "memory"),
+			Routes:   getRouteRateLimitsEnv("RATE_LIMIT_ROUTES"),
+
+			RetryAfterFormat: getEnv("RETRY_AFTER_FORMAT", "seconds"),
 		},
 		Redis: RedisConfig{
 			Host:     getEnv("REDIS_HOST", "localhost"),
 			Port:     getEnv("REDIS_PORT", "6379"),
 			Password: getEnv("REDIS_PASSWORD", ""),
 			DB:       getIntEnv("REDIS_DB", 0),
+			CacheTTL: getDurationEnv("REDIS_CACHE_TTL", 24*time.Hour),
+
+			CacheJitter: getFloatEnv("REDIS_CACHE_JITTER", 0.1),
+
+			CacheBackend: getEnv("CACHE_BACKEND", "redis"),
+			CacheSize:    getIntEnv("CACHE_MEMORY_SIZE", 10000),
+
+			NegativeTTL: getDurationEnv("REDIS_NEGATIVE_TTL", 30*time.Second),
+		},
+		Tracing: TracingConfig{
+			Enabled: getBoolEnv("TRACE_CONTEXT_ENABLED", false),
+
+			TrustRequestID:     getBoolEnv("REQUEST_ID_TRUST_INCOMING", true),
+			RequestIDMaxLength: getIntEnv("REQUEST_ID_MAX_LENGTH", 64),
+		},
+		CreateLimit: CreateLimitConfig{
+			Enabled: getBoolEnv("CREATE_LIMIT_ENABLED", false),
+			Limit:   getIntEnv("CREATE_LIMIT_MAX", 100),
+			Window:  getDurationEnv("CREATE_LIMIT_WINDOW", time.Hour),
+		},
+		LinkLimit: LinkLimitConfig{
+			Enabled: getBoolEnv("LINK_LIMIT_ENABLED", false),
+			Limit:   getIntEnv("LINK_LIMIT_MAX", 1000),
+			Window:  getDurationEnv("LINK_LIMIT_WINDOW", time.Minute),
+		},
+		Admin: AdminConfig{
+			Token:         getEnv("ADMIN_TOKEN", ""),
+			TagStatsLimit: getIntEnv("ADMIN_TAG_STATS_LIMIT", 100),
+			AliasesLimit:  getIntEnv("ADMIN_ALIASES_LIMIT", 100),
+			RecentLimit:   getIntEnv("ADMIN_RECENT_LIMIT", 100),
+		},
+		Metrics: MetricsConfig{
+			Enabled: getBoolEnv("METRICS_ENABLED", false),
+
+			Latency:            getBoolEnv("LATENCY_PERCENTILES_ENABLED", false),
+			LatencySamples:     getIntEnv("LATENCY_SAMPLES", 1024),
+			LatencyPercentiles: getFloatSliceEnv("LATENCY_PERCENTILES", []float64{50, 95, 99}),
+
+			Links:         getBoolEnv("LINK_METRICS_ENABLED", false),
+			LinkMax:       getIntEnv("LINK_METRICS_MAX", 100),
+			LinkMinClicks: getIntEnv("LINK_METRICS_MIN_CLICKS", 100),
+		},
+		Outbound: OutboundConfig{
+			Timeout:       getDurationEnv("OUTBOUND_TIMEOUT", 10*time.Second),
+			TLSMinVersion: getEnv("OUTBOUND_TLS_MIN_VERSION", "1.2"),
+		},
+		APIKeys: APIKeysConfig{
+			Keys: getAPIKeysEnv("API_KEYS"),
+		},
+		DestinationCheck: DestinationCheckConfig{
+			Enabled: getBoolEnv("DESTINATION_CHECK_ENABLED", false),
+			TTL:     getDurationEnv("DESTINATION_CHECK_TTL", time.Hour),
+		},
+		Webhook: WebhookConfig{
+			URL:       getEnv("WEBHOOK_URL", ""),
+			Secret:    getEnv("WEBHOOK_SECRET", ""),
+			QueueSize: getIntEnv("WEBHOOK_QUEUE_SIZE", 1000),
+		},
+		SignedLink: SignedLinkConfig{
+			Secret: getEnv("SIGNED_LINK_SECRET", ""),
+			TTL:    getDurationEnv("SIGNED_LINK_TTL", time.Hour),
+		},
+		Analytics: AnalyticsConfig{
+			ClickEvents:       getBoolEnv("ANALYTICS_CLICK_EVENTS", false),
+			ClickEventQueue:   getIntEnv("ANALYTICS_CLICK_EVENT_QUEUE", 1000),
+			HonorDNT:          getBoolEnv("ANALYTICS_HONOR_DNT", true),
+			DNTCountAggregate: getBoolEnv("ANALYTICS_DNT_COUNT_AGGREGATE", true),
+			StoreClientIP:     getBoolEnv("ANALYTICS_STORE_CLIENT_IP", true),
+
+			ClickWriteBehind:   getBoolEnv("CLICK_WRITE_BEHIND", false),
+			ClickFlushInterval: getDurationEnv("CLICK_FLUSH_INTERVAL", 10*time.Second),
+			ClickFlushBatch:    getIntEnv("CLICK_FLUSH_BATCH_SIZE", 500),
+			MaxClickRows:       getIntEnv("ANALYTICS_MAX_CLICK_ROWS", 0),
+
+			ClickRetry:            getBoolEnv("CLICK_RETRY_ENABLED", false),
+			ClickRetryQueueSize:   getIntEnv("CLICK_RETRY_QUEUE_SIZE", 1000),
+			ClickRetryMaxAttempts: getIntEnv("CLICK_RETRY_MAX_ATTEMPTS", 5),
+			ClickRetryBackoff:     getDurationEnv("CLICK_RETRY_BACKOFF", time.Second),
+
+			APIClickStep:    getIntEnv("API_CLICK_STEP", 1),
+			ClickStepHeader: getEnv("CLICK_STEP_HEADER", ""),
+			MaxClickStep:    getIntEnv("MAX_CLICK_STEP", 100),
+		},
+		Gzip: GzipConfig{
+			Enabled: getBoolEnv("GZIP_ENABLED", false),
+			MinSize: getIntEnv("GZIP_MIN_SIZE", 1024),
+			ContentTypes: getSliceEnv("GZIP_CONTENT_TYPES", []string{
+				"application/json",
+				"text/html",
+				"image/svg+xml",
+			}),
 		},
 	}
 
@@ -138,6 +639,12 @@ func Load() (*Config, error) {
 	if cfg.App.BaseURL == "" {
 		cfg.App.BaseURL = fmt.Sprintf("http://localhost:%s", cfg.Server.Port)
 	}
+	if cfg.App.RedirectStatus == 0 {
+		cfg.App.RedirectStatus = http.StatusMovedPermanently
+		if cfg.IsDevelopment() {
+			cfg.App.RedirectStatus = http.StatusFound
+		}
+	}
 
 	// Validate configuration
 	if err := cfg.Validate(); err != nil {
@@ -157,17 +664,222 @@ func (d *DatabaseConfig) BuildPostgresConnectionString(host string) string {
 
 // Validate checks if the configuration is valid
 func (c *Config) Validate() error {
+	requireHTTPS := c.IsProduction() && !c.App.AllowInsecureBaseURL
+	if parsed, err := url.Parse(c.App.BaseURL); err == nil && requireHTTPS && parsed.Scheme != "https" {
+		return fmt.Errorf("BASE_URL %q must use https in production (set ALLOW_INSECURE_BASE_URL to override)", c.App.BaseURL)
+	}
+	for region, base := range c.App.RegionBaseURLs {
+		parsed, err := url.Parse(base)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("invalid base URL for region %s: %q", region, base)
+		}
+		if requireHTTPS && parsed.Scheme != "https" {
+			return fmt.Errorf("base URL for region %s %q must use https in production", region, base)
+		}
+	}
+	if c.App.DefaultRegion != "" {
+		if _, ok := c.App.RegionBaseURLs[c.App.DefaultRegion]; !ok {
+			return fmt.Errorf("APP_REGION %q has no entry in REGION_BASE_URLS", c.App.DefaultRegion)
+		}
+	}
+	if c.Metrics.Latency {
+		if c.Metrics.LatencySamples < 1 {
+			return fmt.Errorf("invalid LATENCY_SAMPLES: %d (must be >= 1)", c.Metrics.LatencySamples)
+		}
+		if len(c.Metrics.LatencyPercentiles) == 0 {
+			return errors.New("LATENCY_PERCENTILES must list at least one percentile")
+		}
+		for _, p := range c.Metrics.LatencyPercentiles {
+			if p <= 0 || p >= 100 {
+				return fmt.Errorf("invalid latency percentile: %g (must be between 0 and 100)", p)
+			}
+		}
+	}
+	if c.Metrics.Links && (c.Metrics.LinkMax < 1 || c.Metrics.LinkMinClicks < 0) {
+		return fmt.Errorf("invalid link metrics: max %d, min clicks %d (max must be >= 1, min clicks >= 0)", c.Metrics.LinkMax, c.Metrics.LinkMinClicks)
+	}
+	if c.App.CodeMinLength < 0 || c.App.CodeMinLength > 11 {
+		return fmt.Errorf("invalid CODE_MIN_LENGTH: %d (must be 0-11)", c.App.CodeMinLength)
+	}
+	for key, owner := range c.APIKeys.Keys {
+		if key == "" || owner == "" {
+			return errors.New("invalid API_KEYS entry: want key=owner")
+		}
+	}
+
+	// Admin routes delete and rewrite links; only a local development
+	// server may leave them open
+	if c.Admin.Token == "" && c.App.Environment != "development" {
+		return fmt.Errorf("ADMIN_TOKEN is required in %s", c.App.Environment)
+	}
+
+	if c.App.UniqueURLPerOwner && c.App.OwnerHeader == "" && len(c.APIKeys.Keys) == 0 {
+		return errors.New("UNIQUE_URL_PER_OWNER requires OWNER_HEADER or API_KEYS")
+	}
 	// Validate port
 	port, err := strconv.Atoi(c.Server.Port)
 	if err != nil || port < 1 || port > 65535 {
 		return fmt.Errorf("invalid port: %s (must be 1-65535)", c.Server.Port)
 	}
+	if c.Server.AdminPort != "" {
+		adminPort, err := strconv.Atoi(c.Server.AdminPort)
+		if err != nil || adminPort < 1 || adminPort > 65535 {
+			return fmt.Errorf("invalid admin port: %s (must be 1-65535)", c.Server.AdminPort)
+		}
+		if adminPort == port {
+			return fmt.Errorf("ADMIN_PORT must differ from PORT (%d)", port)
+		}
+	}
+
+	if n := len(c.Database.ReplicaWeights); n > 0 {
+		if n != len(c.Database.ReplicaHosts) {
+			return fmt.Errorf("DB_REPLICA_WEIGHTS has %d entries for %d replica hosts", n, len(c.Database.ReplicaHosts))
+		}
+		for i, w := range c.Database.ReplicaWeights {
+			if w < 1 || w > maxReplicaWeight {
+				return fmt.Errorf("invalid weight for replica %d: %d (must be 1-%d)", i, w, maxReplicaWeight)
+			}
+		}
+	}
+
+	if c.Database.ReplicaCheckInterval < 0 {
+		return fmt.Errorf("invalid replica check interval: %s", c.Database.ReplicaCheckInterval)
+	}
 
 	// Validate database path
 	if c.Database.Path == "" {
 		return errors.New("database path cannot be empty")
 	}
 
+	if c.App.RedirectStatus != http.StatusMovedPermanently && c.App.RedirectStatus != http.StatusFound {
+		return fmt.Errorf("invalid redirect status: %d (must be 301 or 302)", c.App.RedirectStatus)
+	}
+
+	if c.App.MaxPathDepth < 1 {
+		return fmt.Errorf("invalid max path depth: %d (must be at least 1)", c.App.MaxPathDepth)
+	}
+
+	if c.Redis.CacheTTL <= 0 {
+		return fmt.Errorf("invalid Redis cache TTL: %s", c.Redis.CacheTTL)
+	}
+	if c.Redis.CacheJitter < 0 || c.Redis.CacheJitter >= 1 {
+		return fmt.Errorf("invalid Redis cache jitter: %g (must be >= 0 and < 1)", c.Redis.CacheJitter)
+	}
+	if c.Redis.NegativeTTL < 0 {
+		return fmt.Errorf("invalid Redis negative cache TTL: %s", c.Redis.NegativeTTL)
+	}
+	if c.SignedLink.Secret != "" && c.SignedLink.TTL <= 0 {
+		return fmt.Errorf("invalid signed link TTL: %s", c.SignedLink.TTL)
+	}
+	if c.Admin.TagStatsLimit < 1 {
+		return fmt.Errorf("invalid tag stats limit: %d", c.Admin.TagStatsLimit)
+	}
+
+	if c.Admin.AliasesLimit < 1 {
+		return fmt.Errorf("invalid aliases page limit: %d", c.Admin.AliasesLimit)
+	}
+
+	if c.Admin.RecentLimit < 1 {
+		return fmt.Errorf("invalid recent links limit: %d", c.Admin.RecentLimit)
+	}
+	if c.LinkLimit.Enabled && (c.LinkLimit.Limit < 1 || c.LinkLimit.Window <= 0) {
+		return fmt.Errorf("invalid link limit: %d per %s", c.LinkLimit.Limit, c.LinkLimit.Window)
+	}
+
+	if c.App.IDOffset < 0 || c.App.IDStride < 1 {
+		return fmt.Errorf("invalid ID sequence: offset %d, stride %d (offset must be >= 0, stride >= 1)", c.App.IDOffset, c.App.IDStride)
+	}
+
+	if c.CreateLimit.Enabled && (c.CreateLimit.Limit < 1 || c.CreateLimit.Window <= 0) {
+		return fmt.Errorf("invalid create limit: %d per %s", c.CreateLimit.Limit, c.CreateLimit.Window)
+	}
+
+	if c.Analytics.ClickRetry && (c.Analytics.ClickRetryQueueSize < 1 || c.Analytics.ClickRetryMaxAttempts < 2 || c.Analytics.ClickRetryBackoff <= 0) {
+		return fmt.Errorf("invalid click retry settings: queue %d, attempts %d, backoff %s",
+			c.Analytics.ClickRetryQueueSize, c.Analytics.ClickRetryMaxAttempts, c.Analytics.ClickRetryBackoff)
+	}
+	if c.Analytics.MaxClickRows < 0 {
+		return fmt.Errorf("invalid max click rows: %d (must be >= 0)", c.Analytics.MaxClickRows)
+	}
+
+	if (c.Analytics.ClickWriteBehind || c.Analytics.MaxClickRows > 0) && c.Analytics.ClickFlushInterval <= 0 {
+		return fmt.Errorf("invalid click flush interval: %s (must be positive)", c.Analytics.ClickFlushInterval)
+	}
+	if c.Analytics.MaxClickStep < 1 || c.Analytics.APIClickStep < 1 || c.Analytics.APIClickStep > c.Analytics.MaxClickStep {
+		return fmt.Errorf("invalid click step: %d (must be between 1 and max click step %d)", c.Analytics.APIClickStep, c.Analytics.MaxClickStep)
+	}
+
+	if c.Analytics.ClickEventQueue < 0 {
+		return fmt.Errorf("invalid click event queue size: %d (must be >= 0)", c.Analytics.ClickEventQueue)
+	}
+	if c.Server.RequestTimeout < 0 {
+		return fmt.Errorf("invalid request timeout: %s", c.Server.RequestTimeout)
+	}
+	// The 503 has to go out before the server gives up on the write
+	if c.Server.RequestTimeout > 0 && c.Server.WriteTimeout > 0 && c.Server.RequestTimeout >= c.Server.WriteTimeout {
+		return fmt.Errorf("REQUEST_TIMEOUT (%s) must be shorter than SERVER_WRITE_TIMEOUT (%s)", c.Server.RequestTimeout, c.Server.WriteTimeout)
+	}
+	if c.Server.BodyReadTimeout < 0 {
+		return fmt.Errorf("invalid body read timeout: %s", c.Server.BodyReadTimeout)
+	}
+	if c.Server.WarmUp && c.Server.WarmUpTimeout <= 0 {
+		return fmt.Errorf("invalid warm-up timeout: %s", c.Server.WarmUpTimeout)
+	}
+
+	if c.Analytics.ClickWriteBehind && c.Analytics.ClickFlushBatch <= 0 {
+		return fmt.Errorf("invalid click flush batch size: %d (must be positive)", c.Analytics.ClickFlushBatch)
+	}
+
+	if c.Tracing.RequestIDMaxLength < 1 {
+		return fmt.Errorf("invalid request ID max length: %d (must be positive)", c.Tracing.RequestIDMaxLength)
+	}
+
+	if c.Gzip.MinSize < 0 {
+		return fmt.Errorf("invalid gzip min size: %d (must be >= 0)", c.Gzip.MinSize)
+	}
+
+	if c.Redis.CacheBackend != "memory" && c.Redis.CacheBackend != "redis" {
+		return fmt.Errorf("invalid cache backend: %s (must be memory or redis)", c.Redis.CacheBackend)
+	}
+	if c.Redis.CacheBackend == "memory" && c.Redis.CacheSize < 1 {
+		return fmt.Errorf("invalid memory cache size: %d", c.Redis.CacheSize)
+	}
+	// The memory backend never connects to Redis, so these would silently
+	// do nothing. The create and per-link limits count per instance instead.
+	if c.Redis.CacheBackend == "memory" && c.Analytics.ClickWriteBehind {
+		return fmt.Errorf("CLICK_WRITE_BEHIND requires CACHE_BACKEND=redis")
+	}
+	if c.Redis.CacheBackend == "memory" && c.RateLimit.Enabled && c.RateLimit.Backend == "redis" {
+		return fmt.Errorf("RATE_LIMIT_BACKEND=redis requires CACHE_BACKEND=redis")
+	}
+
+	if c.RateLimit.Backend != "memory" && c.RateLimit.Backend != "redis" {
+		return fmt.Errorf("invalid rate limit backend: %s (must be memory or redis)", c.RateLimit.Backend)
+	}
+
+	// Limits are only checked when they apply, so RATE_LIMIT_ENABLED=false
+	// works whatever the rest of RATE_LIMIT_* says
+	if c.RateLimit.Enabled {
+		if c.RateLimit.Rate < 1 {
+			return fmt.Errorf("invalid rate limit rate: %d (must be positive)", c.RateLimit.Rate)
+		}
+		// The bucket holds at most burst tokens, so a smaller burst would
+		// silently cap the rate
+		if c.RateLimit.Burst < c.RateLimit.Rate {
+			return fmt.Errorf("invalid rate limit burst: %d (must be at least the rate, %d)", c.RateLimit.Burst, c.RateLimit.Rate)
+		}
+
+		for _, route := range c.RateLimit.Routes {
+			if !strings.HasPrefix(route.PathPrefix, "/") || route.Rate < 1 || route.Burst < route.Rate {
+				return fmt.Errorf("invalid rate limit route %q: want /prefix=rate:burst with positive rate and burst at least the rate", route.PathPrefix)
+			}
+		}
+	}
+
+	if c.RateLimit.RetryAfterFormat != "seconds" && c.RateLimit.RetryAfterFormat != "http-date" {
+		return fmt.Errorf("invalid retry-after format: %s (must be seconds or http-date)", c.RateLimit.RetryAfterFormat)
+	}
+
 	// Validate environment
 	validEnvs := map[string]bool{
 		"development": true,
@@ -188,6 +900,48 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("invalid log level: %s", c.Log.Level)
 	}
 
+	// Validate URL redaction mode
+	validRedact := map[string]bool{
+		"off":   true,
+		"query": true,
+		"full":  true,
+	}
+	if !validRedact[c.Log.RedactURLs] {
+		return fmt.Errorf("invalid log URL redaction: %s (must be off, query, or full)", c.Log.RedactURLs)
+	}
+
+	if c.Log.AccessFormat != "structured" && c.Log.AccessFormat != "combined" {
+		return fmt.Errorf("invalid access log format: %s (must be structured or combined)", c.Log.AccessFormat)
+	}
+
+	if _, err := c.Server.TrustedProxyPrefixes(); err != nil {
+		return err
+	}
+	if c.Outbound.MinTLSVersion() == 0 {
+		return fmt.Errorf("invalid outbound TLS minimum version: %s (must be 1.2 or 1.3)", c.Outbound.TLSMinVersion)
+	}
+	// Unsigned deliveries couldn't be told apart from forgeries
+	if c.Webhook.URL != "" && c.Webhook.Secret == "" {
+		return errors.New("WEBHOOK_URL requires WEBHOOK_SECRET")
+	}
+	if c.Webhook.URL != "" {
+		if u, err := url.Parse(c.Webhook.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid WEBHOOK_URL: %s", c.Webhook.URL)
+		}
+	}
+	if c.Webhook.Secret != "" {
+		if c.Webhook.QueueSize < 1 {
+			return fmt.Errorf("invalid webhook queue size: %d", c.Webhook.QueueSize)
+		}
+	}
+	if c.DestinationCheck.Enabled && c.DestinationCheck.TTL <= 0 {
+		return fmt.Errorf("invalid destination check TTL: %s", c.DestinationCheck.TTL)
+	}
+
+	if c.Outbound.Timeout <= 0 {
+		return fmt.Errorf("invalid outbound timeout: %s", c.Outbound.Timeout)
+	}
+
 	return nil
 }
 
@@ -196,6 +950,11 @@ func (c *Config) IsDevelopment() bool {
 	return c.App.Environment == "development"
 }
 
+// RetryAfterHTTPDate reports whether Retry-After should be sent as an HTTP-date
+func (r *RateLimitConfig) RetryAfterHTTPDate() bool {
+	return r.RetryAfterFormat == "http-date"
+}
+
 // IsProduction returns true if running in production mode
 func (c *Config) IsProduction() bool {
 	return c.App.Environment == "production"
@@ -235,6 +994,18 @@ func getIntEnv(key string, defaultValue int) int {
 	}
 	return intValue
 }
+func getFloatEnv(key string, defaultValue float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	floatValue, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return defaultValue
+	}
+	return floatValue
+}
 func getBoolEnv(key string, defaultValue bool) bool {
 	if value := os.Getenv(key); value != "" {
 		if b, err := strconv.ParseBool(value); err == nil {
@@ -259,3 +1030,81 @@ func getSliceEnv(key string, defaultValue []string) []string {
 	}
 	return result
 }
+
+// getMapEnv parses "key=value,key=value"; keys are lowercased and
+// malformed pairs are skipped
+// getFloatSliceEnv parses a comma-separated list of numbers, falling back
+// to the default if any entry isn't one
+func getFloatSliceEnv(key string, defaultValue []float64) []float64 {
+	parts := getSliceEnv(key, nil)
+	if parts == nil {
+		return defaultValue
+	}
+	values := make([]float64, 0, len(parts))
+	for _, part := range parts {
+		v, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return defaultValue
+		}
+		values = append(values, v)
+	}
+	return values
+}
+
+// getIntSliceEnv parses "3,1,1". Entries that aren't integers become 0 so
+// Validate reports them instead of the list being silently dropped.
+func getIntSliceEnv(key string) []int {
+	parts := getSliceEnv(key, nil)
+	values := make([]int, 0, len(parts))
+	for _, part := range parts {
+		v, err := strconv.Atoi(part)
+		if err != nil {
+			v = 0
+		}
+		values = append(values, v)
+	}
+	return values
+}
+
+// getAPIKeysEnv parses "key=owner,...". Unlike getMapEnv, keys keep their
+// case; entries without an owner are kept so Validate reports them.
+func getAPIKeysEnv(key string) map[string]string {
+	keys := make(map[string]string)
+	for _, entry := range getSliceEnv(key, nil) {
+		k, owner, _ := strings.Cut(entry, "=")
+		keys[strings.TrimSpace(k)] = strings.TrimSpace(owner)
+	}
+	return keys
+}
+
+// getRouteRateLimitsEnv parses "prefix=rate:burst,...". Malformed entries
+// are kept with a zero rate so Validate reports them.
+func getRouteRateLimitsEnv(key string) []RouteRateLimit {
+	var routes []RouteRateLimit
+	for _, entry := range getSliceEnv(key, nil) {
+		prefix, limit, _ := strings.Cut(entry, "=")
+		route := RouteRateLimit{PathPrefix: strings.TrimSpace(prefix)}
+		rate, burst, ok := strings.Cut(limit, ":")
+		if ok {
+			r, rateErr := strconv.Atoi(strings.TrimSpace(rate))
+			b, burstErr := strconv.Atoi(strings.TrimSpace(burst))
+			if rateErr == nil && burstErr == nil {
+				route.Rate, route.Burst = r, b
+			}
+		}
+		routes = append(routes, route)
+	}
+	return routes
+}
+
+func getMapEnv(key string) map[string]string {
+	result := make(map[string]string)
+	for _, pair := range getSliceEnv(key, nil) {
+		k, v, ok := strings.Cut(pair, "=")
+		k, v = strings.ToLower(strings.TrimSpace(k)), strings.TrimSpace(v)
+		if ok && k != "" && v != "" {
+			result[k] = v
+		}
+	}
+	return result
+}
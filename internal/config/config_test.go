@@ -0,0 +1,195 @@
+package config
+
+import "testing"
+
+func TestLoad_BaseURLScheme(t *testing.T) {
+	tests := []struct {
+		name        string
+		environment string
+		baseURL     string
+		allow       string
+		wantErr     bool
+	}{
+		{"production rejects http", "production", "http://sho.rt", "", true},
+		{"production rejects the localhost default", "production", "", "", true},
+		{"production accepts https", "production", "https://sho.rt", "", false},
+		{"production override", "production", "http://sho.rt", "true", false},
+		{"development allows http", "development", "http://sho.rt", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ENVIRONMENT", tt.environment)
+			t.Setenv("ADMIN_TOKEN", "secret")
+			t.Setenv("BASE_URL", tt.baseURL)
+			t.Setenv("ALLOW_INSECURE_BASE_URL", tt.allow)
+
+			_, err := Load()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLoad_RegionBaseURLScheme(t *testing.T) {
+	t.Setenv("ENVIRONMENT", "production")
+	t.Setenv("ADMIN_TOKEN", "secret")
+	t.Setenv("BASE_URL", "https://sho.rt")
+	t.Setenv("REGION_BASE_URLS", "eu=http://eu.sho.rt")
+
+	if _, err := Load(); err == nil {
+		t.Error("Expected an http regional base URL rejected in production")
+	}
+}
+
+func TestLoad_RateLimitDefaultsByEnvironment(t *testing.T) {
+	tests := []struct {
+		environment string
+		rate, burst string
+		wantRate    int
+		wantBurst   int
+	}{
+		{"development", "", "", 10, 20},
+		{"testing", "", "", 10, 20},
+		{"production", "", "", 100, 200},
+		{"production", "50", "", 50, 100}, // burst scales with an explicit rate
+		{"production", "50", "60", 50, 60},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.environment+"/"+tt.rate+":"+tt.burst, func(t *testing.T) {
+			t.Setenv("ENVIRONMENT", tt.environment)
+			t.Setenv("ADMIN_TOKEN", "secret")
+			t.Setenv("BASE_URL", "https://sho.rt")
+			t.Setenv("RATE_LIMIT_RATE", tt.rate)
+			t.Setenv("RATE_LIMIT_BURST", tt.burst)
+
+			cfg, err := Load()
+			if err != nil {
+				t.Fatalf("Load failed: %v", err)
+			}
+			if cfg.RateLimit.Rate != tt.wantRate || cfg.RateLimit.Burst != tt.wantBurst {
+				t.Errorf("Expected %d/%d, got: %d/%d", tt.wantRate, tt.wantBurst, cfg.RateLimit.Rate, cfg.RateLimit.Burst)
+			}
+		})
+	}
+}
+
+func TestLoad_RedactURLsDefaultsByEnvironment(t *testing.T) {
+	tests := []struct {
+		environment, redact, want string
+	}{
+		{"development", "", "off"},
+		{"testing", "", "query"},
+		{"production", "", "query"},
+		{"production", "off", "off"},
+		{"development", "full", "full"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.environment+"/"+tt.redact, func(t *testing.T) {
+			t.Setenv("ENVIRONMENT", tt.environment)
+			t.Setenv("ADMIN_TOKEN", "secret")
+			t.Setenv("BASE_URL", "https://sho.rt")
+			t.Setenv("LOG_REDACT_URLS", tt.redact)
+
+			cfg, err := Load()
+			if err != nil {
+				t.Fatalf("Load failed: %v", err)
+			}
+			if cfg.Log.RedactURLs != tt.want {
+				t.Errorf("Expected LOG_REDACT_URLS %s, got: %s", tt.want, cfg.Log.RedactURLs)
+			}
+		})
+	}
+}
+
+func TestLoad_RateLimitBurstAtLeastRate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rate    string
+		burst   string
+		routes  string
+		enabled string
+		wantErr bool
+	}{
+		{"burst equal to rate", "10", "10", "", "true", false},
+		{"burst below rate", "10", "5", "", "true", true},
+		{"zero rate", "0", "5", "", "true", true},
+		{"route burst below its rate", "10", "20", "/shorten=5:2", "true", true},
+		{"route burst equal to its rate", "10", "20", "/shorten=5:5", "true", false},
+		{"disabled with zero rate", "0", "5", "", "false", false},
+		{"disabled with burst below rate", "10", "5", "/shorten=5:2", "false", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ENVIRONMENT", "development")
+			t.Setenv("RATE_LIMIT_ENABLED", tt.enabled)
+			t.Setenv("RATE_LIMIT_RATE", tt.rate)
+			t.Setenv("RATE_LIMIT_BURST", tt.burst)
+			t.Setenv("RATE_LIMIT_ROUTES", tt.routes)
+
+			_, err := Load()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLoad_AdminTokenRequiredOutsideDevelopment(t *testing.T) {
+	tests := []struct {
+		environment string
+		token       string
+		wantErr     bool
+	}{
+		{"development", "", false},
+		{"testing", "", true},
+		{"production", "", true},
+		{"production", "secret", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.environment+"/"+tt.token, func(t *testing.T) {
+			t.Setenv("ENVIRONMENT", tt.environment)
+			t.Setenv("BASE_URL", "https://sho.rt")
+			t.Setenv("ADMIN_TOKEN", tt.token)
+
+			_, err := Load()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLoad_RedisFeaturesNeedRedisBackend(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr bool
+	}{
+		{"memory cache alone", map[string]string{}, false},
+		{"click write-behind", map[string]string{"CLICK_WRITE_BEHIND": "true"}, true},
+		{"redis rate limit backend", map[string]string{"RATE_LIMIT_ENABLED": "true", "RATE_LIMIT_BACKEND": "redis"}, true},
+		{"redis backend with rate limiting off", map[string]string{"RATE_LIMIT_ENABLED": "false", "RATE_LIMIT_BACKEND": "redis"}, false},
+		{"create limit counts per instance", map[string]string{"CREATE_LIMIT_ENABLED": "true"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("ENVIRONMENT", "development")
+			t.Setenv("CACHE_BACKEND", "memory")
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			_, err := Load()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
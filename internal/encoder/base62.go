@@ -1,41 +1,84 @@
 package encoder
 
+import (
+	"errors"
+	"math"
+)
+
 const alphabet = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
 const base = uint64(len(alphabet))
 
+// MaxLength is the longest code that can decode into a uint64 (62^11 > 2^64)
+const MaxLength = 11
+
+var (
+	ErrCodeTooLong = errors.New("code is longer than any encodable ID")
+	ErrOverflow    = errors.New("code decodes beyond the uint64 range")
+	ErrInvalidChar = errors.New("code contains a character outside the alphabet")
+	ErrEmptyCode   = errors.New("code is empty")
+)
+
 // Encode converts a number to a base62 string
 func Encode(num uint64) string {
-	if num == 0 {
-		return string(alphabet[0])
+	return Default.Encode(num)
+}
+
+// EncodePadded converts a number to a base62 string of at least minLen
+// characters, left-padded with "0"
+func EncodePadded(num uint64, minLen int) string {
+	return Default.EncodePadded(num, minLen)
+}
+
+// Capacity returns how many distinct values fit in codes of the given length
+// (62^length), saturating at the largest uint64
+func Capacity(length int) uint64 {
+	capacity := uint64(1)
+	for i := 0; i < length; i++ {
+		if capacity > math.MaxUint64/base {
+			return math.MaxUint64
+		}
+		capacity *= base
 	}
+	return capacity
+}
 
-	encoded := ""
-	for num > 0 {
-		remainder := num % base
-		encoded = string(alphabet[remainder]) + encoded
-		num = num / base
+// MaxID returns the largest ID that encodes in at most length characters
+// (62^length - 1), saturating at the largest uint64
+func MaxID(length int) uint64 {
+	if length <= 0 {
+		return 0
 	}
+	capacity := Capacity(length)
+	if capacity == math.MaxUint64 {
+		return math.MaxUint64
+	}
+	return capacity - 1
+}
 
-	return encoded
+// MinLength returns how many characters are needed to encode id
+func MinLength(id uint64) int {
+	length := 1
+	for id >= base {
+		id /= base
+		length++
+	}
+	return length
 }
 
 // Decode converts a base62 string back to a number
 func Decode(encoded string) uint64 {
-	var num uint64 = 0
-
-	for _, char := range encoded {
-		num = num * base
-		num += uint64(indexOf(byte(char)))
-	}
+	return Default.Decode(encoded)
+}
 
-	return num
+// DecodeSafe is Decode with guards: it rejects codes longer than MaxLength,
+// values that would overflow uint64, and characters outside the alphabet,
+// instead of silently wrapping onto some other ID
+func DecodeSafe(encoded string) (uint64, error) {
+	return Default.DecodeSafe(encoded)
 }
 
-func indexOf(char byte) int {
-	for i, c := range []byte(alphabet) {
-		if c == char {
-			return i
-		}
-	}
-	return -1
+// DecodeStrict is DecodeSafe that also rejects the empty string, which
+// DecodeSafe and Decode both read as 0
+func DecodeStrict(encoded string) (uint64, error) {
+	return Default.DecodeStrict(encoded)
 }
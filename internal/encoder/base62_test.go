@@ -1,6 +1,10 @@
 package encoder
 
-import "testing"
+import (
+	"math"
+	"strings"
+	"testing"
+)
 
 func TestEncode(t *testing.T) {
 	tests := []struct {
@@ -101,3 +105,164 @@ func TestEncodedLength(t *testing.T) {
 		})
 	}
 }
+
+func TestDecodeSafe(t *testing.T) {
+	maxCode := Encode(math.MaxUint64) // "lYGhA16ahyf"
+
+	tests := []struct {
+		name     string
+		input    string
+		expected uint64
+		err      error
+	}{
+		{"realistic ID", "8m0Kx", 123456789, nil},
+		{"max uint64", maxCode, math.MaxUint64, nil},
+		{"11 chars past max", "ZZZZZZZZZZZ", 0, ErrOverflow},
+		{"12 chars", "100000000000", 0, ErrCodeTooLong},
+		{"20 chars", strings.Repeat("a", 20), 0, ErrCodeTooLong},
+		{"invalid char", "ab-c", 0, ErrInvalidChar},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := DecodeSafe(tt.input)
+			if err != tt.err {
+				t.Fatalf("DecodeSafe(%s) error = %v; want %v", tt.input, err, tt.err)
+			}
+			if result != tt.expected {
+				t.Errorf("DecodeSafe(%s) = %d; want %d", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestEncodePadded(t *testing.T) {
+	tests := []struct {
+		num      uint64
+		minLen   int
+		expected string
+	}{
+		{0, 6, "000000"},
+		{1, 6, "000001"},
+		{61, 6, "00000Z"},
+		{123456789, 6, "08m0Kx"},
+		{123456789, 3, "8m0Kx"}, // already longer
+		{7, 0, "7"},
+		{7, 20, "00000000007"}, // capped at MaxLength
+		{math.MaxUint64, 11, Encode(math.MaxUint64)},
+	}
+
+	for _, tt := range tests {
+		code := EncodePadded(tt.num, tt.minLen)
+		if code != tt.expected {
+			t.Errorf("EncodePadded(%d, %d) = %s; want %s", tt.num, tt.minLen, code, tt.expected)
+		}
+		if got := Decode(code); got != tt.num {
+			t.Errorf("Decode(%s) = %d; want %d", code, got, tt.num)
+		}
+		if got, err := DecodeSafe(code); err != nil || got != tt.num {
+			t.Errorf("DecodeSafe(%s) = %d, %v; want %d", code, got, err, tt.num)
+		}
+	}
+}
+
+func TestEncodePaddedWithChecksum(t *testing.T) {
+	for _, num := range []uint64{0, 1, 3843, 1 << 40} {
+		code := Default.EncodePaddedWithChecksum(num, 6)
+		if len(code) < 7 {
+			t.Errorf("Expected %d padded to 6 plus a check character, got: %s", num, code)
+		}
+		if got, err := DecodeWithChecksum(code); err != nil || got != num {
+			t.Errorf("DecodeWithChecksum(%s) = %d, %v; want %d", code, got, err, num)
+		}
+	}
+}
+
+func TestDecodeStrict(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected uint64
+		err      error
+	}{
+		{"realistic ID", "8m0Kx", 123456789, nil},
+		{"zero", "0", 0, nil},
+		{"empty", "", 0, ErrEmptyCode},
+		{"invalid char", "ab-c", 0, ErrInvalidChar},
+		{"non-ASCII", "abç", 0, ErrInvalidChar},
+		{"12 chars", "100000000000", 0, ErrCodeTooLong},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := DecodeStrict(tt.input)
+			if err != tt.err {
+				t.Fatalf("DecodeStrict(%s) error = %v; want %v", tt.input, err, tt.err)
+			}
+			if result != tt.expected {
+				t.Errorf("DecodeStrict(%s) = %d; want %d", tt.input, result, tt.expected)
+			}
+		})
+	}
+
+	// Decode keeps its lenient behavior
+	if Decode("") != 0 {
+		t.Errorf("Expected Decode(\"\") to stay 0, got: %d", Decode(""))
+	}
+}
+
+func TestDecodeSafe_NoWrap(t *testing.T) {
+	// Plain Decode wraps long codes onto small IDs; DecodeSafe must not
+	long := "1" + strings.Repeat("0", 11) // 62^11, wraps in uint64
+	if _, err := DecodeSafe(long); err == nil {
+		t.Errorf("Expected %s to be rejected, Decode wraps it to %d", long, Decode(long))
+	}
+}
+
+func TestMaxID(t *testing.T) {
+	tests := []struct {
+		length   int
+		expected uint64
+	}{
+		{1, 61},
+		{2, 62*62 - 1},              // 3843
+		{3, 62*62*62 - 1},           // 238,327
+		{4, 62*62*62*62 - 1},        // 14,776,335
+		{6, 56800235583},            // 62^6 - 1
+		{MaxLength, math.MaxUint64}, // 62^11 exceeds uint64
+	}
+
+	for _, tt := range tests {
+		if got := MaxID(tt.length); got != tt.expected {
+			t.Errorf("MaxID(%d) = %d; want %d", tt.length, got, tt.expected)
+		}
+		if tt.length < MaxLength && len(Encode(tt.expected)) != tt.length {
+			t.Errorf("Encode(MaxID(%d)) has length %d", tt.length, len(Encode(tt.expected)))
+		}
+	}
+}
+
+func TestMinLength(t *testing.T) {
+	tests := []struct {
+		input    uint64
+		expected int
+	}{
+		{0, 1},
+		{61, 1},
+		{62, 2},
+		{62*62 - 1, 2},
+		{1000000, 4}, // 1 million fits in 4
+		{56800235583, 6},
+		{56800235584, 7},
+		{math.MaxUint64, MaxLength},
+	}
+
+	for _, tt := range tests {
+		if got := MinLength(tt.input); got != tt.expected {
+			t.Errorf("MinLength(%d) = %d; want %d", tt.input, got, tt.expected)
+		}
+		if got := len(Encode(tt.input)); got != tt.expected {
+			t.Errorf("len(Encode(%d)) = %d; want %d", tt.input, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package encoder
+
+import "errors"
+
+// ErrChecksum means the trailing check character doesn't match the code,
+// which usually indicates a typo
+var ErrChecksum = errors.New("code checksum does not match")
+
+// EncodeWithChecksum encodes num and appends one check character
+func EncodeWithChecksum(num uint64) string {
+	return Default.EncodeWithChecksum(num)
+}
+
+// DecodeWithChecksum verifies and strips the check character, then decodes
+// the remaining code with DecodeSafe
+func DecodeWithChecksum(encoded string) (uint64, error) {
+	return Default.DecodeWithChecksum(encoded)
+}
+
+// ValidChecksum reports whether the last character of encoded is the
+// correct check character for the rest
+func ValidChecksum(encoded string) bool {
+	return Default.ValidChecksum(encoded)
+}
+
+// EncodeWithChecksum encodes num and appends one check character
+func (e *Encoder) EncodeWithChecksum(num uint64) string {
+	return e.appendCheckDigit(e.Encode(num))
+}
+
+// EncodePaddedWithChecksum pads the code to minLen like EncodePadded, then
+// appends the check character, which covers the padding too
+func (e *Encoder) EncodePaddedWithChecksum(num uint64, minLen int) string {
+	return e.appendCheckDigit(e.pad(e.Encode(num), minLen))
+}
+
+func (e *Encoder) appendCheckDigit(code string) string {
+	return code + string(e.alphabet[e.checkDigit(code)])
+}
+
+// DecodeWithChecksum verifies and strips the check character, then decodes
+// the remaining code with DecodeSafe
+func (e *Encoder) DecodeWithChecksum(encoded string) (uint64, error) {
+	if len(encoded) < 2 {
+		return 0, ErrChecksum
+	}
+	for i := 0; i < len(encoded); i++ {
+		if e.indexOf(encoded[i]) < 0 {
+			return 0, ErrInvalidChar
+		}
+	}
+	if !e.ValidChecksum(encoded) {
+		return 0, ErrChecksum
+	}
+	return e.DecodeSafe(encoded[:len(encoded)-1])
+}
+
+// ValidChecksum reports whether the last character of encoded is the
+// correct check character for the rest
+func (e *Encoder) ValidChecksum(encoded string) bool {
+	if len(encoded) < 2 {
+		return false
+	}
+	body := encoded[:len(encoded)-1]
+	for i := 0; i < len(body); i++ {
+		if e.indexOf(body[i]) < 0 {
+			return false
+		}
+	}
+	return e.indexOf(encoded[len(encoded)-1]) == e.checkDigit(body)
+}
+
+// checkDigit is a weighted mod-62 checksum. Weights alternate 1 and 3, both
+// coprime with 62, so any single-character substitution changes the result
+// and almost all adjacent transpositions do too.
+func (e *Encoder) checkDigit(code string) int {
+	sum := 0
+	for i := 0; i < len(code); i++ {
+		weight := 1
+		if (len(code)-i)%2 == 0 {
+			weight = 3
+		}
+		sum += weight * e.indexOf(code[i])
+	}
+	return (int(base) - sum%int(base)) % int(base)
+}
@@ -0,0 +1,52 @@
+package encoder
+
+import "testing"
+
+func TestChecksumRoundTrip(t *testing.T) {
+	for _, num := range []uint64{0, 1, 61, 62, 12345, 123456789} {
+		code := EncodeWithChecksum(num)
+		if len(code) != len(Encode(num))+1 {
+			t.Errorf("EncodeWithChecksum(%d) = %s; want one extra character", num, code)
+		}
+		decoded, err := DecodeWithChecksum(code)
+		if err != nil || decoded != num {
+			t.Errorf("Round trip failed: %d -> %s -> %d (%v)", num, code, decoded, err)
+		}
+	}
+}
+
+func TestChecksum_FlippedCharacter(t *testing.T) {
+	code := EncodeWithChecksum(123456789)
+
+	// Every single-character substitution must be caught
+	for i := 0; i < len(code); i++ {
+		for j := 0; j < len(alphabet); j++ {
+			if alphabet[j] == code[i] {
+				continue
+			}
+			typo := code[:i] + string(alphabet[j]) + code[i+1:]
+			if _, err := DecodeWithChecksum(typo); err != ErrChecksum {
+				t.Fatalf("DecodeWithChecksum(%s) error = %v; want ErrChecksum", typo, err)
+			}
+		}
+	}
+}
+
+func TestChecksum_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		err   error
+	}{
+		{"too short", "a", ErrChecksum},
+		{"bad character", "a-b", ErrInvalidChar},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeWithChecksum(tt.input); err != tt.err {
+				t.Errorf("DecodeWithChecksum(%s) error = %v; want %v", tt.input, err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package encoder
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+var ErrInvalidAlphabet = errors.New("alphabet must be 62 distinct characters from A-Z, a-z, 0-9, _ and -")
+
+// Encoder encodes numbers with one particular 62-character alphabet.
+// Codes produced by different alphabets are not interchangeable; shuffling
+// the alphabet per deployment keeps sequential IDs from producing
+// sequential-looking codes.
+type Encoder struct {
+	alphabet string
+	index    [256]int8 // position of each byte in alphabet, -1 if absent
+}
+
+// Default uses the standard 0-9a-z-A-Z alphabet and backs the
+// package-level functions
+var Default = mustNewEncoder(alphabet)
+
+// NewEncoder builds an encoder for a custom alphabet. The alphabet must be
+// exactly 62 distinct characters from A-Z, a-z, 0-9, '_' and '-', so codes
+// need no escaping in a URL path and can't collide with routes like
+// /{code}.{lang} or /{code}/stats.
+func NewEncoder(alphabet string) (*Encoder, error) {
+	if len(alphabet) != int(base) {
+		return nil, fmt.Errorf("%w: got %d characters", ErrInvalidAlphabet, len(alphabet))
+	}
+
+	e := &Encoder{alphabet: alphabet}
+	for i := range e.index {
+		e.index[i] = -1
+	}
+	for i := 0; i < len(alphabet); i++ {
+		ch := alphabet[i]
+		if !urlSafe(ch) {
+			return nil, fmt.Errorf("%w: %q at position %d", ErrInvalidAlphabet, ch, i)
+		}
+		if e.index[ch] >= 0 {
+			return nil, fmt.Errorf("%w: %q appears more than once", ErrInvalidAlphabet, ch)
+		}
+		e.index[ch] = int8(i)
+	}
+	return e, nil
+}
+
+// urlSafe reports whether ch may appear in a code alphabet
+func urlSafe(ch byte) bool {
+	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || '0' <= ch && ch <= '9' || ch == '_' || ch == '-'
+}
+
+func mustNewEncoder(alphabet string) *Encoder {
+	e, err := NewEncoder(alphabet)
+	if err != nil {
+		panic(err)
+	}
+	return e
+}
+
+// Alphabet returns the characters in digit order
+func (e *Encoder) Alphabet() string {
+	return e.alphabet
+}
+
+// Encode converts a number to a code in this alphabet
+func (e *Encoder) Encode(num uint64) string {
+	if num == 0 {
+		return string(e.alphabet[0])
+	}
+
+	var buf [MaxLength]byte
+	i := len(buf)
+	for num > 0 {
+		i--
+		buf[i] = e.alphabet[num%base]
+		num /= base
+	}
+	return string(buf[i:])
+}
+
+// EncodePadded is Encode left-padded with the alphabet's zero character to
+// at least minLen characters (capped at MaxLength). Padding doesn't change
+// the value, so every decoder reads the code back as num.
+func (e *Encoder) EncodePadded(num uint64, minLen int) string {
+	return e.pad(e.Encode(num), minLen)
+}
+
+func (e *Encoder) pad(code string, minLen int) string {
+	minLen = min(minLen, MaxLength)
+	if len(code) >= minLen {
+		return code
+	}
+	return strings.Repeat(e.alphabet[:1], minLen-len(code)) + code
+}
+
+// Decode converts a code back to a number without any checks; see
+// DecodeSafe for untrusted input
+func (e *Encoder) Decode(encoded string) uint64 {
+	var num uint64 = 0
+
+	for _, char := range encoded {
+		num = num * base
+		num += uint64(e.indexOf(byte(char)))
+	}
+
+	return num
+}
+
+// DecodeSafe converts a code back to a number, rejecting codes longer than
+// MaxLength, values that overflow uint64, and characters outside the alphabet
+func (e *Encoder) DecodeSafe(encoded string) (uint64, error) {
+	if len(encoded) > MaxLength {
+		return 0, ErrCodeTooLong
+	}
+
+	var num uint64 = 0
+	for i := 0; i < len(encoded); i++ {
+		digit := e.indexOf(encoded[i])
+		if digit < 0 {
+			return 0, ErrInvalidChar
+		}
+		if num > (math.MaxUint64-uint64(digit))/base {
+			return 0, ErrOverflow
+		}
+		num = num*base + uint64(digit)
+	}
+
+	return num, nil
+}
+
+// DecodeStrict is DecodeSafe that also rejects the empty string
+func (e *Encoder) DecodeStrict(encoded string) (uint64, error) {
+	if encoded == "" {
+		return 0, ErrEmptyCode
+	}
+	return e.DecodeSafe(encoded)
+}
+
+func (e *Encoder) indexOf(char byte) int {
+	return int(e.index[char])
+}
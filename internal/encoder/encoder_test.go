@@ -0,0 +1,66 @@
+package encoder
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestEncoder_CustomAlphabet(t *testing.T) {
+	reversed := []byte(alphabet)
+	for i, j := 0, len(reversed)-1; i < j; i, j = i+1, j-1 {
+		reversed[i], reversed[j] = reversed[j], reversed[i]
+	}
+	enc, err := NewEncoder(string(reversed))
+	if err != nil {
+		t.Fatalf("NewEncoder failed: %v", err)
+	}
+
+	for _, num := range []uint64{0, 1, 61, 62, 3843, 1 << 50} {
+		code := enc.Encode(num)
+		got, err := enc.DecodeSafe(code)
+		if err != nil || got != num {
+			t.Errorf("Round trip %d via %q: got %d, %v", num, code, got, err)
+		}
+		if enc.Decode(code) != num {
+			t.Errorf("Expected Decode(%q) = %d, got: %d", code, num, enc.Decode(code))
+		}
+		if num > 0 && code == Encode(num) {
+			t.Errorf("Expected %d to encode differently from the default alphabet", num)
+		}
+	}
+}
+
+func TestNewEncoder_Invalid(t *testing.T) {
+	tests := []string{
+		"",
+		alphabet[:61],
+		alphabet[:61] + "0", // duplicate
+		alphabet[:61] + "é"[:1],
+		alphabet[:61] + "/", // breaks the path
+		alphabet[:61] + ".", // reads as a language suffix
+		alphabet[:61] + "?",
+		alphabet[:61] + " ",
+	}
+	for _, a := range tests {
+		if _, err := NewEncoder(a); !errors.Is(err, ErrInvalidAlphabet) {
+			t.Errorf("Expected ErrInvalidAlphabet for %q, got: %v", a, err)
+		}
+	}
+}
+
+func TestNewEncoder_AcceptsURLSafePunctuation(t *testing.T) {
+	if _, err := NewEncoder(alphabet[:60] + "_-"); err != nil {
+		t.Errorf("Expected '_' and '-' to be allowed, got: %v", err)
+	}
+}
+
+func TestDefaultEncoder_MatchesPackageFunctions(t *testing.T) {
+	for _, num := range []uint64{0, 7, 12345, 1 << 40} {
+		if Default.Encode(num) != Encode(num) {
+			t.Errorf("Expected Default.Encode(%d) to match Encode", num)
+		}
+		if Default.Decode(Encode(num)) != Decode(Encode(num)) {
+			t.Errorf("Expected Default.Decode(%d) to match Decode", num)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package encoder
+
+import (
+	"errors"
+	"math"
+)
+
+var (
+	ErrInvalidStride = errors.New("stride must be positive and coprime with 62")
+	ErrNotInSequence = errors.New("number is not produced by this sequence")
+)
+
+// Sequence spreads record IDs over the code space so codes don't start at
+// "0" and consecutive IDs don't produce adjacent codes: id maps to
+// Offset + id*Stride. The zero value is the identity mapping.
+type Sequence struct {
+	Offset uint64
+	Stride uint64
+}
+
+// NewSequence validates offset and stride. A stride sharing a factor with
+// 62 would leave some trailing characters unused, so it is rejected.
+func NewSequence(offset, stride uint64) (Sequence, error) {
+	if stride == 0 || gcd(stride, base) != 1 {
+		return Sequence{}, ErrInvalidStride
+	}
+	return Sequence{Offset: offset, Stride: stride}, nil
+}
+
+// Apply maps a record ID to the number that gets encoded
+func (s Sequence) Apply(id uint64) (uint64, error) {
+	stride := s.Step()
+	if id > (math.MaxUint64-s.Offset)/stride {
+		return 0, ErrOverflow
+	}
+	return s.Offset + id*stride, nil
+}
+
+// Invert recovers the record ID from a decoded number
+func (s Sequence) Invert(num uint64) (uint64, error) {
+	stride := s.Step()
+	if num < s.Offset || (num-s.Offset)%stride != 0 {
+		return 0, ErrNotInSequence
+	}
+	return (num - s.Offset) / stride, nil
+}
+
+// Step is the distance between consecutive encoded numbers
+func (s Sequence) Step() uint64 {
+	if s.Stride == 0 {
+		return 1
+	}
+	return s.Stride
+}
+
+func gcd(a, b uint64) uint64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
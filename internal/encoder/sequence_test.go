@@ -0,0 +1,50 @@
+package encoder
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSequence_RoundTrip(t *testing.T) {
+	seq, err := NewSequence(916132832, 7919)
+	if err != nil {
+		t.Fatalf("NewSequence failed: %v", err)
+	}
+
+	for _, id := range []uint64{0, 1, 2, 1000, 1 << 40} {
+		num, err := seq.Apply(id)
+		if err != nil {
+			t.Fatalf("Apply(%d) failed: %v", id, err)
+		}
+		got, err := seq.Invert(num)
+		if err != nil || got != id {
+			t.Errorf("Invert(Apply(%d)): got %d, %v", id, got, err)
+		}
+	}
+}
+
+func TestSequence_Invalid(t *testing.T) {
+	for _, stride := range []uint64{0, 2, 31, 62, 124} {
+		if _, err := NewSequence(0, stride); err != ErrInvalidStride {
+			t.Errorf("Expected ErrInvalidStride for stride %d, got: %v", stride, err)
+		}
+	}
+
+	seq, _ := NewSequence(1000, 7)
+	for _, num := range []uint64{0, 999, 1001} {
+		if _, err := seq.Invert(num); err != ErrNotInSequence {
+			t.Errorf("Expected ErrNotInSequence for %d, got: %v", num, err)
+		}
+	}
+	if _, err := seq.Apply(math.MaxUint64); err != ErrOverflow {
+		t.Errorf("Expected ErrOverflow, got: %v", err)
+	}
+}
+
+func TestSequence_ZeroValueIsIdentity(t *testing.T) {
+	var seq Sequence
+	num, err := seq.Apply(42)
+	if err != nil || num != 42 {
+		t.Errorf("Expected 42, got: %d, %v", num, err)
+	}
+}
@@ -0,0 +1,79 @@
+package errors
+
+import "net/http"
+
+// Error codes sent in AppError.Code. Codes are stable: clients may branch
+// on them, so existing codes are never renamed or reused.
+const (
+	CodeBadRequest          = "BAD_REQUEST"
+	CodeInvalidURL          = "INVALID_URL"
+	CodeInvalidJSON         = "INVALID_JSON"
+	CodeMissingField        = "MISSING_FIELD"
+	CodeNotFound            = "NOT_FOUND"
+	CodeURLNotFound         = "URL_NOT_FOUND"
+	CodeURLExpired          = "URL_EXPIRED"
+	CodeCodeTypo            = "CODE_TYPO"
+	CodeUnauthorized        = "UNAUTHORIZED"
+	CodeInvalidAPIKey       = "INVALID_API_KEY"
+	CodeInvalidSignature    = "INVALID_SIGNATURE"
+	CodeSignatureExpired    = "SIGNATURE_EXPIRED"
+	CodeAdminOnly           = "ADMIN_ONLY"
+	CodeNotOwner            = "NOT_OWNER"
+	CodeReadOnly            = "READ_ONLY"
+	CodeRequestTimeout      = "REQUEST_TIMEOUT"
+	CodeConflict            = "CONFLICT"
+	CodeURLExists           = "URL_EXISTS"
+	CodeRateLimitExceeded   = "RATE_LIMIT_EXCEEDED"
+	CodeCreateLimitExceeded = "CREATE_LIMIT_EXCEEDED"
+	CodeLinkRateLimited     = "LINK_RATE_LIMITED"
+	CodeDatabaseTimeout     = "DATABASE_TIMEOUT"
+	CodeTimeout             = "TIMEOUT"
+	CodeInternal            = "INTERNAL_ERROR"
+	CodeDatabaseError       = "DATABASE_ERROR"
+)
+
+// CatalogEntry describes one error code for GET /errors
+type CatalogEntry struct {
+	Code        string `json:"code"`
+	Status      int    `json:"status"`
+	Description string `json:"description"`
+}
+
+// Catalog lists every code an AppError can carry, in constructor order
+var Catalog = []CatalogEntry{
+	{CodeBadRequest, http.StatusBadRequest, "The request is malformed; the message says why"},
+	{CodeInvalidURL, http.StatusBadRequest, "The destination URL failed validation"},
+	{CodeInvalidJSON, http.StatusBadRequest, "The request body is not valid JSON"},
+	{CodeMissingField, http.StatusBadRequest, "A required field is missing from the request body"},
+	{CodeNotFound, http.StatusNotFound, "The requested resource does not exist"},
+	{CodeURLNotFound, http.StatusNotFound, "No short URL has this code"},
+	{CodeURLExpired, http.StatusGone, "The short URL existed but has expired"},
+	{CodeCodeTypo, http.StatusNotFound, "The code's check character doesn't match; it was probably mistyped"},
+	{CodeUnauthorized, http.StatusUnauthorized, "The admin token is missing or invalid"},
+	{CodeInvalidAPIKey, http.StatusUnauthorized, "The API key is missing or invalid"},
+	{CodeInvalidSignature, http.StatusForbidden, "The signed link's signature is missing or invalid"},
+	{CodeSignatureExpired, http.StatusForbidden, "The signed link's signature has expired"},
+	{CodeAdminOnly, http.StatusForbidden, "A field in the request requires the admin token"},
+	{CodeNotOwner, http.StatusForbidden, "The short URL belongs to another owner"},
+	{CodeReadOnly, http.StatusForbidden, "The deployment is read-only and rejects writes"},
+	{CodeRequestTimeout, http.StatusRequestTimeout, "The request body arrived too slowly; send it again"},
+	{CodeConflict, http.StatusConflict, "The request conflicts with the current state"},
+	{CodeURLExists, http.StatusConflict, "The short code is already taken"},
+	{CodeRateLimitExceeded, http.StatusTooManyRequests, "Too many requests from this client; honor retry_after"},
+	{CodeCreateLimitExceeded, http.StatusTooManyRequests, "The link creation quota for the window is used up"},
+	{CodeLinkRateLimited, http.StatusTooManyRequests, "The short URL is receiving too many requests"},
+	{CodeDatabaseTimeout, http.StatusServiceUnavailable, "The database timed out; the request may be retried"},
+	{CodeTimeout, http.StatusServiceUnavailable, "The request took longer than the server allows; it may be retried"},
+	{CodeInternal, http.StatusInternalServerError, "An unexpected server error"},
+	{CodeDatabaseError, http.StatusInternalServerError, "The database failed the request"},
+}
+
+// InCatalog reports whether code is listed in Catalog
+func InCatalog(code string) bool {
+	for _, entry := range Catalog {
+		if entry.Code == code {
+			return true
+		}
+	}
+	return false
+}
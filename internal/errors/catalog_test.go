@@ -0,0 +1,57 @@
+package errors
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConstructorsUseCatalogCodes(t *testing.T) {
+	constructed := []*AppError{
+		BadRequest("bad"),
+		InvalidURL("details"),
+		InvalidJSON("details"),
+		MissingField("url"),
+		NotFound("thing"),
+		URLNotFound("abc"),
+		URLExpired("abc"),
+		CodeTypo("abc"),
+		Unauthorized(),
+		InvalidAPIKey(),
+		SignatureInvalid(),
+		SignatureExpired(),
+		AdminOnly("initial_clicks"),
+		NotOwner("abc"),
+		ReadOnly(),
+		RequestTimeout(),
+		Conflict("conflict"),
+		URLExists("abc"),
+		RateLimitExceeded(),
+		CreateLimitExceeded(10, time.Hour, time.Now()),
+		LinkRateLimited("abc"),
+		DatabaseTimeout(),
+		Timeout(),
+		Internal(""),
+		DatabaseError(),
+	}
+
+	statuses := make(map[string]int, len(Catalog))
+	for _, entry := range Catalog {
+		if _, dup := statuses[entry.Code]; dup {
+			t.Errorf("Code %s is listed twice", entry.Code)
+		}
+		statuses[entry.Code] = entry.Status
+	}
+
+	for _, err := range constructed {
+		if !InCatalog(err.Code) {
+			t.Errorf("Code %s is not in the catalog", err.Code)
+			continue
+		}
+		if statuses[err.Code] != err.StatusCode {
+			t.Errorf("Code %s: catalog says %d, constructor sends %d", err.Code, statuses[err.Code], err.StatusCode)
+		}
+	}
+	if len(constructed) != len(Catalog) {
+		t.Errorf("Expected one constructor per catalog code, got %d constructors for %d codes", len(constructed), len(Catalog))
+	}
+}
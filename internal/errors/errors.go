@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"time"
 )
 
 // AppError represents an application error with HTTP context
@@ -11,6 +12,7 @@ type AppError struct {
 	Code       string `json:"code"`
 	Message    string `json:"message"`
 	Details    string `json:"details,omitempty"`
+	RetryAfter int    `json:"retry_after,omitempty"` // seconds, mirrors the Retry-After header
 	StatusCode int    `json:"-"`
 }
 
@@ -18,6 +20,12 @@ func (e *AppError) Error() string {
 	return e.Message
 }
 
+// WithRetryAfter tells the client how many seconds to wait before retrying
+func (e *AppError) WithRetryAfter(seconds int) *AppError {
+	e.RetryAfter = seconds
+	return e
+}
+
 // ErrorResponse is the JSON response format for errors
 type ErrorResponse struct {
 	Error *AppError `json:"error"`
@@ -37,7 +45,7 @@ func (e *AppError) WriteJSON(w http.ResponseWriter) {
 // Validation Errors (400)
 func BadRequest(message string) *AppError {
 	return &AppError{
-		Code:       "BAD_REQUEST",
+		Code:       CodeBadRequest,
 		Message:    message,
 		StatusCode: http.StatusBadRequest,
 	}
@@ -45,7 +53,7 @@ func BadRequest(message string) *AppError {
 
 func InvalidURL(details string) *AppError {
 	return &AppError{
-		Code:       "INVALID_URL",
+		Code:       CodeInvalidURL,
 		Message:    "The provided URL is invalid",
 		Details:    details,
 		StatusCode: http.StatusBadRequest,
@@ -54,7 +62,7 @@ func InvalidURL(details string) *AppError {
 
 func InvalidJSON(details string) *AppError {
 	return &AppError{
-		Code:       "INVALID_JSON",
+		Code:       CodeInvalidJSON,
 		Message:    "Invalid JSON in request body",
 		Details:    details,
 		StatusCode: http.StatusBadRequest,
@@ -63,7 +71,7 @@ func InvalidJSON(details string) *AppError {
 
 func MissingField(field string) *AppError {
 	return &AppError{
-		Code:       "MISSING_FIELD",
+		Code:       CodeMissingField,
 		Message:    fmt.Sprintf("Required field '%s' is missing", field),
 		StatusCode: http.StatusBadRequest,
 	}
@@ -72,7 +80,7 @@ func MissingField(field string) *AppError {
 // Not Found Errors (404)
 func NotFound(resource string) *AppError {
 	return &AppError{
-		Code:       "NOT_FOUND",
+		Code:       CodeNotFound,
 		Message:    fmt.Sprintf("%s not found", resource),
 		StatusCode: http.StatusNotFound,
 	}
@@ -80,16 +88,103 @@ func NotFound(resource string) *AppError {
 
 func URLNotFound(code string) *AppError {
 	return &AppError{
-		Code:       "URL_NOT_FOUND",
+		Code:       CodeURLNotFound,
+		Message:    fmt.Sprintf("Short URL '%s' not found", code),
+		StatusCode: http.StatusNotFound,
+	}
+}
+
+// Gone (410)
+func URLExpired(code string) *AppError {
+	return &AppError{
+		Code:       CodeURLExpired,
+		Message:    fmt.Sprintf("Short URL '%s' has expired", code),
+		Details:    "Its stats still show when it expired",
+		StatusCode: http.StatusGone,
+	}
+}
+
+func CodeTypo(code string) *AppError {
+	return &AppError{
+		Code:       CodeCodeTypo,
 		Message:    fmt.Sprintf("Short URL '%s' not found", code),
+		Details:    "The code's check character doesn't match; it was probably mistyped or truncated",
 		StatusCode: http.StatusNotFound,
 	}
 }
 
+// Auth Errors (401)
+func Unauthorized() *AppError {
+	return &AppError{
+		Code:       CodeUnauthorized,
+		Message:    "Missing or invalid admin token",
+		StatusCode: http.StatusUnauthorized,
+	}
+}
+
+func InvalidAPIKey() *AppError {
+	return &AppError{
+		Code:       CodeInvalidAPIKey,
+		Message:    "Missing or invalid API key",
+		Details:    "Send the key as 'Authorization: Bearer <key>' or 'X-API-Key: <key>'",
+		StatusCode: http.StatusUnauthorized,
+	}
+}
+
+// Forbidden Errors (403)
+func SignatureInvalid() *AppError {
+	return &AppError{
+		Code:       CodeInvalidSignature,
+		Message:    "This link requires a valid signature",
+		StatusCode: http.StatusForbidden,
+	}
+}
+
+func SignatureExpired() *AppError {
+	return &AppError{
+		Code:       CodeSignatureExpired,
+		Message:    "This link has expired",
+		StatusCode: http.StatusForbidden,
+	}
+}
+
+func AdminOnly(field string) *AppError {
+	return &AppError{
+		Code:       CodeAdminOnly,
+		Message:    fmt.Sprintf("Field '%s' requires the admin token", field),
+		StatusCode: http.StatusForbidden,
+	}
+}
+
+func NotOwner(code string) *AppError {
+	return &AppError{
+		Code:       CodeNotOwner,
+		Message:    fmt.Sprintf("Short URL '%s' belongs to another owner", code),
+		StatusCode: http.StatusForbidden,
+	}
+}
+
+func ReadOnly() *AppError {
+	return &AppError{
+		Code:       CodeReadOnly,
+		Message:    "This deployment is read-only",
+		StatusCode: http.StatusForbidden,
+	}
+}
+
+// Request Timeout (408)
+func RequestTimeout() *AppError {
+	return &AppError{
+		Code:       CodeRequestTimeout,
+		Message:    "The request body was not received in time",
+		StatusCode: http.StatusRequestTimeout,
+	}
+}
+
 // Conflict Errors (409)
 func Conflict(message string) *AppError {
 	return &AppError{
-		Code:       "CONFLICT",
+		Code:       CodeConflict,
 		Message:    message,
 		StatusCode: http.StatusConflict,
 	}
@@ -97,7 +192,7 @@ func Conflict(message string) *AppError {
 
 func URLExists(code string) *AppError {
 	return &AppError{
-		Code:       "URL_EXISTS",
+		Code:       CodeURLExists,
 		Message:    fmt.Sprintf("Short code '%s' already exists", code),
 		StatusCode: http.StatusConflict,
 	}
@@ -106,16 +201,50 @@ func URLExists(code string) *AppError {
 // Rate Limit Error (429)
 func RateLimitExceeded() *AppError {
 	return &AppError{
-		Code:       "RATE_LIMIT_EXCEEDED",
+		Code:       CodeRateLimitExceeded,
 		Message:    "Too many requests, please try again later",
 		StatusCode: http.StatusTooManyRequests,
 	}
 }
 
+func CreateLimitExceeded(limit int, window time.Duration, resetAt time.Time) *AppError {
+	return &AppError{
+		Code:       CodeCreateLimitExceeded,
+		Message:    fmt.Sprintf("Link creation limit of %d per %s reached", limit, window),
+		Details:    "Window resets at " + resetAt.UTC().Format(time.RFC3339),
+		StatusCode: http.StatusTooManyRequests,
+	}
+}
+
+func LinkRateLimited(code string) *AppError {
+	return &AppError{
+		Code:       CodeLinkRateLimited,
+		Message:    fmt.Sprintf("Short URL '%s' is receiving too many requests, please try again later", code),
+		StatusCode: http.StatusTooManyRequests,
+	}
+}
+
+// Unavailable Errors (503)
+func DatabaseTimeout() *AppError {
+	return &AppError{
+		Code:       CodeDatabaseTimeout,
+		Message:    "The database took too long to respond, please retry",
+		StatusCode: http.StatusServiceUnavailable,
+	}
+}
+
+func Timeout() *AppError {
+	return &AppError{
+		Code:       CodeTimeout,
+		Message:    "The request took too long to process, please retry",
+		StatusCode: http.StatusServiceUnavailable,
+	}
+}
+
 // Server Errors (500)
 func Internal(details string) *AppError {
 	return &AppError{
-		Code:       "INTERNAL_ERROR",
+		Code:       CodeInternal,
 		Message:    "An internal server error occurred",
 		Details:    details,
 		StatusCode: http.StatusInternalServerError,
@@ -124,7 +253,7 @@ func Internal(details string) *AppError {
 
 func DatabaseError() *AppError {
 	return &AppError{
-		Code:       "DATABASE_ERROR",
+		Code:       CodeDatabaseError,
 		Message:    "A database error occurred",
 		StatusCode: http.StatusInternalServerError,
 	}
@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"html/template"
+	"net/http"
+)
+
+// brokenLinkPage is served instead of the redirect when the destination
+// failed its last health probe. Users can still follow the link.
+var brokenLinkPage = template.Must(template.New("broken").Parse(`<!DOCTYPE html>
+<html><head><title>Link may be broken</title></head>
+<body><h1>This link may be broken</h1>
+<p>The destination did not respond the last time we checked:</p>
+<p><a href="{{.}}" rel="nofollow noopener">{{.}}</a></p>
+<p>It may be down temporarily. You can still follow the link above.</p>
+</body></html>
+`))
+
+// writeBrokenLink renders the warning page for a destination that looks dead
+func writeBrokenLink(w http.ResponseWriter, originalURL string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store") // the destination may recover
+	w.WriteHeader(http.StatusOK)
+	brokenLinkPage.Execute(w, originalURL)
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+)
+
+// countdownPage is served for links with a redirect delay. The meta
+// refresh works without JavaScript; the script only updates the counter.
+var countdownPage = template.Must(template.New("countdown").Parse(`<!DOCTYPE html>
+<html><head><title>Redirecting</title>
+<meta http-equiv="refresh" content="{{.Delay}};url={{.URL}}">
+</head>
+<body><h1>Redirecting</h1>
+<p>You will be taken to <a href="{{.URL}}">{{.URL}}</a> in <span id="countdown">{{.Delay}}</span> seconds.</p>
+<script>
+(function() {
+  var left = {{.Delay}}, el = document.getElementById("countdown");
+  var timer = setInterval(function() {
+    left--;
+    if (left <= 0) { clearInterval(timer); window.location.replace({{.URL}}); return; }
+    el.textContent = left;
+  }, 1000);
+})();
+</script>
+</body></html>
+`))
+
+// crawlerAgents are User-Agent fragments of bots and link unfurlers, which
+// get the redirect directly so they index or preview the destination
+var crawlerAgents = []string{"bot", "crawl", "spider", "slurp", "facebookexternalhit", "embedly", "preview"}
+
+// isCrawler reports whether the request looks like it comes from a bot
+func isCrawler(r *http.Request) bool {
+	agent := strings.ToLower(r.UserAgent())
+	for _, fragment := range crawlerAgents {
+		if strings.Contains(agent, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeCountdown renders the timer page that redirects after delay
+// seconds. It is rendered before anything is written, so when rendering
+// fails it reports false with w untouched and the caller can redirect.
+func writeCountdown(w http.ResponseWriter, originalURL string, delay int) bool {
+	var page bytes.Buffer
+	if err := countdownPage.Execute(&page, struct {
+		URL   string
+		Delay int
+	}{originalURL, delay}); err != nil {
+		fmt.Printf("Warning: failed to render countdown page: %v\n", err)
+		return false
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store") // each view counts as a click
+	w.WriteHeader(http.StatusOK)
+	w.Write(page.Bytes())
+	return true
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/darkodi/url-shortener/internal/errors"
+)
+
+// defaultPages are used for any template the operator doesn't override
+//
+//go:embed pages/*.html
+var defaultPages embed.FS
+
+// errorPageNames are the templates looked up in the pages directory
+var errorPageNames = map[int]string{
+	http.StatusNotFound:            "404.html",
+	http.StatusInternalServerError: "500.html",
+}
+
+// ErrorPages renders HTML error responses for browsers
+type ErrorPages struct {
+	templates map[int]*template.Template
+}
+
+// LoadErrorPages parses 404.html and 500.html from dir, falling back to the
+// embedded defaults for missing files. An empty dir uses only the defaults.
+func LoadErrorPages(dir string) (*ErrorPages, error) {
+	pages := &ErrorPages{templates: make(map[int]*template.Template)}
+
+	for status, name := range errorPageNames {
+		var (
+			content []byte
+			err     error
+		)
+		if dir != "" {
+			content, err = os.ReadFile(filepath.Join(dir, name))
+			if err != nil && !os.IsNotExist(err) {
+				return nil, fmt.Errorf("failed to read error page %s: %w", name, err)
+			}
+		}
+		if dir == "" || err != nil {
+			content, err = defaultPages.ReadFile("pages/" + name)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		tmpl, err := template.New(name).Parse(string(content))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse error page %s: %w", name, err)
+		}
+		pages.templates[status] = tmpl
+	}
+
+	return pages, nil
+}
+
+// WithErrorPages renders 404 and 500 responses as HTML for clients that
+// accept it; others keep getting JSON
+func (h *URLHandler) WithErrorPages(pages *ErrorPages) *URLHandler {
+	h.errorPages = pages
+	return h
+}
+
+// writeError sends appErr as an HTML page when one exists for its status and
+// the client accepts HTML, and as JSON otherwise
+func (h *URLHandler) writeError(w http.ResponseWriter, r *http.Request, appErr *errors.AppError) {
+	if h.errorPages != nil && acceptsHTML(r) {
+		status := appErr.StatusCode
+		if status >= 500 {
+			status = http.StatusInternalServerError
+		}
+		if tmpl, ok := h.errorPages.templates[status]; ok {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(appErr.StatusCode)
+			tmpl.Execute(w, appErr)
+			return
+		}
+	}
+	appErr.WriteJSON(w)
+}
+
+// acceptsHTML reports whether the Accept header lists text/html
+func acceptsHTML(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if strings.EqualFold(strings.TrimSpace(mediaType), "text/html") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/darkodi/url-shortener/internal/errors"
+	"github.com/darkodi/url-shortener/internal/middleware"
+	"github.com/darkodi/url-shortener/internal/model"
+	"github.com/darkodi/url-shortener/internal/service"
+)
+
+const (
+	// importBatchSize is how many lines go into each batch transaction
+	importBatchSize = 100
+
+	// maxImportLine bounds one NDJSON line; a URL is at most 2048 bytes
+	maxImportLine = 16 << 10
+)
+
+// importEvent is one line of the streamed import response: a failed
+// input line, a progress report after each batch, or the final summary
+type importEvent struct {
+	Line  int              `json:"line,omitempty"`
+	Error *errors.AppError `json:"error,omitempty"`
+
+	Progress *importProgress `json:"progress,omitempty"`
+	Done     *importProgress `json:"done,omitempty"`
+}
+
+type importProgress struct {
+	Lines   int `json:"lines"`
+	Created int `json:"created"`
+	Failed  int `json:"failed"`
+}
+
+// pendingImport is a parsed line waiting for its batch
+type pendingImport struct {
+	line int
+	req  model.CreateURLRequest
+}
+
+// HandleImport creates links from an NDJSON stream, one POST /shorten body
+// per line, without holding the whole upload in memory. Lines are created
+// in batches; failed lines, progress after each batch, and a final summary
+// are streamed back as NDJSON. A bad line fails alone.
+// POST /shorten/import
+func (h *URLHandler) HandleImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		errors.BadRequest("Use POST method").WriteJSON(w)
+		return
+	}
+
+	// Without full duplex, net/http drains and closes the upload as soon as
+	// the first progress line is flushed. Test recorders don't support it
+	// and don't need it.
+	flusher := http.NewResponseController(w)
+	_ = flusher.EnableFullDuplex()
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+
+	var progress importProgress
+	fail := func(line int, appErr *errors.AppError) {
+		progress.Failed++
+		enc.Encode(importEvent{Line: line, Error: appErr})
+	}
+
+	batch := make([]pendingImport, 0, importBatchSize)
+	flush := func() {
+		if len(batch) > 0 {
+			reqs := make([]model.CreateURLRequest, len(batch))
+			for i, item := range batch {
+				reqs[i] = item.req
+			}
+			// Each batch counts against the create limit, or none of it
+			var results []service.BatchResult
+			var err error
+			limitErr := middleware.ChargeCreates(r.Context(), len(reqs))
+			if limitErr == nil {
+				results, err = h.service.CreateShortURLBatch(reqs)
+			}
+			for i, item := range batch {
+				switch {
+				case limitErr != nil:
+					fail(item.line, limitErr)
+				case err != nil:
+					fail(item.line, serverError(err))
+				case results[i].Err != nil:
+					fail(item.line, createError(results[i].Err, item.req.CustomAlias))
+				default:
+					progress.Created++
+				}
+			}
+			batch = batch[:0]
+		}
+		enc.Encode(importEvent{Progress: &progress})
+		flusher.Flush()
+	}
+
+	reader := bufio.NewReaderSize(r.Body, maxImportLine)
+	for {
+		raw, tooLong, err := readImportLine(reader)
+		if err != nil && len(raw) == 0 && !tooLong {
+			if err != io.EOF {
+				// The upload broke off; report what was read so far
+				fail(progress.Lines+1, errors.BadRequest("Failed to read request body: "+err.Error()))
+			}
+			break
+		}
+		progress.Lines++
+		line := progress.Lines
+
+		if tooLong {
+			fail(line, errors.BadRequest("Line exceeds 16 KB"))
+			continue
+		}
+		raw = bytes.TrimSpace(raw)
+		if len(raw) == 0 {
+			progress.Lines-- // blank lines don't count
+			continue
+		}
+
+		var req model.CreateURLRequest
+		if err := json.Unmarshal(raw, &req); err != nil {
+			fail(line, errors.InvalidJSON(err.Error()))
+			continue
+		}
+		if appErr := h.prepareCreate(r, &req); appErr != nil {
+			fail(line, appErr)
+			continue
+		}
+
+		batch = append(batch, pendingImport{line: line, req: req})
+		if len(batch) == importBatchSize {
+			flush()
+		}
+	}
+	if len(batch) > 0 {
+		flush()
+	}
+
+	enc.Encode(importEvent{Done: &progress})
+}
+
+// readImportLine returns the next line without its newline. A line longer
+// than the reader's buffer is discarded and reported as tooLong, so one bad
+// line can't force the whole upload into memory.
+func readImportLine(r *bufio.Reader) (line []byte, tooLong bool, err error) {
+	line, err = r.ReadSlice('\n')
+	if err != bufio.ErrBufferFull {
+		return line, false, err
+	}
+	for err == bufio.ErrBufferFull {
+		_, err = r.ReadSlice('\n')
+	}
+	if err == io.EOF {
+		err = nil
+	}
+	return nil, true, err
+}
@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+)
+
+// jsonpCallback accepts dotted JavaScript identifiers such as
+// "cb" or "widget.onStats", and nothing that could carry markup or code
+var jsonpCallback = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*(\.[A-Za-z_$][A-Za-z0-9_$]*)*$`)
+
+// maxJSONPCallback bounds the callback name echoed into the response
+const maxJSONPCallback = 64
+
+// validJSONPCallback reports whether name is safe to echo as a callback
+func validJSONPCallback(name string) bool {
+	return len(name) <= maxJSONPCallback && jsonpCallback.MatchString(name)
+}
+
+// writeJSONP writes v as callback({...}); for script-tag embeds. The
+// leading comment keeps the body from being read as a Flash file, and
+// nosniff stops browsers treating it as anything but script.
+func writeJSONP(w http.ResponseWriter, callback string, v any) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		serverError(err).WriteJSON(w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/javascript; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Write([]byte("/**/" + callback + "("))
+	w.Write(body)
+	w.Write([]byte(");"))
+}
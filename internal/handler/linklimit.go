@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/darkodi/url-shortener/internal/errors"
+	"github.com/darkodi/url-shortener/internal/metrics"
+	"github.com/darkodi/url-shortener/internal/service"
+)
+
+// LinkLimitStore counts events in a sliding window shared by all
+// instances. *cache.RedisCache satisfies it.
+type LinkLimitStore interface {
+	Allow(ctx context.Context, key string, limit int, window time.Duration, now time.Time) (bool, time.Time, error)
+}
+
+// LinkLimit caps redirects per short code, independent of the per-IP limiter
+type LinkLimit struct {
+	Limit              int           // Redirects per code per window
+	Window             time.Duration // Sliding window length
+	RetryAfterHTTPDate bool          // Send Retry-After as an HTTP-date
+}
+
+// WithLinkLimit protects destinations from a single viral or abused code
+func (h *URLHandler) WithLinkLimit(store LinkLimitStore, limit LinkLimit) *URLHandler {
+	h.linkLimitStore = store
+	h.linkLimit = limit
+	return h
+}
+
+// linkLimited is the resolve error of a code over its redirect limit
+type linkLimited struct {
+	now     time.Time
+	seconds int // until the window frees up, at least 1
+}
+
+func (linkLimited) Error() string { return "short code exceeded its redirect limit" }
+
+// withLinkLimit makes resolves through ctx charge the per-link limit.
+// The service charges a code only once it is known to resolve, so
+// lookups of codes that don't exist never fill the store; a code over
+// its limit fails the resolve with linkLimited. Store errors fail open
+// so redirects keep working.
+func (h *URLHandler) withLinkLimit(ctx context.Context) context.Context {
+	if h.linkLimitStore == nil {
+		return ctx
+	}
+	return service.WithAdmission(ctx, func(ctx context.Context, shortCode string) error {
+		now := time.Now()
+		allowed, resetAt, err := h.linkLimitStore.Allow(ctx, "linklimit:"+shortCode, h.linkLimit.Limit, h.linkLimit.Window, now)
+		if err != nil || allowed {
+			return nil
+		}
+		seconds := int((resetAt.Sub(now) + time.Second - 1) / time.Second)
+		return linkLimited{now: now, seconds: max(seconds, 1)}
+	})
+}
+
+// writeLinkLimited answers the 429 for a resolve refused by withLinkLimit
+func (h *URLHandler) writeLinkLimited(w http.ResponseWriter, shortCode string, limited linkLimited) {
+	metrics.RateLimitRejections.Inc(metrics.LinkLimit)
+	if h.linkLimit.RetryAfterHTTPDate {
+		w.Header().Set("Retry-After", limited.now.Add(time.Duration(limited.seconds)*time.Second).UTC().Format(http.TimeFormat))
+	} else {
+		w.Header().Set("Retry-After", strconv.Itoa(limited.seconds))
+	}
+	errors.LinkRateLimited(shortCode).WithRetryAfter(limited.seconds).WriteJSON(w)
+}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/darkodi/url-shortener/internal/errors"
+	"github.com/darkodi/url-shortener/internal/service"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// QR image sizes in pixels; ?size= is clamped to [minQRSize, maxQRSize]
+const (
+	defaultQRSize = 256
+	minQRSize     = 64
+	maxQRSize     = 1024
+)
+
+// qrCacheControl keeps QR images in the caller's cache only, and briefly:
+// a code can be deleted or renamed, and its domain follows the region
+const qrCacheControl = "private, max-age=300"
+
+// handleQR returns a PNG QR code of the full short URL. Looking a code up
+// for its QR is not a visit, so no click is counted.
+// GET /{shortCode}/qr?size=256
+func (h *URLHandler) handleQR(w http.ResponseWriter, r *http.Request, shortCode string) {
+	if appErr := h.validator.ValidateShortCode(shortCode); appErr != nil {
+		appErr.WriteJSON(w)
+		return
+	}
+
+	size := defaultQRSize
+	if raw := r.URL.Query().Get("size"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			errors.BadRequest(fmt.Sprintf("size must be a number of pixels between %d and %d", minQRSize, maxQRSize)).WriteJSON(w)
+			return
+		}
+		size = min(max(parsed, minQRSize), maxQRSize)
+	}
+
+	stats, err := h.service.GetURLStatsContext(service.WithRegion(r.Context(), h.callerRegion(r)), shortCode)
+	if err != nil {
+		if err == service.ErrURLNotFound {
+			h.writeError(w, r, errors.URLNotFound(shortCode))
+			return
+		}
+		h.writeError(w, r, serverError(err))
+		return
+	}
+
+	png, err := qrcode.Encode(stats.ShortURL, qrcode.Medium, size)
+	if err != nil {
+		h.writeError(w, r, errors.Internal(""))
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Content-Length", strconv.Itoa(len(png)))
+	w.Header().Set("Cache-Control", qrCacheControl)
+	if h.regionHeader != "" {
+		w.Header().Set("Vary", h.regionHeader)
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(png)
+}
@@ -1,28 +1,306 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 
+	"github.com/darkodi/url-shortener/internal/encoder"
 	"github.com/darkodi/url-shortener/internal/errors"
+	"github.com/darkodi/url-shortener/internal/metrics"
+	"github.com/darkodi/url-shortener/internal/middleware"
 	"github.com/darkodi/url-shortener/internal/model"
 	"github.com/darkodi/url-shortener/internal/service"
 	"github.com/darkodi/url-shortener/internal/validator"
 )
 
+const (
+	// defaultCapacityWindow is the lookback used by /admin/capacity when no window is given
+	defaultCapacityWindow = time.Hour
+
+	// defaultTagStatsLimit caps /admin/stats/by-tag unless configured
+	defaultTagStatsLimit = 100
+
+	// defaultAliasPageLimit caps each page of /admin/aliases unless configured
+	defaultAliasPageLimit = 100
+
+	// defaultRecentLimit caps /admin/recent unless configured
+	defaultRecentLimit = 100
+
+	// defaultStatsDays and maxStatsDays bound the ?granularity=day series
+	defaultStatsDays = 30
+	maxStatsDays     = 366
+
+	// maxURLPageLimit caps each page of GET /urls
+	maxURLPageLimit = 100
+
+	// readyTimeout bounds the dependency pings of /health/ready, so a hung
+	// database fails the probe instead of stalling it
+	readyTimeout = 2 * time.Second
+
+	// defaultMaxPathDepth allows /{code} and /{code}/stats
+	defaultMaxPathDepth = 2
+
+	// robotsTxt allows only the landing page; every other path is a short code or API
+	robotsTxt = `User-agent: *
+Allow: /$
+Disallow: /
+`
+
+	// comingSoonPage is served for reserved codes that have no destination yet
+	comingSoonPage = `<!DOCTYPE html>
+<html><head><title>Coming soon</title></head>
+<body><h1>Coming soon</h1><p>This short link has been reserved and will be available shortly.</p></body></html>
+`
+)
+
 // URLHandler handles HTTP requests for URL operations
 type URLHandler struct {
-	service   *service.URLService
-	validator *validator.URLValidator
+	service      *service.URLService
+	validator    *validator.URLValidator
+	maxPathDepth int  // max "/"-separated segments accepted by the catch-all
+	noIndex      bool // send X-Robots-Tag: noindex, nofollow on short links
+	startedAt    time.Time
+	errorPages   *ErrorPages             // HTML 404/500 pages for browsers; nil means JSON only
+	redirectBody bool                    // echo the destination in the body and a Link header
+	redirectCode int                     // 301, cached by browsers, or 302, counted on every visit
+	conditional  bool                    // Last-Modified and 304s on 301 redirects
+	tagStatsMax  int                     // max tags returned by /admin/stats/by-tag
+	aliasPageMax int                     // max taken aliases per /admin/aliases page
+	recentMax    int                     // max links returned by /admin/recent
+	decodeAPI    bool                    // serve GET /api/decode/{code}
+	regionHeader string                  // request header naming the caller's region
+	ownerHeader  string                  // request header naming the account that owns new links
+	metrics      bool                    // serve GET /metrics
+	latency      *metrics.LatencyTracker // serve GET /admin/latency when set
+	adminApart   bool                    // admin routes are left to SetupAdminRoutes
+
+	// Per-link click gauges on /admin/metrics/links; zero linkMetricsMax
+	// disables the endpoint
+	linkMetricsMax int
+	linkMetricsMin uint64
+	statsJSONP     bool // honor ?callback= on stats for legacy embeds
+	cacheHeader    bool // send X-Cache: HIT|MISS on redirects
+
+	// Click increments (see WithClickStep); zero apiClickStep counts 1
+	apiClickStep    uint64
+	clickStepHeader string
+	maxClickStep    uint64
+
+	// Guards the routes that create, list, or delete links (see
+	// WithAPIKeys); nil leaves them open
+	apiKeyAuth middleware.Middleware
+
+	// Per-code redirect cap (see linklimit.go); nil store means unlimited
+	linkLimitStore LinkLimitStore
+	linkLimit      LinkLimit
+
+	// Set while connection pools warm up (see warmup.go); /health reports
+	// starting until WarmUp returns
+	warming atomic.Bool
+
+	// Longest POST /shorten waits for its body; zero means no limit
+	bodyTimeout time.Duration
 }
 
 // NewURLHandler creates a new handler instance
 func NewURLHandler(svc *service.URLService) *URLHandler {
 	return &URLHandler{
-		service:   svc,
-		validator: validator.NewURLValidator(),
+		service:      svc,
+		validator:    validator.NewURLValidator(),
+		maxPathDepth: defaultMaxPathDepth,
+		startedAt:    time.Now(),
+		tagStatsMax:  defaultTagStatsLimit,
+		aliasPageMax: defaultAliasPageLimit,
+		recentMax:    defaultRecentLimit,
+		redirectCode: http.StatusMovedPermanently,
+	}
+}
+
+// WithValidator replaces the default input validator
+func (h *URLHandler) WithValidator(v *validator.URLValidator) *URLHandler {
+	h.validator = v
+	return h
+}
+
+// WithTagStatsLimit caps how many tags /admin/stats/by-tag returns
+func (h *URLHandler) WithTagStatsLimit(limit int) *URLHandler {
+	if limit > 0 {
+		h.tagStatsMax = limit
+	}
+	return h
+}
+
+// WithAliasPageLimit caps how many taken aliases one /admin/aliases page returns
+func (h *URLHandler) WithAliasPageLimit(limit int) *URLHandler {
+	if limit > 0 {
+		h.aliasPageMax = limit
+	}
+	return h
+}
+
+// WithRecentLimit caps how many links /admin/recent returns
+func (h *URLHandler) WithRecentLimit(limit int) *URLHandler {
+	if limit > 0 {
+		h.recentMax = limit
+	}
+	return h
+}
+
+// WithDecodeEndpoint serves GET /api/decode/{code}. It reveals internal
+// IDs, so it should only be enabled behind admin auth.
+func (h *URLHandler) WithDecodeEndpoint(enabled bool) *URLHandler {
+	h.decodeAPI = enabled
+	return h
+}
+
+// WithMetrics serves Prometheus-format counters on GET /metrics
+func (h *URLHandler) WithMetrics(enabled bool) *URLHandler {
+	h.metrics = enabled
+	return h
+}
+
+// WithAdminListener leaves the admin endpoints, /metrics, and the decode
+// API out of SetupRoutes, for serving SetupAdminRoutes on a separate port
+// that isn't exposed publicly
+func (h *URLHandler) WithAdminListener(enabled bool) *URLHandler {
+	h.adminApart = enabled
+	return h
+}
+
+// WithLatency serves the tracker's per-route percentiles on
+// GET /admin/latency
+func (h *URLHandler) WithLatency(tracker *metrics.LatencyTracker) *URLHandler {
+	h.latency = tracker
+	return h
+}
+
+// WithLinkMetrics serves click counts of the maxLinks busiest links with at
+// least minClicks clicks as Prometheus gauges, so a dashboard can follow
+// individual links without one series per stored code
+func (h *URLHandler) WithLinkMetrics(maxLinks int, minClicks uint64) *URLHandler {
+	h.linkMetricsMax = maxLinks
+	h.linkMetricsMin = minClicks
+	return h
+}
+
+// WithRegionHeader reads the caller's region for regional short domains
+// from the named request header
+func (h *URLHandler) WithRegionHeader(name string) *URLHandler {
+	h.regionHeader = name
+	return h
+}
+
+// callerRegion is the region r names in the region header, if any
+func (h *URLHandler) callerRegion(r *http.Request) string {
+	if h.regionHeader == "" {
+		return ""
+	}
+	return strings.TrimSpace(r.Header.Get(h.regionHeader))
+}
+
+// WithOwnerHeader reads the account that owns new links from the named
+// request header. It should be set by a trusted proxy, not the client.
+func (h *URLHandler) WithOwnerHeader(name string) *URLHandler {
+	h.ownerHeader = name
+	return h
+}
+
+// WithAPIKeys requires one of keys (mapping key to owner) on the routes
+// that create links (/shorten, /shorten/batch, /shorten/import, /reserve),
+// on GET /urls, and on DELETE /{code}. The key's owner owns the links it
+// creates, and can list and delete only those. Redirects stay public.
+// An empty map leaves these routes open.
+func (h *URLHandler) WithAPIKeys(keys map[string]string) *URLHandler {
+	h.apiKeyAuth = nil
+	if len(keys) > 0 {
+		h.apiKeyAuth = middleware.APIKeyAuth(keys)
+	}
+	return h
+}
+
+// WithNoIndex asks search engines not to index or follow short links
+func (h *URLHandler) WithNoIndex(enabled bool) *URLHandler {
+	h.noIndex = enabled
+	return h
+}
+
+// WithRedirectStatus sets the status of short-link redirects. Browsers
+// cache 301s and skip the server on repeat visits, so 302 counts every click.
+func (h *URLHandler) WithRedirectStatus(status int) *URLHandler {
+	if status == http.StatusMovedPermanently || status == http.StatusFound {
+		h.redirectCode = status
+	}
+	return h
+}
+
+// WithConditionalRedirects sends Last-Modified, the link's creation time,
+// on 301 redirects and answers If-Modified-Since with 304 Not Modified,
+// which is not counted as a click. Crawlers revalidate this way.
+func (h *URLHandler) WithConditionalRedirects(enabled bool) *URLHandler {
+	h.conditional = enabled
+	return h
+}
+
+// WithRedirectBody also reports the destination in a JSON body and a Link
+// header, for clients that can't read Location easily
+func (h *URLHandler) WithRedirectBody(enabled bool) *URLHandler {
+	h.redirectBody = enabled
+	return h
+}
+
+// WithCacheStatusHeader adds X-Cache to redirects: HIT when the link came
+// from the cache, MISS when it was read from the database. For debugging.
+func (h *URLHandler) WithCacheStatusHeader(enabled bool) *URLHandler {
+	h.cacheHeader = enabled
+	return h
+}
+
+// WithClickStep sets how much one resolve adds to the click count:
+// apiStep for POST /api/resolve, 1 for redirects. When header is set, a
+// request may override the step with a value from 1 to maxStep; like
+// WithOwnerHeader, it should be set by a trusted proxy, not the client.
+func (h *URLHandler) WithClickStep(apiStep uint64, header string, maxStep uint64) *URLHandler {
+	h.apiClickStep = apiStep
+	h.clickStepHeader = header
+	h.maxClickStep = maxStep
+	return h
+}
+
+// clickStep returns the click increment for r, defaulting to base.
+// Header values that are not a number in range are ignored.
+func (h *URLHandler) clickStep(r *http.Request, base uint64) uint64 {
+	if h.clickStepHeader != "" {
+		if v := r.Header.Get(h.clickStepHeader); v != "" {
+			if n, err := strconv.ParseUint(v, 10, 64); err == nil && n >= 1 && n <= h.maxClickStep {
+				return n
+			}
+		}
+	}
+	return max(base, 1)
+}
+
+// WithStatsJSONP answers GET /{code}/stats?callback=fn with JSONP for
+// embeds that can only load scripts. Off by default.
+func (h *URLHandler) WithStatsJSONP(enabled bool) *URLHandler {
+	h.statsJSONP = enabled
+	return h
+}
+
+// WithMaxPathDepth sets the maximum number of path segments the redirect
+// catch-all will consider before returning 404
+func (h *URLHandler) WithMaxPathDepth(depth int) *URLHandler {
+	if depth > 0 {
+		h.maxPathDepth = depth
 	}
+	return h
 }
 
 // ============ HANDLERS ============
@@ -38,51 +316,304 @@ func (h *URLHandler) HandleShorten(w http.ResponseWriter, r *http.Request) {
 
 	// Parse JSON body
 	var req model.CreateURLRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := h.decodeBody(w, r, &req); err != nil {
+		if err == context.DeadlineExceeded {
+			errors.RequestTimeout().WriteJSON(w)
+			return
+		}
 		errors.InvalidJSON(err.Error()).WriteJSON(w)
 		return
 	}
 
+	if appErr := h.prepareCreate(r, &req); appErr != nil {
+		appErr.WriteJSON(w)
+		return
+	}
+
+	// Call service
+	resp, err := h.service.CreateShortURLContext(r.Context(), req)
+	if err != nil {
+		createError(err, req.CustomAlias).WriteJSON(w)
+		return
+	}
+
+	// Success! 200 rather than 201 when the owner's existing link came back
+	status := http.StatusCreated
+	if resp.Existing {
+		status = http.StatusOK
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// prepareCreate validates and normalizes a create request and fills in
+// what comes from the request headers
+func (h *URLHandler) prepareCreate(r *http.Request, req *model.CreateURLRequest) *errors.AppError {
 	// Validate URL with enhanced validator
 	if appErr := h.validator.ValidateURL(req.URL); appErr != nil {
+		return appErr
+	}
+
+	req.URL = h.validator.NormalizeURL(req.URL)
+
+	// Language variants are checked and normalized like the main URL
+	for lang, originalURL := range req.LanguageURLs {
+		if appErr := h.validator.ValidateURL(originalURL); appErr != nil {
+			return appErr
+		}
+		req.LanguageURLs[lang] = h.validator.NormalizeURL(originalURL)
+	}
+
+	// Validate custom alias if provided; surrounding spaces are dropped
+	req.CustomAlias = strings.TrimSpace(req.CustomAlias)
+	if appErr := h.validator.ValidateCustomCode(req.CustomAlias); appErr != nil {
+		return appErr
+	}
+
+	req.Region = h.callerRegion(r)
+	req.Owner = h.callerOwner(r)
+
+	// Seeding click counts would let anyone fake popularity
+	if req.InitialClicks > 0 && !middleware.IsAdmin(r.Context()) {
+		return errors.AdminOnly("initial_clicks")
+	}
+	return nil
+}
+
+// createError maps CreateShortURL errors to AppErrors
+func createError(err error, alias string) *errors.AppError {
+	switch err {
+	case service.ErrEmptyURL:
+		return errors.MissingField("url")
+	case service.ErrInvalidURL:
+		return errors.InvalidURL("URL must be valid http/https")
+	case service.ErrAliasExists:
+		return errors.URLExists(alias)
+	case service.ErrOwnerHasURL:
+		return errors.Conflict("You already have a short link to this URL; create it without custom_alias to get it back")
+	case service.ErrInvalidAlias:
+		return errors.BadRequest("Alias must be 3-20 alphanumeric characters")
+	case service.ErrAliasSpace:
+		return errors.BadRequest("Alias must not contain spaces")
+	case service.ErrInvalidTag:
+		return errors.BadRequest("Tag must be up to 64 alphanumeric characters")
+	case service.ErrSigningDisabled:
+		return errors.BadRequest("Signed links are not enabled")
+	case service.ErrWebhooksDisabled:
+		return errors.BadRequest("Click webhooks are not enabled")
+	case service.ErrInvalidWebhook:
+		return errors.BadRequest(service.ErrInvalidWebhook.Error())
+	case service.ErrInvalidOwner:
+		return errors.BadRequest("Owner must be up to 64 characters")
+	case service.ErrInvalidExpiry:
+		return errors.BadRequest("Use either expires_in (a positive duration, e.g. 72h) or a future expires_at")
+	case service.ErrInvalidDelay:
+		return errors.BadRequest(fmt.Sprintf("delay_seconds must be between 0 and %d", service.MaxRedirectDelay))
+	case service.ErrInvalidLang:
+		return errors.BadRequest(service.ErrInvalidLang.Error())
+	}
+	return serverError(err)
+}
+
+// batchItem is one entry of the /shorten/batch response: the created
+// link's fields, or error
+type batchItem struct {
+	*model.CreateURLResponse
+	Error *errors.AppError `json:"error,omitempty"`
+}
+
+// HandleShortenBatch creates up to service.MaxBatchSize links in one transaction
+// POST /shorten/batch [{"url": ...}, {"url": ..., "custom_alias": ...}]
+//
+// The response is 200 with one entry per request item, in order. Items
+// that fail carry an error and don't affect the others.
+func (h *URLHandler) HandleShortenBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		errors.BadRequest("Use POST method").WriteJSON(w)
+		return
+	}
+
+	var reqs []model.CreateURLRequest
+	if err := json.NewDecoder(r.Body).Decode(&reqs); err != nil {
+		errors.InvalidJSON(err.Error()).WriteJSON(w)
+		return
+	}
+	if len(reqs) == 0 {
+		errors.BadRequest("Batch must contain at least one item").WriteJSON(w)
+		return
+	}
+	if len(reqs) > service.MaxBatchSize {
+		errors.BadRequest(service.ErrBatchTooLarge.Error()).WriteJSON(w)
+		return
+	}
+
+	items := make([]batchItem, len(reqs))
+	valid := make([]model.CreateURLRequest, 0, len(reqs))
+	positions := make([]int, 0, len(reqs)) // index in reqs of each valid item
+	for i := range reqs {
+		if appErr := h.prepareCreate(r, &reqs[i]); appErr != nil {
+			items[i].Error = appErr
+			continue
+		}
+		valid = append(valid, reqs[i])
+		positions = append(positions, i)
+	}
+
+	// The whole batch counts against the create limit, or none of it
+	if appErr := middleware.ChargeCreates(r.Context(), len(valid)); appErr != nil {
 		appErr.WriteJSON(w)
 		return
 	}
 
-	// Validate custom alias if provided
+	if len(valid) > 0 {
+		results, err := h.service.CreateShortURLBatch(valid)
+		if err != nil {
+			serverError(err).WriteJSON(w)
+			return
+		}
+		for j, result := range results {
+			i := positions[j]
+			if result.Err != nil {
+				items[i].Error = createError(result.Err, reqs[i].CustomAlias)
+				continue
+			}
+			items[i].CreateURLResponse = result.Response
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(items)
+}
+
+// HandleReserve holds a custom alias without a destination
+// POST /reserve
+func (h *URLHandler) HandleReserve(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		errors.BadRequest("Use POST method").WriteJSON(w)
+		return
+	}
+
+	var req model.ReserveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		errors.InvalidJSON(err.Error()).WriteJSON(w)
+		return
+	}
+
+	req.CustomAlias = strings.TrimSpace(req.CustomAlias)
+	if req.CustomAlias == "" {
+		errors.MissingField("custom_alias").WriteJSON(w)
+		return
+	}
 	if appErr := h.validator.ValidateCustomCode(req.CustomAlias); appErr != nil {
 		appErr.WriteJSON(w)
 		return
 	}
 
-	// Call service
-	resp, err := h.service.CreateShortURL(req)
+	req.Owner = h.callerOwner(r)
+	req.Region = h.callerRegion(r)
+
+	resp, err := h.service.ReserveShortCodeContext(r.Context(), req)
+	if err != nil {
+		switch err {
+		case service.ErrAliasExists:
+			errors.URLExists(req.CustomAlias).WriteJSON(w)
+		case service.ErrInvalidAlias, service.ErrAliasSpace:
+			createError(err, req.CustomAlias).WriteJSON(w)
+		default:
+			serverError(err).WriteJSON(w)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// handleActivate sets the destination of a reserved code
+// PUT /{shortCode}
+func (h *URLHandler) handleActivate(w http.ResponseWriter, r *http.Request, shortCode string) {
+	if appErr := h.validator.ValidateShortCode(shortCode); appErr != nil {
+		appErr.WriteJSON(w)
+		return
+	}
+
+	var req model.ActivateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		errors.InvalidJSON(err.Error()).WriteJSON(w)
+		return
+	}
+
+	if appErr := h.validator.ValidateURL(req.URL); appErr != nil {
+		appErr.WriteJSON(w)
+		return
+	}
+	req.URL = h.validator.NormalizeURL(req.URL)
+	req.Region = h.callerRegion(r)
+
+	resp, err := h.service.ActivateShortCodeContext(r.Context(), shortCode, h.callerOwner(r), middleware.IsAdmin(r.Context()), req)
 	if err != nil {
-		// Map service errors to AppErrors
 		switch err {
 		case service.ErrEmptyURL:
 			errors.MissingField("url").WriteJSON(w)
 		case service.ErrInvalidURL:
 			errors.InvalidURL("URL must be valid http/https").WriteJSON(w)
-		case service.ErrAliasExists:
-			errors.URLExists(req.CustomAlias).WriteJSON(w)
-		case service.ErrInvalidAlias:
-			errors.BadRequest("Alias must be 3-20 alphanumeric characters").WriteJSON(w)
+		case service.ErrURLNotFound:
+			errors.URLNotFound(shortCode).WriteJSON(w)
+		case service.ErrNotOwner:
+			errors.NotOwner(shortCode).WriteJSON(w)
+		case service.ErrNotReserved:
+			errors.Conflict("Short code is already active").WriteJSON(w)
 		default:
-			errors.Internal("").WriteJSON(w)
+			serverError(err).WriteJSON(w)
 		}
 		return
 	}
 
-	// Success!
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(resp)
 }
 
+// handleDelete removes a short code; 204 on success
+func (h *URLHandler) handleDelete(w http.ResponseWriter, r *http.Request, shortCode string) {
+	if appErr := h.validator.ValidateShortCode(shortCode); appErr != nil {
+		appErr.WriteJSON(w)
+		return
+	}
+
+	if err := h.service.DeleteURLContext(r.Context(), shortCode, h.callerOwner(r), middleware.IsAdmin(r.Context())); err != nil {
+		switch err {
+		case service.ErrURLNotFound:
+			errors.URLNotFound(shortCode).WriteJSON(w)
+		case service.ErrNotOwner:
+			errors.NotOwner(shortCode).WriteJSON(w)
+		default:
+			serverError(err).WriteJSON(w)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeComingSoon renders the placeholder for a reserved code
+func writeComingSoon(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store") // destination will change on activation
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(comingSoonPage))
+}
+
 // HandleRedirect redirects to the original URL
 // GET /{shortCode}
 func (h *URLHandler) HandleRedirect(w http.ResponseWriter, r *http.Request) {
+	// Reject deep paths before doing any string work on them
+	if exceedsDepth(r.URL.Path, h.maxPathDepth) {
+		http.NotFound(w, r)
+		return
+	}
+
 	// Extract short code from path: /abc → abc
 	shortCode := strings.TrimPrefix(r.URL.Path, "/")
 
@@ -93,7 +624,7 @@ func (h *URLHandler) HandleRedirect(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Skip if it's a known route
-	if shortCode == "shorten" || shortCode == "health" {
+	if shortCode == "shorten" || shortCode == "health" || shortCode == "reserve" || shortCode == "errors" {
 		http.NotFound(w, r)
 		return
 	}
@@ -105,70 +636,844 @@ func (h *URLHandler) HandleRedirect(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Validate short code format
-	if appErr := h.validator.ValidateShortCode(shortCode); appErr != nil {
-		appErr.WriteJSON(w)
+	// QR code of the short link: /abc/qr or /abc/qr.png
+	for _, suffix := range []string{"/qr", "/qr.png"} {
+		if code, ok := strings.CutSuffix(shortCode, suffix); ok {
+			h.handleQR(w, r, code)
+			return
+		}
+	}
+
+	// Activate a reserved code: PUT /abc. Gated like reserving it.
+	if r.Method == http.MethodPut {
+		h.requireAPIKey(func(w http.ResponseWriter, r *http.Request) {
+			h.handleActivate(w, r, shortCode)
+		}).ServeHTTP(w, r)
 		return
 	}
 
-	// Resolve the short code
-	originalURL, err := h.service.Resolve(shortCode)
-	if err != nil {
-		if err == service.ErrURLNotFound {
-			errors.URLNotFound(shortCode).WriteJSON(w)
-			return
-		}
-		errors.Internal("").WriteJSON(w)
+	// Delete a code: DELETE /abc. Needs an API key like creating one.
+	if r.Method == http.MethodDelete {
+		h.requireAPIKey(func(w http.ResponseWriter, r *http.Request) {
+			h.handleDelete(w, r, shortCode)
+		}).ServeHTTP(w, r)
 		return
 	}
 
-	// Redirect!
-	http.Redirect(w, r, originalURL, http.StatusMovedPermanently)
-}
+	// A language suffix (/abc123.fr) picks that language's destination
+	var lang string
+	if code, suffix, ok := strings.Cut(shortCode, "."); ok && service.ValidLanguageTag(strings.ToLower(suffix)) {
+		shortCode, lang = code, suffix
+	}
 
-// handleStats returns statistics for a short URL
-// GET /{shortCode}/stats
-func (h *URLHandler) handleStats(w http.ResponseWriter, r *http.Request, shortCode string) {
 	// Validate short code format
 	if appErr := h.validator.ValidateShortCode(shortCode); appErr != nil {
 		appErr.WriteJSON(w)
 		return
 	}
 
-	stats, err := h.service.GetURLStats(shortCode)
+	// Resolve the short code
+	ctx := h.withLinkLimit(r.Context())
+	if r.Header.Get("DNT") == "1" {
+		ctx = service.WithDoNotTrack(ctx) // service applies the configured DNT policy
+	}
+	ctx = service.WithClickWeight(ctx, h.clickStep(r, 1))
+	ctx = service.WithClickMeta(ctx, clickMeta(r))
+	if lang != "" {
+		ctx = service.WithLanguage(ctx, lang)
+	}
+	query := r.URL.Query()
+	if sig := query.Get(service.SignatureParam); sig != "" {
+		ctx = service.WithSignature(ctx, query.Get(service.SignatureExpiresParam), sig)
+	}
+	conditional := h.conditional && h.redirectCode == http.StatusMovedPermanently
+	if since, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && conditional {
+		ctx = service.WithIfModifiedSince(ctx, since)
+	}
+
+	link, err := h.service.ResolveLinkContext(ctx, shortCode)
+	if err == service.ErrNotModified {
+		h.setRobotsTag(w)
+		w.Header().Set("Last-Modified", link.CreatedAt.UTC().Format(http.TimeFormat))
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
 	if err != nil {
+		if limited, ok := err.(linkLimited); ok {
+			h.writeLinkLimited(w, shortCode, limited)
+			return
+		}
 		if err == service.ErrURLNotFound {
-			errors.URLNotFound(shortCode).WriteJSON(w)
+			h.writeError(w, r, errors.URLNotFound(shortCode))
 			return
 		}
-		errors.Internal("").WriteJSON(w)
+		if err == service.ErrCodeChecksum {
+			h.writeError(w, r, errors.CodeTypo(shortCode))
+			return
+		}
+		if err == service.ErrURLExpired {
+			h.writeError(w, r, errors.URLExpired(shortCode))
+			return
+		}
+		if err == service.ErrSignatureInvalid {
+			h.writeError(w, r, errors.SignatureInvalid())
+			return
+		}
+		if err == service.ErrSignatureExpired {
+			h.writeError(w, r, errors.SignatureExpired())
+			return
+		}
+		if err == service.ErrURLReserved {
+			h.setRobotsTag(w)
+			writeComingSoon(w)
+			return
+		}
+		h.writeError(w, r, serverError(err))
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(stats)
-}
-
-// HandleHealth returns service health status
-// GET /health
-func (h *URLHandler) HandleHealth(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(`{"status": "healthy"}`))
+	// Redirect! Delayed links show a countdown first, and links to dead
+	// destinations a warning, except to crawlers
+	h.setRobotsTag(w)
+	if h.cacheHeader {
+		status := "MISS"
+		if link.Source == service.SourceCache {
+			status = "HIT"
+		}
+		w.Header().Set("X-Cache", status)
+	}
+	if link.Broken && !isCrawler(r) {
+		writeBrokenLink(w, link.OriginalURL)
+		return
+	}
+	// A page that fails to render still gets the visitor there, just sooner
+	if link.DelaySeconds > 0 && !isCrawler(r) && writeCountdown(w, link.OriginalURL, link.DelaySeconds) {
+		return
+	}
+	if conditional && !link.CreatedAt.IsZero() {
+		w.Header().Set("Last-Modified", link.CreatedAt.UTC().Format(http.TimeFormat))
+	}
+	h.writeRedirect(w, shortCode, link.OriginalURL)
 }
 
-// ============ ROUTER SETUP ============
+// HandleResolve returns a code's destination as JSON instead of
+// redirecting, for clients behind proxies that mishandle redirects.
+// The lookup is only counted as a click when the body asks for it.
+// POST /api/resolve
+func (h *URLHandler) HandleResolve(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		errors.BadRequest("Use POST method").WriteJSON(w)
+		return
+	}
+
+	var req model.ResolveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		errors.InvalidJSON(err.Error()).WriteJSON(w)
+		return
+	}
+	if req.Code == "" {
+		errors.MissingField("code").WriteJSON(w)
+		return
+	}
+	if appErr := h.validator.ValidateShortCode(req.Code); appErr != nil {
+		appErr.WriteJSON(w)
+		return
+	}
+
+	ctx := h.withLinkLimit(r.Context())
+	if !req.CountClick {
+		ctx = service.WithoutClick(ctx)
+	} else {
+		if r.Header.Get("DNT") == "1" {
+			ctx = service.WithDoNotTrack(ctx)
+		}
+		ctx = service.WithClickWeight(ctx, h.clickStep(r, h.apiClickStep))
+		ctx = service.WithClickMeta(ctx, clickMeta(r))
+	}
+	if req.Signature != "" {
+		ctx = service.WithSignature(ctx, req.Expires, req.Signature)
+	}
+
+	originalURL, err := h.service.ResolveContext(ctx, req.Code)
+	if limited, ok := err.(linkLimited); ok {
+		h.writeLinkLimited(w, req.Code, limited)
+		return
+	}
+	if err != nil {
+		switch err {
+		case service.ErrURLNotFound, service.ErrURLReserved:
+			errors.URLNotFound(req.Code).WriteJSON(w)
+		case service.ErrCodeChecksum:
+			errors.CodeTypo(req.Code).WriteJSON(w)
+		case service.ErrURLExpired:
+			errors.URLExpired(req.Code).WriteJSON(w)
+		case service.ErrSignatureInvalid:
+			errors.SignatureInvalid().WriteJSON(w)
+		case service.ErrSignatureExpired:
+			errors.SignatureExpired().WriteJSON(w)
+		default:
+			serverError(err).WriteJSON(w)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(model.ResolveResponse{ShortCode: req.Code, OriginalURL: originalURL})
+}
+
+// clickMeta describes the visit for click rows; absent headers stay empty
+func clickMeta(r *http.Request) service.ClickMeta {
+	return service.ClickMeta{
+		Referrer:  r.Referer(),
+		UserAgent: r.UserAgent(),
+		ClientIP:  middleware.ClientIP(r),
+	}
+}
+
+// serverError maps an unexpected service error to a response.
+// Database timeouts are retryable, so they get 503 rather than 500.
+func serverError(err error) *errors.AppError {
+	switch err {
+	case service.ErrTimeout:
+		return errors.DatabaseTimeout()
+	case service.ErrReadOnly:
+		return errors.ReadOnly()
+	}
+	return errors.Internal("")
+}
+
+// handleStats returns statistics for a short URL, with a per-day click
+// series for ?granularity=day
+// GET /{shortCode}/stats?granularity=day&days=30
+func (h *URLHandler) handleStats(w http.ResponseWriter, r *http.Request, shortCode string) {
+	// Validate short code format
+	if appErr := h.validator.ValidateShortCode(shortCode); appErr != nil {
+		appErr.WriteJSON(w)
+		return
+	}
+
+	query := r.URL.Query()
+	days := 0
+	switch query.Get("granularity") {
+	case "":
+	case "day":
+		days = defaultStatsDays
+		if raw := query.Get("days"); raw != "" {
+			parsed, err := strconv.Atoi(raw)
+			if err != nil || parsed < 1 || parsed > maxStatsDays {
+				errors.BadRequest(fmt.Sprintf("days must be between 1 and %d", maxStatsDays)).WriteJSON(w)
+				return
+			}
+			days = parsed
+		}
+	default:
+		errors.BadRequest("granularity must be day").WriteJSON(w)
+		return
+	}
+
+	callback := ""
+	if h.statsJSONP {
+		callback = r.URL.Query().Get("callback")
+		if callback != "" && !validJSONPCallback(callback) {
+			errors.BadRequest("callback must be a JavaScript identifier").WriteJSON(w)
+			return
+		}
+	}
+
+	ctx := service.WithRegion(r.Context(), h.callerRegion(r))
+	if sig := query.Get(service.SignatureParam); sig != "" {
+		ctx = service.WithSignature(ctx, query.Get(service.SignatureExpiresParam), sig)
+	}
+	stats, err := h.service.GetURLStatsContext(ctx, shortCode)
+	if err != nil {
+		if err == service.ErrURLNotFound {
+			errors.URLNotFound(shortCode).WriteJSON(w)
+			return
+		}
+		serverError(err).WriteJSON(w)
+		return
+	}
+	if days > 0 {
+		// Today counts as one of the days
+		since := time.Now().UTC().AddDate(0, 0, 1-days)
+		if stats.ClicksByDay, err = h.service.GetClicksByDay(stats.ShortCode, since); err != nil {
+			serverError(err).WriteJSON(w)
+			return
+		}
+	}
+
+	if callback != "" {
+		writeJSONP(w, callback, stats)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
+// HandleCapacity reports creation throughput and remaining code space
+// GET /admin/capacity?window=1h
+func (h *URLHandler) HandleCapacity(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		errors.BadRequest("Use GET method").WriteJSON(w)
+		return
+	}
+
+	window := defaultCapacityWindow
+	if raw := r.URL.Query().Get("window"); raw != "" {
+		parsed, err := time.ParseDuration(raw)
+		if err != nil || parsed <= 0 {
+			errors.BadRequest("window must be a positive duration, e.g. 15m or 1h").WriteJSON(w)
+			return
+		}
+		window = parsed
+	}
+
+	stats, err := h.service.GetCapacityStats(window)
+	if err != nil {
+		serverError(err).WriteJSON(w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
+// HandleMigrateCodes re-encodes generated codes issued under an older
+// scheme so they match the current one, keeping redirects from old codes
+// POST /admin/migrate-codes
+func (h *URLHandler) HandleMigrateCodes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		errors.BadRequest("Use POST method").WriteJSON(w)
+		return
+	}
+
+	var req model.MigrateCodesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		errors.InvalidJSON(err.Error()).WriteJSON(w)
+		return
+	}
+
+	from := service.CodeScheme{Checksum: req.Checksum}
+	if req.Alphabet != "" {
+		enc, err := encoder.NewEncoder(req.Alphabet)
+		if err != nil {
+			errors.BadRequest(err.Error()).WriteJSON(w)
+			return
+		}
+		from.Encoder = enc
+	}
+	stride := req.Stride
+	if stride == 0 {
+		stride = 1
+	}
+	sequence, err := encoder.NewSequence(req.Offset, stride)
+	if err != nil {
+		errors.BadRequest(err.Error()).WriteJSON(w)
+		return
+	}
+	from.Sequence = sequence
+
+	report, err := h.service.MigrateCodes(r.Context(), from, req.DryRun)
+	if err != nil {
+		serverError(err).WriteJSON(w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// HandleDeleteCodes removes a batch of short codes
+// POST /admin/delete {"codes": ["abc", "xyz"]}
+func (h *URLHandler) HandleDeleteCodes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		errors.BadRequest("Use POST method").WriteJSON(w)
+		return
+	}
+
+	var req model.DeleteCodesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		errors.InvalidJSON(err.Error()).WriteJSON(w)
+		return
+	}
+
+	resp, err := h.service.DeleteURLs(req.Codes)
+	if err != nil {
+		switch err {
+		case service.ErrNoCodes:
+			errors.MissingField("codes").WriteJSON(w)
+		case service.ErrBatchTooLarge:
+			errors.BadRequest(err.Error()).WriteJSON(w)
+		default:
+			serverError(err).WriteJSON(w)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// HandleStatsByTag reports link and click totals per campaign tag
+// GET /admin/stats/by-tag?limit=20
+func (h *URLHandler) HandleStatsByTag(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		errors.BadRequest("Use GET method").WriteJSON(w)
+		return
+	}
+
+	limit := h.tagStatsMax
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 {
+			errors.BadRequest("limit must be a positive integer").WriteJSON(w)
+			return
+		}
+		if parsed < limit {
+			limit = parsed
+		}
+	}
+
+	stats, err := h.service.GetTagStats(r.Context(), limit)
+	if err != nil {
+		serverError(err).WriteJSON(w)
+		return
+	}
+	if stats == nil {
+		stats = []model.TagStats{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
+// HandleLinkMetrics exposes per-link click counts in the Prometheus text format
+// GET /admin/metrics/links
+func (h *URLHandler) HandleLinkMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		errors.BadRequest("Use GET method").WriteJSON(w)
+		return
+	}
+
+	links, err := h.service.GetTopLinks(r.Context(), h.linkMetricsMin, h.linkMetricsMax)
+	if err != nil {
+		serverError(err).WriteJSON(w)
+		return
+	}
+	samples := make([]metrics.Sample, len(links))
+	for i, link := range links {
+		samples[i] = metrics.Sample{Label: link.ShortCode, Value: float64(link.Clicks)}
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	metrics.WriteGauges(w, "urlshortener_link_clicks", "Click count per short link, for the busiest links.", "short_code", samples)
+}
+
+// HandleRecent lists the most recently created links, newest first
+// GET /admin/recent?limit=20
+func (h *URLHandler) HandleRecent(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		errors.BadRequest("Use GET method").WriteJSON(w)
+		return
+	}
+
+	limit := h.recentMax
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 {
+			errors.BadRequest("limit must be a positive integer").WriteJSON(w)
+			return
+		}
+		if parsed < limit {
+			limit = parsed
+		}
+	}
+
+	links, err := h.service.GetRecentLinks(r.Context(), limit)
+	if err != nil {
+		serverError(err).WriteJSON(w)
+		return
+	}
+	if links == nil {
+		links = []model.RecentLink{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(links)
+}
+
+// HandleListAliases lists reserved words and the codes already taken
+// GET /admin/aliases?after=0&limit=100
+func (h *URLHandler) HandleListAliases(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		errors.BadRequest("Use GET method").WriteJSON(w)
+		return
+	}
+
+	query := r.URL.Query()
+	limit := h.aliasPageMax
+	if raw := query.Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 {
+			errors.BadRequest("limit must be a positive integer").WriteJSON(w)
+			return
+		}
+		if parsed < limit {
+			limit = parsed
+		}
+	}
+	var after uint64
+	if raw := query.Get("after"); raw != "" {
+		parsed, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			errors.BadRequest("after must be a non-negative integer").WriteJSON(w)
+			return
+		}
+		after = parsed
+	}
+
+	taken, next, err := h.service.ListTakenAliases(after, limit)
+	if err != nil {
+		serverError(err).WriteJSON(w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(model.AliasList{
+		Reserved:  h.validator.ReservedWords(),
+		Taken:     taken,
+		NextAfter: next,
+	})
+}
+
+// HandleLatency reports recent response time percentiles per route
+// GET /admin/latency
+func (h *URLHandler) HandleLatency(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		errors.BadRequest("Use GET method").WriteJSON(w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.latency.Summary())
+}
+
+// HandleDecode reports the record ID a generated code decodes to, or that
+// the code is a custom alias
+// GET /api/decode/{code}
+func (h *URLHandler) HandleDecode(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		errors.BadRequest("Use GET method").WriteJSON(w)
+		return
+	}
+
+	shortCode := strings.TrimPrefix(r.URL.Path, "/api/decode/")
+	if appErr := h.validator.ValidateShortCode(shortCode); appErr != nil {
+		appErr.WriteJSON(w)
+		return
+	}
+
+	result, err := h.service.DecodeCode(r.Context(), shortCode)
+	if err != nil {
+		serverError(err).WriteJSON(w)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// HandleRuntime reports goroutine, memory, and GC stats plus uptime
+// GET /admin/runtime
+func (h *URLHandler) HandleRuntime(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		errors.BadRequest("Use GET method").WriteJSON(w)
+		return
+	}
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	uptime := time.Since(h.startedAt)
+	stats := model.RuntimeStats{
+		Uptime:        uptime.Round(time.Second).String(),
+		UptimeSeconds: uptime.Seconds(),
+		Goroutines:    runtime.NumGoroutine(),
+		HeapAlloc:     mem.HeapAlloc,
+		HeapSys:       mem.HeapSys,
+		Sys:           mem.Sys,
+		TotalAlloc:    mem.TotalAlloc,
+		NumGC:         mem.NumGC,
+		PauseTotalNs:  mem.PauseTotalNs,
+		GCCPUFraction: mem.GCCPUFraction,
+	}
+	if mem.LastGC > 0 {
+		stats.LastGC = time.Unix(0, int64(mem.LastGC)).UTC().Format(time.RFC3339)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
+// HandleRobots keeps crawlers out of the code space but allows the landing page
+// GET /robots.txt
+func (h *URLHandler) HandleRobots(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte(robotsTxt))
+}
+
+// HandleHealth returns service health status
+// GET /health
+func (h *URLHandler) HandleHealth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if h.warming.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"status": "starting"}`))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"status": "healthy"}`))
+}
+
+// HandleErrorCatalog lists every error code responses can carry
+// GET /errors
+func (h *URLHandler) HandleErrorCatalog(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	json.NewEncoder(w).Encode(map[string][]errors.CatalogEntry{"errors": errors.Catalog})
+}
+
+// HandleLive reports that the process is serving, without touching any
+// dependency, for liveness probes
+// GET /health/live
+func (h *URLHandler) HandleLive(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"status": "alive"}`))
+}
+
+// HandleReady pings the database and Redis and reports each, with 503
+// if any is unreachable or the pools are still warming up
+// GET /health/ready
+func (h *URLHandler) HandleReady(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+	defer cancel()
+
+	resp := h.service.Readiness(ctx)
+	if h.warming.Load() && resp.Status == model.StatusReady {
+		resp.Status = model.StatusStarting
+	}
+	status := http.StatusOK
+	if resp.Status != model.StatusReady {
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// ============ HELPERS ============
+
+// setRobotsTag marks short-link responses as not for indexing when enabled
+func (h *URLHandler) setRobotsTag(w http.ResponseWriter) {
+	if h.noIndex {
+		w.Header().Set("X-Robots-Tag", "noindex, nofollow")
+	}
+}
+
+// writeRedirect sends the redirect with an empty body, or with the
+// destination echoed in JSON and a Link header when enabled
+func (h *URLHandler) writeRedirect(w http.ResponseWriter, shortCode, originalURL string) {
+	w.Header().Set("Location", originalURL)
+
+	if !h.redirectBody {
+		w.WriteHeader(h.redirectCode)
+		return
+	}
+
+	w.Header().Set("Link", "<"+originalURL+`>; rel="original"`)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(h.redirectCode)
+	json.NewEncoder(w).Encode(map[string]string{
+		"short_code":   shortCode,
+		"original_url": originalURL,
+	})
+}
+
+// exceedsDepth reports whether path has more than max "/" separators,
+// stopping as soon as the limit is crossed
+func exceedsDepth(path string, max int) bool {
+	depth := 0
+	for i := 0; i < len(path); i++ {
+		if path[i] == '/' {
+			depth++
+			if depth > max {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// callerOwner identifies the account making the request: the owner of its
+// API key, else the owner header; empty if neither is configured or sent
+func (h *URLHandler) callerOwner(r *http.Request) string {
+	if owner := middleware.APIKeyOwner(r.Context()); owner != "" {
+		return owner
+	}
+	if h.ownerHeader != "" {
+		return strings.TrimSpace(r.Header.Get(h.ownerHeader))
+	}
+	return ""
+}
+
+// HandleListURLs returns the caller's links, newest first. Admin requests
+// carry no owner and get every stored link along with the total count.
+// GET /urls?limit=100&offset=0
+func (h *URLHandler) HandleListURLs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		errors.BadRequest("Use GET method").WriteJSON(w)
+		return
+	}
+
+	owner := h.callerOwner(r)
+	if owner == "" && !middleware.IsAdmin(r.Context()) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+		errors.InvalidAPIKey().WriteJSON(w)
+		return
+	}
+
+	query := r.URL.Query()
+	limit, offset := maxURLPageLimit, 0
+	if raw := query.Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 {
+			errors.BadRequest("limit must be a positive integer").WriteJSON(w)
+			return
+		}
+		limit = min(parsed, maxURLPageLimit)
+	}
+	if raw := query.Get("offset"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 0 {
+			errors.BadRequest("offset must be a non-negative integer").WriteJSON(w)
+			return
+		}
+		offset = parsed
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if owner == "" {
+		urls, total, err := h.service.ListURLs(limit, offset)
+		if err != nil {
+			serverError(err).WriteJSON(w)
+			return
+		}
+		list := model.URLList{URLs: urls, Total: total}
+		if list.URLs == nil {
+			list.URLs = []*model.URL{}
+		}
+		if uint64(offset+len(urls)) < total {
+			list.NextOffset = offset + len(urls)
+		}
+		json.NewEncoder(w).Encode(list)
+		return
+	}
+
+	// One extra row tells whether another page follows
+	urls, err := h.service.ListURLsByOwner(owner, limit+1, offset)
+	if err != nil {
+		serverError(err).WriteJSON(w)
+		return
+	}
+	list := model.OwnerURLList{URLs: urls}
+	if len(urls) > limit {
+		list.URLs = urls[:limit]
+		list.NextOffset = offset + limit
+	}
+	if list.URLs == nil {
+		list.URLs = []*model.URL{}
+	}
+	json.NewEncoder(w).Encode(list)
+}
+
+// ============ ROUTER SETUP ============
+
+// requireAPIKey wraps a route with the API key check when configured
+func (h *URLHandler) requireAPIKey(fn http.HandlerFunc) http.Handler {
+	if h.apiKeyAuth == nil {
+		return fn
+	}
+	return h.apiKeyAuth(fn)
+}
 
 // SetupRoutes configures all HTTP routes
 func (h *URLHandler) SetupRoutes() http.Handler {
 	mux := http.NewServeMux()
 
 	// Specific routes first
-	mux.HandleFunc("/shorten", h.HandleShorten)
+	mux.Handle("/shorten", h.requireAPIKey(h.HandleShorten))
+	mux.Handle("/shorten/batch", h.requireAPIKey(h.HandleShortenBatch))
+	mux.Handle("/shorten/import", h.requireAPIKey(h.HandleImport))
 	mux.HandleFunc("/health", h.HandleHealth)
+	mux.HandleFunc("/health/live", h.HandleLive)
+	mux.HandleFunc("/health/ready", h.HandleReady)
+	mux.HandleFunc("/errors", h.HandleErrorCatalog)
+	mux.Handle("/reserve", h.requireAPIKey(h.HandleReserve))
+	mux.Handle("/urls", h.requireAPIKey(h.HandleListURLs))
+	mux.HandleFunc("/api/resolve", h.HandleResolve)
+	mux.HandleFunc("/robots.txt", h.HandleRobots)
+	if !h.adminApart {
+		h.registerAdminRoutes(mux)
+	}
 
 	// Catch-all for redirects (must be last)
 	mux.HandleFunc("/", h.HandleRedirect)
 
 	return mux
 }
+
+// SetupAdminRoutes serves the admin endpoints, /metrics, the decode API,
+// and pprof under /debug/pprof/, for a listener separate from SetupRoutes
+// (see WithAdminListener). Health checks are included for probes.
+func (h *URLHandler) SetupAdminRoutes() http.Handler {
+	mux := http.NewServeMux()
+	h.registerAdminRoutes(mux)
+	mux.HandleFunc("/health", h.HandleHealth)
+	mux.HandleFunc("/health/ready", h.HandleReady)
+
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
+
+// registerAdminRoutes adds the endpoints meant for operators rather than
+// link visitors
+func (h *URLHandler) registerAdminRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/admin/capacity", h.HandleCapacity)
+	mux.HandleFunc("/admin/runtime", h.HandleRuntime)
+	mux.HandleFunc("/admin/stats/by-tag", h.HandleStatsByTag)
+	mux.HandleFunc("/admin/aliases", h.HandleListAliases)
+	mux.HandleFunc("/admin/recent", h.HandleRecent)
+	if h.decodeAPI {
+		mux.HandleFunc("/api/decode/", h.HandleDecode)
+	}
+	if h.metrics {
+		mux.Handle("/metrics", metrics.Handler())
+	}
+	if h.latency != nil {
+		mux.HandleFunc("/admin/latency", h.HandleLatency)
+	}
+	if h.linkMetricsMax > 0 {
+		mux.HandleFunc("/admin/metrics/links", h.HandleLinkMetrics)
+	}
+	mux.HandleFunc("/admin/migrate-codes", h.HandleMigrateCodes)
+	mux.HandleFunc("/admin/delete", h.HandleDeleteCodes)
+}
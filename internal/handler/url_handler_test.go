@@ -0,0 +1,1946 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/darkodi/url-shortener/internal/cache/redistest"
+	"github.com/darkodi/url-shortener/internal/config"
+	"github.com/darkodi/url-shortener/internal/errors"
+	"github.com/darkodi/url-shortener/internal/middleware"
+	"github.com/darkodi/url-shortener/internal/model"
+	"github.com/darkodi/url-shortener/internal/repository"
+	"github.com/darkodi/url-shortener/internal/service"
+	"github.com/darkodi/url-shortener/internal/validator"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func setupTestHandler(t *testing.T) *URLHandler {
+	// Use in-memory SQLite for tests (single connection so every query sees the same DB)
+	repo, err := repository.NewURLRepository(&config.DatabaseConfig{
+		Driver:       "sqlite3",
+		Path:         ":memory:",
+		MaxOpenConns: 1,
+		MaxIdleConns: 1,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create repo: %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+
+	svc := service.NewURLService(repo, "http://localhost:8080", nil)
+	if _, err := svc.CreateShortURL(model.CreateURLRequest{
+		URL:         "https://example.com",
+		CustomAlias: "test",
+	}); err != nil {
+		t.Fatalf("Failed to seed URL: %v", err)
+	}
+
+	return NewURLHandler(svc)
+}
+
+func TestHandleRedirect_MaxPathDepth(t *testing.T) {
+	h := setupTestHandler(t)
+
+	tests := []struct {
+		name   string
+		path   string
+		status int
+	}{
+		{"code", "/test", http.StatusMovedPermanently},
+		{"stats", "/test/stats", http.StatusOK},
+		{"three segments", "/test/stats/extra", http.StatusNotFound},
+		{"very deep", "/" + strings.Repeat("a/", 1000) + "z", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.HandleRedirect(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if rec.Code != tt.status {
+				t.Errorf("GET %.40s: expected %d, got %d", tt.path, tt.status, rec.Code)
+			}
+		})
+	}
+}
+
+func TestExceedsDepth_StopsEarly(t *testing.T) {
+	// A huge path must be rejected after scanning only past the limit
+	path := "/a/b/c" + strings.Repeat("x", 1<<20)
+	if !exceedsDepth(path, 2) {
+		t.Error("Expected deep path to exceed depth 2")
+	}
+	if exceedsDepth("/abc/stats", 2) {
+		t.Error("Expected /abc/stats within depth 2")
+	}
+}
+
+func TestHandleRedirect_Reserved(t *testing.T) {
+	h := setupTestHandler(t)
+
+	rec := httptest.NewRecorder()
+	h.HandleReserve(rec, httptest.NewRequest(http.MethodPost, "/reserve",
+		strings.NewReader(`{"custom_alias":"soon"}`)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Reserve: expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// Placeholder while reserved
+	rec = httptest.NewRecorder()
+	h.HandleRedirect(rec, httptest.NewRequest(http.MethodGet, "/soon", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Coming soon") {
+		t.Errorf("Expected coming soon placeholder, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// Activate via PUT
+	rec = httptest.NewRecorder()
+	h.HandleRedirect(rec, httptest.NewRequest(http.MethodPut, "/soon",
+		strings.NewReader(`{"url":"https://example.com/live"}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Activate: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	h.HandleRedirect(rec, httptest.NewRequest(http.MethodGet, "/soon", nil))
+	if rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "https://example.com/live" {
+		t.Errorf("Expected redirect to activated URL, got %d %s", rec.Code, rec.Header().Get("Location"))
+	}
+}
+
+func TestHandleRedirect_Delete(t *testing.T) {
+	h := setupTestHandler(t)
+	routes := middleware.AdminAuth(middleware.DefaultAdminAuthConfig("s3cr3t"))(http.HandlerFunc(h.HandleRedirect))
+	deleteTest := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodDelete, "/test", nil)
+		req.Header.Set("Authorization", "Bearer s3cr3t")
+		rec := httptest.NewRecorder()
+		routes.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Without an owner or the admin token nobody owns the link
+	rec := httptest.NewRecorder()
+	h.HandleRedirect(rec, httptest.NewRequest(http.MethodDelete, "/test", nil))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("Expected 403 deleting without an owner, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = deleteTest()
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	h.HandleRedirect(rec, httptest.NewRequest(http.MethodGet, "/test", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 after delete, got %d", rec.Code)
+	}
+
+	if rec = deleteTest(); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 deleting a missing code, got %d", rec.Code)
+	}
+}
+
+func TestHandleShortenBatch(t *testing.T) {
+	h := setupTestHandler(t)
+
+	body := `[{"url":"https://example.com/one"},{"url":"https://example.com/two","custom_alias":"test"},{"url":"ftp://example.com"}]`
+	rec := httptest.NewRecorder()
+	h.HandleShortenBatch(rec, httptest.NewRequest(http.MethodPost, "/shorten/batch", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var items []struct {
+		ShortURL string `json:"short_url"`
+		Error    *struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&items); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(items) != 3 {
+		t.Fatalf("Expected 3 items, got: %d", len(items))
+	}
+	if items[0].ShortURL == "" || items[0].Error != nil {
+		t.Errorf("Expected item 0 created, got: %+v", items[0])
+	}
+	if items[1].Error == nil || items[1].Error.Code != "URL_EXISTS" {
+		t.Errorf("Expected item 1 to report the taken alias, got: %+v", items[1])
+	}
+	if items[2].Error == nil || items[2].ShortURL != "" {
+		t.Errorf("Expected item 2 rejected by validation, got: %+v", items[2])
+	}
+
+	rec = httptest.NewRecorder()
+	h.HandleShortenBatch(rec, httptest.NewRequest(http.MethodPost, "/shorten/batch", strings.NewReader(`[]`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an empty batch, got %d", rec.Code)
+	}
+}
+
+func TestHandleDeleteCodes(t *testing.T) {
+	h := setupTestHandler(t)
+
+	rec := httptest.NewRecorder()
+	h.HandleDeleteCodes(rec, httptest.NewRequest(http.MethodPost, "/admin/delete",
+		strings.NewReader(`{"codes":["test","nope"]}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp model.DeleteCodesResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Requested != 2 || resp.Deleted != 1 {
+		t.Errorf("Expected 2 requested and 1 deleted, got: %+v", resp)
+	}
+
+	rec = httptest.NewRecorder()
+	h.HandleDeleteCodes(rec, httptest.NewRequest(http.MethodPost, "/admin/delete",
+		strings.NewReader(`{"codes":[]}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an empty batch, got %d", rec.Code)
+	}
+}
+
+func TestHandleRedirect_ConditionalGet(t *testing.T) {
+	h := setupTestHandler(t).WithRedirectStatus(http.StatusMovedPermanently).WithConditionalRedirects(true)
+
+	rec := httptest.NewRecorder()
+	h.HandleRedirect(rec, httptest.NewRequest(http.MethodGet, "/test", nil))
+	if rec.Code != http.StatusMovedPermanently {
+		t.Fatalf("Expected 301, got: %d", rec.Code)
+	}
+	lastModified := rec.Header().Get("Last-Modified")
+	if _, err := http.ParseTime(lastModified); err != nil {
+		t.Fatalf("Expected a Last-Modified date, got: %q", lastModified)
+	}
+
+	// Revalidating with the date we were given is answered with 304
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set("If-Modified-Since", lastModified)
+	req.Header.Set("User-Agent", "Googlebot/2.1")
+	rec = httptest.NewRecorder()
+	h.HandleRedirect(rec, req)
+	if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Errorf("Expected an empty 304, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("Location") != "" {
+		t.Errorf("Expected no Location on a 304, got: %s", rec.Header().Get("Location"))
+	}
+
+	// A date before the link existed gets the redirect
+	req = httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set("If-Modified-Since", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat))
+	rec = httptest.NewRecorder()
+	h.HandleRedirect(rec, req)
+	if rec.Code != http.StatusMovedPermanently {
+		t.Errorf("Expected 301 for an older date, got: %d", rec.Code)
+	}
+
+	stats, _ := h.service.GetURLStats("test")
+	if stats.ClickCount != 2 {
+		t.Errorf("Expected the 304 not counted, got %d clicks", stats.ClickCount)
+	}
+
+	// Temporary redirects are never conditional
+	h.WithRedirectStatus(http.StatusFound)
+	req = httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set("If-Modified-Since", lastModified)
+	rec = httptest.NewRecorder()
+	h.HandleRedirect(rec, req)
+	if rec.Code != http.StatusFound || rec.Header().Get("Last-Modified") != "" {
+		t.Errorf("Expected a plain 302, got: %d, Last-Modified %q", rec.Code, rec.Header().Get("Last-Modified"))
+	}
+}
+
+func TestHandleStats_Response(t *testing.T) {
+	h := setupTestHandler(t)
+
+	rec := httptest.NewRecorder()
+	h.HandleRedirect(rec, httptest.NewRequest(http.MethodGet, "/test/stats", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got: %d", rec.Code)
+	}
+	var body map[string]any
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode stats: %v", err)
+	}
+	if _, ok := body["id"]; ok {
+		t.Errorf("Expected the internal id omitted, got: %v", body)
+	}
+	if body["short_code"] != "test" || body["short_url"] != "http://localhost:8080/test" || body["original_url"] != "https://example.com" {
+		t.Errorf("Expected the link's codes and URLs, got: %v", body)
+	}
+	if age, ok := body["age_seconds"].(float64); !ok || age < 0 || age > 60 {
+		t.Errorf("Expected a small age_seconds for a new link, got: %v", body["age_seconds"])
+	}
+}
+
+func TestHandleStats_ClicksByDay(t *testing.T) {
+	h := setupTestHandler(t)
+
+	rec := httptest.NewRecorder()
+	h.HandleRedirect(rec, httptest.NewRequest(http.MethodGet, "/test/stats?granularity=day&days=3", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var stats model.StatsResponse
+	if err := json.NewDecoder(rec.Body).Decode(&stats); err != nil {
+		t.Fatalf("Failed to decode stats: %v", err)
+	}
+	if len(stats.ClicksByDay) != 3 {
+		t.Fatalf("Expected 3 days, got: %v", stats.ClicksByDay)
+	}
+	if last := stats.ClicksByDay[2].Date; last != time.Now().UTC().Format(time.DateOnly) {
+		t.Errorf("Expected the series to end today, got: %s", last)
+	}
+
+	for _, query := range []string{"granularity=hour", "granularity=day&days=0", "granularity=day&days=1000"} {
+		rec = httptest.NewRecorder()
+		h.HandleRedirect(rec, httptest.NewRequest(http.MethodGet, "/test/stats?"+query, nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got: %d", query, rec.Code)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	h.HandleRedirect(rec, httptest.NewRequest(http.MethodGet, "/test/stats", nil))
+	if strings.Contains(rec.Body.String(), "clicks_by_day") {
+		t.Errorf("Expected no series without granularity, got: %s", rec.Body.String())
+	}
+}
+
+func TestHandleRedirect_LanguageSuffix(t *testing.T) {
+	h := setupTestHandler(t)
+
+	body := `{"url": "https://example.com/en", "custom_alias": "intl", "language_urls": {"FR": "https://example.fr", "pt-br": "https://example.com.br"}}`
+	rec := httptest.NewRecorder()
+	h.HandleShorten(rec, httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(body)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	tests := []struct {
+		path     string
+		location string
+	}{
+		{"/intl", "https://example.com/en"},
+		{"/intl.fr", "https://example.fr"},
+		{"/intl.FR", "https://example.fr"},
+		{"/intl.pt-br", "https://example.com.br"},
+		{"/intl.de", "https://example.com/en"}, // no variant: default
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.HandleRedirect(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if rec.Code != http.StatusMovedPermanently {
+			t.Fatalf("%s: expected 301, got %d: %s", tt.path, rec.Code, rec.Body.String())
+		}
+		if got := rec.Header().Get("Location"); got != tt.location {
+			t.Errorf("%s: expected Location %s, got: %s", tt.path, tt.location, got)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	h.HandleRedirect(rec, httptest.NewRequest(http.MethodGet, "/missing.fr", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown code with a language, got: %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.HandleRedirect(rec, httptest.NewRequest(http.MethodGet, "/intl/stats", nil))
+	if !strings.Contains(rec.Body.String(), `"fr":"https://example.fr"`) {
+		t.Errorf("Expected stats to list language_urls, got: %s", rec.Body.String())
+	}
+
+	for _, bad := range []string{`{"english": "https://example.com"}`, `{"fr": "not a url"}`} {
+		body := `{"url": "https://example.com", "language_urls": ` + bad + `}`
+		rec := httptest.NewRecorder()
+		h.HandleShorten(rec, httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", bad, rec.Code)
+		}
+	}
+}
+
+func TestHandleQR(t *testing.T) {
+	h := setupTestHandler(t)
+
+	tests := []struct {
+		name string
+		path string
+		want int // expected image width
+	}{
+		{"default size", "/test/qr", defaultQRSize},
+		{"png suffix", "/test/qr.png?size=300", 300},
+		{"clamped up", "/test/qr?size=1", minQRSize},
+		{"clamped down", "/test/qr?size=100000", maxQRSize},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.HandleRedirect(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "image/png" {
+				t.Errorf("Expected image/png, got: %s", ct)
+			}
+			if cc := rec.Header().Get("Cache-Control"); strings.Contains(cc, "public") {
+				t.Errorf("Expected QR images kept out of shared caches, got: %s", cc)
+			}
+			img, err := png.Decode(rec.Body)
+			if err != nil {
+				t.Fatalf("Expected a PNG, got: %v", err)
+			}
+			if width := img.Bounds().Dx(); width != tt.want {
+				t.Errorf("Expected width %d, got: %d", tt.want, width)
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	h.HandleRedirect(rec, httptest.NewRequest(http.MethodGet, "/missing/qr", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown code, got: %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.HandleRedirect(rec, httptest.NewRequest(http.MethodGet, "/test/qr?size=big", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a non-numeric size, got: %d", rec.Code)
+	}
+
+	stats, _ := h.service.GetURLStats("test")
+	if stats.ClickCount != 0 {
+		t.Errorf("Expected QR lookups not counted, got %d clicks", stats.ClickCount)
+	}
+}
+
+func TestHandleStats_JSONP(t *testing.T) {
+	h := setupTestHandler(t).WithStatsJSONP(true)
+
+	rec := httptest.NewRecorder()
+	h.HandleRedirect(rec, httptest.NewRequest(http.MethodGet, "/test/stats?callback=widget.onStats", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/javascript") {
+		t.Errorf("Expected application/javascript, got: %s", ct)
+	}
+	body := rec.Body.String()
+	if !strings.HasPrefix(body, "/**/widget.onStats({") || !strings.HasSuffix(body, "});") {
+		t.Errorf("Expected callback-wrapped stats, got: %s", body)
+	}
+	if !strings.Contains(body, `"short_code":"test"`) {
+		t.Errorf("Expected stats in the payload, got: %s", body)
+	}
+}
+
+func TestHandleStats_JSONPRejectsUnsafeCallback(t *testing.T) {
+	h := setupTestHandler(t).WithStatsJSONP(true)
+
+	for _, callback := range []string{
+		"alert(document.cookie)//",
+		"<script>",
+		"cb;evil",
+		"a..b",
+		"1abc",
+		strings.Repeat("a", 65),
+	} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/test/stats", nil)
+		req.URL.RawQuery = "callback=" + url.QueryEscape(callback)
+		h.HandleRedirect(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("callback %.20q: expected 400, got %d", callback, rec.Code)
+		}
+		if strings.Contains(rec.Body.String(), callback) {
+			t.Errorf("callback %.20q: expected it not echoed, got: %s", callback, rec.Body.String())
+		}
+	}
+}
+
+func TestHandleStats_JSONPDisabledByDefault(t *testing.T) {
+	h := setupTestHandler(t)
+
+	rec := httptest.NewRecorder()
+	h.HandleRedirect(rec, httptest.NewRequest(http.MethodGet, "/test/stats?callback=cb", nil))
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Expected plain JSON when JSONP is off, got: %s", ct)
+	}
+}
+
+func TestHandleRedirect_RobotsTag(t *testing.T) {
+	tests := []struct {
+		name    string
+		noIndex bool
+		header  string
+	}{
+		{"enabled", true, "noindex, nofollow"},
+		{"disabled", false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := setupTestHandler(t).WithNoIndex(tt.noIndex)
+
+			rec := httptest.NewRecorder()
+			h.HandleRedirect(rec, httptest.NewRequest(http.MethodGet, "/test", nil))
+
+			if rec.Code != http.StatusMovedPermanently {
+				t.Fatalf("Expected redirect, got %d", rec.Code)
+			}
+			if got := rec.Header().Get("X-Robots-Tag"); got != tt.header {
+				t.Errorf("X-Robots-Tag = %q; want %q", got, tt.header)
+			}
+		})
+	}
+}
+
+func TestHandleRedirect_Status(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		want   int
+	}{
+		{"permanent", http.StatusMovedPermanently, http.StatusMovedPermanently},
+		{"found", http.StatusFound, http.StatusFound},
+		{"unsupported keeps default", http.StatusTemporaryRedirect, http.StatusMovedPermanently},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := setupTestHandler(t).WithRedirectStatus(tt.status)
+
+			rec := httptest.NewRecorder()
+			h.HandleRedirect(rec, httptest.NewRequest(http.MethodGet, "/test", nil))
+			if rec.Code != tt.want || rec.Header().Get("Location") != "https://example.com" {
+				t.Errorf("Expected %d to https://example.com, got: %d %q", tt.want, rec.Code, rec.Header().Get("Location"))
+			}
+
+			// The JSON body variant uses the same status
+			rec = httptest.NewRecorder()
+			h.WithRedirectBody(true).HandleRedirect(rec, httptest.NewRequest(http.MethodGet, "/test", nil))
+			if rec.Code != tt.want {
+				t.Errorf("Expected %d with a redirect body, got: %d", tt.want, rec.Code)
+			}
+		})
+	}
+}
+
+// deadProber reports every destination as unreachable
+type deadProber struct{}
+
+func (deadProber) Probe(ctx context.Context, rawURL string) bool { return false }
+
+func TestHandleRedirect_BrokenDestinationWarns(t *testing.T) {
+	h := setupTestHandler(t)
+	checker := service.NewDestinationChecker(deadProber{}, time.Hour)
+	h.service.WithDestinationChecker(checker)
+	checker.Check(context.Background(), "https://example.com")
+
+	rec := httptest.NewRecorder()
+	h.HandleRedirect(rec, httptest.NewRequest(http.MethodGet, "/test", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Location") != "" {
+		t.Fatalf("Expected the warning page instead of a redirect, got: %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+	if body := rec.Body.String(); !strings.Contains(body, "may be broken") || !strings.Contains(body, `href="https://example.com"`) {
+		t.Errorf("Expected a warning linking the destination, got: %s", body)
+	}
+
+	// Crawlers still get the redirect
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set("User-Agent", "Googlebot/2.1")
+	rec = httptest.NewRecorder()
+	h.HandleRedirect(rec, req)
+	if rec.Code != http.StatusMovedPermanently {
+		t.Errorf("Expected crawlers to be redirected, got: %d", rec.Code)
+	}
+}
+
+func TestHandleRobots(t *testing.T) {
+	h := setupTestHandler(t)
+
+	rec := httptest.NewRecorder()
+	h.SetupRoutes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/robots.txt", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+	body := rec.Body.String()
+	for _, line := range []string{"User-agent: *", "Allow: /$", "Disallow: /\n"} {
+		if !strings.Contains(body, line) {
+			t.Errorf("Expected robots.txt to contain %q, got:\n%s", line, body)
+		}
+	}
+}
+
+func TestHandleRedirect_CodeChecksum(t *testing.T) {
+	h := setupTestHandler(t)
+	h.service.WithCodeChecksum(true)
+
+	resp, err := h.service.CreateShortURL(model.CreateURLRequest{URL: "https://example.com/sum"})
+	if err != nil {
+		t.Fatalf("Failed to create URL: %v", err)
+	}
+
+	shortCode := strings.TrimPrefix(resp.ShortURL, "http://localhost:8080/")
+
+	rec := httptest.NewRecorder()
+	h.HandleRedirect(rec, httptest.NewRequest(http.MethodGet, "/"+shortCode, nil))
+	if rec.Code != http.StatusMovedPermanently {
+		t.Fatalf("Expected 301 for valid code, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// Flip the check character
+	code := []byte(shortCode)
+	if code[len(code)-1] == 'a' {
+		code[len(code)-1] = 'b'
+	} else {
+		code[len(code)-1] = 'a'
+	}
+
+	rec = httptest.NewRecorder()
+	h.HandleRedirect(rec, httptest.NewRequest(http.MethodGet, "/"+string(code), nil))
+	if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), "CODE_TYPO") {
+		t.Errorf("Expected CODE_TYPO 404, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// Aliases with non-alphabet characters are plain misses
+	rec = httptest.NewRecorder()
+	h.HandleRedirect(rec, httptest.NewRequest(http.MethodGet, "/no-such-alias", nil))
+	if rec.Code != http.StatusNotFound || strings.Contains(rec.Body.String(), "CODE_TYPO") {
+		t.Errorf("Expected plain 404, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestHandleRuntime(t *testing.T) {
+	h := setupTestHandler(t)
+
+	rec := httptest.NewRecorder()
+	h.HandleRuntime(rec, httptest.NewRequest(http.MethodGet, "/admin/runtime", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var body map[string]any
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	for _, field := range []string{"uptime", "uptime_seconds", "goroutines", "heap_alloc", "sys", "num_gc", "gc_cpu_fraction"} {
+		if _, ok := body[field]; !ok {
+			t.Errorf("Expected field %q in response", field)
+		}
+	}
+	if uptime, _ := body["uptime_seconds"].(float64); uptime < 0 {
+		t.Errorf("Expected non-negative uptime, got: %v", uptime)
+	}
+	if goroutines, _ := body["goroutines"].(float64); goroutines < 1 {
+		t.Errorf("Expected at least one goroutine, got: %v", goroutines)
+	}
+}
+
+func TestHandleRedirect_ErrorPages(t *testing.T) {
+	dir := t.TempDir()
+	custom := `<html><body class="brand">Nothing at {{.Message}}</body></html>`
+	if err := os.WriteFile(filepath.Join(dir, "404.html"), []byte(custom), 0o644); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+
+	pages, err := LoadErrorPages(dir)
+	if err != nil {
+		t.Fatalf("LoadErrorPages failed: %v", err)
+	}
+	h := setupTestHandler(t).WithErrorPages(pages)
+
+	tests := []struct {
+		name        string
+		accept      string
+		wantType    string
+		wantContent string
+	}{
+		{"browser gets custom page", "text/html,application/xhtml+xml;q=0.9", "text/html", `class="brand"`},
+		{"api client gets JSON", "application/json", "application/json", "URL_NOT_FOUND"},
+		{"no accept gets JSON", "", "application/json", "URL_NOT_FOUND"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/missing", nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			h.HandleRedirect(rec, req)
+
+			if rec.Code != http.StatusNotFound {
+				t.Errorf("Expected 404, got: %d", rec.Code)
+			}
+			if !strings.HasPrefix(rec.Header().Get("Content-Type"), tt.wantType) {
+				t.Errorf("Expected content type %s, got: %s", tt.wantType, rec.Header().Get("Content-Type"))
+			}
+			if !strings.Contains(rec.Body.String(), tt.wantContent) {
+				t.Errorf("Expected body to contain %q, got: %s", tt.wantContent, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestLoadErrorPages_Defaults(t *testing.T) {
+	pages, err := LoadErrorPages("")
+	if err != nil {
+		t.Fatalf("LoadErrorPages failed: %v", err)
+	}
+	h := setupTestHandler(t).WithErrorPages(pages)
+
+	req := httptest.NewRequest(http.MethodGet, "/missing", nil)
+	req.Header.Set("Accept", "text/html")
+	rec := httptest.NewRecorder()
+	h.HandleRedirect(rec, req)
+
+	if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), "Link not found") {
+		t.Errorf("Expected built-in 404 page, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestHandleMigrateCodes_InvalidScheme(t *testing.T) {
+	h := setupTestHandler(t)
+
+	for _, body := range []string{`{"alphabet":"abc"}`, `{"stride":62}`, `not json`} {
+		rec := httptest.NewRecorder()
+		h.HandleMigrateCodes(rec, httptest.NewRequest(http.MethodPost, "/admin/migrate-codes", strings.NewReader(body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d: %s", body, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestHandleRedirect_RedirectBody(t *testing.T) {
+	tests := []struct {
+		name    string
+		enabled bool
+	}{
+		{"disabled", false},
+		{"enabled", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := setupTestHandler(t).WithRedirectBody(tt.enabled)
+
+			rec := httptest.NewRecorder()
+			h.HandleRedirect(rec, httptest.NewRequest(http.MethodGet, "/test", nil))
+
+			if rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "https://example.com" {
+				t.Fatalf("Expected 301 to https://example.com, got %d %s", rec.Code, rec.Header().Get("Location"))
+			}
+
+			if !tt.enabled {
+				if rec.Body.Len() != 0 || rec.Header().Get("Link") != "" {
+					t.Errorf("Expected empty body and no Link header, got %q / %q", rec.Body.String(), rec.Header().Get("Link"))
+				}
+				return
+			}
+
+			if link := rec.Header().Get("Link"); link != `<https://example.com>; rel="original"` {
+				t.Errorf("Unexpected Link header: %q", link)
+			}
+			var body map[string]string
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("Failed to decode body: %v", err)
+			}
+			if body["original_url"] != "https://example.com" || body["short_code"] != "test" {
+				t.Errorf("Unexpected body: %v", body)
+			}
+		})
+	}
+}
+
+func TestHandleRedirect_LinkLimit(t *testing.T) {
+	h := setupTestHandler(t).WithLinkLimit(middleware.NewMemoryWindowStore(), LinkLimit{Limit: 2, Window: time.Minute})
+	if _, err := h.service.CreateShortURL(model.CreateURLRequest{URL: "https://example.com/other", CustomAlias: "other"}); err != nil {
+		t.Fatalf("Failed to seed URL: %v", err)
+	}
+
+	redirect := func(code string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.HandleRedirect(rec, httptest.NewRequest(http.MethodGet, "/"+code, nil))
+		return rec
+	}
+
+	for i := 0; i < 2; i++ {
+		if rec := redirect("test"); rec.Code != http.StatusMovedPermanently {
+			t.Fatalf("Redirect %d: expected 301, got %d", i, rec.Code)
+		}
+	}
+
+	rec := redirect("test")
+	if rec.Code != http.StatusTooManyRequests || !strings.Contains(rec.Body.String(), "LINK_RATE_LIMITED") {
+		t.Fatalf("Expected 429 once the code is exhausted, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("Expected Retry-After header")
+	}
+
+	// Other codes are unaffected
+	if rec := redirect("other"); rec.Code != http.StatusMovedPermanently {
+		t.Errorf("Expected other code to resolve freely, got %d", rec.Code)
+	}
+
+	// Refused redirects aren't visits
+	stats, err := h.service.GetURLStats("test")
+	if err != nil {
+		t.Fatalf("GetURLStats failed: %v", err)
+	}
+	if stats.ClickCount != 2 {
+		t.Errorf("Expected 2 clicks, got: %d", stats.ClickCount)
+	}
+
+	// Codes that don't exist are answered without being charged
+	for i := 0; i < 3; i++ {
+		if rec := redirect("missing"); rec.Code != http.StatusNotFound {
+			t.Fatalf("Expected 404 for an unknown code, got %d", rec.Code)
+		}
+	}
+	if allowed, _, _ := h.linkLimitStore.Allow(context.Background(), "linklimit:missing", 1, time.Minute, time.Now()); !allowed {
+		t.Error("Expected unknown codes to leave the link limit untouched")
+	}
+}
+
+func TestHandleRecent(t *testing.T) {
+	h := setupTestHandler(t).WithRecentLimit(3)
+
+	for i := 0; i < 5; i++ {
+		alias := fmt.Sprintf("recent%d", i)
+		if _, err := h.service.CreateShortURL(model.CreateURLRequest{URL: "https://example.com", CustomAlias: alias}); err != nil {
+			t.Fatalf("Failed to create %s: %v", alias, err)
+		}
+		time.Sleep(2 * time.Millisecond) // distinct created_at
+	}
+
+	tests := []struct {
+		query     string
+		wantCode  int
+		wantCodes []string
+	}{
+		{"", http.StatusOK, []string{"recent4", "recent3", "recent2"}},          // capped by the configured limit
+		{"?limit=2", http.StatusOK, []string{"recent4", "recent3"}},             // lower limit honoured
+		{"?limit=50", http.StatusOK, []string{"recent4", "recent3", "recent2"}}, // cannot exceed the cap
+		{"?limit=0", http.StatusBadRequest, nil},
+		{"?limit=abc", http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.HandleRecent(rec, httptest.NewRequest(http.MethodGet, "/admin/recent"+tt.query, nil))
+		if rec.Code != tt.wantCode {
+			t.Errorf("%q: Expected %d, got %d: %s", tt.query, tt.wantCode, rec.Code, rec.Body.String())
+			continue
+		}
+		if tt.wantCode != http.StatusOK {
+			continue
+		}
+
+		var links []model.RecentLink
+		if err := json.NewDecoder(rec.Body).Decode(&links); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		var codes []string
+		for i, link := range links {
+			codes = append(codes, link.ShortCode)
+			if i > 0 && link.CreatedAt.After(links[i-1].CreatedAt) {
+				t.Errorf("%q: Expected descending created_at, got %v after %v", tt.query, link.CreatedAt, links[i-1].CreatedAt)
+			}
+		}
+		if strings.Join(codes, ",") != strings.Join(tt.wantCodes, ",") {
+			t.Errorf("%q: Expected %v, got %v", tt.query, tt.wantCodes, codes)
+		}
+	}
+}
+
+func TestHandleStatsByTag(t *testing.T) {
+	h := setupTestHandler(t).WithTagStatsLimit(2)
+
+	for i, tag := range []string{"a", "b", "b", "c"} {
+		alias := fmt.Sprintf("tagged%d", i)
+		if _, err := h.service.CreateShortURL(model.CreateURLRequest{URL: "https://example.com", CustomAlias: alias, Tag: tag}); err != nil {
+			t.Fatalf("Failed to create %s: %v", alias, err)
+		}
+		if _, err := h.service.Resolve(alias); err != nil {
+			t.Fatalf("Resolve failed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		query    string
+		wantCode int
+		wantTags []string
+	}{
+		{"", http.StatusOK, []string{"b", "a"}},          // capped by the configured limit
+		{"?limit=1", http.StatusOK, []string{"b"}},       // lower limit honoured
+		{"?limit=50", http.StatusOK, []string{"b", "a"}}, // cannot exceed the cap
+		{"?limit=0", http.StatusBadRequest, nil},
+		{"?limit=abc", http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.HandleStatsByTag(rec, httptest.NewRequest(http.MethodGet, "/admin/stats/by-tag"+tt.query, nil))
+		if rec.Code != tt.wantCode {
+			t.Errorf("%q: Expected %d, got %d: %s", tt.query, tt.wantCode, rec.Code, rec.Body.String())
+			continue
+		}
+		if tt.wantCode != http.StatusOK {
+			continue
+		}
+
+		var stats []model.TagStats
+		if err := json.NewDecoder(rec.Body).Decode(&stats); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		var tags []string
+		for _, s := range stats {
+			tags = append(tags, s.Tag)
+		}
+		if strings.Join(tags, ",") != strings.Join(tt.wantTags, ",") {
+			t.Errorf("%q: Expected tags %v, got: %v", tt.query, tt.wantTags, tags)
+		}
+		if len(stats) > 0 && (stats[0].Links != 2 || stats[0].Clicks != 2) {
+			t.Errorf("%q: Expected tag b with 2 links and 2 clicks, got: %+v", tt.query, stats[0])
+		}
+	}
+}
+
+func TestHandleRedirect_SignedLink(t *testing.T) {
+	h := setupTestHandler(t)
+	h.service.WithSignedLinks("test-secret", time.Hour)
+
+	resp, err := h.service.CreateShortURL(model.CreateURLRequest{URL: "https://example.com/secret", CustomAlias: "oneoff", Signed: true})
+	if err != nil {
+		t.Fatalf("Failed to create signed link: %v", err)
+	}
+	signedPath := strings.TrimPrefix(resp.ShortURL, "http://localhost:8080")
+
+	tests := []struct {
+		name     string
+		path     string
+		wantCode int
+	}{
+		{"valid signature", signedPath, http.StatusMovedPermanently},
+		{"no signature", "/oneoff", http.StatusForbidden},
+		{"tampered signature", signedPath[:len(signedPath)-1] + "x", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.HandleRedirect(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if rec.Code != tt.wantCode {
+			t.Errorf("%s: Expected %d, got %d: %s", tt.name, tt.wantCode, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestReadOnlyMode(t *testing.T) {
+	h := setupTestHandler(t)
+	h.service.WithReadOnly(true)
+
+	rec := httptest.NewRecorder()
+	body := strings.NewReader(`{"url": "https://example.com/new"}`)
+	h.HandleShorten(rec, httptest.NewRequest(http.MethodPost, "/shorten", body))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for shorten, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	h.HandleRedirect(rec, httptest.NewRequest(http.MethodGet, "/test", nil))
+	if rec.Code != http.StatusMovedPermanently {
+		t.Errorf("Expected redirect to work, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	h.HandleRedirect(rec, httptest.NewRequest(http.MethodGet, "/test/stats", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected stats to work, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestHandleDecode(t *testing.T) {
+	h := setupTestHandler(t).WithDecodeEndpoint(true)
+	routes := h.SetupRoutes()
+
+	resp, err := h.service.CreateShortURL(model.CreateURLRequest{URL: "https://example.com/generated"})
+	if err != nil {
+		t.Fatalf("Failed to create: %v", err)
+	}
+	generated := strings.TrimPrefix(resp.ShortURL, "http://localhost:8080/")
+	newest, _, _ := h.service.ListURLs(1, 0) // stats don't expose the ID
+	stored := newest[0]
+	_, _ = h.service.CreateShortURL(model.CreateURLRequest{URL: "https://example.com", CustomAlias: "my-link"})
+
+	tests := []struct {
+		code       string
+		wantKind   string
+		wantID     uint64
+		wantExists bool
+	}{
+		{generated, model.CodeKindGenerated, stored.ID, true},
+		{"my-link", model.CodeKindCustom, 0, true},
+		{"test", model.CodeKindCustom, 0, true}, // decodable, but not to its record's ID
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		routes.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/decode/"+tt.code, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: Expected 200, got %d: %s", tt.code, rec.Code, rec.Body.String())
+		}
+
+		var got model.CodeDecode
+		if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if got.Kind != tt.wantKind || got.Exists != tt.wantExists {
+			t.Errorf("%s: Expected kind %s exists %v, got: %+v", tt.code, tt.wantKind, tt.wantExists, got)
+		}
+		if tt.wantKind == model.CodeKindGenerated && (got.ID == nil || *got.ID != tt.wantID) {
+			t.Errorf("%s: Expected ID %d, got: %v", tt.code, tt.wantID, got.ID)
+		}
+		if tt.wantKind == model.CodeKindCustom && (got.ID != nil || got.Reason == "") {
+			t.Errorf("%s: Expected no ID and a reason for a custom alias, got: %+v", tt.code, got)
+		}
+	}
+}
+
+func TestHandleDecode_DisabledByDefault(t *testing.T) {
+	h := setupTestHandler(t)
+
+	rec := httptest.NewRecorder()
+	h.SetupRoutes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/decode/1", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 when disabled, got %d", rec.Code)
+	}
+}
+
+// slowBody sends one byte per delay, like a slowloris client
+type slowBody struct {
+	data  string
+	delay time.Duration
+}
+
+func (b *slowBody) Read(p []byte) (int, error) {
+	if b.data == "" {
+		return 0, io.EOF
+	}
+	time.Sleep(b.delay)
+	p[0], b.data = b.data[0], b.data[1:]
+	return 1, nil
+}
+
+func TestHandleShorten_BodyReadTimeout(t *testing.T) {
+	h := setupTestHandler(t).WithBodyReadTimeout(50 * time.Millisecond)
+	body := `{"url": "https://example.com/slow"}`
+
+	rec := httptest.NewRecorder()
+	start := time.Now()
+	h.HandleShorten(rec, httptest.NewRequest(http.MethodPost, "/shorten", &slowBody{data: body, delay: 20 * time.Millisecond}))
+	if rec.Code != http.StatusRequestTimeout {
+		t.Fatalf("Expected 408 for a slow body, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected the handler to give up at the deadline, took: %s", elapsed)
+	}
+	if !strings.Contains(rec.Body.String(), errors.CodeRequestTimeout) {
+		t.Errorf("Expected %s in the body, got: %s", errors.CodeRequestTimeout, rec.Body.String())
+	}
+
+	// A body that arrives in time is unaffected
+	rec = httptest.NewRecorder()
+	h.HandleShorten(rec, httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(body)))
+	if rec.Code != http.StatusCreated {
+		t.Errorf("Expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestHandleShorten_WhitespaceAlias(t *testing.T) {
+	h := setupTestHandler(t)
+
+	rec := httptest.NewRecorder()
+	h.HandleShorten(rec, httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(`{"url": "https://example.com", "custom_alias": "   "}`)))
+	if rec.Code != http.StatusCreated {
+		t.Errorf("Expected a blank alias to generate a code, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	h.HandleShorten(rec, httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(`{"url": "https://example.com", "custom_alias": "my link"}`)))
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "must not contain spaces") {
+		t.Errorf("Expected 400 naming the space, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestHandleShorten_EmojiAlias(t *testing.T) {
+	table, err := validator.ParseRuneRanges("1F300-1FAFF")
+	if err != nil {
+		t.Fatalf("ParseRuneRanges failed: %v", err)
+	}
+	h := setupTestHandler(t).WithValidator(validator.NewURLValidator().WithUnicodeAliases(table))
+	h.service.WithUnicodeAliases(table)
+
+	rec := httptest.NewRecorder()
+	h.HandleShorten(rec, httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(`{"url": "https://example.com/party", "custom_alias": "party🎉"}`)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got: %d %s", rec.Code, rec.Body.String())
+	}
+	var resp model.CreateURLResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.ShortURL != "http://localhost:8080/party🎉" {
+		t.Errorf("Expected emoji short URL, got: %s", resp.ShortURL)
+	}
+
+	// Browsers send the path percent-encoded
+	rec = httptest.NewRecorder()
+	h.HandleRedirect(rec, httptest.NewRequest(http.MethodGet, "/"+url.PathEscape("party🎉"), nil))
+	if rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "https://example.com/party" {
+		t.Errorf("Expected redirect for the emoji alias, got: %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+}
+
+func TestHandleShorten_AliasCollidesWithGeneratedCode(t *testing.T) {
+	h := setupTestHandler(t)
+	h.service.WithCodeMinLength(6) // long enough to be a valid alias
+
+	rec := httptest.NewRecorder()
+	h.HandleShorten(rec, httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(`{"url": "https://example.com/generated"}`)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got: %d %s", rec.Code, rec.Body.String())
+	}
+	var created model.CreateURLResponse
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	code := strings.TrimPrefix(created.ShortURL, "http://localhost:8080/")
+
+	rec = httptest.NewRecorder()
+	body := fmt.Sprintf(`{"url": "https://example.com/custom", "custom_alias": %q}`, code)
+	h.HandleShorten(rec, httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(body)))
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("Expected 409 for an alias spelling a generated code, got: %d %s", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), `"URL_EXISTS"`) {
+		t.Errorf("Expected URL_EXISTS, got: %s", rec.Body.String())
+	}
+}
+
+func TestHandleShorten_StripsTrackingParams(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		validator *validator.URLValidator
+		want      string
+		wantFR    string
+	}{
+		{"enabled", validator.NewURLValidator().WithTrackingParamStripping(validator.DefaultTrackingParams...), "https://example.com/post?id=7", "https://example.fr/post?id=7"},
+		{"disabled", validator.NewURLValidator(), "https://example.com/post?utm_source=news&id=7&fbclid=abc", "https://example.fr/post?id=7&utm_medium=mail"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			h := setupTestHandler(t).WithValidator(tt.validator)
+
+			rec := httptest.NewRecorder()
+			h.HandleShorten(rec, httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(`{"url": "https://example.com/post?utm_source=news&id=7&fbclid=abc", "custom_alias": "tracked", "language_urls": {"fr": "https://example.fr/post?id=7&utm_medium=mail"}}`)))
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("Expected 201, got: %d %s", rec.Code, rec.Body.String())
+			}
+
+			stats, err := h.service.GetURLStats("tracked")
+			if err != nil {
+				t.Fatalf("GetURLStats failed: %v", err)
+			}
+			if stats.OriginalURL != tt.want {
+				t.Errorf("Expected stored URL %s, got: %s", tt.want, stats.OriginalURL)
+			}
+			if stats.LanguageURLs["fr"] != tt.wantFR {
+				t.Errorf("Expected stored fr URL %s, got: %s", tt.wantFR, stats.LanguageURLs["fr"])
+			}
+		})
+	}
+}
+
+func TestHandleShorten_RegionHeader(t *testing.T) {
+	h := setupTestHandler(t).WithRegionHeader("X-Region")
+	h.service.WithRegionalBaseURLs(map[string]string{"eu": "https://eu.sho.rt"}, "")
+
+	for region, wantPrefix := range map[string]string{
+		"eu": "https://eu.sho.rt/",
+		"":   "http://localhost:8080/",
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(`{"url": "https://example.com/regional"}`))
+		if region != "" {
+			req.Header.Set("X-Region", region)
+		}
+		rec := httptest.NewRecorder()
+		h.HandleShorten(rec, req)
+
+		var resp model.CreateURLResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if !strings.HasPrefix(resp.ShortURL, wantPrefix) {
+			t.Errorf("region %q: Expected short URL on %s, got: %s", region, wantPrefix, resp.ShortURL)
+		}
+
+		// Stats name the link on the same domain
+		statsReq := httptest.NewRequest(http.MethodGet, strings.TrimPrefix(resp.ShortURL, wantPrefix[:len(wantPrefix)-1])+"/stats", nil)
+		if region != "" {
+			statsReq.Header.Set("X-Region", region)
+		}
+		rec = httptest.NewRecorder()
+		h.HandleRedirect(rec, statsReq)
+
+		var stats model.StatsResponse
+		if err := json.NewDecoder(rec.Body).Decode(&stats); err != nil {
+			t.Fatalf("Failed to decode stats: %v", err)
+		}
+		if stats.ShortURL != resp.ShortURL {
+			t.Errorf("region %q: Expected stats short URL %s, got: %s", region, resp.ShortURL, stats.ShortURL)
+		}
+
+		// The QR image depends on the region, so caches must key on it
+		rec = httptest.NewRecorder()
+		h.HandleRedirect(rec, httptest.NewRequest(http.MethodGet, "/"+stats.ShortCode+"/qr", nil))
+		if vary := rec.Header().Get("Vary"); vary != "X-Region" {
+			t.Errorf("region %q: Expected QR to vary on X-Region, got: %q", region, vary)
+		}
+	}
+}
+
+func TestHandleShorten_OwnerExistingLink(t *testing.T) {
+	h := setupTestHandler(t).WithOwnerHeader("X-Owner")
+	h.service.WithUniqueURLPerOwner(true)
+
+	var codes []string
+	for i, wantStatus := range []int{http.StatusCreated, http.StatusOK} {
+		req := httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(`{"url": "https://example.com/owned"}`))
+		req.Header.Set("X-Owner", "acme")
+		rec := httptest.NewRecorder()
+		h.HandleShorten(rec, req)
+
+		if rec.Code != wantStatus {
+			t.Fatalf("create %d: Expected status %d, got: %d", i+1, wantStatus, rec.Code)
+		}
+		var resp model.CreateURLResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		codes = append(codes, resp.ShortURL)
+	}
+	if codes[0] != codes[1] {
+		t.Errorf("Expected the same short URL for the same owner, got: %v", codes)
+	}
+
+	// Asking for a different alias is a conflict, not a silent swap
+	req := httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(`{"url": "https://example.com/owned", "custom_alias": "mine"}`))
+	req.Header.Set("X-Owner", "acme")
+	rec := httptest.NewRecorder()
+	h.HandleShorten(rec, req)
+	if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), "CONFLICT") {
+		t.Errorf("Expected 409 for another alias, got: %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestHandleShorten_InitialClicksAdminOnly(t *testing.T) {
+	h := setupTestHandler(t)
+	shorten := middleware.AdminAuth(middleware.DefaultAdminAuthConfig("s3cr3t"))(http.HandlerFunc(h.HandleShorten))
+
+	tests := []struct {
+		alias      string
+		auth       string
+		wantCode   int
+		wantClicks uint64
+	}{
+		{"seeded", "Bearer s3cr3t", http.StatusCreated, 250},
+		{"notadmin", "", http.StatusForbidden, 0},
+		{"wrongtoken", "Bearer nope", http.StatusForbidden, 0},
+	}
+
+	for _, tt := range tests {
+		body := fmt.Sprintf(`{"url": "https://example.com", "custom_alias": %q, "initial_clicks": 250}`, tt.alias)
+		req := httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(body))
+		if tt.auth != "" {
+			req.Header.Set("Authorization", tt.auth)
+		}
+		rec := httptest.NewRecorder()
+		shorten.ServeHTTP(rec, req)
+
+		if rec.Code != tt.wantCode {
+			t.Errorf("%s: Expected %d, got %d: %s", tt.alias, tt.wantCode, rec.Code, rec.Body.String())
+			continue
+		}
+
+		stats, err := h.service.GetURLStats(tt.alias)
+		if tt.wantCode != http.StatusCreated {
+			if err != service.ErrURLNotFound {
+				t.Errorf("%s: Expected rejected link not to be created, got: %v", tt.alias, err)
+			}
+			continue
+		}
+		if err != nil || stats.ClickCount != tt.wantClicks {
+			t.Errorf("%s: Expected %d initial clicks, got: %+v, %v", tt.alias, tt.wantClicks, stats, err)
+		}
+	}
+}
+
+func TestHandleResolve(t *testing.T) {
+	h := setupTestHandler(t)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantClicks uint64
+	}{
+		{"found, not counted", `{"code": "test"}`, http.StatusOK, 0},
+		{"found and counted", `{"code": "test", "count_click": true}`, http.StatusOK, 1},
+		{"not found", `{"code": "missing", "count_click": true}`, http.StatusNotFound, 1},
+		{"missing code", `{}`, http.StatusBadRequest, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/resolve", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			h.HandleResolve(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got: %d (%s)", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if tt.wantStatus == http.StatusOK {
+				var resp model.ResolveResponse
+				if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+					t.Fatalf("Failed to decode response: %v", err)
+				}
+				if resp.OriginalURL != "https://example.com" || resp.ShortCode != "test" {
+					t.Errorf("Unexpected response: %+v", resp)
+				}
+			}
+
+			stats, err := h.service.GetURLStats("test")
+			if err != nil {
+				t.Fatalf("GetURLStats failed: %v", err)
+			}
+			if stats.ClickCount != tt.wantClicks {
+				t.Errorf("Expected %d clicks, got: %d", tt.wantClicks, stats.ClickCount)
+			}
+		})
+	}
+}
+
+func TestHandleResolve_ClickStep(t *testing.T) {
+	h := setupTestHandler(t).WithClickStep(3, "X-Click-Step", 10)
+
+	tests := []struct {
+		name   string
+		path   string
+		header string
+		want   uint64
+	}{
+		{"redirect counts 1", "/test", "", 1},
+		{"api uses its step", "/api/resolve", "", 3},
+		{"header overrides", "/test", "7", 7},
+		{"header above max ignored", "/api/resolve", "11", 3},
+		{"malformed header ignored", "/test", "lots", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before, _ := h.service.GetURLStats("test")
+
+			var req *http.Request
+			if tt.path == "/api/resolve" {
+				req = httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(`{"code": "test", "count_click": true}`))
+			} else {
+				req = httptest.NewRequest(http.MethodGet, tt.path, nil)
+			}
+			if tt.header != "" {
+				req.Header.Set("X-Click-Step", tt.header)
+			}
+			h.SetupRoutes().ServeHTTP(httptest.NewRecorder(), req)
+
+			after, _ := h.service.GetURLStats("test")
+			if got := after.ClickCount - before.ClickCount; got != tt.want {
+				t.Errorf("Expected %d clicks added, got: %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestHandleRedirect_ExpiredIsGone(t *testing.T) {
+	h := setupTestHandler(t)
+	if _, err := h.service.CreateShortURL(model.CreateURLRequest{URL: "https://example.com/old", CustomAlias: "old", ExpiresIn: "20ms"}); err != nil {
+		t.Fatalf("Failed to create: %v", err)
+	}
+	time.Sleep(30 * time.Millisecond)
+
+	rec := httptest.NewRecorder()
+	h.HandleRedirect(rec, httptest.NewRequest(http.MethodGet, "/old", nil))
+	if rec.Code != http.StatusGone {
+		t.Errorf("Expected 410 for an expired link, got: %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	h.HandleRedirect(rec, httptest.NewRequest(http.MethodGet, "/old/stats", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"expires_at"`) {
+		t.Errorf("Expected stats with expires_at, got: %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestHandleRedirect_Delay(t *testing.T) {
+	h := setupTestHandler(t)
+	if _, err := h.service.CreateShortURL(model.CreateURLRequest{URL: "https://example.com/ad?a=1&b=2", CustomAlias: "later", DelaySeconds: 5}); err != nil {
+		t.Fatalf("Failed to create: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	h.HandleRedirect(rec, httptest.NewRequest(http.MethodGet, "/later", nil))
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") {
+		t.Fatalf("Expected 200 HTML timer page, got: %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, `content="5;url=https://example.com/ad?a=1&amp;b=2"`) || !strings.Contains(body, `<span id="countdown">5</span>`) {
+		t.Errorf("Expected a 5 second countdown to the destination, got: %s", body)
+	}
+	if rec.Header().Get("Location") != "" {
+		t.Errorf("Expected no Location header on the timer page")
+	}
+
+	// Crawlers skip the timer
+	req := httptest.NewRequest(http.MethodGet, "/later", nil)
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; Googlebot/2.1)")
+	rec = httptest.NewRecorder()
+	h.HandleRedirect(rec, req)
+	if rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "https://example.com/ad?a=1&b=2" {
+		t.Errorf("Expected immediate redirect for a crawler, got: %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+
+	// Links without a delay redirect at once
+	rec = httptest.NewRecorder()
+	h.HandleRedirect(rec, httptest.NewRequest(http.MethodGet, "/test", nil))
+	if rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "https://example.com" {
+		t.Errorf("Expected immediate redirect, got: %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+}
+
+func TestHandleRedirect_DelayRenderFailureRedirects(t *testing.T) {
+	h := setupTestHandler(t)
+	if _, err := h.service.CreateShortURL(model.CreateURLRequest{URL: "https://example.com/ad", CustomAlias: "later", DelaySeconds: 5}); err != nil {
+		t.Fatalf("Failed to create: %v", err)
+	}
+
+	// A template that fails partway, after it would have written output
+	saved := countdownPage
+	countdownPage = template.Must(template.New("countdown").Parse(`<html>{{.Missing}}`))
+	t.Cleanup(func() { countdownPage = saved })
+
+	rec := httptest.NewRecorder()
+	h.HandleRedirect(rec, httptest.NewRequest(http.MethodGet, "/later", nil))
+	if rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "https://example.com/ad" {
+		t.Errorf("Expected a plain redirect, got: %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+	if strings.Contains(rec.Body.String(), "<html>") {
+		t.Errorf("Expected no partial page, got: %s", rec.Body.String())
+	}
+}
+
+func TestHandleShorten_InvalidDelay(t *testing.T) {
+	h := setupTestHandler(t)
+
+	rec := httptest.NewRecorder()
+	h.HandleShorten(rec, httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(`{"url": "https://example.com", "delay_seconds": 31}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a delay over the maximum, got: %d", rec.Code)
+	}
+}
+
+func TestHandleRedirect_CacheStatusHeader(t *testing.T) {
+	repo, err := repository.New(&config.DatabaseConfig{Driver: "memory"})
+	if err != nil {
+		t.Fatalf("Failed to create repo: %v", err)
+	}
+	// Stored directly, so the cache doesn't know it until the first resolve
+	if err := repo.Create(&model.URL{ShortCode: "test", OriginalURL: "https://example.com"}); err != nil {
+		t.Fatalf("Failed to seed URL: %v", err)
+	}
+	redisCache, _ := redistest.NewCache(t)
+	svc := service.NewURLService(repo, "http://localhost:8080", redisCache)
+	h := NewURLHandler(svc).WithCacheStatusHeader(true)
+
+	for i, want := range []string{"MISS", "HIT"} {
+		rec := httptest.NewRecorder()
+		h.HandleRedirect(rec, httptest.NewRequest(http.MethodGet, "/test", nil))
+		if rec.Code != http.StatusMovedPermanently {
+			t.Fatalf("Request %d: expected 301, got: %d", i+1, rec.Code)
+		}
+		if got := rec.Header().Get("X-Cache"); got != want {
+			t.Errorf("Request %d: expected X-Cache %s, got: %q", i+1, want, got)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	NewURLHandler(svc).HandleRedirect(rec, httptest.NewRequest(http.MethodGet, "/test", nil))
+	if rec.Header().Get("X-Cache") != "" {
+		t.Error("Expected no X-Cache header unless enabled")
+	}
+}
+
+func TestHandleLinkMetrics(t *testing.T) {
+	h := setupTestHandler(t).WithLinkMetrics(2, 10)
+	for alias, clicks := range map[string]uint64{"hot": 500, "warm": 50, "mild": 20, "cold": 5} {
+		if _, err := h.service.CreateShortURL(model.CreateURLRequest{URL: "https://example.com", CustomAlias: alias, InitialClicks: clicks}); err != nil {
+			t.Fatalf("Failed to create %s: %v", alias, err)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	h.SetupRoutes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/metrics/links", nil))
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain") {
+		t.Fatalf("Expected 200 text/plain, got: %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+
+	// Capped at the two busiest links above the threshold
+	want := "# HELP urlshortener_link_clicks Click count per short link, for the busiest links.\n" +
+		"# TYPE urlshortener_link_clicks gauge\n" +
+		"urlshortener_link_clicks{short_code=\"hot\"} 500\n" +
+		"urlshortener_link_clicks{short_code=\"warm\"} 50\n"
+	if got := rec.Body.String(); got != want {
+		t.Errorf("Unexpected exposition:\n%s\nwant:\n%s", got, want)
+	}
+
+	// Not served unless enabled
+	rec = httptest.NewRecorder()
+	setupTestHandler(t).SetupRoutes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/metrics/links", nil))
+	if rec.Code == http.StatusOK {
+		t.Errorf("Expected no link metrics endpoint by default, got: %d", rec.Code)
+	}
+}
+
+func TestHandleListAliases(t *testing.T) {
+	h := setupTestHandler(t).WithAliasPageLimit(2)
+	for _, alias := range []string{"promo", "launch"} {
+		if _, err := h.service.CreateShortURL(model.CreateURLRequest{URL: "https://example.com", CustomAlias: alias}); err != nil {
+			t.Fatalf("Failed to create %s: %v", alias, err)
+		}
+	}
+
+	var taken []string
+	after := "0"
+	for page := 0; after != ""; page++ {
+		if page > 3 {
+			t.Fatal("Expected pagination to end")
+		}
+		rec := httptest.NewRecorder()
+		h.HandleListAliases(rec, httptest.NewRequest(http.MethodGet, "/admin/aliases?after="+after, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got: %d (%s)", rec.Code, rec.Body.String())
+		}
+		var resp model.AliasList
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if !slices.Contains(resp.Reserved, "admin") || !slices.Contains(resp.Reserved, "shorten") {
+			t.Errorf("Expected reserved words listed, got: %v", resp.Reserved)
+		}
+		if len(resp.Taken) > 2 {
+			t.Errorf("Expected at most 2 aliases per page, got: %d", len(resp.Taken))
+		}
+		for _, alias := range resp.Taken {
+			taken = append(taken, alias.ShortCode)
+		}
+		after = ""
+		if resp.NextAfter != 0 {
+			after = strconv.FormatUint(resp.NextAfter, 10)
+		}
+	}
+
+	if want := []string{"test", "promo", "launch"}; !slices.Equal(taken, want) {
+		t.Errorf("Expected taken aliases %v, got: %v", want, taken)
+	}
+}
+
+func TestSetupRoutes_APIKeysGuardCreate(t *testing.T) {
+	h := setupTestHandler(t).WithAPIKeys(map[string]string{"k3y-a": "team-a", "k3y-b": "team-b"})
+	h.service.WithUniqueURLPerOwner(true)
+	routes := h.SetupRoutes()
+
+	shorten := func(key string) int {
+		req := httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(`{"url": "https://example.com/keyed"}`))
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		rec := httptest.NewRecorder()
+		routes.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := shorten(""); code != http.StatusUnauthorized {
+		t.Fatalf("Expected 401 without a key, got: %d", code)
+	}
+	// The key's owner owns the link: the same key gets it back, another
+	// key gets its own
+	for i, tt := range []struct {
+		key  string
+		want int
+	}{
+		{"k3y-a", http.StatusCreated},
+		{"k3y-a", http.StatusOK},
+		{"k3y-b", http.StatusCreated},
+	} {
+		if code := shorten(tt.key); code != tt.want {
+			t.Errorf("create %d with %s: Expected %d, got: %d", i+1, tt.key, tt.want, code)
+		}
+	}
+
+	// Redirects stay public
+	rec := httptest.NewRecorder()
+	routes.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/test", nil))
+	if rec.Code != http.StatusFound && rec.Code != http.StatusMovedPermanently {
+		t.Errorf("Expected a redirect without a key, got: %d", rec.Code)
+	}
+}
+
+func TestHandleImport_MixedLines(t *testing.T) {
+	h := setupTestHandler(t)
+
+	body := strings.Join([]string{
+		`{"url": "https://example.com/one", "custom_alias": "one"}`,
+		`{"url": "https://example.com/two", "custom_alias": "two"`,
+		``,
+		`{"url": "not a url"}`,
+		`{"url": "https://example.com/taken", "custom_alias": "test"}`,
+		`{"url": "https://example.com/` + strings.Repeat("x", maxImportLine) + `"}`,
+		`{"url": "https://example.com/three", "custom_alias": "three"}`,
+	}, "\n")
+	rec := httptest.NewRecorder()
+	h.HandleImport(rec, httptest.NewRequest(http.MethodPost, "/shorten/import", strings.NewReader(body)))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got: %d", rec.Code)
+	}
+	failed := map[int]string{}
+	var done *importProgress
+	dec := json.NewDecoder(rec.Body)
+	for dec.More() {
+		var event importEvent
+		if err := dec.Decode(&event); err != nil {
+			t.Fatalf("Failed to decode event: %v", err)
+		}
+		if event.Error != nil {
+			failed[event.Line] = event.Error.Code
+		}
+		if event.Done != nil {
+			done = event.Done
+		}
+	}
+
+	wantFailed := map[int]string{2: "INVALID_JSON", 3: "INVALID_URL", 4: "URL_EXISTS", 5: "BAD_REQUEST"}
+	for line, code := range wantFailed {
+		if failed[line] != code {
+			t.Errorf("Line %d: expected %s, got: %q", line, code, failed[line])
+		}
+	}
+	if len(failed) != len(wantFailed) {
+		t.Errorf("Expected %d failed lines, got: %v", len(wantFailed), failed)
+	}
+	if done == nil || *done != (importProgress{Lines: 6, Created: 2, Failed: 4}) {
+		t.Errorf("Unexpected summary: %+v", done)
+	}
+
+	for _, alias := range []string{"one", "three"} {
+		if _, err := h.service.GetURLStats(alias); err != nil {
+			t.Errorf("Expected %s imported, got: %v", alias, err)
+		}
+	}
+}
+
+func TestSetupRoutes_AdminListsAllURLs(t *testing.T) {
+	h := setupTestHandler(t)
+	for _, alias := range []string{"first", "second", "third"} {
+		if _, err := h.service.CreateShortURL(model.CreateURLRequest{URL: "https://example.com", CustomAlias: alias}); err != nil {
+			t.Fatalf("Failed to create %s: %v", alias, err)
+		}
+	}
+	routes := middleware.AdminAuth(middleware.DefaultAdminAuthConfig("s3cr3t"))(h.SetupRoutes())
+	list := func(query, auth string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/urls"+query, nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		routes.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := list("?limit=2", "Bearer s3cr3t")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got: %d %s", rec.Code, rec.Body.String())
+	}
+	var page model.URLList
+	if err := json.NewDecoder(rec.Body).Decode(&page); err != nil {
+		t.Fatalf("Failed to decode list: %v", err)
+	}
+	var codes []string
+	for _, url := range page.URLs {
+		codes = append(codes, url.ShortCode)
+	}
+	if want := []string{"third", "second"}; !slices.Equal(codes, want) {
+		t.Errorf("Expected %v, got: %v", want, codes)
+	}
+	if page.Total != 4 || page.NextOffset != 2 {
+		t.Errorf("Expected total 4 and next offset 2, got: %d, %d", page.Total, page.NextOffset)
+	}
+
+	rec = list("?limit=1000&offset=2", "Bearer s3cr3t")
+	page = model.URLList{}
+	if err := json.NewDecoder(rec.Body).Decode(&page); err != nil {
+		t.Fatalf("Failed to decode list: %v", err)
+	}
+	if len(page.URLs) != 2 || page.NextOffset != 0 {
+		t.Errorf("Expected the last 2 links and no next page, got: %d, %d", len(page.URLs), page.NextOffset)
+	}
+
+	for query, want := range map[string]int{"?offset=-1": http.StatusBadRequest, "?limit=0": http.StatusBadRequest, "?limit=abc": http.StatusBadRequest} {
+		if rec := list(query, "Bearer s3cr3t"); rec.Code != want {
+			t.Errorf("%s: Expected %d, got: %d", query, want, rec.Code)
+		}
+	}
+	if rec := list("", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 listing without the admin token, got: %d", rec.Code)
+	}
+}
+
+func TestSetupRoutes_OwnersSeeAndDeleteOwnLinks(t *testing.T) {
+	routes := setupTestHandler(t).WithAPIKeys(map[string]string{"k3y-a": "team-a", "k3y-b": "team-b"}).SetupRoutes()
+	do := func(method, path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		rec := httptest.NewRecorder()
+		routes.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for _, alias := range []string{"a-one", "a-two"} {
+		if rec := do(http.MethodPost, "/shorten", "k3y-a", `{"url": "https://example.com", "custom_alias": "`+alias+`"}`); rec.Code != http.StatusCreated {
+			t.Fatalf("Failed to create %s: %d", alias, rec.Code)
+		}
+	}
+	if rec := do(http.MethodPost, "/shorten", "k3y-b", `{"url": "https://example.com", "custom_alias": "b-one"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("Failed to create b-one: %d", rec.Code)
+	}
+
+	rec := do(http.MethodGet, "/urls", "k3y-a", "")
+	var list model.OwnerURLList
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatalf("Failed to decode list: %v", err)
+	}
+	var codes []string
+	for _, url := range list.URLs {
+		codes = append(codes, url.ShortCode)
+	}
+	if want := []string{"a-two", "a-one"}; !slices.Equal(codes, want) {
+		t.Errorf("Expected %v, got: %v", want, codes)
+	}
+	if rec := do(http.MethodGet, "/urls", "", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 listing without a key, got: %d", rec.Code)
+	}
+
+	if rec := do(http.MethodDelete, "/b-one", "k3y-a", ""); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 deleting another owner's link, got: %d", rec.Code)
+	}
+	if rec := do(http.MethodDelete, "/a-one", "", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 deleting without a key, got: %d", rec.Code)
+	}
+	if rec := do(http.MethodDelete, "/a-one", "k3y-a", ""); rec.Code != http.StatusNoContent {
+		t.Errorf("Expected 204 deleting an own link, got: %d", rec.Code)
+	}
+}
+
+func TestSetupRoutes_OnlyReserverActivates(t *testing.T) {
+	routes := setupTestHandler(t).WithAPIKeys(map[string]string{"k3y-a": "team-a", "k3y-b": "team-b"}).SetupRoutes()
+	do := func(method, path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		rec := httptest.NewRecorder()
+		routes.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do(http.MethodPost, "/reserve", "k3y-a", `{"custom_alias": "launch"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("Failed to reserve: %d %s", rec.Code, rec.Body.String())
+	}
+
+	activate := `{"url": "https://example.com/launch"}`
+	if rec := do(http.MethodPut, "/launch", "", activate); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 activating without a key, got: %d", rec.Code)
+	}
+	if rec := do(http.MethodPut, "/launch", "k3y-b", activate); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 activating another owner's reservation, got: %d", rec.Code)
+	}
+	if rec := do(http.MethodPut, "/launch", "k3y-a", activate); rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 activating an own reservation, got: %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestSetupRoutes_ErrorCatalog(t *testing.T) {
+	h := setupTestHandler(t)
+
+	rec := httptest.NewRecorder()
+	h.SetupRoutes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/errors", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got: %d", rec.Code)
+	}
+	var body struct {
+		Errors []struct {
+			Code   string `json:"code"`
+			Status int    `json:"status"`
+		} `json:"errors"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode catalog: %v", err)
+	}
+	found := false
+	for _, entry := range body.Errors {
+		if entry.Code == "URL_NOT_FOUND" && entry.Status == http.StatusNotFound {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected URL_NOT_FOUND listed with 404, got: %+v", body.Errors)
+	}
+}
+
+func TestWarmUp_ReadyOnlyAfterWarmers(t *testing.T) {
+	h := setupTestHandler(t).WithWarmUp(true)
+	health := func() int {
+		rec := httptest.NewRecorder()
+		h.HandleHealth(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+		return rec.Code
+	}
+
+	release := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- h.WarmUp(context.Background(),
+			func(ctx context.Context) error { <-release; return nil },
+			func(ctx context.Context) error { return fmt.Errorf("redis unreachable") },
+		)
+	}()
+
+	// Not ready while a warmer is still running
+	if code := health(); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 during warm-up, got: %d", code)
+	}
+	close(release)
+	if err := <-done; err == nil {
+		t.Error("Expected the failed warmer's error")
+	}
+	if code := health(); code != http.StatusOK {
+		t.Errorf("Expected 200 after warm-up, even with a failed warmer, got: %d", code)
+	}
+}
+
+func TestHandleReady(t *testing.T) {
+	repo, err := repository.NewURLRepository(&config.DatabaseConfig{Driver: "sqlite3", Path: ":memory:", MaxOpenConns: 1, MaxIdleConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create repo: %v", err)
+	}
+	h := NewURLHandler(service.NewURLService(repo, "http://localhost:8080", nil))
+	routes := h.SetupRoutes()
+	ready := func() (int, model.ReadinessResponse) {
+		rec := httptest.NewRecorder()
+		routes.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+		var body model.ReadinessResponse
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatalf("Failed to decode readiness: %v", err)
+		}
+		return rec.Code, body
+	}
+
+	code, body := ready()
+	if code != http.StatusOK || body.Status != model.StatusReady {
+		t.Fatalf("Expected 200 ready, got %d: %+v", code, body)
+	}
+	if body.Driver != "sqlite3" || body.Replicas != 0 || body.Dependencies["database"].Status != "up" {
+		t.Errorf("Expected sqlite3 with no replicas and the database up, got: %+v", body)
+	}
+
+	h.WithWarmUp(true)
+	if code, body := ready(); code != http.StatusServiceUnavailable || body.Status != model.StatusStarting {
+		t.Errorf("Expected 503 starting during warm-up, got %d: %+v", code, body)
+	}
+	h.WithWarmUp(false)
+
+	repo.Close() // the database goes away
+	code, body = ready()
+	if code != http.StatusServiceUnavailable || body.Status != model.StatusUnavailable {
+		t.Errorf("Expected 503 unavailable, got %d: %+v", code, body)
+	}
+	if db := body.Dependencies["database"]; db.Status != "down" {
+		t.Errorf("Expected the database reported down, got: %+v", db)
+	}
+	// Error details are logged, not sent to unauthenticated callers
+	rec := httptest.NewRecorder()
+	routes.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+	if strings.Contains(rec.Body.String(), "error") {
+		t.Errorf("Expected no error details in the response, got: %s", rec.Body.String())
+	}
+
+	// Liveness doesn't depend on the database
+	rec = httptest.NewRecorder()
+	routes.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health/live", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected /health/live 200 with the database down, got: %d", rec.Code)
+	}
+}
+
+func TestHandleShortenBatch_ChargesCreateLimitPerItem(t *testing.T) {
+	limiter := middleware.NewCreateLimiter(middleware.CreateLimiterConfig{Limit: 2, Window: time.Hour}, nil, nil)
+	routes := limiter.Middleware()(setupTestHandler(t).SetupRoutes())
+
+	rec := httptest.NewRecorder()
+	routes.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/shorten/batch",
+		strings.NewReader(`[{"url":"https://example.com/1"},{"url":"https://example.com/2"},{"url":"https://example.com/3"}]`)))
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429 for a batch over the limit, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	routes.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/shorten/batch",
+		strings.NewReader(`[{"url":"https://example.com/1"},{"url":"https://example.com/2"}]`)))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected a batch that fits to succeed, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"context"
+	"sync"
+)
+
+// Warmer opens a dependency's connections ahead of traffic
+type Warmer func(ctx context.Context) error
+
+// WithWarmUp makes /health answer 503 "starting" until WarmUp returns,
+// so load balancers hold traffic while connection pools are cold
+func (h *URLHandler) WithWarmUp(enabled bool) *URLHandler {
+	h.warming.Store(enabled)
+	return h
+}
+
+// WarmUp runs warmers concurrently, then reports the service ready.
+// Readiness is restored even if a warmer fails: the dependencies did
+// connect at startup, so traffic only loses the head start. The first
+// error is returned for logging.
+func (h *URLHandler) WarmUp(ctx context.Context, warmers ...Warmer) error {
+	defer h.warming.Store(false)
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(warmers))
+	for i, warm := range warmers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = warm(ctx)
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package logger
 import (
 	"io"
 	"log/slog"
+	"net/url"
 	"os"
 )
 
@@ -17,6 +18,24 @@ type Config struct {
 	Format      string // "json", "text"
 	Output      io.Writer
 	Environment string
+	RedactURLs  string // "off", "query" (strip query strings), "full" (hide URLs entirely)
+}
+
+// Redaction modes for URL-bearing log attributes
+const (
+	RedactOff   = "off"
+	RedactQuery = "query"
+	RedactFull  = "full"
+
+	redacted = "[REDACTED]"
+)
+
+// urlAttrKeys are attribute keys whose values may carry sensitive URLs
+var urlAttrKeys = map[string]bool{
+	"url":          true,
+	"original_url": true,
+	"location":     true,
+	"referer":      true,
 }
 
 // New creates a new Logger instance
@@ -30,6 +49,12 @@ func New(cfg Config) *Logger {
 	opts := &slog.HandlerOptions{
 		Level: level,
 	}
+	if cfg.RedactURLs == RedactQuery || cfg.RedactURLs == RedactFull {
+		mode := cfg.RedactURLs
+		opts.ReplaceAttr = func(groups []string, a slog.Attr) slog.Attr {
+			return redactAttr(mode, a)
+		}
+	}
 
 	var handler slog.Handler
 	if cfg.Format == "json" || cfg.Environment == "production" {
@@ -57,3 +82,27 @@ func parseLevel(level string) slog.Level {
 		return slog.LevelInfo
 	}
 }
+
+// redactAttr hides query strings (or whole URLs) in URL-bearing attributes.
+// Short codes live in the path, so "path" is always kept.
+func redactAttr(mode string, a slog.Attr) slog.Attr {
+	if a.Key == "query" && a.Value.String() != "" {
+		return slog.String(a.Key, redacted)
+	}
+	if !urlAttrKeys[a.Key] {
+		return a
+	}
+	if mode == RedactFull {
+		return slog.String(a.Key, redacted)
+	}
+
+	parsed, err := url.Parse(a.Value.String())
+	if err != nil {
+		return slog.String(a.Key, redacted)
+	}
+	if parsed.RawQuery != "" {
+		parsed.RawQuery = redacted
+	}
+	parsed.Fragment = ""
+	return slog.String(a.Key, parsed.String())
+}
@@ -0,0 +1,45 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRedactURLs(t *testing.T) {
+	const sensitive = "https://example.com/reset?token=s3cr3t&user=42"
+
+	tests := []struct {
+		mode     string
+		contains string
+		hidden   bool
+	}{
+		{RedactOff, "token=s3cr3t", false},
+		{RedactQuery, "https://example.com/reset", true},
+		{RedactFull, "[REDACTED]", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			var buf bytes.Buffer
+			log := New(Config{Level: "info", Format: "json", Output: &buf, RedactURLs: tt.mode})
+
+			log.Info("request completed",
+				"path", "/abc",
+				"query", "token=s3cr3t",
+				"location", sensitive,
+			)
+			out := buf.String()
+
+			if !strings.Contains(out, tt.contains) {
+				t.Errorf("Expected log to contain %q, got: %s", tt.contains, out)
+			}
+			if hidden := !strings.Contains(out, "s3cr3t"); hidden != tt.hidden {
+				t.Errorf("Token hidden = %v; want %v. Log: %s", hidden, tt.hidden, out)
+			}
+			if !strings.Contains(out, `"path":"/abc"`) {
+				t.Errorf("Expected short code path to be kept, got: %s", out)
+			}
+		})
+	}
+}
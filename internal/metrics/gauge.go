@@ -0,0 +1,37 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// labelEscaper applies the text exposition format's label value escaping.
+// Unlike Go's %q it leaves non-ASCII characters, like emoji aliases, as is.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// labelValue quotes v for use as a label value
+func labelValue(v string) string {
+	return `"` + labelEscaper.Replace(v) + `"`
+}
+
+// Sample is one labeled value of a gauge family
+type Sample struct {
+	Label string
+	Value float64
+}
+
+// WriteGauges writes samples as a single gauge family in the Prometheus
+// text exposition format, each labeled label="Sample.Label", in the order given
+func WriteGauges(w io.Writer, name, help, label string, samples []Sample) error {
+	if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name); err != nil {
+		return err
+	}
+	for _, s := range samples {
+		if _, err := fmt.Fprintf(w, "%s{%s=%s} %s\n", name, label, labelValue(s.Label), strconv.FormatFloat(s.Value, 'f', -1, 64)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
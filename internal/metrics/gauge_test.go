@@ -0,0 +1,29 @@
+package metrics
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteGauges(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteGauges(&buf, "test_clicks", "Clicks per link.", "short_code", []Sample{
+		{Label: "promo", Value: 1234567},
+		{Label: `odd"code`, Value: 0.5},
+		{Label: `back\slash`, Value: 2},
+		{Label: "🚀launch", Value: 3},
+	})
+	if err != nil {
+		t.Fatalf("WriteGauges failed: %v", err)
+	}
+
+	want := "# HELP test_clicks Clicks per link.\n" +
+		"# TYPE test_clicks gauge\n" +
+		"test_clicks{short_code=\"promo\"} 1234567\n" +
+		"test_clicks{short_code=\"odd\\\"code\"} 0.5\n" +
+		"test_clicks{short_code=\"back\\\\slash\"} 2\n" +
+		"test_clicks{short_code=\"🚀launch\"} 3\n" // no \u escapes
+	if got := buf.String(); got != want {
+		t.Errorf("Unexpected exposition:\n%s\nwant:\n%s", got, want)
+	}
+}
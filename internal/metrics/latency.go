@@ -0,0 +1,147 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// LatencyTracker keeps the most recent response times per route in a
+// fixed-size ring and reports percentiles over them. Memory is bounded by
+// routes * size, and old traffic ages out, so the percentiles follow
+// current tail latency rather than the all-time distribution.
+// Safe for concurrent use.
+type LatencyTracker struct {
+	name        string
+	help        string
+	size        int
+	percentiles []float64
+
+	mu     sync.Mutex
+	routes map[string]*latencyRing
+}
+
+type latencyRing struct {
+	samples []time.Duration
+	next    int    // slot the next sample overwrites once full
+	count   uint64 // every sample ever observed, for _count
+}
+
+// LatencySummary is the percentile snapshot for one route
+type LatencySummary struct {
+	Count       uint64             `json:"count"`   // requests observed since startup
+	Samples     int                `json:"samples"` // requests the percentiles are computed over
+	Percentiles map[string]float64 `json:"percentiles_ms"`
+}
+
+// NewLatencyTracker keeps up to size samples per route and reports the
+// given percentiles (each in (0, 100))
+func NewLatencyTracker(name, help string, size int, percentiles ...float64) *LatencyTracker {
+	if size < 1 {
+		size = 1
+	}
+	sorted := slices.Clone(percentiles)
+	slices.Sort(sorted)
+	return &LatencyTracker{
+		name:        name,
+		help:        help,
+		size:        size,
+		percentiles: sorted,
+		routes:      make(map[string]*latencyRing),
+	}
+}
+
+// Observe records one response time for route
+func (t *LatencyTracker) Observe(route string, d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	ring, ok := t.routes[route]
+	if !ok {
+		ring = &latencyRing{samples: make([]time.Duration, 0, t.size)}
+		t.routes[route] = ring
+	}
+	if len(ring.samples) < t.size {
+		ring.samples = append(ring.samples, d)
+	} else {
+		ring.samples[ring.next] = d
+		ring.next = (ring.next + 1) % t.size
+	}
+	ring.count++
+}
+
+// Summary returns the configured percentiles for every route seen so far
+func (t *LatencyTracker) Summary() map[string]LatencySummary {
+	t.mu.Lock()
+	snapshot := make(map[string]latencyRing, len(t.routes))
+	for route, ring := range t.routes {
+		snapshot[route] = latencyRing{samples: slices.Clone(ring.samples), count: ring.count}
+	}
+	t.mu.Unlock()
+
+	result := make(map[string]LatencySummary, len(snapshot))
+	for route, ring := range snapshot {
+		slices.Sort(ring.samples)
+		summary := LatencySummary{
+			Count:       ring.count,
+			Samples:     len(ring.samples),
+			Percentiles: make(map[string]float64, len(t.percentiles)),
+		}
+		for _, p := range t.percentiles {
+			summary.Percentiles[percentileLabel(p)] = milliseconds(percentile(ring.samples, p))
+		}
+		result[route] = summary
+	}
+	return result
+}
+
+// WriteText writes the percentiles as a Prometheus summary, in seconds
+func (t *LatencyTracker) WriteText(w io.Writer) error {
+	summaries := t.Summary()
+	routes := make([]string, 0, len(summaries))
+	for route := range summaries {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+
+	if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s summary\n", t.name, t.help, t.name); err != nil {
+		return err
+	}
+	for _, route := range routes {
+		summary := summaries[route]
+		for _, p := range t.percentiles {
+			seconds := summary.Percentiles[percentileLabel(p)] / 1000
+			if _, err := fmt.Fprintf(w, "%s{route=%s,quantile=\"%s\"} %g\n", t.name, labelValue(route), strconv.FormatFloat(p/100, 'f', -1, 64), seconds); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "%s_count{route=%s} %d\n", t.name, labelValue(route), summary.Count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// percentile picks the nearest-rank sample from sorted samples
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	rank = min(max(rank, 1), len(sorted))
+	return sorted[rank-1]
+}
+
+// percentileLabel formats 99.9 as "p99.9"
+func percentileLabel(p float64) string {
+	return "p" + strconv.FormatFloat(p, 'f', -1, 64)
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
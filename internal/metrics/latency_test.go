@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLatencyTracker_Percentiles(t *testing.T) {
+	tracker := NewLatencyTracker("test_seconds", "Test latency.", 1000, 50, 95, 99)
+
+	// 1ms..1000ms, shuffled by striding so order doesn't matter
+	for i := 0; i < 1000; i++ {
+		ms := (i*7)%1000 + 1
+		tracker.Observe("/", time.Duration(ms)*time.Millisecond)
+	}
+
+	summary := tracker.Summary()["/"]
+	if summary.Count != 1000 || summary.Samples != 1000 {
+		t.Fatalf("Expected 1000 samples, got: %+v", summary)
+	}
+	for label, want := range map[string]float64{"p50": 500, "p95": 950, "p99": 990} {
+		if got := summary.Percentiles[label]; math.Abs(got-want) > 10 {
+			t.Errorf("Expected %s ~%.0fms, got: %.1fms", label, want, got)
+		}
+	}
+}
+
+func TestLatencyTracker_OldSamplesAgeOut(t *testing.T) {
+	tracker := NewLatencyTracker("test_seconds", "Test latency.", 100, 50, 99)
+
+	for i := 0; i < 100; i++ {
+		tracker.Observe("/shorten", time.Second)
+	}
+	for i := 0; i < 100; i++ {
+		tracker.Observe("/shorten", 2*time.Millisecond)
+	}
+
+	summary := tracker.Summary()["/shorten"]
+	if summary.Count != 200 || summary.Samples != 100 {
+		t.Errorf("Expected 200 observed and 100 kept, got: %+v", summary)
+	}
+	if got := summary.Percentiles["p99"]; got != 2 {
+		t.Errorf("Expected slow samples aged out (p99 2ms), got: %.1fms", got)
+	}
+	if _, ok := tracker.Summary()["/"]; ok {
+		t.Error("Expected no entry for a route that saw no requests")
+	}
+}
+
+func TestLatencyTracker_WriteText(t *testing.T) {
+	tracker := NewLatencyTracker("test_seconds", "Test latency.", 10, 99, 50)
+	tracker.Observe("/", 250*time.Millisecond)
+
+	var out strings.Builder
+	if err := tracker.WriteText(&out); err != nil {
+		t.Fatalf("WriteText failed: %v", err)
+	}
+	want := `# HELP test_seconds Test latency.
+# TYPE test_seconds summary
+test_seconds{route="/",quantile="0.5"} 0.25
+test_seconds{route="/",quantile="0.99"} 0.25
+test_seconds_count{route="/"} 1
+`
+	if out.String() != want {
+		t.Errorf("Expected:\n%s\ngot:\n%s", want, out.String())
+	}
+}
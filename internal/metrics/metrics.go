@@ -0,0 +1,176 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Degradation kinds, counted each time the service falls back to a
+// slower or less complete path instead of failing the request
+const (
+	CacheRead   = "cache_read"   // Redis read failed; served from the database
+	CacheWrite  = "cache_write"  // Redis write failed; link left uncached
+	ClickBuffer = "click_buffer" // click buffer failed; counted directly in the database
+	ReplicaRead = "replica_read" // replica query failed; retried on the primary
+	RateLimit   = "rate_limit"   // shared rate limit store failed; limited per instance
+)
+
+// Degradations counts fallbacks by kind
+var Degradations = NewCounterVec(
+	"urlshortener_degradations_total",
+	"Requests served through a fallback path, by kind of degradation.",
+	"kind",
+	CacheRead, CacheWrite, ClickBuffer, ReplicaRead, RateLimit,
+)
+
+// Cache lookup results
+const (
+	CacheHit  = "hit"  // served from Redis
+	CacheMiss = "miss" // not in Redis; looked up in the database
+)
+
+// CacheLookups counts resolves that consulted Redis, by result. Lookups
+// where Redis was unreachable are counted as CacheRead degradations instead.
+var CacheLookups = NewCounterVec(
+	"urlshortener_cache_lookups_total",
+	"Short code lookups that consulted Redis, by result.",
+	"result",
+	CacheHit, CacheMiss,
+)
+
+// Limiters that reject requests with 429
+const (
+	RequestLimit = "request" // per-IP request rate limit
+	CreateLimit  = "create"  // per-IP create cap
+	LinkLimit    = "link"    // per-link redirect limit
+)
+
+// RateLimitRejections counts requests answered 429, by limiter
+var RateLimitRejections = NewCounterVec(
+	"urlshortener_rate_limit_rejections_total",
+	"Requests rejected by a rate limit, by limiter.",
+	"limiter",
+	RequestLimit, CreateLimit, LinkLimit,
+)
+
+// Background queues that drop work when full
+const (
+	WebhookQueue    = "webhook"     // click webhook deliveries
+	ClickEventQueue = "click_event" // detailed click rows
+	ClickRetryQueue = "click_retry" // failed click increments
+)
+
+// Drops counts work dropped because a background queue was full
+var Drops = NewCounterVec(
+	"urlshortener_dropped_total",
+	"Background work dropped because its queue was full, by queue.",
+	"queue",
+	WebhookQueue, ClickEventQueue, ClickRetryQueue,
+)
+
+// Collector is anything Handler can write in the text format
+type Collector interface {
+	WriteText(w io.Writer) error
+}
+
+// registry holds every collector written by Handler
+var (
+	registryMu sync.RWMutex
+	registry   = []Collector{Degradations, CacheLookups, RateLimitRejections, Drops}
+)
+
+// Register adds a collector to the ones Handler serves
+func Register(c Collector) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry = append(registry, c)
+}
+
+// CounterVec is a set of counters sharing a name, split by one label.
+// Safe for concurrent use.
+type CounterVec struct {
+	name  string
+	help  string
+	label string
+
+	mu     sync.RWMutex
+	counts map[string]*atomic.Uint64
+}
+
+// NewCounterVec creates counters for the given label values, which are
+// reported as 0 until first incremented
+func NewCounterVec(name, help, label string, values ...string) *CounterVec {
+	v := &CounterVec{name: name, help: help, label: label, counts: make(map[string]*atomic.Uint64)}
+	for _, value := range values {
+		v.counts[value] = new(atomic.Uint64)
+	}
+	return v
+}
+
+// Inc adds one to the counter for value
+func (v *CounterVec) Inc(value string) {
+	v.mu.RLock()
+	c, ok := v.counts[value]
+	v.mu.RUnlock()
+	if !ok {
+		v.mu.Lock()
+		if c, ok = v.counts[value]; !ok {
+			c = new(atomic.Uint64)
+			v.counts[value] = c
+		}
+		v.mu.Unlock()
+	}
+	c.Add(1)
+}
+
+// Get returns the current count for value
+func (v *CounterVec) Get(value string) uint64 {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	if c, ok := v.counts[value]; ok {
+		return c.Load()
+	}
+	return 0
+}
+
+// WriteText writes the counters in the Prometheus text exposition format
+func (v *CounterVec) WriteText(w io.Writer) error {
+	v.mu.RLock()
+	values := make([]string, 0, len(v.counts))
+	for value := range v.counts {
+		values = append(values, value)
+	}
+	v.mu.RUnlock()
+	sort.Strings(values)
+
+	if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", v.name, v.help, v.name); err != nil {
+		return err
+	}
+	for _, value := range values {
+		if _, err := fmt.Fprintf(w, "%s{%s=%s} %d\n", v.name, v.label, labelValue(value), v.Get(value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Handler serves all registered metrics
+// GET /metrics
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		registryMu.RLock()
+		collectors := slices.Clone(registry)
+		registryMu.RUnlock()
+		for _, c := range collectors {
+			if err := c.WriteText(w); err != nil {
+				return
+			}
+		}
+	})
+}
@@ -0,0 +1,54 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCounterVec(t *testing.T) {
+	v := NewCounterVec("test_total", "Test counter.", "kind", "a")
+
+	v.Inc("a")
+	v.Inc("a")
+	v.Inc("b") // values not declared up front are created on first use
+
+	if got := v.Get("a"); got != 2 {
+		t.Errorf("Expected a=2, got: %d", got)
+	}
+	if got := v.Get("b"); got != 1 {
+		t.Errorf("Expected b=1, got: %d", got)
+	}
+	if got := v.Get("missing"); got != 0 {
+		t.Errorf("Expected missing=0, got: %d", got)
+	}
+
+	var out strings.Builder
+	if err := v.WriteText(&out); err != nil {
+		t.Fatalf("WriteText failed: %v", err)
+	}
+	want := `# HELP test_total Test counter.
+# TYPE test_total counter
+test_total{kind="a"} 2
+test_total{kind="b"} 1
+`
+	if out.String() != want {
+		t.Errorf("Expected:\n%s\ngot:\n%s", want, out.String())
+	}
+}
+
+func TestHandler_ListsAllDegradationKinds(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	body := rec.Body.String()
+	for _, kind := range []string{CacheRead, CacheWrite, ClickBuffer, ReplicaRead, RateLimit} {
+		if !strings.Contains(body, `urlshortener_degradations_total{kind="`+kind+`"}`) {
+			t.Errorf("Expected %s counter in output, got:\n%s", kind, body)
+		}
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("Expected text/plain content type, got: %s", ct)
+	}
+}
@@ -0,0 +1,135 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultDurationBuckets are the histogram upper bounds, in seconds
+var DefaultDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// RequestMetrics counts HTTP requests by route and status code and keeps a
+// duration histogram per route. Routes should be mux patterns so the number
+// of series stays bounded. Safe for concurrent use.
+type RequestMetrics struct {
+	buckets []float64
+
+	mu        sync.Mutex
+	counts    map[requestKey]uint64
+	durations map[string]*histogram
+}
+
+type requestKey struct {
+	route string
+	code  int
+}
+
+type histogram struct {
+	counts []uint64 // per bucket, not cumulative
+	sum    float64
+	count  uint64
+}
+
+// NewRequestMetrics uses buckets (sorted upper bounds in seconds) for the
+// duration histogram, or DefaultDurationBuckets when none are given
+func NewRequestMetrics(buckets ...float64) *RequestMetrics {
+	if len(buckets) == 0 {
+		buckets = DefaultDurationBuckets
+	}
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+	return &RequestMetrics{
+		buckets:   sorted,
+		counts:    make(map[requestKey]uint64),
+		durations: make(map[string]*histogram),
+	}
+}
+
+// Observe records one request to route that answered code after d
+func (m *RequestMetrics) Observe(route string, code int, d time.Duration) {
+	seconds := d.Seconds()
+	bucket := sort.SearchFloat64s(m.buckets, seconds) // first bound >= seconds
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.counts[requestKey{route, code}]++
+	h, ok := m.durations[route]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(m.buckets))}
+		m.durations[route] = h
+	}
+	if bucket < len(h.counts) {
+		h.counts[bucket]++
+	}
+	h.sum += seconds
+	h.count++
+}
+
+// Count returns how many requests to route answered code
+func (m *RequestMetrics) Count(route string, code int) uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.counts[requestKey{route, code}]
+}
+
+// WriteText writes urlshortener_http_requests_total and
+// urlshortener_http_request_duration_seconds in the text format
+func (m *RequestMetrics) WriteText(w io.Writer) error {
+	m.mu.Lock()
+	keys := make([]requestKey, 0, len(m.counts))
+	counts := make(map[requestKey]uint64, len(m.counts))
+	for key, n := range m.counts {
+		keys = append(keys, key)
+		counts[key] = n
+	}
+	routes := make([]string, 0, len(m.durations))
+	durations := make(map[string]histogram, len(m.durations))
+	for route, h := range m.durations {
+		routes = append(routes, route)
+		durations[route] = histogram{counts: append([]uint64(nil), h.counts...), sum: h.sum, count: h.count}
+	}
+	m.mu.Unlock()
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].route != keys[j].route {
+			return keys[i].route < keys[j].route
+		}
+		return keys[i].code < keys[j].code
+	})
+	sort.Strings(routes)
+
+	const requests = "urlshortener_http_requests_total"
+	if _, err := fmt.Fprintf(w, "# HELP %s HTTP requests, by route and status code.\n# TYPE %s counter\n", requests, requests); err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if _, err := fmt.Fprintf(w, "%s{route=%s,code=\"%d\"} %d\n", requests, labelValue(key.route), key.code, counts[key]); err != nil {
+			return err
+		}
+	}
+
+	const duration = "urlshortener_http_request_duration_seconds"
+	if _, err := fmt.Fprintf(w, "# HELP %s HTTP request duration, by route.\n# TYPE %s histogram\n", duration, duration); err != nil {
+		return err
+	}
+	for _, route := range routes {
+		h := durations[route]
+		var cumulative uint64
+		for i, bound := range m.buckets {
+			cumulative += h.counts[i]
+			if _, err := fmt.Fprintf(w, "%s_bucket{route=%s,le=\"%s\"} %d\n", duration, labelValue(route), strconv.FormatFloat(bound, 'g', -1, 64), cumulative); err != nil {
+				return err
+			}
+		}
+		label := labelValue(route)
+		if _, err := fmt.Fprintf(w, "%s_bucket{route=%s,le=\"+Inf\"} %d\n%s_sum{route=%s} %g\n%s_count{route=%s} %d\n",
+			duration, label, h.count, duration, label, h.sum, duration, label, h.count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRequestMetrics_WriteText(t *testing.T) {
+	m := NewRequestMetrics(0.1, 1)
+	m.Observe("/", 301, 50*time.Millisecond)
+	m.Observe("/", 301, 500*time.Millisecond)
+	m.Observe("/", 404, 2*time.Second)
+	m.Observe("/shorten", 201, 100*time.Millisecond)
+
+	if got := m.Count("/", 301); got != 2 {
+		t.Errorf("Expected 2 redirects counted, got: %d", got)
+	}
+
+	var out strings.Builder
+	if err := m.WriteText(&out); err != nil {
+		t.Fatalf("WriteText failed: %v", err)
+	}
+	want := `# HELP urlshortener_http_requests_total HTTP requests, by route and status code.
+# TYPE urlshortener_http_requests_total counter
+urlshortener_http_requests_total{route="/",code="301"} 2
+urlshortener_http_requests_total{route="/",code="404"} 1
+urlshortener_http_requests_total{route="/shorten",code="201"} 1
+# HELP urlshortener_http_request_duration_seconds HTTP request duration, by route.
+# TYPE urlshortener_http_request_duration_seconds histogram
+urlshortener_http_request_duration_seconds_bucket{route="/",le="0.1"} 1
+urlshortener_http_request_duration_seconds_bucket{route="/",le="1"} 2
+urlshortener_http_request_duration_seconds_bucket{route="/",le="+Inf"} 3
+urlshortener_http_request_duration_seconds_sum{route="/"} 2.55
+urlshortener_http_request_duration_seconds_count{route="/"} 3
+urlshortener_http_request_duration_seconds_bucket{route="/shorten",le="0.1"} 1
+urlshortener_http_request_duration_seconds_bucket{route="/shorten",le="1"} 1
+urlshortener_http_request_duration_seconds_bucket{route="/shorten",le="+Inf"} 1
+urlshortener_http_request_duration_seconds_sum{route="/shorten"} 0.1
+urlshortener_http_request_duration_seconds_count{route="/shorten"} 1
+`
+	if out.String() != want {
+		t.Errorf("Unexpected output:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// combinedLine matches host ident user [time] "request" status bytes "referer" "agent"
+var combinedLine = regexp.MustCompile(`^(\S+) - (\S+) \[(\d{2}/[A-Z][a-z]{2}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4})\] "([^"]*)" (\d{3}) (\d+|-) "((?:[^"\\]|\\.)*)" "((?:[^"\\]|\\.)*)"\n$`)
+
+func TestCombinedLog_FormatsLine(t *testing.T) {
+	var out bytes.Buffer
+	h := CombinedLog(&out, false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("not found"))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/abc?utm=x", nil)
+	req.RemoteAddr = "203.0.113.7:51234"
+	req.Header.Set("Referer", "https://example.com/page")
+	req.Header.Set("User-Agent", `Mozilla/5.0 "quoted"`)
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	m := combinedLine.FindStringSubmatch(out.String())
+	if m == nil {
+		t.Fatalf("Expected a combined log line, got: %q", out.String())
+	}
+	want := map[int]string{
+		1: "203.0.113.7",
+		2: "-",
+		4: "GET /abc?utm=x HTTP/1.1",
+		5: "404",
+		6: "9",
+		7: "https://example.com/page",
+		8: `Mozilla/5.0 \"quoted\"`,
+	}
+	for i, v := range want {
+		if m[i] != v {
+			t.Errorf("Field %d: expected %q, got %q", i, v, m[i])
+		}
+	}
+}
+
+func TestCombinedLog_EmptyFieldsAndRedaction(t *testing.T) {
+	var out bytes.Buffer
+	h := CombinedLog(&out, true)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/abc?token=secret", nil)
+	req.Header.Del("User-Agent")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	line := out.String()
+	if strings.Contains(line, "secret") {
+		t.Errorf("Expected query redacted, got: %q", line)
+	}
+	if !strings.Contains(line, `"GET /abc?[REDACTED] HTTP/1.1" 204 - "-" "-"`) {
+		t.Errorf("Expected dashes for empty size, referer, and agent, got: %q", line)
+	}
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/darkodi/url-shortener/internal/errors"
+)
+
+// ============================================================
+// ADMIN AUTH MIDDLEWARE
+// ============================================================
+
+// AdminKey is the context key set on requests carrying a valid admin token
+const AdminKey ContextKey = "admin"
+
+// AdminAuthConfig holds configuration for the admin auth middleware
+type AdminAuthConfig struct {
+	Token    string   // Shared secret expected as "Authorization: Bearer <token>"
+	Prefixes []string // Path prefixes that require the token
+}
+
+// DefaultAdminAuthConfig protects everything under /admin/ and the
+// decode API, which reveals internal IDs
+func DefaultAdminAuthConfig(token string) AdminAuthConfig {
+	return AdminAuthConfig{
+		Token:    token,
+		Prefixes: []string{"/admin/", "/api/decode/"},
+	}
+}
+
+// AdminAuth rejects requests to protected paths that lack the admin token.
+// Other paths pass through, marked with IsAdmin when they carry the token.
+func AdminAuth(config AdminAuthConfig) Middleware {
+	expected := []byte(config.Token)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			admin := ok && len(expected) > 0 && subtle.ConstantTimeCompare([]byte(token), expected) == 1
+
+			if admin {
+				r = r.WithContext(context.WithValue(r.Context(), AdminKey, true))
+			} else if hasAnyPrefix(r.URL.Path, config.Prefixes) {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+				errors.Unauthorized().WriteJSON(w)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// IsAdmin reports whether the request carried a valid admin token
+func IsAdmin(ctx context.Context) bool {
+	admin, _ := ctx.Value(AdminKey).(bool)
+	return admin
+}
+
+func hasAnyPrefix(path string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
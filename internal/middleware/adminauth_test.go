@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAdminAuth(t *testing.T) {
+	h := AdminAuth(DefaultAdminAuthConfig("s3cr3t"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name   string
+		path   string
+		header string
+		want   int
+	}{
+		{"admin with token", "/admin/runtime", "Bearer s3cr3t", http.StatusOK},
+		{"admin without token", "/admin/runtime", "", http.StatusUnauthorized},
+		{"admin wrong token", "/admin/capacity", "Bearer nope", http.StatusUnauthorized},
+		{"admin wrong scheme", "/admin/runtime", "Basic s3cr3t", http.StatusUnauthorized},
+		{"decode without token", "/api/decode/abc", "", http.StatusUnauthorized},
+		{"decode with token", "/api/decode/abc", "Bearer s3cr3t", http.StatusOK},
+		{"public path", "/abc123", "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("Expected %d, got: %d", tt.want, rec.Code)
+			}
+		})
+	}
+}
+
+func TestAdminAuth_MarksAdminOnPublicPaths(t *testing.T) {
+	var sawAdmin bool
+	h := AdminAuth(DefaultAdminAuthConfig("s3cr3t"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sawAdmin = IsAdmin(r.Context())
+	}))
+
+	for header, want := range map[string]bool{
+		"Bearer s3cr3t": true,
+		"Bearer nope":   false,
+		"":              false,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/shorten", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("%q: Expected public path to pass through, got: %d", header, rec.Code)
+		}
+		if sawAdmin != want {
+			t.Errorf("%q: Expected IsAdmin %v, got: %v", header, want, sawAdmin)
+		}
+	}
+}
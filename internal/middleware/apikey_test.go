@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAPIKeyAuth(t *testing.T) {
+	var owner string
+	h := APIKeyAuth(map[string]string{"k3y-alpha": "alpha", "k3y-beta": "beta"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		owner = APIKeyOwner(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name      string
+		header    string
+		value     string
+		want      int
+		wantOwner string
+	}{
+		{"bearer key", "Authorization", "Bearer k3y-alpha", http.StatusOK, "alpha"},
+		{"x-api-key", "X-API-Key", "k3y-beta", http.StatusOK, "beta"},
+		{"missing key", "", "", http.StatusUnauthorized, ""},
+		{"unknown key", "X-API-Key", "k3y-gamma", http.StatusUnauthorized, ""},
+		{"wrong scheme", "Authorization", "Basic k3y-alpha", http.StatusUnauthorized, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			owner = ""
+			req := httptest.NewRequest(http.MethodPost, "/shorten", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("Expected %d, got: %d", tt.want, rec.Code)
+			}
+			if owner != tt.wantOwner {
+				t.Errorf("Expected owner %q, got: %q", tt.wantOwner, owner)
+			}
+			if tt.want == http.StatusUnauthorized && !strings.Contains(rec.Body.String(), "INVALID_API_KEY") {
+				t.Errorf("Expected AppError body, got: %s", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestAPIKeyAuth_AdminPasses(t *testing.T) {
+	h := APIKeyAuth(map[string]string{"k3y": "alpha"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/shorten", nil)
+	req = req.WithContext(context.WithValue(req.Context(), AdminKey, true))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected admin request allowed without a key, got: %d", rec.Code)
+	}
+}
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/darkodi/url-shortener/internal/repository"
+)
+
+// ============================================================
+// READ CONSISTENCY OVERRIDE MIDDLEWARE
+// ============================================================
+
+// ConsistencyHeader lets a client force primary reads for a single request
+const ConsistencyHeader = "X-Consistency"
+
+// Consistency routes reads to the primary when the client sends
+// "X-Consistency: strong", bypassing possibly-lagging replicas
+func Consistency(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.EqualFold(strings.TrimSpace(r.Header.Get(ConsistencyHeader)), "strong") {
+			r = r.WithContext(repository.WithStrongConsistency(r.Context()))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,253 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/darkodi/url-shortener/internal/errors"
+	"github.com/darkodi/url-shortener/internal/logger"
+	"github.com/darkodi/url-shortener/internal/metrics"
+)
+
+// ============================================================
+// PER-IP CREATE LIMIT (sliding window)
+// ============================================================
+
+// WindowStore counts events per key over a sliding window.
+// AllowN records n events at now only if they fit, with the events of the
+// preceding window, within limit, and reports when the oldest counted
+// event expires.
+type WindowStore interface {
+	AllowN(ctx context.Context, key string, n, limit int, window time.Duration, now time.Time) (allowed bool, resetAt time.Time, err error)
+}
+
+// CreateLimiterConfig holds create-limit settings
+type CreateLimiterConfig struct {
+	Limit   int           // Max links per IP per window
+	Window  time.Duration // Rolling window length
+	Methods []string      // Methods that count as creates (default POST)
+	Paths   []string      // Paths that create one link each (default /shorten, /reserve)
+
+	// Paths that create many links per request (default /shorten/batch,
+	// /shorten/import). Their handlers charge each item with ChargeCreates.
+	ItemPaths []string
+
+	RetryAfterHTTPDate bool // Send Retry-After as an HTTP-date rather than delta-seconds
+}
+
+// CreateLimiter caps how many links a single IP can create in a rolling
+// window. Other traffic (redirects, stats) passes through untouched.
+type CreateLimiter struct {
+	cfg   CreateLimiterConfig
+	store WindowStore
+	log   *logger.Logger
+	now   func() time.Time
+}
+
+// NewCreateLimiter creates a create limiter. A nil store falls back to
+// process-local memory, which is only accurate for a single instance.
+func NewCreateLimiter(cfg CreateLimiterConfig, store WindowStore, log *logger.Logger) *CreateLimiter {
+	if len(cfg.Methods) == 0 {
+		cfg.Methods = []string{http.MethodPost}
+	}
+	if len(cfg.Paths) == 0 {
+		cfg.Paths = []string{"/shorten", "/reserve"}
+	}
+	if len(cfg.ItemPaths) == 0 {
+		cfg.ItemPaths = []string{"/shorten/batch", "/shorten/import"}
+	}
+	if store == nil {
+		store = NewMemoryWindowStore()
+	}
+	return &CreateLimiter{cfg: cfg, store: store, log: log, now: time.Now}
+}
+
+// Middleware returns the create limiting middleware
+func (cl *CreateLimiter) Middleware() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !cl.isCreate(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if containsPath(cl.cfg.ItemPaths, r.URL.Path) {
+				charge := func(n int) *errors.AppError { return cl.charge(w, r, n) }
+				next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), createChargeKey, charge)))
+				return
+			}
+
+			if appErr := cl.charge(w, r, 1); appErr != nil {
+				appErr.WriteJSON(w)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// charge records n creates for r's client. Over the limit it sets the
+// Retry-After and X-RateLimit-Reset headers on w and returns the error to
+// write; nothing is recorded then.
+func (cl *CreateLimiter) charge(w http.ResponseWriter, r *http.Request, n int) *errors.AppError {
+	ip := getClientIP(r)
+	now := cl.now()
+	allowed, resetAt, err := cl.store.AllowN(r.Context(), "createlimit:"+ip, n, cl.cfg.Limit, cl.cfg.Window, now)
+	if err != nil {
+		// Fail open: a store outage must not block link creation
+		if cl.log != nil {
+			cl.log.Warn("create limit check failed", withTraceID(r.Context(),
+				"request_id", getRequestID(r.Context()),
+				"error", err.Error(),
+			)...)
+		}
+		return nil
+	}
+	if allowed {
+		return nil
+	}
+
+	metrics.RateLimitRejections.Inc(metrics.CreateLimit)
+	if cl.log != nil {
+		cl.log.Warn("create limit exceeded", withTraceID(r.Context(),
+			"request_id", getRequestID(r.Context()),
+			"ip", ip,
+			"items", n,
+			"reset_at", resetAt,
+		)...)
+	}
+
+	retryAfter := setRetryAfter(w, resetAt.Sub(now), cl.cfg.RetryAfterHTTPDate)
+	w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(resetAt.Unix(), 10))
+	return errors.CreateLimitExceeded(cl.cfg.Limit, cl.cfg.Window, resetAt).WithRetryAfter(retryAfter)
+}
+
+func (cl *CreateLimiter) isCreate(r *http.Request) bool {
+	methodMatch := false
+	for _, m := range cl.cfg.Methods {
+		if r.Method == m {
+			methodMatch = true
+			break
+		}
+	}
+	if !methodMatch {
+		return false
+	}
+	return containsPath(cl.cfg.Paths, r.URL.Path) || containsPath(cl.cfg.ItemPaths, r.URL.Path)
+}
+
+func containsPath(paths []string, path string) bool {
+	for _, p := range paths {
+		if path == p {
+			return true
+		}
+	}
+	return false
+}
+
+// createChargeKey holds the charge function for item paths
+const createChargeKey ContextKey = "create_charge"
+
+// ChargeCreates counts n links against the caller's create limit, for
+// handlers on CreateLimiterConfig.ItemPaths. It returns the error to
+// report when they don't all fit, in which case none are counted, and nil
+// when they do or no create limit applies.
+func ChargeCreates(ctx context.Context, n int) *errors.AppError {
+	charge, ok := ctx.Value(createChargeKey).(func(int) *errors.AppError)
+	if !ok || n <= 0 {
+		return nil
+	}
+	return charge(n)
+}
+
+// ============================================================
+// IN-MEMORY WINDOW STORE
+// ============================================================
+
+// memoryWindowSweep is how often MemoryWindowStore drops keys whose
+// events have all slid out of their window
+const memoryWindowSweep = time.Minute
+
+// MemoryWindowStore is a process-local WindowStore
+type MemoryWindowStore struct {
+	mu     sync.Mutex
+	events map[string]*windowEvents
+}
+
+// windowEvents are one key's events, oldest first, and when the newest
+// leaves the window
+type windowEvents struct {
+	times   []time.Time
+	expires time.Time
+}
+
+// NewMemoryWindowStore creates an empty in-memory window store. Idle keys
+// are swept periodically so a stream of distinct clients or codes doesn't
+// grow it without bound.
+func NewMemoryWindowStore() *MemoryWindowStore {
+	m := &MemoryWindowStore{events: make(map[string]*windowEvents)}
+	go m.cleanupLoop()
+	return m
+}
+
+// cleanupLoop sweeps idle keys periodically
+func (m *MemoryWindowStore) cleanupLoop() {
+	ticker := time.NewTicker(memoryWindowSweep)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		m.sweep(now)
+	}
+}
+
+// sweep deletes keys with no events left in their window at now
+func (m *MemoryWindowStore) sweep(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, entry := range m.events {
+		if !entry.expires.After(now) {
+			delete(m.events, key)
+		}
+	}
+}
+
+// Allow records one event; see AllowN
+func (m *MemoryWindowStore) Allow(ctx context.Context, key string, limit int, window time.Duration, now time.Time) (bool, time.Time, error) {
+	return m.AllowN(ctx, key, 1, limit, window, now)
+}
+
+// AllowN implements WindowStore
+func (m *MemoryWindowStore) AllowN(ctx context.Context, key string, n, limit int, window time.Duration, now time.Time) (bool, time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Drop events that slid out of the window
+	cutoff := now.Add(-window)
+	var events []time.Time
+	if entry, ok := m.events[key]; ok {
+		events = entry.times
+	}
+	i := 0
+	for i < len(events) && !events[i].After(cutoff) {
+		i++
+	}
+	events = events[i:]
+
+	if len(events)+n > limit {
+		if len(events) == 0 {
+			delete(m.events, key)
+			return false, now.Add(window), nil
+		}
+		m.events[key] = &windowEvents{times: events, expires: events[len(events)-1].Add(window)}
+		return false, events[0].Add(window), nil
+	}
+
+	for i := 0; i < n; i++ {
+		events = append(events, now)
+	}
+	m.events[key] = &windowEvents{times: events, expires: now.Add(window)}
+	return true, events[0].Add(window), nil
+}
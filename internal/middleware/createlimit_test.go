@@ -0,0 +1,140 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCreateLimiter_ExhaustAndReset(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	cl := NewCreateLimiter(CreateLimiterConfig{Limit: 3, Window: time.Hour}, nil, nil)
+	cl.now = func() time.Time { return now }
+
+	h := cl.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	create := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/shorten", strings.NewReader(`{}`)))
+		return rec
+	}
+
+	// Three creates spread over the window are allowed
+	for i := 0; i < 3; i++ {
+		if rec := create(); rec.Code != http.StatusCreated {
+			t.Fatalf("Create %d: expected 201, got %d", i, rec.Code)
+		}
+		now = now.Add(10 * time.Minute)
+	}
+
+	// Fourth is rejected with the reset time of the oldest create
+	rec := create()
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429, got %d", rec.Code)
+	}
+	wantReset := time.Date(2026, 1, 1, 13, 0, 0, 0, time.UTC)
+	if got := rec.Header().Get("X-RateLimit-Reset"); got != strconv.FormatInt(wantReset.Unix(), 10) {
+		t.Errorf("X-RateLimit-Reset = %s; want %d", got, wantReset.Unix())
+	}
+	if got := rec.Header().Get("Retry-After"); got != "1800" {
+		t.Errorf("Retry-After = %s; want 1800", got)
+	}
+	if !strings.Contains(rec.Body.String(), "CREATE_LIMIT_EXCEEDED") {
+		t.Errorf("Expected error body, got: %s", rec.Body.String())
+	}
+
+	// Redirects are not limited
+	redirect := httptest.NewRecorder()
+	h.ServeHTTP(redirect, httptest.NewRequest(http.MethodGet, "/abc", nil))
+	if redirect.Code != http.StatusCreated {
+		t.Errorf("Expected GET to pass through, got %d", redirect.Code)
+	}
+
+	// Once the oldest create slides out, one slot frees up
+	now = wantReset.Add(time.Second)
+	if rec := create(); rec.Code != http.StatusCreated {
+		t.Errorf("Expected create after reset, got %d", rec.Code)
+	}
+	if rec := create(); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected window full again, got %d", rec.Code)
+	}
+}
+
+func TestCreateLimiter_PerIP(t *testing.T) {
+	cl := NewCreateLimiter(CreateLimiterConfig{Limit: 1, Window: time.Hour}, nil, nil)
+	h := cl.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for _, ip := range []string{"203.0.113.1", "203.0.113.2"} {
+		req := httptest.NewRequest(http.MethodPost, "/shorten", nil)
+		req.RemoteAddr = ip + ":1234"
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Errorf("IP %s: expected first create allowed, got %d", ip, rec.Code)
+		}
+	}
+}
+
+func TestCreateLimiter_ChargesBatchItems(t *testing.T) {
+	cl := NewCreateLimiter(CreateLimiterConfig{Limit: 5, Window: time.Hour}, nil, nil)
+	h := cl.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := strconv.Atoi(r.URL.Query().Get("n"))
+		if appErr := ChargeCreates(r.Context(), n); appErr != nil {
+			appErr.WriteJSON(w)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	post := func(target string) int {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, target, nil))
+		return rec.Code
+	}
+
+	if code := post("/shorten/batch?n=3"); code != http.StatusCreated {
+		t.Fatalf("Expected a 3-item batch allowed, got %d", code)
+	}
+	// Three items don't fit in the two left, and none are counted
+	if code := post("/shorten/import?n=3"); code != http.StatusTooManyRequests {
+		t.Errorf("Expected a 3-item import rejected, got %d", code)
+	}
+	if code := post("/reserve"); code != http.StatusCreated {
+		t.Errorf("Expected a reserve allowed, got %d", code)
+	}
+	if code := post("/shorten"); code != http.StatusCreated {
+		t.Errorf("Expected the last create allowed, got %d", code)
+	}
+	if code := post("/shorten"); code != http.StatusTooManyRequests {
+		t.Errorf("Expected the window full, got %d", code)
+	}
+}
+
+func TestMemoryWindowStore_DropsIdleKeys(t *testing.T) {
+	m := NewMemoryWindowStore()
+	ctx := context.Background()
+	start := time.Now()
+
+	for _, key := range []string{"a", "b", "c"} {
+		if ok, _, _ := m.Allow(ctx, key, 1, time.Minute, start); !ok {
+			t.Fatalf("Expected first event for %s allowed", key)
+		}
+	}
+	// A key still in its window survives the sweep
+	later := start.Add(2 * time.Minute)
+	_, _, _ = m.Allow(ctx, "a", 1, time.Minute, later)
+	m.sweep(later)
+	if n := len(m.events); n != 1 {
+		t.Errorf("Expected only the active key left after the window, got: %d", n)
+	}
+
+	// A denial that leaves no events in the window keeps no entry
+	_, _, _ = m.AllowN(ctx, "d", 2, 1, time.Minute, later)
+	if _, ok := m.events["d"]; ok {
+		t.Error("Expected no entry for a key with no events")
+	}
+}
@@ -0,0 +1,203 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// ============================================================
+// GZIP COMPRESSION MIDDLEWARE
+// ============================================================
+
+// GzipConfig holds response compression settings
+type GzipConfig struct {
+	MinSize      int      // Bodies smaller than this are sent uncompressed
+	ContentTypes []string // Compressible media types, e.g. "application/json" or "text/*"
+}
+
+// DefaultGzipConfig returns sensible defaults
+func DefaultGzipConfig() GzipConfig {
+	return GzipConfig{
+		MinSize: 1024, // below ~1KB gzip overhead isn't worth it
+		ContentTypes: []string{
+			"application/json",
+			"text/html",
+			"image/svg+xml",
+		},
+	}
+}
+
+// Gzip compresses responses with DefaultGzipConfig
+func Gzip(next http.Handler) http.Handler {
+	return GzipWithConfig(DefaultGzipConfig())(next)
+}
+
+// GzipWithConfig compresses responses for clients that accept gzip, but only
+// when the body reaches cfg.MinSize and its Content-Type is in the allowlist.
+// Already-compressed formats (e.g. PNG) should simply be left off the list.
+func GzipWithConfig(cfg GzipConfig) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			if !acceptsGzip(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			gw := &gzipResponseWriter{
+				ResponseWriter: w,
+				cfg:            &cfg,
+				statusCode:     http.StatusOK,
+			}
+			defer gw.Close()
+
+			next.ServeHTTP(gw, r)
+		})
+	}
+}
+
+// gzipResponseWriter buffers the start of the body until it can decide
+// whether compression is worthwhile, then either streams through gzip or
+// writes the buffer as-is
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	cfg *GzipConfig
+
+	statusCode int
+	buf        []byte
+	decided    bool
+	gz         *gzip.Writer
+}
+
+func (g *gzipResponseWriter) WriteHeader(code int) {
+	// Delay the real header until we know whether we compress
+	if !g.decided {
+		g.statusCode = code
+	}
+}
+
+func (g *gzipResponseWriter) Write(b []byte) (int, error) {
+	if g.decided {
+		if g.gz != nil {
+			return g.gz.Write(b)
+		}
+		return g.ResponseWriter.Write(b)
+	}
+
+	g.buf = append(g.buf, b...)
+	if len(g.buf) >= g.cfg.MinSize {
+		if err := g.decide(); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// decide chooses compression based on what has been buffered so far
+// and flushes the buffer
+func (g *gzipResponseWriter) decide() error {
+	g.decided = true
+	header := g.Header()
+
+	if header.Get("Content-Type") == "" && len(g.buf) > 0 {
+		header.Set("Content-Type", http.DetectContentType(g.buf))
+	}
+
+	// Redirects and bodiless statuses go out untouched, even with MinSize 0
+	compress := len(g.buf) >= g.cfg.MinSize &&
+		len(g.buf) > 0 &&
+		g.statusCode >= http.StatusOK && g.statusCode != http.StatusNoContent &&
+		(g.statusCode < 300 || g.statusCode >= 400) &&
+		header.Get("Content-Encoding") == "" &&
+		g.isCompressible(header.Get("Content-Type"))
+
+	if compress {
+		header.Del("Content-Length")
+		header.Set("Content-Encoding", "gzip")
+		g.gz = gzip.NewWriter(g.ResponseWriter)
+	}
+
+	g.ResponseWriter.WriteHeader(g.statusCode)
+
+	if len(g.buf) == 0 {
+		return nil
+	}
+	buf := g.buf
+	g.buf = nil
+	if g.gz != nil {
+		_, err := g.gz.Write(buf)
+		return err
+	}
+	_, err := g.ResponseWriter.Write(buf)
+	return err
+}
+
+// Close flushes any buffered body and finishes the gzip stream
+func (g *gzipResponseWriter) Close() error {
+	if !g.decided {
+		if err := g.decide(); err != nil {
+			return err
+		}
+	}
+	if g.gz != nil {
+		return g.gz.Close()
+	}
+	return nil
+}
+
+// Flush sends what has been written so far, for streaming handlers. Flushed
+// before MinSize bytes, the response goes out uncompressed.
+func (g *gzipResponseWriter) Flush() {
+	if !g.decided {
+		if err := g.decide(); err != nil {
+			return
+		}
+	}
+	if g.gz != nil {
+		if err := g.gz.Flush(); err != nil {
+			return
+		}
+	}
+	http.NewResponseController(g.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the connection underneath
+func (g *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return g.ResponseWriter
+}
+
+func (g *gzipResponseWriter) isCompressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	for _, allowed := range g.cfg.ContentTypes {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if allowed == mediaType {
+			return true
+		}
+		// "text/*" style wildcards
+		if prefix, ok := strings.CutSuffix(allowed, "/*"); ok &&
+			strings.HasPrefix(mediaType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptsGzip checks whether the client listed gzip in Accept-Encoding
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(coding), "gzip") {
+			continue
+		}
+		// "gzip;q=0" explicitly refuses gzip
+		return strings.ReplaceAll(strings.TrimSpace(params), " ", "") != "q=0"
+	}
+	return false
+}
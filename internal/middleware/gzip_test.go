@@ -0,0 +1,160 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func serveGzip(t *testing.T, contentType string, body []byte) *httptest.ResponseRecorder {
+	t.Helper()
+
+	h := GzipWithConfig(DefaultGzipConfig())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.Write(body)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/abc/stats", nil)
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestGzip_SmallJSONNotCompressed(t *testing.T) {
+	body := []byte(`{"short_code":"abc"}`)
+	rec := serveGzip(t, "application/json", body)
+
+	if rec.Header().Get("Content-Encoding") != "" {
+		t.Errorf("Expected small body uncompressed, got Content-Encoding %q", rec.Header().Get("Content-Encoding"))
+	}
+	if rec.Body.String() != string(body) {
+		t.Errorf("Body mismatch: %s", rec.Body.String())
+	}
+}
+
+func TestGzip_LargeJSONCompressed(t *testing.T) {
+	body := []byte(`{"data":"` + strings.Repeat("x", 4096) + `"}`)
+	rec := serveGzip(t, "application/json; charset=utf-8", body)
+
+	if rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Expected gzip encoding, got %q", rec.Header().Get("Content-Encoding"))
+	}
+
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("Invalid gzip body: %v", err)
+	}
+	decoded, _ := io.ReadAll(zr)
+	if string(decoded) != string(body) {
+		t.Error("Decompressed body does not match original")
+	}
+}
+
+func TestGzip_PNGNeverCompressed(t *testing.T) {
+	body := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 8192)...)
+	rec := serveGzip(t, "image/png", body)
+
+	if rec.Header().Get("Content-Encoding") != "" {
+		t.Errorf("Expected PNG uncompressed, got Content-Encoding %q", rec.Header().Get("Content-Encoding"))
+	}
+	if rec.Body.Len() != len(body) {
+		t.Errorf("Expected %d bytes, got %d", len(body), rec.Body.Len())
+	}
+}
+
+func TestGzip_ClientWithoutGzip(t *testing.T) {
+	h := GzipWithConfig(DefaultGzipConfig())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(strings.Repeat("x", 4096)))
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/abc/stats", nil))
+
+	if rec.Header().Get("Content-Encoding") != "" {
+		t.Error("Expected no compression without Accept-Encoding: gzip")
+	}
+}
+
+func TestGzip_RedirectNotCompressed(t *testing.T) {
+	h := GzipWithConfig(GzipConfig{MinSize: 0, ContentTypes: []string{"application/json"}})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", "https://example.com")
+		w.WriteHeader(http.StatusMovedPermanently)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/abc", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "https://example.com" {
+		t.Errorf("Expected the redirect passed through, got: %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+	if rec.Header().Get("Content-Encoding") != "" || rec.Body.Len() != 0 {
+		t.Errorf("Expected an empty, uncompressed redirect, got: %q with %d bytes", rec.Header().Get("Content-Encoding"), rec.Body.Len())
+	}
+}
+
+func TestGzip_StatusVisibleToOuterWriter(t *testing.T) {
+	var status int
+	outer := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			wrapped := wrapResponseWriter(w)
+			next.ServeHTTP(wrapped, r)
+			status = wrapped.statusCode
+		})
+	}
+	h := outer(Gzip(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error":"` + strings.Repeat("x", 2048) + `"}`))
+	})))
+
+	req := httptest.NewRequest(http.MethodGet, "/missing", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if status != http.StatusNotFound || rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 captured through the gzip writer, got: %d (sent %d)", status, rec.Code)
+	}
+	if rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Errorf("Expected the error body compressed, got %q", rec.Header().Get("Content-Encoding"))
+	}
+}
+
+func TestGzip_FlushSendsCompressedData(t *testing.T) {
+	flushed := make(chan string, 1)
+	h := GzipWithConfig(GzipConfig{MinSize: 0, ContentTypes: []string{"application/x-ndjson"}})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Write([]byte("{\"progress\":1}\n"))
+		w.(http.Flusher).Flush()
+
+		// Everything written so far can be decoded before the handler returns
+		rec := w.(*gzipResponseWriter).ResponseWriter.(*httptest.ResponseRecorder)
+		zr, err := gzip.NewReader(strings.NewReader(rec.Body.String()))
+		if err != nil {
+			flushed <- "error: " + err.Error()
+			return
+		}
+		line := make([]byte, len("{\"progress\":1}\n"))
+		io.ReadFull(zr, line)
+		flushed <- string(line)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/shorten/import", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if !rec.Flushed {
+		t.Error("Expected the flush passed to the underlying writer")
+	}
+	if got := <-flushed; got != "{\"progress\":1}\n" {
+		t.Errorf("Expected the flushed line readable, got: %q", got)
+	}
+}
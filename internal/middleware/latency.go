@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/darkodi/url-shortener/internal/metrics"
+)
+
+// ============================================================
+// LATENCY PERCENTILES MIDDLEWARE
+// ============================================================
+
+// Latency records each request's duration under the mux pattern that
+// served it, e.g. "/shorten" or "/" for redirects, which keeps the number
+// of routes bounded. It reads the pattern the router sets on the request,
+// so it must be the last middleware before the router.
+func Latency(tracker *metrics.LatencyTracker) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			next.ServeHTTP(w, r)
+
+			route := r.Pattern
+			if route == "" {
+				route = "unmatched"
+			}
+			tracker.Observe(route, time.Since(start))
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/darkodi/url-shortener/internal/metrics"
+)
+
+func TestLatency_RecordsRoutePattern(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/shorten", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {})
+
+	tracker := metrics.NewLatencyTracker("test_seconds", "Test latency.", 10, 50)
+	h := Chain(mux, RequestID, Latency(tracker))
+
+	for _, path := range []string{"/shorten", "/abc", "/xyz"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	summary := tracker.Summary()
+	if summary["/shorten"].Count != 1 {
+		t.Errorf("Expected 1 request on /shorten, got: %+v", summary["/shorten"])
+	}
+	if summary["/"].Count != 2 {
+		t.Errorf("Expected both redirects grouped under /, got: %+v", summary["/"])
+	}
+}
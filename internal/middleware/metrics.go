@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/darkodi/url-shortener/internal/metrics"
+)
+
+// ============================================================
+// REQUEST METRICS MIDDLEWARE
+// ============================================================
+
+// MetricsPath is where metrics are served. Requests to it are neither
+// counted nor rate limited, so scrapes don't skew or lose the numbers.
+const MetricsPath = "/metrics"
+
+// Metrics counts each request by route and status code and records its
+// duration. Like Latency it reads the pattern the router sets on the
+// request, so only Latency may sit between it and the router.
+func Metrics(m *metrics.RequestMetrics) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == MetricsPath {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			start := time.Now()
+			wrapped := wrapResponseWriter(w)
+			next.ServeHTTP(wrapped, r)
+
+			route := r.Pattern
+			if route == "" {
+				route = "unmatched"
+			}
+			m.Observe(route, wrapped.statusCode, time.Since(start))
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/darkodi/url-shortener/internal/metrics"
+)
+
+func TestMetrics_CountsByRouteAndStatus(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/shorten", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusCreated) })
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) { http.NotFound(w, r) })
+	mux.Handle(MetricsPath, metrics.Handler())
+
+	m := metrics.NewRequestMetrics()
+	h := Chain(mux, RequestID, Metrics(m))
+
+	for _, path := range []string{"/shorten", "/abc", "/xyz", MetricsPath} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	if got := m.Count("/shorten", http.StatusCreated); got != 1 {
+		t.Errorf("Expected 1 created on /shorten, got: %d", got)
+	}
+	if got := m.Count("/", http.StatusNotFound); got != 2 {
+		t.Errorf("Expected both misses grouped under /, got: %d", got)
+	}
+	if got := m.Count(MetricsPath, http.StatusOK); got != 0 {
+		t.Errorf("Expected scrapes not counted, got: %d", got)
+	}
+}
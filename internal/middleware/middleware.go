@@ -25,11 +25,13 @@ const (
 	RequestIDKey ContextKey = "request_id"
 )
 
-// responseWriter wraps http.ResponseWriter to capture status code
+// responseWriter wraps http.ResponseWriter to capture status code and
+// body size
 type responseWriter struct {
 	http.ResponseWriter
 	statusCode  int
 	wroteHeader bool
+	bytes       int
 }
 
 func wrapResponseWriter(w http.ResponseWriter) *responseWriter {
@@ -44,27 +46,90 @@ func (rw *responseWriter) WriteHeader(code int) {
 	}
 }
 
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	rw.wroteHeader = true
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytes += n
+	return n, err
+}
+
+// Flush sends what has been written so far, for streaming handlers
+func (rw *responseWriter) Flush() {
+	rw.wroteHeader = true
+	http.NewResponseController(rw.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the connection underneath
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 // ============================================================
 // REQUEST ID MIDDLEWARE
 // ============================================================
 
+// RequestIDConfig controls how an incoming X-Request-ID is treated
+type RequestIDConfig struct {
+	TrustIncoming bool // reuse a valid incoming ID; otherwise always generate
+	MaxLength     int  // incoming IDs longer than this are replaced
+}
+
+// DefaultRequestIDConfig returns sensible defaults
+func DefaultRequestIDConfig() RequestIDConfig {
+	return RequestIDConfig{
+		TrustIncoming: true,
+		MaxLength:     64, // fits UUIDs and most load balancer trace IDs
+	}
+}
+
 // RequestID adds a unique request ID to each request
 func RequestID(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Check if request already has an ID (from load balancer, etc.)
-		requestID := r.Header.Get("X-Request-ID")
-		if requestID == "" {
-			requestID = uuid.New().String()[:8] // Short ID for readability
-		}
+	return RequestIDWithConfig(DefaultRequestIDConfig())(next)
+}
+
+// RequestIDWithConfig adds a request ID to each request, reusing the one a
+// load balancer sent when cfg allows. Incoming IDs end up in logs, so one
+// that is too long or has characters outside [A-Za-z0-9._:-] is replaced
+// rather than passed on. With duplicate headers the first is used.
+func RequestIDWithConfig(cfg RequestIDConfig) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Check if request already has an ID (from load balancer, etc.)
+			requestID := ""
+			if cfg.TrustIncoming {
+				if incoming := r.Header.Get("X-Request-ID"); validRequestID(incoming, cfg.MaxLength) {
+					requestID = incoming
+				}
+			}
+			if requestID == "" {
+				requestID = uuid.New().String()[:8] // Short ID for readability
+			}
+
+			// Add to response headers
+			w.Header().Set("X-Request-ID", requestID)
 
-		// Add to response headers
-		w.Header().Set("X-Request-ID", requestID)
+			// Add to request context for use in handlers and other middleware
+			ctx := context.WithValue(r.Context(), RequestIDKey, requestID)
 
-		// Add to request context for use in handlers and other middleware
-		ctx := context.WithValue(r.Context(), RequestIDKey, requestID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
 
-		next.ServeHTTP(w, r.WithContext(ctx))
-	})
+// validRequestID reports whether id is safe to log and echo: non-empty, at
+// most maxLength bytes, and free of spaces, quotes, and control characters
+func validRequestID(id string, maxLength int) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') &&
+			c != '-' && c != '_' && c != '.' && c != ':' {
+			return false
+		}
+	}
+	return true
 }
 
 // ============================================================
@@ -87,14 +152,21 @@ func LoggingWithLogger(log *logger.Logger) Middleware {
 			next.ServeHTTP(wrapped, r)
 
 			// Log the request
-			log.Info("request completed",
+			args := []any{
 				"request_id", reqID,
 				"method", r.Method,
 				"path", r.URL.Path,
 				"status", wrapped.statusCode,
 				"duration_ms", time.Since(start).Milliseconds(),
 				"remote_addr", r.RemoteAddr,
-			)
+			}
+			if r.URL.RawQuery != "" {
+				args = append(args, "query", r.URL.RawQuery) // redacted by the logger when configured
+			}
+			if location := wrapped.Header().Get("Location"); location != "" {
+				args = append(args, "location", location)
+			}
+			log.Info("request completed", withTraceID(r.Context(), args...)...)
 		})
 	}
 }
@@ -111,13 +183,13 @@ func RecoveryWithLogger(log *logger.Logger) Middleware {
 				if err := recover(); err != nil {
 					reqID := getRequestID(r.Context())
 
-					log.Error("panic recovered",
+					log.Error("panic recovered", withTraceID(r.Context(),
 						"request_id", reqID,
 						"error", err,
 						"stack", string(debug.Stack()),
 						"method", r.Method,
 						"path", r.URL.Path,
-					)
+					)...)
 
 					http.Error(w,
 						`{"error": "Internal server error"}`,
@@ -153,3 +225,11 @@ func getRequestID(ctx context.Context) string {
 	}
 	return "unknown"
 }
+
+// withTraceID appends the trace ID to log args when trace context is enabled
+func withTraceID(ctx context.Context, args ...any) []any {
+	if traceID := getTraceID(ctx); traceID != "" {
+		args = append(args, "trace_id", traceID)
+	}
+	return args
+}
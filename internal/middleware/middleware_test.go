@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/darkodi/url-shortener/internal/logger"
+)
+
+func TestLoggingWithLogger_RedactsQueryToken(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.New(logger.Config{Level: "info", Format: "json", Output: &buf, RedactURLs: logger.RedactQuery})
+
+	h := LoggingWithLogger(log)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "https://example.com/reset?token=s3cr3t", http.StatusMovedPermanently)
+	}))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/abc?token=other", nil))
+	out := buf.String()
+
+	if strings.Contains(out, "s3cr3t") || strings.Contains(out, "token=other") {
+		t.Errorf("Expected tokens redacted, got: %s", out)
+	}
+	if !strings.Contains(out, `"path":"/abc"`) {
+		t.Errorf("Expected short code in log, got: %s", out)
+	}
+}
+
+func TestRequestID_ReplacesInvalidIncoming(t *testing.T) {
+	var seen string
+	h := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = getRequestID(r.Context())
+	}))
+
+	tests := []struct {
+		name     string
+		incoming []string
+		keep     bool
+	}{
+		{"valid", []string{"lb-7f3a.2:abc_1"}, true},
+		{"oversized", []string{strings.Repeat("a", 65)}, false},
+		{"log injection", []string{"abc\n level=error msg=forged"}, false},
+		{"quotes", []string{`abc" admin="true`}, false},
+		{"duplicates use the first", []string{"first-id", "second-id"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/abc", nil)
+			for _, id := range tt.incoming {
+				req.Header.Add("X-Request-ID", id)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if tt.keep && seen != tt.incoming[0] {
+				t.Errorf("Expected %q kept, got: %q", tt.incoming[0], seen)
+			}
+			if !tt.keep && (seen == tt.incoming[0] || len(seen) != 8) {
+				t.Errorf("Expected a generated ID, got: %q", seen)
+			}
+			if rec.Header().Get("X-Request-ID") != seen {
+				t.Errorf("Expected response header %q, got: %q", seen, rec.Header().Get("X-Request-ID"))
+			}
+		})
+	}
+}
+
+func TestRequestID_UntrustedIncomingIgnored(t *testing.T) {
+	var seen string
+	h := RequestIDWithConfig(RequestIDConfig{TrustIncoming: false, MaxLength: 64})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = getRequestID(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/abc", nil)
+	req.Header.Set("X-Request-ID", "client-chosen")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if seen == "client-chosen" {
+		t.Error("Expected the incoming ID ignored when not trusted")
+	}
+}
@@ -1,13 +1,26 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/darkodi/url-shortener/internal/errors"
 	"github.com/darkodi/url-shortener/internal/logger"
+	"github.com/darkodi/url-shortener/internal/metrics"
 )
 
+// TokenStore keeps token buckets shared by every instance. Take removes one
+// token from key's bucket, first adding rate tokens per whole interval
+// elapsed up to burst, and reports whether a token was available.
+type TokenStore interface {
+	Take(ctx context.Context, key string, rate, burst int, interval time.Duration, now time.Time) (bool, error)
+}
+
 // RateLimiter implements a token bucket rate limiter
 type RateLimiter struct {
 	mu       sync.RWMutex
@@ -17,6 +30,10 @@ type RateLimiter struct {
 	interval time.Duration // how often to add tokens
 	cleanup  time.Duration // cleanup old entries
 	log      *logger.Logger
+	store    TokenStore   // shared buckets; nil keeps them in memory
+	routes   []RouteLimit // per-prefix overrides, longest prefix first
+
+	retryAfterHTTPDate bool // emit Retry-After as an HTTP-date instead of seconds
 }
 
 type client struct {
@@ -30,6 +47,22 @@ type RateLimiterConfig struct {
 	Burst    int           // Max burst size
 	Interval time.Duration // Token refill interval
 	Cleanup  time.Duration // Cleanup interval for old clients
+
+	// Routes overrides the limit for paths under a prefix; see RouteLimit
+	Routes []RouteLimit
+
+	RetryAfterHTTPDate bool // Send Retry-After as an HTTP-date rather than delta-seconds
+}
+
+// RouteLimit is a stricter (or looser) limit for paths starting with
+// PathPrefix. Each route has its own bucket per IP, independent of the
+// default bucket and of other routes. When several prefixes match a path,
+// the longest one wins; paths no prefix matches use the default limit.
+type RouteLimit struct {
+	PathPrefix string        // e.g. "/shorten"
+	Rate       int           // Requests per interval
+	Burst      int           // Max burst size
+	Interval   time.Duration // Token refill interval; zero uses the default
 }
 
 // DefaultRateLimiterConfig returns sensible defaults
@@ -51,7 +84,18 @@ func NewRateLimiter(cfg RateLimiterConfig, log *logger.Logger) *RateLimiter {
 		interval: cfg.Interval,
 		cleanup:  cfg.Cleanup,
 		log:      log,
+
+		retryAfterHTTPDate: cfg.RetryAfterHTTPDate,
+	}
+	for _, route := range cfg.Routes {
+		if route.Interval <= 0 {
+			route.Interval = cfg.Interval
+		}
+		rl.routes = append(rl.routes, route)
 	}
+	sort.SliceStable(rl.routes, func(i, j int) bool {
+		return len(rl.routes[i].PathPrefix) > len(rl.routes[j].PathPrefix)
+	})
 
 	// Start cleanup goroutine
 	go rl.cleanupLoop()
@@ -59,18 +103,71 @@ func NewRateLimiter(cfg RateLimiterConfig, log *logger.Logger) *RateLimiter {
 	return rl
 }
 
-// Allow checks if a request from the given IP is allowed
+// WithStore shares buckets across instances through store. If the store
+// fails, the limiter falls back to its in-memory buckets for that request.
+func (rl *RateLimiter) WithStore(store TokenStore) *RateLimiter {
+	rl.store = store
+	return rl
+}
+
+// Allow checks if a request from the given IP is allowed by the default
+// limit; route overrides apply only through Middleware
 func (rl *RateLimiter) Allow(ip string) bool {
+	return rl.allow(context.Background(), ip, rl.defaultLimit())
+}
+
+// defaultLimit is the limit for paths no route matches
+func (rl *RateLimiter) defaultLimit() RouteLimit {
+	return RouteLimit{Rate: rl.rate, Burst: rl.burst, Interval: rl.interval}
+}
+
+// limitFor returns the route limit with the longest prefix of path
+func (rl *RateLimiter) limitFor(path string) RouteLimit {
+	for _, route := range rl.routes {
+		if strings.HasPrefix(path, route.PathPrefix) {
+			return route
+		}
+	}
+	return rl.defaultLimit()
+}
+
+func (rl *RateLimiter) allow(ctx context.Context, ip string, limit RouteLimit) bool {
+	// Route buckets are keyed by ip+route so they don't drain the default
+	key := ip
+	if limit.PathPrefix != "" {
+		key = ip + " " + limit.PathPrefix
+	}
+	if rl.store == nil {
+		return rl.allowLocal(key, limit)
+	}
+
+	allowed, err := rl.store.Take(ctx, "ratelimit:"+key, limit.Rate, limit.Burst, limit.Interval, time.Now())
+	if err != nil {
+		// Limit per instance rather than letting every request through
+		metrics.Degradations.Inc(metrics.RateLimit)
+		if rl.log != nil {
+			rl.log.Warn("shared rate limit check failed, using local limit", withTraceID(ctx,
+				"request_id", getRequestID(ctx),
+				"error", err.Error(),
+			)...)
+		}
+		return rl.allowLocal(key, limit)
+	}
+	return allowed
+}
+
+// allowLocal checks the in-memory bucket for key
+func (rl *RateLimiter) allowLocal(key string, limit RouteLimit) bool {
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
 
 	now := time.Now()
 
-	c, exists := rl.clients[ip]
+	c, exists := rl.clients[key]
 	if !exists {
 		// New client gets full bucket
-		rl.clients[ip] = &client{
-			tokens:    rl.burst - 1, // -1 for current request
+		rl.clients[key] = &client{
+			tokens:    limit.Burst - 1, // -1 for current request
 			lastCheck: now,
 		}
 		return true
@@ -78,10 +175,10 @@ func (rl *RateLimiter) Allow(ip string) bool {
 
 	// Calculate tokens to add based on time elapsed
 	elapsed := now.Sub(c.lastCheck)
-	tokensToAdd := int(elapsed/rl.interval) * rl.rate
+	tokensToAdd := int(elapsed/limit.Interval) * limit.Rate
 
 	if tokensToAdd > 0 {
-		c.tokens = min(c.tokens+tokensToAdd, rl.burst)
+		c.tokens = min(c.tokens+tokensToAdd, limit.Burst)
 		c.lastCheck = now
 	}
 
@@ -120,24 +217,35 @@ func (rl *RateLimiter) cleanupLoop() {
 func (rl *RateLimiter) Middleware() Middleware {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Scrapes must keep working while clients are being limited,
+			// but only authenticated ones: anyone else scraping the public
+			// listener is limited like any client. The admin listener
+			// doesn't rate limit at all.
+			if r.URL.Path == MetricsPath && IsAdmin(r.Context()) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
 			// Get client IP
 			ip := getClientIP(r)
+			limit := rl.limitFor(r.URL.Path)
 
-			if !rl.Allow(ip) {
+			if !rl.allow(r.Context(), ip, limit) {
 				reqID := getRequestID(r.Context())
+				metrics.RateLimitRejections.Inc(metrics.RequestLimit)
 
 				if rl.log != nil {
-					rl.log.Warn("rate limit exceeded",
+					rl.log.Warn("rate limit exceeded", withTraceID(r.Context(),
 						"request_id", reqID,
 						"ip", ip,
 						"path", r.URL.Path,
-					)
+						"route", limit.PathPrefix,
+					)...)
 				}
 
-				w.Header().Set("Content-Type", "application/json")
-				w.Header().Set("Retry-After", "1") // Suggest retry after 1 second
-				w.WriteHeader(http.StatusTooManyRequests)
-				w.Write([]byte(`{"error":{"code":"RATE_LIMIT_EXCEEDED","message":"Too many requests, please try again later"}}`))
+				// Suggest retrying once the next tokens are added
+				retryAfter := setRetryAfter(w, limit.Interval, rl.retryAfterHTTPDate)
+				errors.RateLimitExceeded().WithRetryAfter(retryAfter).WriteJSON(w)
 				return
 			}
 
@@ -146,31 +254,35 @@ func (rl *RateLimiter) Middleware() Middleware {
 	}
 }
 
-// getClientIP extracts the client IP from the request
-func getClientIP(r *http.Request) string {
-	// Check X-Forwarded-For header (if behind proxy/load balancer)
-	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		// Take the first IP in the list
-		for i := 0; i < len(xff); i++ {
-			if xff[i] == ',' {
-				return xff[:i]
-			}
-		}
-		return xff
+// setRetryAfter writes Retry-After either as whole delta-seconds (rounded up,
+// at least 1) or as an HTTP-date that many seconds from now.
+// Returns the delta-seconds so the body can carry the same value.
+func setRetryAfter(w http.ResponseWriter, after time.Duration, httpDate bool) int {
+	seconds := int((after + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
 	}
 
-	// Check X-Real-IP header
-	if xri := r.Header.Get("X-Real-IP"); xri != "" {
-		return xri
+	if httpDate {
+		retryAt := time.Now().Add(time.Duration(seconds) * time.Second)
+		w.Header().Set("Retry-After", retryAt.UTC().Format(http.TimeFormat))
+		return seconds
 	}
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	return seconds
+}
 
-	// Fall back to RemoteAddr
-	// Remove port if present
-	ip := r.RemoteAddr
-	for i := len(ip) - 1; i >= 0; i-- {
-		if ip[i] == ':' {
-			return ip[:i]
-		}
+// ClientIP is the address rate limits key on, for recording who sent r
+func ClientIP(r *http.Request) string {
+	return getClientIP(r)
+}
+
+// getClientIP returns the client address RealIP resolved, or without
+// RealIP in the chain, the connection's address: forwarding headers are
+// never believed unless RealIP was told which proxies send them
+func getClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(ClientIPKey).(string); ok {
+		return ip
 	}
-	return ip
+	return remoteIP(r)
 }
@@ -0,0 +1,213 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/darkodi/url-shortener/internal/errors"
+	"github.com/darkodi/url-shortener/internal/metrics"
+)
+
+func exhaustLimiter(t *testing.T, cfg RateLimiterConfig) *httptest.ResponseRecorder {
+	t.Helper()
+
+	rl := NewRateLimiter(cfg, nil)
+	h := rl.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	var rec *httptest.ResponseRecorder
+	for i := 0; i <= cfg.Burst; i++ {
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/abc", nil))
+	}
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429 after burst, got %d", rec.Code)
+	}
+	return rec
+}
+
+func TestRateLimiter_CountsRejectionsAndExemptsMetrics(t *testing.T) {
+	cfg := DefaultRateLimiterConfig()
+	cfg.Burst = 1
+
+	before := metrics.RateLimitRejections.Get(metrics.RequestLimit)
+	rl := NewRateLimiter(cfg, nil)
+	h := rl.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for i := 0; i < 3; i++ {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/abc", nil))
+	}
+	if n := metrics.RateLimitRejections.Get(metrics.RequestLimit) - before; n != 2 {
+		t.Errorf("Expected 2 rejections counted, got: %d", n)
+	}
+
+	// An unauthenticated scrape is limited like anything else
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, MetricsPath, nil))
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected an unauthenticated /metrics limited, got: %d", rec.Code)
+	}
+
+	// An admin scrape from the same client still goes through
+	req := httptest.NewRequest(http.MethodGet, MetricsPath, nil)
+	req = req.WithContext(context.WithValue(req.Context(), AdminKey, true))
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected an admin /metrics exempt from the limit, got: %d", rec.Code)
+	}
+}
+
+func TestRateLimiter_RetryAfterSeconds(t *testing.T) {
+	cfg := DefaultRateLimiterConfig()
+	cfg.Burst = 1
+	rec := exhaustLimiter(t, cfg)
+
+	seconds, err := strconv.Atoi(rec.Header().Get("Retry-After"))
+	if err != nil || seconds != 1 {
+		t.Errorf("Expected delta-seconds 1, got %q", rec.Header().Get("Retry-After"))
+	}
+}
+
+func TestRateLimiter_RetryAfterHTTPDate(t *testing.T) {
+	cfg := DefaultRateLimiterConfig()
+	cfg.Burst = 1
+	cfg.RetryAfterHTTPDate = true
+	rec := exhaustLimiter(t, cfg)
+
+	retryAt, err := http.ParseTime(rec.Header().Get("Retry-After"))
+	if err != nil {
+		t.Fatalf("Expected HTTP-date, got %q: %v", rec.Header().Get("Retry-After"), err)
+	}
+	// HTTP-date has whole-second resolution
+	if until := time.Until(retryAt); until < -time.Second || until > 2*time.Second {
+		t.Errorf("Expected retry about 1s from now, got %v", until)
+	}
+}
+
+func TestRateLimiter_BodyMatchesAppError(t *testing.T) {
+	cfg := DefaultRateLimiterConfig()
+	cfg.Burst = 1
+	rec := exhaustLimiter(t, cfg)
+
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Expected application/json, got %q", ct)
+	}
+
+	var body errors.ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode body: %v", err)
+	}
+	if body.Error == nil {
+		t.Fatal("Expected error envelope, got none")
+	}
+
+	want := errors.RateLimitExceeded()
+	if body.Error.Code != want.Code || body.Error.Message != want.Message {
+		t.Errorf("Expected %s / %q, got: %s / %q", want.Code, want.Message, body.Error.Code, body.Error.Message)
+	}
+	if strconv.Itoa(body.Error.RetryAfter) != rec.Header().Get("Retry-After") {
+		t.Errorf("Expected retry_after %s in body, got: %d", rec.Header().Get("Retry-After"), body.Error.RetryAfter)
+	}
+}
+
+// sharedTokenStore is an in-memory TokenStore two limiters can share
+type sharedTokenStore struct {
+	mu     sync.Mutex
+	tokens map[string]int
+	err    error
+}
+
+func (s *sharedTokenStore) Take(ctx context.Context, key string, rate, burst int, interval time.Duration, now time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return false, s.err
+	}
+	tokens, ok := s.tokens[key]
+	if !ok {
+		tokens = burst
+	}
+	if tokens == 0 {
+		return false, nil
+	}
+	s.tokens[key] = tokens - 1
+	return true, nil
+}
+
+func TestRateLimiter_SharedStoreAcrossInstances(t *testing.T) {
+	store := &sharedTokenStore{tokens: make(map[string]int)}
+	cfg := DefaultRateLimiterConfig()
+	cfg.Burst = 4
+	a := NewRateLimiter(cfg, nil).WithStore(store)
+	b := NewRateLimiter(cfg, nil).WithStore(store)
+
+	// Each instance alone would allow 4; together they share one bucket
+	allowed := 0
+	for i := 0; i < 4; i++ {
+		if a.Allow("10.0.0.1") {
+			allowed++
+		}
+		if b.Allow("10.0.0.1") {
+			allowed++
+		}
+	}
+	if allowed != 4 {
+		t.Errorf("Expected 4 requests allowed across instances, got: %d", allowed)
+	}
+}
+
+func TestRateLimiter_StoreFailureFallsBackToMemory(t *testing.T) {
+	store := &sharedTokenStore{tokens: make(map[string]int), err: context.DeadlineExceeded}
+	cfg := DefaultRateLimiterConfig()
+	cfg.Burst = 2
+	rl := NewRateLimiter(cfg, nil).WithStore(store)
+
+	before := metrics.Degradations.Get(metrics.RateLimit)
+	got := []bool{rl.Allow("10.0.0.2"), rl.Allow("10.0.0.2"), rl.Allow("10.0.0.2")}
+	if !got[0] || !got[1] || got[2] {
+		t.Errorf("Expected the local burst of 2 enforced, got: %v", got)
+	}
+	if n := metrics.Degradations.Get(metrics.RateLimit) - before; n != 3 {
+		t.Errorf("Expected 3 rate limit fallbacks counted, got: %d", n)
+	}
+}
+
+func TestRateLimiter_RouteOverrides(t *testing.T) {
+	cfg := DefaultRateLimiterConfig()
+	cfg.Burst = 5
+	cfg.Routes = []RouteLimit{
+		{PathPrefix: "/shorten", Rate: 1, Burst: 2},
+		{PathPrefix: "/shorten/batch", Rate: 1, Burst: 1},
+	}
+	rl := NewRateLimiter(cfg, nil)
+	h := rl.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	allowed := func(method, path string, n int) int {
+		ok := 0
+		for i := 0; i < n; i++ {
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+			if rec.Code != http.StatusTooManyRequests {
+				ok++
+			}
+		}
+		return ok
+	}
+
+	if got := allowed(http.MethodPost, "/shorten", 4); got != 2 {
+		t.Errorf("Expected /shorten limited to its burst of 2, got: %d", got)
+	}
+	// The longest matching prefix wins, with its own bucket
+	if got := allowed(http.MethodPost, "/shorten/batch", 3); got != 1 {
+		t.Errorf("Expected /shorten/batch limited to its burst of 1, got: %d", got)
+	}
+	// Exhausting the write routes leaves the default bucket untouched
+	if got := allowed(http.MethodGet, "/abc", 6); got != 5 {
+		t.Errorf("Expected redirects limited to the default burst of 5, got: %d", got)
+	}
+}
@@ -0,0 +1,175 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/darkodi/url-shortener/internal/errors"
+)
+
+// ============================================================
+// REQUEST TIMEOUT MIDDLEWARE
+// ============================================================
+
+// streamingPaths write their response as they go and run as long as the
+// upload does, so they get no timeout
+var streamingPaths = []string{"/shorten/import"}
+
+// Timeout cancels the request context after d and answers 503 TIMEOUT if
+// the handler hasn't finished by then. Context-aware repository calls
+// abandon their queries on the cancel. The response is buffered until the
+// handler returns or flushes, so a late handler can't write over the 503;
+// once a handler has flushed, a timeout can only cut the response short.
+// Zero d disables the limit.
+func Timeout(d time.Duration) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if d <= 0 || isStreaming(r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+
+			tw := &timeoutWriter{w: w, header: make(http.Header), code: http.StatusOK}
+			done := make(chan struct{})
+			panicked := make(chan any, 1)
+			go func() {
+				defer func() {
+					if p := recover(); p != nil {
+						panicked <- p
+					}
+				}()
+				next.ServeHTTP(tw, r.WithContext(ctx))
+				close(done)
+			}()
+
+			select {
+			case p := <-panicked:
+				panic(p) // for Recovery, which can't see the handler goroutine
+			case <-done:
+			case <-ctx.Done():
+				// Both may be ready at once; a handler that made it in
+				// time keeps its answer
+				select {
+				case p := <-panicked:
+					panic(p)
+				case <-done:
+					tw.finish()
+					return
+				default:
+				}
+
+				if ctx.Err() == context.DeadlineExceeded {
+					tw.mu.Lock()
+					defer tw.mu.Unlock()
+					tw.timedOut = true
+					if !tw.committed {
+						errors.Timeout().WriteJSON(w)
+					}
+					return
+				}
+				// Canceled: the client went away, or a failed body read
+				// cancelled the request after the handler cut it off. The
+				// handler still answers, so wait for it.
+				select {
+				case p := <-panicked:
+					panic(p)
+				case <-done:
+				}
+			}
+
+			tw.finish()
+		})
+	}
+}
+
+func isStreaming(path string) bool {
+	for _, prefix := range streamingPaths {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// timeoutWriter buffers a response until the handler returns. Writes after
+// the timeout fail with http.ErrHandlerTimeout.
+type timeoutWriter struct {
+	w      http.ResponseWriter
+	header http.Header
+
+	mu          sync.Mutex
+	body        bytes.Buffer
+	code        int
+	wroteHeader bool
+	committed   bool // header sent to w by a Flush
+	timedOut    bool
+}
+
+func (tw *timeoutWriter) Header() http.Header { return tw.header }
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.wroteHeader {
+		return
+	}
+	tw.code = code
+	tw.wroteHeader = true
+}
+
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	tw.wroteHeader = true
+	return tw.body.Write(b)
+}
+
+// Flush sends the buffered response on to w and flushes it, committing
+// the status code: a timeout after this can no longer answer 503
+func (tw *timeoutWriter) Flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut {
+		return
+	}
+	tw.writeBuffered()
+	http.NewResponseController(tw.w).Flush()
+}
+
+// finish sends whatever the handler left in the buffer
+func (tw *timeoutWriter) finish() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.writeBuffered()
+}
+
+// writeBuffered writes the header, unless a Flush already did, and the
+// buffered body to w. Callers hold tw.mu.
+func (tw *timeoutWriter) writeBuffered() {
+	if !tw.committed {
+		dst := tw.w.Header()
+		for k, v := range tw.header {
+			dst[k] = v
+		}
+		tw.w.WriteHeader(tw.code)
+		tw.committed = true
+	}
+	tw.w.Write(tw.body.Bytes())
+	tw.body.Reset()
+}
+
+// Unwrap lets http.ResponseController reach the connection underneath, so
+// handlers can still bound slow request bodies
+func (tw *timeoutWriter) Unwrap() http.ResponseWriter {
+	return tw.w
+}
@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/darkodi/url-shortener/internal/errors"
+	"github.com/darkodi/url-shortener/internal/logger"
+)
+
+func TestTimeout_SlowHandlerGets503(t *testing.T) {
+	cancelled := make(chan error, 1)
+	h := Timeout(20 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done() // like a query waiting on the database
+		cancelled <- r.Context().Err()
+		time.Sleep(20 * time.Millisecond) // still unwinding when the 503 goes out
+		w.Write([]byte("too late"))
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/abc", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected 503, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var body errors.ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body.Error == nil || body.Error.Code != errors.CodeTimeout {
+		t.Errorf("Expected a %s error body, got: %s, %v", errors.CodeTimeout, rec.Body.String(), err)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Error("Expected the handler's context cancelled")
+	}
+}
+
+func TestTimeout_FastHandlerPassesThrough(t *testing.T) {
+	h := Timeout(time.Second)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", "https://example.com")
+		w.WriteHeader(http.StatusMovedPermanently)
+		w.Write([]byte("moved"))
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/abc", nil))
+	if rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "https://example.com" || rec.Body.String() != "moved" {
+		t.Errorf("Expected the handler's response unchanged, got %d %v %q", rec.Code, rec.Header(), rec.Body.String())
+	}
+}
+
+func TestTimeout_PanicReachesRecovery(t *testing.T) {
+	h := Chain(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}), RecoveryWithLogger(logger.New(logger.Config{Level: "error", Format: "json", Output: io.Discard})), Timeout(time.Second))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/abc", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("Expected 500 from Recovery, got: %d", rec.Code)
+	}
+}
+
+func TestTimeout_StreamingPathsExempt(t *testing.T) {
+	h := Timeout(10 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(30 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/shorten/import", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected the import to run past the timeout, got: %d", rec.Code)
+	}
+}
+
+func TestTimeout_FlushCommitsResponse(t *testing.T) {
+	h := Timeout(20 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Write([]byte("{\"progress\":1}\n"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte("too late"))
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/abc", nil))
+	if !rec.Flushed {
+		t.Error("Expected the flush passed on")
+	}
+	// The status went out with the flush, so the timeout only ends the body
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/x-ndjson" {
+		t.Errorf("Expected the flushed 200 kept, got %d %v", rec.Code, rec.Header())
+	}
+	if rec.Body.String() != "{\"progress\":1}\n" {
+		t.Errorf("Expected only the flushed body, got: %q", rec.Body.String())
+	}
+}
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// ============================================================
+// W3C TRACE CONTEXT MIDDLEWARE
+// ============================================================
+
+const (
+	// TraceParentKey is the context key for this hop's traceparent header value
+	TraceParentKey ContextKey = "traceparent"
+	// TraceIDKey is the context key for the 32-hex-char trace ID
+	TraceIDKey ContextKey = "trace_id"
+
+	traceParentHeader = "traceparent"
+	traceStateHeader  = "tracestate"
+	traceVersion      = "00"
+	defaultTraceFlags = "01" // sampled
+)
+
+// TraceContext propagates W3C Trace Context headers.
+// An incoming traceparent keeps its trace ID and flags; a new span ID is issued
+// for this hop. If the header is absent or malformed a fresh trace is started.
+// tracestate is passed through untouched when the parent is valid.
+func TraceContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceID, flags, ok := parseTraceParent(r.Header.Get(traceParentHeader))
+		if !ok {
+			traceID = randomHex(16)
+			flags = defaultTraceFlags
+		}
+
+		traceParent := traceVersion + "-" + traceID + "-" + randomHex(8) + "-" + flags
+
+		w.Header().Set(traceParentHeader, traceParent)
+		if ok {
+			if state := r.Header.Get(traceStateHeader); state != "" {
+				w.Header().Set(traceStateHeader, state)
+			}
+		}
+
+		ctx := context.WithValue(r.Context(), TraceParentKey, traceParent)
+		ctx = context.WithValue(ctx, TraceIDKey, traceID)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// parseTraceParent validates a version-00 traceparent and returns its trace ID and flags
+func parseTraceParent(header string) (traceID, flags string, ok bool) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) != 4 || parts[0] != traceVersion {
+		return "", "", false
+	}
+
+	traceID, parentID, flags := parts[1], parts[2], parts[3]
+	if !isLowerHex(traceID, 32) || !isLowerHex(parentID, 16) || !isLowerHex(flags, 2) {
+		return "", "", false
+	}
+
+	// All-zero IDs are invalid per spec
+	if strings.Trim(traceID, "0") == "" || strings.Trim(parentID, "0") == "" {
+		return "", "", false
+	}
+
+	return traceID, flags, true
+}
+
+func isLowerHex(s string, length int) bool {
+	if len(s) != length {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c >= '0' && c <= '9') && !(c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// getTraceID returns the trace ID from context, or "" when tracing is disabled
+func getTraceID(ctx context.Context) string {
+	if traceID, ok := ctx.Value(TraceIDKey).(string); ok {
+		return traceID
+	}
+	return ""
+}
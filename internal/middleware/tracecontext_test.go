@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTraceContext_IncomingPresent(t *testing.T) {
+	const incoming = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	var ctxTraceID string
+	h := TraceContext(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctxTraceID = getTraceID(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/abc", nil)
+	req.Header.Set("traceparent", incoming)
+	req.Header.Set("tracestate", "vendor=value")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if ctxTraceID != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("Expected incoming trace ID in context, got: %s", ctxTraceID)
+	}
+
+	out := rec.Header().Get("traceparent")
+	traceID, flags, ok := parseTraceParent(out)
+	if !ok {
+		t.Fatalf("Response traceparent is invalid: %s", out)
+	}
+	if traceID != ctxTraceID || flags != "01" {
+		t.Errorf("Expected trace ID and flags preserved, got: %s", out)
+	}
+	if strings.Contains(out, "00f067aa0ba902b7") {
+		t.Errorf("Expected a new span ID for this hop, got: %s", out)
+	}
+	if got := rec.Header().Get("tracestate"); got != "vendor=value" {
+		t.Errorf("Expected tracestate echoed, got: %s", got)
+	}
+}
+
+func TestTraceContext_Absent(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+	}{
+		{"missing", ""},
+		{"malformed", "not-a-traceparent"},
+		{"zero trace id", "00-00000000000000000000000000000000-00f067aa0ba902b7-01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ctxTraceID string
+			h := TraceContext(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ctxTraceID = getTraceID(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/abc", nil)
+			if tt.header != "" {
+				req.Header.Set("traceparent", tt.header)
+			}
+			req.Header.Set("tracestate", "vendor=value")
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			traceID, _, ok := parseTraceParent(rec.Header().Get("traceparent"))
+			if !ok {
+				t.Fatalf("Expected generated traceparent, got: %s", rec.Header().Get("traceparent"))
+			}
+			if traceID != ctxTraceID {
+				t.Errorf("Context trace ID %s does not match header %s", ctxTraceID, traceID)
+			}
+			if got := rec.Header().Get("tracestate"); got != "" {
+				t.Errorf("Expected tracestate dropped for a new trace, got: %s", got)
+			}
+		})
+	}
+}
@@ -2,23 +2,286 @@ package model
 
 import "time"
 
+// Link states
+const (
+	StatusActive   = "active"   // resolves to OriginalURL
+	StatusReserved = "reserved" // code is held, destination not set yet
+)
+
 // URL represents a shortened URL mapping
 type URL struct {
-	ID          uint64    `json:"id"`           // input to Base62 encoder
-	ShortCode   string    `json:"short_code"`   // base62 encoded string
-	OriginalURL string    `json:"original_url"` // original long URL
-	CreatedAt   time.Time `json:"created_at"`   // timestamp of creation
-	ClickCount  uint64    `json:"click_count"`  // how many times the short URL was accessed
+	ID          uint64    `json:"id"`              // input to Base62 encoder
+	ShortCode   string    `json:"short_code"`      // base62 encoded string
+	OriginalURL string    `json:"original_url"`    // original long URL
+	CreatedAt   time.Time `json:"created_at"`      // timestamp of creation
+	ClickCount  uint64    `json:"click_count"`     // how many times the short URL was accessed
+	Status      string    `json:"status"`          // StatusActive or StatusReserved
+	Tag         string    `json:"tag,omitempty"`   // optional campaign tag
+	Signed      bool      `json:"signed"`          // resolves only with a valid exp/sig query
+	Owner       string    `json:"owner,omitempty"` // account that created it, empty if unknown
+
+	// Stops resolving after this time; nil never expires
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	// Seconds a countdown page is shown before redirecting; 0 redirects at once
+	DelaySeconds int `json:"delay_seconds,omitempty"`
+
+	// Receives this link's click webhooks instead of WEBHOOK_URL
+	WebhookURL string `json:"webhook_url,omitempty"`
+
+	// Per-language destinations, stored with the link on create; reads
+	// leave it nil (see Repository.GetLanguageURLs)
+	LanguageURLs map[string]string `json:"-"`
+}
+
+// Click is a single recorded visit to a short URL
+type Click struct {
+	ShortCode string    `json:"short_code"`
+	ClickedAt time.Time `json:"clicked_at"`
+	Referrer  string    `json:"referrer,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	ClientIP  string    `json:"client_ip,omitempty"`
+}
+
+// DailyClicks is the click count for one UTC day of a stats time series
+type DailyClicks struct {
+	Date   string `json:"date"` // e.g. "2024-01-15"
+	Clicks int    `json:"clicks"`
 }
 
 // CreateURLRequest is the API request body
 type CreateURLRequest struct {
 	URL         string `json:"url"`                    // original long URL
 	CustomAlias string `json:"custom_alias,omitempty"` // optional custom short code
+	Tag         string `json:"tag,omitempty"`          // optional campaign tag for grouped stats
+	Signed      bool   `json:"signed,omitempty"`       // return a link that expires after SIGNED_LINK_TTL
+	Region      string `json:"-"`                      // caller's region, from the region header
+	Owner       string `json:"-"`                      // account creating the link, from the owner header
+
+	// Starting click count, e.g. for imported links. Admin only.
+	InitialClicks uint64 `json:"initial_clicks,omitempty"`
+
+	// Optional expiry, either relative ("72h") or absolute; not both
+	ExpiresIn string     `json:"expires_in,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	// Show a countdown page for this many seconds before redirecting
+	DelaySeconds int `json:"delay_seconds,omitempty"`
+
+	// Destinations for /{code}.{lang}, keyed by language tag, e.g.
+	// {"fr": "https://example.com/fr/"}; other languages get URL
+	LanguageURLs map[string]string `json:"language_urls,omitempty"`
+
+	// Send this link's click webhooks here instead of WEBHOOK_URL
+	WebhookURL string `json:"webhook_url,omitempty"`
+}
+
+// ReserveRequest is the API request body for reserving a code without a URL
+type ReserveRequest struct {
+	CustomAlias string `json:"custom_alias"` // short code to hold
+	Owner       string `json:"-"`            // account holding the code; only it may activate
+	Region      string `json:"-"`            // caller's region, from the region header
+}
+
+// ActivateRequest sets the destination of a reserved code
+type ActivateRequest struct {
+	URL    string `json:"url"` // original long URL
+	Region string `json:"-"`   // caller's region, from the region header
+}
+
+// ResolveRequest looks up a code without following a redirect
+type ResolveRequest struct {
+	Code       string `json:"code"`
+	CountClick bool   `json:"count_click,omitempty"` // record the lookup as a click
+	Expires    string `json:"exp,omitempty"`         // signed links only
+	Signature  string `json:"sig,omitempty"`         // signed links only
+}
+
+// ResolveResponse is the destination of a resolved code
+type ResolveResponse struct {
+	ShortCode   string `json:"short_code"`
+	OriginalURL string `json:"original_url"`
 }
 
 // CreateURLResponse is the API response
 type CreateURLResponse struct {
 	ShortURL    string `json:"short_url"`    // full shortened URL
 	OriginalURL string `json:"original_url"` // original long URL
+
+	// Set when UNIQUE_URL_PER_OWNER returned the owner's earlier link
+	Existing bool `json:"existing,omitempty"`
+}
+
+// StatsResponse is the GET /{code}/stats body. It is built from URL rather
+// than exposing it, so the internal ID stays private and schema changes
+// don't leak into the API.
+type StatsResponse struct {
+	ShortCode   string    `json:"short_code"`
+	ShortURL    string    `json:"short_url"`
+	OriginalURL string    `json:"original_url,omitempty"` // omitted for signed links without a valid signature
+	ClickCount  uint64    `json:"click_count"`            // includes clicks not yet flushed
+	CreatedAt   time.Time `json:"created_at"`
+	AgeSeconds  int64     `json:"age_seconds"` // whole seconds since created_at
+	Status      string    `json:"status"`
+	Tag         string    `json:"tag,omitempty"`
+	Signed      bool      `json:"signed"`
+
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	DelaySeconds int        `json:"delay_seconds,omitempty"`
+
+	// Per-language destinations served at /{code}.{lang}
+	LanguageURLs map[string]string `json:"language_urls,omitempty"`
+
+	// Clicks per day, oldest first, with ?granularity=day
+	ClicksByDay []DailyClicks `json:"clicks_by_day,omitempty"`
+}
+
+// LinkClicks is one link's click count, for per-link metrics
+type LinkClicks struct {
+	ShortCode string
+	Clicks    uint64
+}
+
+// CapacityStats reports creation throughput and remaining code space
+type CapacityStats struct {
+	Window            string  `json:"window"`             // lookback window, e.g. "1h0m0s"
+	CreatedInWindow   uint64  `json:"created_in_window"`  // links created during the window
+	LinksPerMinute    float64 `json:"links_per_minute"`   // average creation rate over the window
+	MaxID             uint64  `json:"max_id"`             // highest ID issued so far
+	CodeLength        int     `json:"code_length"`        // current generated code length
+	CodeSpace         uint64  `json:"code_space"`         // total IDs representable at that length
+	RemainingCapacity uint64  `json:"remaining_capacity"` // IDs left before codes grow longer
+	Utilization       float64 `json:"utilization"`        // fraction of the code space used, 0-1
+}
+
+// MigrateCodesRequest describes the scheme existing generated codes were
+// issued under; they are re-encoded with the server's current scheme
+type MigrateCodesRequest struct {
+	Alphabet string `json:"alphabet,omitempty"` // empty means the default alphabet
+	Offset   uint64 `json:"offset"`
+	Stride   uint64 `json:"stride,omitempty"` // 0 means 1
+	Checksum bool   `json:"checksum"`
+	DryRun   bool   `json:"dry_run"`
+}
+
+// MigrationReport summarizes a code migration
+type MigrationReport struct {
+	DryRun    bool     `json:"dry_run"`
+	Scanned   int      `json:"scanned"`   // rows examined
+	Migrated  int      `json:"migrated"`  // codes renamed (or that would be, on a dry run)
+	Unchanged int      `json:"unchanged"` // already match the current scheme
+	Skipped   int      `json:"skipped"`   // custom aliases and codes not from the old scheme
+	Conflicts []string `json:"conflicts"` // old codes whose new code was already taken
+}
+
+// DeleteCodesRequest lists short codes to delete in one batch
+type DeleteCodesRequest struct {
+	Codes []string `json:"codes"`
+}
+
+// DeleteCodesResponse reports how many of the requested codes existed and
+// were deleted
+type DeleteCodesResponse struct {
+	Requested int   `json:"requested"` // distinct codes in the request
+	Deleted   int64 `json:"deleted"`
+}
+
+// RuntimeStats is a snapshot of process health for operators
+type RuntimeStats struct {
+	Uptime        string  `json:"uptime"`          // time since the server started, e.g. "3h2m1s"
+	UptimeSeconds float64 `json:"uptime_seconds"`  // same, as a number
+	Goroutines    int     `json:"goroutines"`      // currently running goroutines
+	HeapAlloc     uint64  `json:"heap_alloc"`      // bytes of allocated heap objects
+	HeapSys       uint64  `json:"heap_sys"`        // bytes of heap obtained from the OS
+	Sys           uint64  `json:"sys"`             // total bytes obtained from the OS
+	TotalAlloc    uint64  `json:"total_alloc"`     // cumulative bytes allocated
+	NumGC         uint32  `json:"num_gc"`          // completed GC cycles
+	LastGC        string  `json:"last_gc"`         // RFC 3339 time of the last GC, empty if none
+	PauseTotalNs  uint64  `json:"pause_total_ns"`  // cumulative GC pause time
+	GCCPUFraction float64 `json:"gc_cpu_fraction"` // fraction of CPU time spent in GC
+}
+
+// Code kinds reported by CodeDecode
+const (
+	CodeKindGenerated = "generated" // decodes to the ID of its record
+	CodeKindCustom    = "custom"    // alias that doesn't decode to its record's ID
+)
+
+// CodeDecode explains what a short code decodes to
+type CodeDecode struct {
+	ShortCode string  `json:"short_code"`
+	Kind      string  `json:"kind"`             // CodeKindGenerated or CodeKindCustom
+	ID        *uint64 `json:"id,omitempty"`     // decoded record ID, generated codes only
+	Exists    bool    `json:"exists"`           // a link is stored under this code
+	Reason    string  `json:"reason,omitempty"` // why a custom code has no ID
+}
+
+// AliasList is the GET /admin/aliases response
+type AliasList struct {
+	Reserved []string     `json:"reserved"` // words no alias may use
+	Taken    []TakenAlias `json:"taken"`    // codes stored in the database, in ID order
+
+	// Pass as ?after= to fetch the next page; omitted on the last page
+	NextAfter uint64 `json:"next_after,omitempty"`
+}
+
+// URLList is the GET /urls response for admins, covering every link
+type URLList struct {
+	URLs  []*URL `json:"urls"` // newest first
+	Total uint64 `json:"total"`
+
+	// Pass as ?offset= to fetch the next page; omitted on the last page
+	NextOffset int `json:"next_offset,omitempty"`
+}
+
+// OwnerURLList is the GET /urls response
+type OwnerURLList struct {
+	URLs []*URL `json:"urls"` // newest first
+
+	// Pass as ?offset= to fetch the next page; omitted on the last page
+	NextOffset int `json:"next_offset,omitempty"`
+}
+
+// TakenAlias is a short code that is in use, active or reserved
+type TakenAlias struct {
+	ShortCode string    `json:"short_code"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RecentLink is one entry of the GET /admin/recent activity feed
+type RecentLink struct {
+	ShortCode   string    `json:"short_code"`
+	OriginalURL string    `json:"original_url,omitempty"` // empty while reserved
+	Status      string    `json:"status"`
+	Tag         string    `json:"tag,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// TagStats aggregates links sharing a campaign tag
+type TagStats struct {
+	Tag    string `json:"tag"`
+	Links  uint64 `json:"links"`
+	Clicks uint64 `json:"clicks"`
+}
+
+// Readiness states
+const (
+	StatusReady       = "ready"
+	StatusUnavailable = "unavailable"
+	StatusStarting    = "starting" // connection pools still warming up
+)
+
+// DependencyHealth is one dependency's entry in GET /health/ready
+type DependencyHealth struct {
+	Status string `json:"status"` // "up", "degraded", or "down"
+}
+
+// ReadinessResponse is the GET /health/ready response
+type ReadinessResponse struct {
+	Status       string                      `json:"status"` // StatusReady, StatusUnavailable, or StatusStarting
+	Driver       string                      `json:"driver"`
+	Replicas     int                         `json:"replicas"`
+	ReplicasDown []int                       `json:"replicas_down,omitempty"` // out of the read rotation
+	Dependencies map[string]DependencyHealth `json:"dependencies"`
 }
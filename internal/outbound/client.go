@@ -0,0 +1,69 @@
+package outbound
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/darkodi/url-shortener/internal/config"
+)
+
+// ErrNonPublicAddress is returned by a public client dialing an address
+// that isn't on the public internet
+var ErrNonPublicAddress = errors.New("outbound: destination address is not public")
+
+// NewClient builds the HTTP client shared by every outbound call, so URL
+// checks and webhooks all refuse to negotiate below the configured TLS
+// version. Proxy and pooling settings follow http.DefaultTransport.
+func NewClient(cfg config.OutboundConfig) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{MinVersion: cfg.MinTLSVersion()}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   cfg.Timeout,
+	}
+}
+
+// NewPublicClient is NewClient for URLs users supply, such as link
+// destinations and per-link webhooks. It refuses to connect to loopback,
+// private, link-local and other non-public addresses, checked on the
+// resolved IP so DNS names pointing inside can't get around it. It dials
+// directly, ignoring proxy settings, since the proxy would be the address
+// checked.
+func NewPublicClient(cfg config.OutboundConfig) *http.Client {
+	client := NewClient(cfg)
+	transport := client.Transport.(*http.Transport)
+	transport.Proxy = nil
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   publicOnly,
+	}
+	transport.DialContext = dialer.DialContext
+	return client
+}
+
+// publicOnly is a net.Dialer Control hook rejecting non-public addresses
+func publicOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !IsPublicIP(ip) {
+		return fmt.Errorf("%w: %s", ErrNonPublicAddress, host)
+	}
+	return nil
+}
+
+// IsPublicIP reports whether ip is routable on the public internet
+func IsPublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast())
+}
@@ -0,0 +1,96 @@
+package outbound
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/darkodi/url-shortener/internal/config"
+)
+
+func TestNewClient_MinTLSVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		want    uint16
+	}{
+		{"1.2", tls.VersionTLS12},
+		{"1.3", tls.VersionTLS13},
+	}
+
+	for _, tt := range tests {
+		client := NewClient(config.OutboundConfig{Timeout: time.Second, TLSMinVersion: tt.version})
+
+		transport, ok := client.Transport.(*http.Transport)
+		if !ok {
+			t.Fatalf("%s: expected *http.Transport, got %T", tt.version, client.Transport)
+		}
+		if got := transport.TLSClientConfig.MinVersion; got != tt.want {
+			t.Errorf("%s: expected MinVersion %x, got %x", tt.version, tt.want, got)
+		}
+		if client.Timeout != time.Second {
+			t.Errorf("%s: expected 1s timeout, got %s", tt.version, client.Timeout)
+		}
+	}
+}
+
+func TestNewClient_RefusesOlderServer(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	srv.StartTLS()
+	defer srv.Close()
+
+	roots := srv.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs
+	for version, wantErr := range map[string]bool{"1.2": false, "1.3": true} {
+		client := NewClient(config.OutboundConfig{Timeout: time.Second, TLSMinVersion: version})
+		// Trust the test server's certificate, keeping the configured minimum
+		client.Transport.(*http.Transport).TLSClientConfig.RootCAs = roots
+
+		resp, err := client.Get(srv.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+		if (err != nil) != wantErr {
+			t.Errorf("min %s against a TLS 1.2 server: expected error %v, got: %v", version, wantErr, err)
+		}
+	}
+}
+
+func TestNewPublicClient_RefusesNonPublicAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	cfg := config.OutboundConfig{Timeout: time.Second, TLSMinVersion: "1.2"}
+	resp, err := NewClient(cfg).Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Expected the shared client to reach a loopback server, got: %v", err)
+	}
+	resp.Body.Close()
+
+	if _, err := NewPublicClient(cfg).Get(srv.URL); !errors.Is(err, ErrNonPublicAddress) {
+		t.Errorf("Expected ErrNonPublicAddress for a loopback server, got: %v", err)
+	}
+}
+
+func TestIsPublicIP(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"93.184.216.34":   true,
+		"2606:4700::1111": true,
+		"127.0.0.1":       false,
+		"::1":             false,
+		"10.1.2.3":        false,
+		"172.16.0.1":      false,
+		"192.168.1.1":     false,
+		"169.254.169.254": false,
+		"fe80::1":         false,
+		"fd00::1":         false,
+		"0.0.0.0":         false,
+	} {
+		if got := IsPublicIP(net.ParseIP(addr)); got != want {
+			t.Errorf("IsPublicIP(%s) = %v; want %v", addr, got, want)
+		}
+	}
+}
@@ -0,0 +1,642 @@
+package repository
+
+import (
+	"context"
+	"maps"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/darkodi/url-shortener/internal/model"
+)
+
+// MemoryRepository keeps URLs in process memory.
+// Intended for demos, ephemeral deployments, and tests; data is lost on restart.
+type MemoryRepository struct {
+	mu        sync.RWMutex
+	urls      map[string]*model.URL // keyed by short code
+	clicks    []model.Click
+	redirects map[string]string            // old code -> new code
+	languages map[string]map[string]string // short code -> language tag -> URL
+	lastID    uint64
+}
+
+// NewMemoryRepository creates an empty in-memory repository
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{
+		urls:      make(map[string]*model.URL),
+		redirects: make(map[string]string),
+		languages: make(map[string]map[string]string),
+	}
+}
+
+// ============================================================
+// READ OPERATIONS
+// ============================================================
+
+// GetByShortCode retrieves a URL by short code
+func (m *MemoryRepository) GetByShortCode(shortCode string) (*model.URL, error) {
+	return m.GetByShortCodeContext(context.Background(), shortCode)
+}
+
+// GetByShortCodeContext retrieves a URL by short code
+func (m *MemoryRepository) GetByShortCodeContext(ctx context.Context, shortCode string) (*model.URL, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	url, ok := m.urls[shortCode]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	copied := *url // callers must not mutate stored records
+	return &copied, nil
+}
+
+// Count returns how many URLs are stored
+func (m *MemoryRepository) Count() (uint64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return uint64(len(m.urls)), nil
+}
+
+// CountCreatedSince returns how many URLs were created at or after since
+func (m *MemoryRepository) CountCreatedSince(since time.Time) (uint64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var count uint64
+	for _, url := range m.urls {
+		if !url.CreatedAt.Before(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// CountClickEvents returns how many detailed click rows exist for a code
+func (m *MemoryRepository) CountClickEvents(shortCode string) (uint64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var count uint64
+	for _, click := range m.clicks {
+		if click.ShortCode == shortCode {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// GetClicksByDay counts a code's click rows per UTC day from since onward
+func (m *MemoryRepository) GetClicksByDay(shortCode string, since time.Time) (map[string]int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	days := make(map[string]int)
+	for _, click := range m.clicks {
+		if click.ShortCode == shortCode && !click.ClickedAt.Before(since) {
+			days[click.ClickedAt.UTC().Format(time.DateOnly)]++
+		}
+	}
+	return days, nil
+}
+
+// GetRedirect returns the code that oldCode was renamed to
+func (m *MemoryRepository) GetRedirect(ctx context.Context, oldCode string) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	newCode, ok := m.redirects[oldCode]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return newCode, nil
+}
+
+// List returns up to limit URLs, newest first, skipping the first offset
+func (m *MemoryRepository) List(limit, offset int) ([]*model.URL, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	urls := make([]*model.URL, 0, len(m.urls))
+	for _, url := range m.urls {
+		copied := *url
+		urls = append(urls, &copied)
+	}
+	sort.Slice(urls, func(i, j int) bool {
+		if !urls[i].CreatedAt.Equal(urls[j].CreatedAt) {
+			return urls[i].CreatedAt.After(urls[j].CreatedAt)
+		}
+		return urls[i].ID > urls[j].ID
+	})
+	if offset >= len(urls) {
+		return nil, nil
+	}
+	urls = urls[offset:]
+	if len(urls) > limit {
+		urls = urls[:limit]
+	}
+	return urls, nil
+}
+
+// ListURLs returns up to limit URLs with IDs greater than afterID, in ID order
+func (m *MemoryRepository) ListURLs(afterID uint64, limit int) ([]*model.URL, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var urls []*model.URL
+	for _, url := range m.urls {
+		if url.ID > afterID {
+			copied := *url
+			urls = append(urls, &copied)
+		}
+	}
+	sort.Slice(urls, func(i, j int) bool { return urls[i].ID < urls[j].ID })
+	if len(urls) > limit {
+		urls = urls[:limit]
+	}
+	return urls, nil
+}
+
+// ListByOwner returns up to limit of owner's URLs, newest first, skipping
+// the first offset
+func (m *MemoryRepository) ListByOwner(owner string, limit, offset int) ([]*model.URL, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var urls []*model.URL
+	for _, url := range m.urls {
+		if url.Owner == owner {
+			copied := *url
+			urls = append(urls, &copied)
+		}
+	}
+	sort.Slice(urls, func(i, j int) bool { return urls[i].ID > urls[j].ID })
+	if offset >= len(urls) {
+		return nil, nil
+	}
+	urls = urls[offset:]
+	if len(urls) > limit {
+		urls = urls[:limit]
+	}
+	return urls, nil
+}
+
+// GetAllByOriginalURL returns every URL pointing at originalURL, oldest first
+func (m *MemoryRepository) GetAllByOriginalURL(ctx context.Context, originalURL string) ([]*model.URL, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var urls []*model.URL
+	for _, url := range m.urls {
+		if url.OriginalURL == originalURL {
+			copied := *url
+			urls = append(urls, &copied)
+		}
+	}
+	sort.Slice(urls, func(i, j int) bool { return urls[i].ID < urls[j].ID })
+	return urls, nil
+}
+
+// StatsByTag aggregates link and click counts per tag, busiest tags first
+func (m *MemoryRepository) StatsByTag(ctx context.Context, limit int) ([]model.TagStats, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	byTag := make(map[string]*model.TagStats)
+	for _, url := range m.urls {
+		if url.Tag == "" {
+			continue
+		}
+		ts, ok := byTag[url.Tag]
+		if !ok {
+			ts = &model.TagStats{Tag: url.Tag}
+			byTag[url.Tag] = ts
+		}
+		ts.Links++
+		ts.Clicks += url.ClickCount
+	}
+
+	stats := make([]model.TagStats, 0, len(byTag))
+	for _, ts := range byTag {
+		stats = append(stats, *ts)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Clicks != stats[j].Clicks {
+			return stats[i].Clicks > stats[j].Clicks
+		}
+		return stats[i].Tag < stats[j].Tag
+	})
+	if len(stats) > limit {
+		stats = stats[:limit]
+	}
+	return stats, nil
+}
+
+// TopByClicks returns up to limit links with at least minClicks clicks,
+// busiest first
+func (m *MemoryRepository) TopByClicks(ctx context.Context, minClicks uint64, limit int) ([]model.LinkClicks, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var links []model.LinkClicks
+	for _, url := range m.urls {
+		if url.ClickCount >= minClicks {
+			links = append(links, model.LinkClicks{ShortCode: url.ShortCode, Clicks: url.ClickCount})
+		}
+	}
+	sort.Slice(links, func(i, j int) bool {
+		if links[i].Clicks != links[j].Clicks {
+			return links[i].Clicks > links[j].Clicks
+		}
+		return links[i].ShortCode < links[j].ShortCode
+	})
+	if len(links) > limit {
+		links = links[:limit]
+	}
+	return links, nil
+}
+
+// ListRecent returns up to limit links, most recently created first
+func (m *MemoryRepository) ListRecent(ctx context.Context, limit int) ([]model.RecentLink, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	urls := make([]*model.URL, 0, len(m.urls))
+	for _, url := range m.urls {
+		urls = append(urls, url)
+	}
+	sort.Slice(urls, func(i, j int) bool {
+		if !urls[i].CreatedAt.Equal(urls[j].CreatedAt) {
+			return urls[i].CreatedAt.After(urls[j].CreatedAt)
+		}
+		return urls[i].ID > urls[j].ID
+	})
+	if len(urls) > limit {
+		urls = urls[:limit]
+	}
+
+	links := make([]model.RecentLink, 0, len(urls))
+	for _, url := range urls {
+		links = append(links, model.RecentLink{ShortCode: url.ShortCode, OriginalURL: url.OriginalURL, Status: url.Status, Tag: url.Tag, CreatedAt: url.CreatedAt})
+	}
+	return links, nil
+}
+
+// ============================================================
+// WRITE OPERATIONS
+// ============================================================
+
+// Create inserts a new URL
+func (m *MemoryRepository) Create(url *model.URL) error {
+	return m.CreateContext(context.Background(), url)
+}
+
+// CreateContext stores a new URL; ctx is accepted for interface parity
+func (m *MemoryRepository) CreateContext(ctx context.Context, url *model.URL) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.urls[url.ShortCode]; exists {
+		return ErrDuplicate
+	}
+
+	if url.CreatedAt.IsZero() {
+		url.CreatedAt = time.Now().UTC()
+	}
+	if url.Status == "" {
+		url.Status = model.StatusActive
+	}
+
+	m.lastID++
+	url.ID = m.lastID
+
+	m.store(url)
+	return nil
+}
+
+// CreateGenerated stores url under codeFor of its new ID
+func (m *MemoryRepository) CreateGenerated(ctx context.Context, url *model.URL, codeFor CodeFunc) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	code, err := codeFor(m.lastID + 1)
+	if err != nil {
+		return err
+	}
+	// The ID is used up either way, as a database sequence would be
+	m.lastID++
+	if _, exists := m.urls[code]; exists {
+		return ErrDuplicate
+	}
+
+	if url.CreatedAt.IsZero() {
+		url.CreatedAt = time.Now().UTC()
+	}
+	if url.Status == "" {
+		url.Status = model.StatusActive
+	}
+
+	url.ID = m.lastID
+	url.ShortCode = code
+
+	m.store(url)
+	return nil
+}
+
+// store saves a copy of a new url and its LanguageURLs; m.mu must be held
+func (m *MemoryRepository) store(url *model.URL) {
+	stored := *url
+	stored.LanguageURLs = nil
+	m.urls[url.ShortCode] = &stored
+	m.setLanguageURLs(url.ShortCode, url.LanguageURLs)
+}
+
+// CreateBatch inserts urls in order, generating codes for rows without
+// one. A row whose short code is already taken is skipped and keeps ID 0.
+func (m *MemoryRepository) CreateBatch(urls []*model.URL, codeFor CodeFunc) error {
+	for _, url := range urls {
+		var err error
+		if url.ShortCode == "" {
+			err = m.CreateGenerated(context.Background(), url, codeFor)
+		} else {
+			err = m.Create(url)
+		}
+		if err != nil && err != ErrDuplicate {
+			return err
+		}
+	}
+	return nil
+}
+
+// Activate sets the destination of a reserved code and marks it active
+func (m *MemoryRepository) Activate(shortCode, originalURL string) error {
+	return m.ActivateContext(context.Background(), shortCode, originalURL)
+}
+
+// ActivateContext is Activate; ctx is accepted for interface parity
+func (m *MemoryRepository) ActivateContext(ctx context.Context, shortCode, originalURL string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	url, ok := m.urls[shortCode]
+	if !ok {
+		return ErrNotFound
+	}
+	if url.Status != model.StatusReserved {
+		return ErrNotReserved
+	}
+
+	url.OriginalURL = originalURL
+	url.Status = model.StatusActive
+	return nil
+}
+
+// IncrementClickCount increments click counter
+func (m *MemoryRepository) IncrementClickCount(shortCode string) error {
+	return m.IncrementClickCountBy(shortCode, 1)
+}
+
+// IncrementClickCountBy adds n to the click counter
+func (m *MemoryRepository) IncrementClickCountBy(shortCode string, n uint64) error {
+	return m.IncrementClickCountByContext(context.Background(), shortCode, n)
+}
+
+// IncrementClickCountByContext adds n to the click counter
+func (m *MemoryRepository) IncrementClickCountByContext(ctx context.Context, shortCode string, n uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if url, ok := m.urls[shortCode]; ok {
+		url.ClickCount += n
+	}
+	return nil
+}
+
+// RenameShortCode moves a URL and its click rows from oldCode to newCode and
+// records a redirect so oldCode keeps resolving. Returns ErrDuplicate if
+// newCode is in use or was ever renamed away from.
+func (m *MemoryRepository) RenameShortCode(oldCode, newCode string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, taken := m.urls[newCode]; taken {
+		return ErrDuplicate
+	}
+	if _, redirected := m.redirects[newCode]; redirected {
+		return ErrDuplicate
+	}
+	url, ok := m.urls[oldCode]
+	if !ok {
+		return ErrNotFound
+	}
+
+	delete(m.urls, oldCode)
+	url.ShortCode = newCode
+	m.urls[newCode] = url
+
+	for i := range m.clicks {
+		if m.clicks[i].ShortCode == oldCode {
+			m.clicks[i].ShortCode = newCode
+		}
+	}
+	for from, to := range m.redirects {
+		if to == oldCode {
+			m.redirects[from] = newCode
+		}
+	}
+	m.redirects[oldCode] = newCode
+	if urls, ok := m.languages[oldCode]; ok {
+		delete(m.languages, oldCode)
+		m.languages[newCode] = urls
+	}
+	return nil
+}
+
+// Delete removes a URL and its click rows. Returns ErrNotFound if the
+// code doesn't exist.
+func (m *MemoryRepository) Delete(shortCode string) error {
+	return m.DeleteContext(context.Background(), shortCode)
+}
+
+// DeleteContext is Delete; ctx is accepted for interface parity
+func (m *MemoryRepository) DeleteContext(ctx context.Context, shortCode string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.urls[shortCode]; !ok {
+		return ErrNotFound
+	}
+	delete(m.urls, shortCode)
+	delete(m.languages, shortCode)
+
+	kept := m.clicks[:0]
+	for _, click := range m.clicks {
+		if click.ShortCode != shortCode {
+			kept = append(kept, click)
+		}
+	}
+	m.clicks = kept
+	return nil
+}
+
+// DeleteByShortCodes removes every listed URL and its click rows. Codes
+// that don't exist are ignored. Returns the number of URLs deleted.
+func (m *MemoryRepository) DeleteByShortCodes(codes []string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	targets := make(map[string]bool, len(codes))
+	var deleted int64
+	for _, code := range codes {
+		if _, ok := m.urls[code]; ok {
+			delete(m.urls, code)
+			deleted++
+		}
+		delete(m.languages, code)
+		targets[code] = true
+	}
+
+	kept := m.clicks[:0]
+	for _, click := range m.clicks {
+		if !targets[click.ShortCode] {
+			kept = append(kept, click)
+		}
+	}
+	m.clicks = kept
+	return deleted, nil
+}
+
+// AddClickCount adds a batch of clicks to the counter in one write
+func (m *MemoryRepository) AddClickCount(shortCode string, delta uint64) error {
+	return m.IncrementClickCountBy(shortCode, delta)
+}
+
+// AddClickCounts adds each code's delta to its counter
+func (m *MemoryRepository) AddClickCounts(counts map[string]uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for shortCode, delta := range counts {
+		if url, ok := m.urls[shortCode]; ok {
+			url.ClickCount += delta
+		}
+	}
+	return nil
+}
+
+// RecordClick stores a detailed click event
+func (m *MemoryRepository) RecordClick(click *model.Click) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if click.ClickedAt.IsZero() {
+		click.ClickedAt = time.Now().UTC()
+	}
+	m.clicks = append(m.clicks, *click)
+	return nil
+}
+
+// PruneClicks deletes the oldest click rows for a code so at most keep
+// remain. The aggregate click count is not touched.
+func (m *MemoryRepository) PruneClicks(shortCode string, keep int) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var matching []int
+	for i, click := range m.clicks {
+		if click.ShortCode == shortCode {
+			matching = append(matching, i)
+		}
+	}
+	excess := len(matching) - keep
+	if excess <= 0 {
+		return 0, nil
+	}
+
+	// Oldest first; ties keep insertion order
+	sort.SliceStable(matching, func(a, b int) bool {
+		return m.clicks[matching[a]].ClickedAt.Before(m.clicks[matching[b]].ClickedAt)
+	})
+	drop := make(map[int]bool, excess)
+	for _, i := range matching[:excess] {
+		drop[i] = true
+	}
+
+	kept := m.clicks[:0]
+	for i, click := range m.clicks {
+		if !drop[i] {
+			kept = append(kept, click)
+		}
+	}
+	m.clicks = kept
+	return int64(excess), nil
+}
+
+// GetNextID returns next available ID
+func (m *MemoryRepository) GetNextID() (uint64, error) {
+	return m.GetNextIDContext(context.Background())
+}
+
+// GetNextIDContext returns next available ID
+func (m *MemoryRepository) GetNextIDContext(ctx context.Context) (uint64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.lastID + 1, nil
+}
+
+// ============================================================
+// LIFECYCLE
+// ============================================================
+
+// SetLanguageURLs stores per-language destinations of shortCode
+func (m *MemoryRepository) SetLanguageURLs(ctx context.Context, shortCode string, urls map[string]string) error {
+	if len(urls) == 0 {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.setLanguageURLs(shortCode, urls)
+	return nil
+}
+
+// setLanguageURLs is SetLanguageURLs with m.mu held
+func (m *MemoryRepository) setLanguageURLs(shortCode string, urls map[string]string) {
+	if len(urls) == 0 {
+		return
+	}
+	stored, ok := m.languages[shortCode]
+	if !ok {
+		stored = make(map[string]string, len(urls))
+		m.languages[shortCode] = stored
+	}
+	for lang, originalURL := range urls {
+		stored[lang] = originalURL
+	}
+}
+
+// GetLanguageURLs returns a copy of shortCode's per-language destinations
+func (m *MemoryRepository) GetLanguageURLs(ctx context.Context, shortCode string) (map[string]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return maps.Clone(m.languages[shortCode]), nil
+}
+
+// WarmUp does nothing: there is no connection pool
+func (m *MemoryRepository) WarmUp(ctx context.Context, conns int) error {
+	return nil
+}
+
+// Ping always succeeds: the data is in process
+func (m *MemoryRepository) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (m *MemoryRepository) Topology() Topology {
+	return Topology{Driver: "memory"}
+}
+
+func (m *MemoryRepository) Close() error {
+	return nil
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/darkodi/url-shortener/internal/model"
+)
+
+// ErrReadOnly is returned by every write on a read-only repository
+var ErrReadOnly = errors.New("repository is read-only")
+
+// readOnlyRepository passes reads through and refuses every write, so a
+// read-only deployment can't modify data even if a caller forgets to check
+type readOnlyRepository struct {
+	Repository
+}
+
+// ReadOnly wraps repo so all write methods return ErrReadOnly
+func ReadOnly(repo Repository) Repository {
+	return readOnlyRepository{Repository: repo}
+}
+
+func (readOnlyRepository) Create(*model.URL) error                         { return ErrReadOnly }
+func (readOnlyRepository) CreateContext(context.Context, *model.URL) error { return ErrReadOnly }
+func (readOnlyRepository) CreateBatch([]*model.URL, CodeFunc) error        { return ErrReadOnly }
+func (readOnlyRepository) CreateGenerated(context.Context, *model.URL, CodeFunc) error {
+	return ErrReadOnly
+}
+func (readOnlyRepository) SetLanguageURLs(context.Context, string, map[string]string) error {
+	return ErrReadOnly
+}
+func (readOnlyRepository) Activate(string, string) error        { return ErrReadOnly }
+func (readOnlyRepository) RenameShortCode(string, string) error { return ErrReadOnly }
+func (readOnlyRepository) Delete(string) error                  { return ErrReadOnly }
+func (readOnlyRepository) ActivateContext(context.Context, string, string) error {
+	return ErrReadOnly
+}
+func (readOnlyRepository) DeleteContext(context.Context, string) error { return ErrReadOnly }
+func (readOnlyRepository) DeleteByShortCodes([]string) (int64, error)  { return 0, ErrReadOnly }
+func (readOnlyRepository) IncrementClickCount(string) error            { return ErrReadOnly }
+func (readOnlyRepository) IncrementClickCountBy(string, uint64) error  { return ErrReadOnly }
+func (readOnlyRepository) IncrementClickCountByContext(context.Context, string, uint64) error {
+	return ErrReadOnly
+}
+func (readOnlyRepository) AddClickCount(string, uint64) error     { return ErrReadOnly }
+func (readOnlyRepository) AddClickCounts(map[string]uint64) error { return ErrReadOnly }
+func (readOnlyRepository) RecordClick(*model.Click) error         { return ErrReadOnly }
+func (readOnlyRepository) PruneClicks(string, int) (int64, error) { return 0, ErrReadOnly }
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/darkodi/url-shortener/internal/config"
+	"github.com/darkodi/url-shortener/internal/model"
+)
+
+// ErrDuplicate is returned when a short code is already stored
+var ErrDuplicate = errors.New("short code already exists")
+
+// Topology describes the databases behind a repository, for readiness reports
+type Topology struct {
+	Driver       string `json:"driver"`
+	Replicas     int    `json:"replicas"`
+	ReplicasDown []int  `json:"replicas_down,omitempty"` // failed their last health check
+}
+
+// CodeFunc derives a generated short code from a record's ID
+type CodeFunc func(id uint64) (string, error)
+
+// Repository is the storage contract used by the service layer.
+// The service depends only on this, so SQL, in-memory, and mock
+// backends are interchangeable.
+type Repository interface {
+	GetByShortCode(shortCode string) (*model.URL, error)
+	GetByShortCodeContext(ctx context.Context, shortCode string) (*model.URL, error)
+	Count() (uint64, error)
+	CountCreatedSince(since time.Time) (uint64, error)
+	CountClickEvents(shortCode string) (uint64, error)
+	GetClicksByDay(shortCode string, since time.Time) (map[string]int, error)
+	GetRedirect(ctx context.Context, oldCode string) (string, error)
+	List(limit, offset int) ([]*model.URL, error)
+	ListURLs(afterID uint64, limit int) ([]*model.URL, error)
+	ListByOwner(owner string, limit, offset int) ([]*model.URL, error)
+	GetAllByOriginalURL(ctx context.Context, originalURL string) ([]*model.URL, error)
+	StatsByTag(ctx context.Context, limit int) ([]model.TagStats, error)
+	TopByClicks(ctx context.Context, minClicks uint64, limit int) ([]model.LinkClicks, error)
+	ListRecent(ctx context.Context, limit int) ([]model.RecentLink, error)
+	GetLanguageURLs(ctx context.Context, shortCode string) (map[string]string, error)
+
+	Create(url *model.URL) error
+	CreateContext(ctx context.Context, url *model.URL) error
+	CreateGenerated(ctx context.Context, url *model.URL, codeFor CodeFunc) error
+	CreateBatch(urls []*model.URL, codeFor CodeFunc) error
+	SetLanguageURLs(ctx context.Context, shortCode string, urls map[string]string) error
+	Activate(shortCode, originalURL string) error
+	ActivateContext(ctx context.Context, shortCode, originalURL string) error
+	RenameShortCode(oldCode, newCode string) error
+	Delete(shortCode string) error
+	DeleteContext(ctx context.Context, shortCode string) error
+	DeleteByShortCodes(codes []string) (int64, error)
+	IncrementClickCount(shortCode string) error
+	IncrementClickCountBy(shortCode string, n uint64) error
+	IncrementClickCountByContext(ctx context.Context, shortCode string, n uint64) error
+	AddClickCount(shortCode string, delta uint64) error
+	AddClickCounts(counts map[string]uint64) error
+	RecordClick(click *model.Click) error
+	PruneClicks(shortCode string, keep int) (int64, error)
+	GetNextID() (uint64, error)
+	GetNextIDContext(ctx context.Context) (uint64, error)
+
+	// WarmUp opens up to conns pooled connections per database ahead of
+	// traffic; backends without pools do nothing
+	WarmUp(ctx context.Context, conns int) error
+	// Ping checks that every database answers; Topology says which they are
+	Ping(ctx context.Context) error
+	Topology() Topology
+	Close() error
+}
+
+// Compile-time checks that both backends satisfy the interface
+var (
+	_ Repository = (*URLRepository)(nil)
+	_ Repository = (*MemoryRepository)(nil)
+)
+
+// New creates the repository selected by cfg.Driver
+// ("postgres", "sqlite3", or "memory"), wrapped by ReadOnly when
+// cfg.ReadOnly is set
+func New(cfg *config.DatabaseConfig) (Repository, error) {
+	var repo Repository
+	if cfg.Driver == "memory" {
+		repo = NewMemoryRepository()
+	} else {
+		sqlRepo, err := NewURLRepository(cfg)
+		if err != nil {
+			return nil, err
+		}
+		repo = sqlRepo
+	}
+
+	if cfg.ReadOnly {
+		return ReadOnly(repo), nil
+	}
+	return repo, nil
+}
@@ -1,20 +1,35 @@
 package repository
 
 import (
+	"context"
+	"crypto/rand"
 	"database/sql"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
-	_ "github.com/lib/pq" // PostgreSQL driver
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/lib/pq" // PostgreSQL driver
+	"github.com/mattn/go-sqlite3"
 
 	"github.com/darkodi/url-shortener/internal/config"
+	"github.com/darkodi/url-shortener/internal/metrics"
 	"github.com/darkodi/url-shortener/internal/model"
 )
 
-var ErrNotFound = errors.New("record not found")
+var (
+	ErrNotFound    = errors.New("record not found")
+	ErrNotReserved = errors.New("record is not reserved")
+	ErrTimeout     = errors.New("database query timed out")
+
+	// ErrSchemaMismatch is returned by schema verification when a table or
+	// column the repository needs is missing
+	ErrSchemaMismatch = errors.New("database schema is missing or outdated")
+)
 
 // URLRepository handles database operations
 type URLRepository struct {
@@ -22,6 +37,20 @@ type URLRepository struct {
 	replicas []*sql.DB // Read operations
 	rrIndex  uint32    // Round-robin index
 	driver   string    // "postgres" or "sqlite3"
+
+	// Replica index for each turn of a weighted rotation; nil means every
+	// replica gets an equal share
+	replicaTurns []int
+
+	// Set for replicas that failed their last health check. Stopping
+	// stopChecks ends the check loop, which closes checksDone.
+	replicaDown []atomic.Bool
+	stopChecks  chan struct{}
+	checksDone  chan struct{}
+
+	// Upper bounds on a single read or write; zero means no limit
+	readTimeout  time.Duration
+	writeTimeout time.Duration
 }
 
 // NewURLRepository creates repository from config
@@ -32,16 +61,21 @@ func NewURLRepository(cfg *config.DatabaseConfig) (*URLRepository, error) {
 
 	// ============ OPEN PRIMARY DATABASE ============
 	if cfg.Driver == "postgres" {
-		primaryConn := cfg.BuildPostgresConnectionString(cfg.Host)
+		// Read-only deployments use the first replica in place of the
+		// primary so they never hold a connection that could write
+		primaryHost := cfg.Host
+		if cfg.ReadOnly && len(cfg.ReplicaHosts) > 0 {
+			primaryHost = cfg.ReplicaHosts[0]
+		}
+		primaryConn := cfg.BuildPostgresConnectionString(primaryHost)
 		primary, err = openPostgres(primaryConn, cfg.MaxOpenConns, cfg.MaxIdleConns)
 		if err != nil {
 			return nil, fmt.Errorf("failed to open primary database: %w", err)
 		}
 
-		// Initialize schema
-		if err := initPostgresSchema(primary); err != nil {
+		if err := prepareSchema(primary, cfg); err != nil {
 			primary.Close()
-			return nil, fmt.Errorf("failed to initialize schema: %w", err)
+			return nil, err
 		}
 
 		// ============ OPEN REPLICA DATABASES ============
@@ -65,17 +99,27 @@ func NewURLRepository(cfg *config.DatabaseConfig) (*URLRepository, error) {
 		if err != nil {
 			return nil, fmt.Errorf("failed to open SQLite database: %w", err)
 		}
-		if err := initSQLiteSchema(primary); err != nil {
+		if err := prepareSchema(primary, cfg); err != nil {
 			primary.Close()
-			return nil, fmt.Errorf("failed to initialize schema: %w", err)
+			return nil, err
 		}
 	}
 
 	repo := &URLRepository{
-		primary:  primary,
-		replicas: replicas,
-		rrIndex:  0,
-		driver:   cfg.Driver,
+		primary:      primary,
+		replicas:     replicas,
+		rrIndex:      0,
+		driver:       cfg.Driver,
+		replicaTurns: weightedTurns(cfg.ReplicaWeights, len(replicas)),
+		replicaDown:  make([]atomic.Bool, len(replicas)),
+
+		readTimeout:  cfg.ReadTimeout,
+		writeTimeout: cfg.WriteTimeout,
+	}
+	if len(replicas) > 0 && cfg.ReplicaCheckInterval > 0 {
+		repo.stopChecks = make(chan struct{})
+		repo.checksDone = make(chan struct{})
+		go repo.runReplicaChecks(cfg.ReplicaCheckInterval)
 	}
 
 	fmt.Printf("Database initialized: %s (1 primary + %d replicas)\n",
@@ -128,6 +172,32 @@ func openSQLite(path string, maxOpen, maxIdle int) (*sql.DB, error) {
 // SCHEMA INITIALIZATION
 // ============================================================
 
+// prepareSchema creates or migrates the schema, or with VerifySchema only
+// checks it. Read-only deployments leave the schema alone unless asked to
+// verify it.
+func prepareSchema(db *sql.DB, cfg *config.DatabaseConfig) error {
+	if cfg.VerifySchema {
+		if err := verifySchema(db, cfg.Driver); err != nil {
+			return fmt.Errorf("failed to verify schema: %w", err)
+		}
+		return nil
+	}
+	if cfg.ReadOnly {
+		return nil
+	}
+
+	var err error
+	if cfg.Driver == "postgres" {
+		err = initPostgresSchema(db)
+	} else {
+		err = initSQLiteSchema(db)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to initialize schema: %w", err)
+	}
+	return nil
+}
+
 func initPostgresSchema(db *sql.DB) error {
 	schema := `
 	CREATE TABLE IF NOT EXISTS urls (
@@ -138,9 +208,33 @@ func initPostgresSchema(db *sql.DB) error {
 		click_count BIGINT DEFAULT 0
 	);
 	CREATE INDEX IF NOT EXISTS idx_short_code ON urls(short_code);
+	CREATE INDEX IF NOT EXISTS idx_original_url ON urls(original_url);
+	CREATE INDEX IF NOT EXISTS idx_created_at ON urls(created_at);
+
+	CREATE TABLE IF NOT EXISTS clicks (
+		id BIGSERIAL PRIMARY KEY,
+		short_code VARCHAR(20) NOT NULL,
+		clicked_at TIMESTAMPTZ NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_clicks_short_code ON clicks(short_code, clicked_at);
+
+	CREATE TABLE IF NOT EXISTS code_redirects (
+		old_code VARCHAR(20) PRIMARY KEY,
+		new_code VARCHAR(20) NOT NULL,
+		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS language_urls (
+		short_code VARCHAR(20) NOT NULL,
+		lang VARCHAR(16) NOT NULL,
+		original_url TEXT NOT NULL,
+		PRIMARY KEY (short_code, lang)
+	);
 	`
-	_, err := db.Exec(schema)
-	return err
+	if _, err := db.Exec(schema); err != nil {
+		return err
+	}
+	return migrateColumns(db, "postgres")
 }
 
 func initSQLiteSchema(db *sql.DB) error {
@@ -153,116 +247,1454 @@ func initSQLiteSchema(db *sql.DB) error {
 		click_count INTEGER DEFAULT 0
 	);
 	CREATE INDEX IF NOT EXISTS idx_short_code ON urls(short_code);
+	CREATE INDEX IF NOT EXISTS idx_original_url ON urls(original_url);
+	CREATE INDEX IF NOT EXISTS idx_created_at ON urls(created_at);
+
+	CREATE TABLE IF NOT EXISTS clicks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		short_code TEXT NOT NULL,
+		clicked_at DATETIME NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_clicks_short_code ON clicks(short_code, clicked_at);
+
+	CREATE TABLE IF NOT EXISTS code_redirects (
+		old_code TEXT PRIMARY KEY,
+		new_code TEXT NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS language_urls (
+		short_code TEXT NOT NULL,
+		lang TEXT NOT NULL,
+		original_url TEXT NOT NULL,
+		PRIMARY KEY (short_code, lang)
+	);
 	`
-	_, err := db.Exec(schema)
-	return err
+	if _, err := db.Exec(schema); err != nil {
+		return err
+	}
+	return migrateColumns(db, "sqlite3")
+}
+
+// columnMigration is a column added to a table after the initial schema
+type columnMigration struct {
+	name       string
+	definition string
+}
+
+// columnMigrations are columns added to urls after the initial schema.
+// Applied in order on startup; each must be safe to re-run.
+var columnMigrations = []columnMigration{
+	{"status", "VARCHAR(16) NOT NULL DEFAULT 'active'"},
+	{"tag", "VARCHAR(64) NOT NULL DEFAULT ''"},
+	{"signed", "BOOLEAN NOT NULL DEFAULT FALSE"},
+	{"owner", "VARCHAR(64) NOT NULL DEFAULT ''"},
+	{"expires_at", "TIMESTAMP"}, // NULL never expires
+	{"delay_seconds", "INTEGER NOT NULL DEFAULT 0"},
+	{"webhook_url", "TEXT NOT NULL DEFAULT ''"},
+}
+
+// clickColumnMigrations are columns added to clicks, applied like columnMigrations
+var clickColumnMigrations = []columnMigration{
+	{"referrer", "TEXT NOT NULL DEFAULT ''"},
+	{"user_agent", "TEXT NOT NULL DEFAULT ''"},
+	{"client_ip", "TEXT NOT NULL DEFAULT ''"},
+}
+
+// expectedColumns lists the columns the repository reads or writes, per
+// table. urls and clicks gain every entry in their column migrations.
+func expectedColumns() map[string][]string {
+	urls := []string{"id", "short_code", "original_url", "created_at", "click_count"}
+	for _, col := range columnMigrations {
+		urls = append(urls, col.name)
+	}
+	clicks := []string{"id", "short_code", "clicked_at"}
+	for _, col := range clickColumnMigrations {
+		clicks = append(clicks, col.name)
+	}
+	return map[string][]string{
+		"urls":           urls,
+		"clicks":         clicks,
+		"code_redirects": {"old_code", "new_code", "created_at"},
+		"language_urls":  {"short_code", "lang", "original_url"},
+	}
+}
+
+// verifySchema checks that every expected table and column exists without
+// issuing any DDL. The error names everything that is missing.
+func verifySchema(db *sql.DB, driver string) error {
+	query := `SELECT name FROM pragma_table_info(?)`
+	if driver == "postgres" {
+		query = `SELECT column_name FROM information_schema.columns
+		         WHERE table_schema = current_schema() AND table_name = $1`
+	}
+
+	expected := expectedColumns()
+	tables := make([]string, 0, len(expected))
+	for table := range expected {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+
+	var problems []string
+	for _, table := range tables {
+		present, err := tableColumns(db, query, table)
+		if err != nil {
+			return fmt.Errorf("inspect table %s: %w", table, err)
+		}
+		if len(present) == 0 {
+			problems = append(problems, fmt.Sprintf("table %s does not exist", table))
+			continue
+		}
+
+		var missing []string
+		for _, col := range expected[table] {
+			if !present[col] {
+				missing = append(missing, col)
+			}
+		}
+		if len(missing) > 0 {
+			problems = append(problems, fmt.Sprintf("table %s is missing columns %s", table, strings.Join(missing, ", ")))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrSchemaMismatch, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func tableColumns(db *sql.DB, query, table string) (map[string]bool, error) {
+	rows, err := db.Query(query, table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	present := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		present[name] = true
+	}
+	return present, rows.Err()
+}
+
+// migrateColumns adds any missing columns to an existing urls table
+func migrateColumns(db *sql.DB, driver string) error {
+	if err := addColumns(db, driver, "urls", columnMigrations); err != nil {
+		return err
+	}
+	if err := addColumns(db, driver, "clicks", clickColumnMigrations); err != nil {
+		return err
+	}
+	if driver == "postgres" {
+		if err := migrateClickTimes(db); err != nil {
+			return err
+		}
+	}
+
+	// Indexes on migrated columns can only be created once they exist
+	if _, err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_owner ON urls(owner, id)`); err != nil {
+		return fmt.Errorf("create owner index: %w", err)
+	}
+	return nil
+}
+
+// migrateClickTimes converts clicks.clicked_at from TIMESTAMP, which
+// held UTC wall times, to TIMESTAMPTZ so per-day counts can bucket in
+// UTC whatever the session time zone. Tables already converted are left
+// alone.
+func migrateClickTimes(db *sql.DB) error {
+	var dataType string
+	err := db.QueryRow(`SELECT data_type FROM information_schema.columns
+	                    WHERE table_schema = current_schema() AND table_name = 'clicks' AND column_name = 'clicked_at'`).Scan(&dataType)
+	if err != nil {
+		return fmt.Errorf("inspect column clicks.clicked_at: %w", err)
+	}
+	if dataType != "timestamp without time zone" {
+		return nil
+	}
+	stmt := `ALTER TABLE clicks ALTER COLUMN clicked_at TYPE TIMESTAMPTZ USING clicked_at AT TIME ZONE 'UTC'`
+	if _, err := db.Exec(stmt); err != nil {
+		return fmt.Errorf("convert clicks.clicked_at: %w", err)
+	}
+	return nil
+}
+
+// addColumns adds each missing column of cols to table
+func addColumns(db *sql.DB, driver, table string, cols []columnMigration) error {
+	for _, col := range cols {
+		if driver == "postgres" {
+			stmt := fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s %s", table, col.name, col.definition)
+			if _, err := db.Exec(stmt); err != nil {
+				return fmt.Errorf("add column %s.%s: %w", table, col.name, err)
+			}
+			continue
+		}
+
+		// SQLite has no ADD COLUMN IF NOT EXISTS
+		var count int
+		err := db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, table, col.name).Scan(&count)
+		if err != nil {
+			return fmt.Errorf("inspect column %s.%s: %w", table, col.name, err)
+		}
+		if count > 0 {
+			continue
+		}
+		stmt := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, col.name, col.definition)
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("add column %s.%s: %w", table, col.name, err)
+		}
+	}
+	return nil
 }
 
 // ============================================================
 // READ OPERATIONS (use replicas if available)
 // ============================================================
 
-func (r *URLRepository) getReadDB() *sql.DB {
-	if len(r.replicas) == 0 {
+// consistencyKey marks a context whose reads must go to the primary
+type consistencyKey struct{}
+
+// WithStrongConsistency returns a context that routes reads to the primary,
+// so the caller sees its own writes regardless of replica lag
+func WithStrongConsistency(ctx context.Context) context.Context {
+	return context.WithValue(ctx, consistencyKey{}, true)
+}
+
+// isStrongConsistency reports whether ctx requests primary reads
+func isStrongConsistency(ctx context.Context) bool {
+	strong, _ := ctx.Value(consistencyKey{}).(bool)
+	return strong
+}
+
+func (r *URLRepository) getReadDB(ctx context.Context) *sql.DB {
+	if len(r.replicas) == 0 || isStrongConsistency(ctx) {
 		return r.primary
 	}
 
-	// Round-robin across replicas
+	// Round-robin across replicas, weighted when configured. A down
+	// replica's turn passes to the next healthy one.
 	idx := atomic.AddUint32(&r.rrIndex, 1)
-	return r.replicas[idx%uint32(len(r.replicas))]
+	turns := uint32(len(r.replicas))
+	if len(r.replicaTurns) > 0 {
+		turns = uint32(len(r.replicaTurns))
+	}
+	for n := uint32(0); n < turns; n++ {
+		i := int((idx + n) % turns)
+		if len(r.replicaTurns) > 0 {
+			i = r.replicaTurns[i]
+		}
+		if r.replicaUp(i) {
+			return r.replicas[i]
+		}
+	}
+	metrics.Degradations.Inc(metrics.ReplicaRead) // every replica is down
+	return r.primary
+}
+
+// replicaUp reports whether replica i passed its last health check.
+// Replicas count as up until a check says otherwise.
+func (r *URLRepository) replicaUp(i int) bool {
+	return i >= len(r.replicaDown) || !r.replicaDown[i].Load()
+}
+
+// runReplicaChecks checks the replicas every interval until Close
+func (r *URLRepository) runReplicaChecks(interval time.Duration) {
+	defer close(r.checksDone)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stopChecks:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			r.checkReplicas(ctx)
+			cancel()
+		}
+	}
+}
+
+// checkReplicas pings every replica at once and records which answered
+func (r *URLRepository) checkReplicas(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i, replica := range r.replicas {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			down := replica.PingContext(ctx) != nil
+			if r.replicaDown[i].Swap(down) != down {
+				if down {
+					fmt.Printf("Replica %d failed its health check; reads skip it\n", i)
+				} else {
+					fmt.Printf("Replica %d recovered; back in the read rotation\n", i)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// weightedTurns spreads one rotation of sum(weights) turns across replicas
+// in proportion to their weights, interleaved (smooth weighted round-robin)
+// so a heavy replica doesn't take its whole share in a burst. Returns nil
+// when weights don't cover every replica exactly once.
+func weightedTurns(weights []int, replicas int) []int {
+	if replicas == 0 || len(weights) != replicas {
+		return nil
+	}
+
+	total := 0
+	for _, w := range weights {
+		if w < 1 {
+			return nil
+		}
+		total += w
+	}
+
+	turns := make([]int, 0, total)
+	current := make([]int, replicas)
+	for len(turns) < total {
+		best := 0
+		for i, w := range weights {
+			current[i] += w
+			if current[i] > current[best] {
+				best = i
+			}
+		}
+		current[best] -= total
+		turns = append(turns, best)
+	}
+	return turns
+}
+
+// readContext bounds a read by the configured read timeout
+func (r *URLRepository) readContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, r.readTimeout)
+}
+
+// writeContext bounds a write by the configured write timeout
+func (r *URLRepository) writeContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, r.writeTimeout)
+}
+
+func withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+// mapTimeout reports an expired query deadline as ErrTimeout
+func mapTimeout(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrTimeout
+	}
+	return err
+}
+
+// pqUniqueViolation is PostgreSQL's SQLSTATE for a unique constraint
+const pqUniqueViolation = "23505"
+
+// mapWriteError is mapTimeout for writes that can collide on a unique
+// column. The create paths guard against taken codes in the statement
+// itself, but a concurrent transaction can still commit the same code
+// first, which the database reports as a constraint error: that is
+// ErrDuplicate too, not a failure.
+func mapWriteError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == pqUniqueViolation {
+		return ErrDuplicate
+	}
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) && (sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique || sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey) {
+		return ErrDuplicate
+	}
+	return mapTimeout(err)
 }
 
 // GetByShortCode retrieves a URL by short code
 func (r *URLRepository) GetByShortCode(shortCode string) (*model.URL, error) {
-	db := r.getReadDB()
+	return r.GetByShortCodeContext(context.Background(), shortCode)
+}
+
+// GetByShortCodeContext retrieves a URL by short code, honoring read routing in ctx
+func (r *URLRepository) GetByShortCodeContext(ctx context.Context, shortCode string) (*model.URL, error) {
+	db := r.getReadDB(ctx)
+	ctx, cancel := r.readContext(ctx)
+	defer cancel()
 
-	query := `SELECT id, short_code, original_url, created_at, click_count 
+	query := `SELECT id, short_code, original_url, created_at, click_count, status, tag, signed, owner, expires_at, delay_seconds, webhook_url 
 	          FROM urls WHERE short_code = $1`
 
 	// SQLite uses ? instead of $1
 	if r.driver == "sqlite3" {
-		query = `SELECT id, short_code, original_url, created_at, click_count 
+		query = `SELECT id, short_code, original_url, created_at, click_count, status, tag, signed, owner, expires_at, delay_seconds, webhook_url 
 		         FROM urls WHERE short_code = ?`
 	}
 
 	var url model.URL
-	err := db.QueryRow(query, shortCode).Scan(
-		&url.ID,
-		&url.ShortCode,
-		&url.OriginalURL,
-		&url.CreatedAt,
-		&url.ClickCount,
-	)
+	scan := func(db *sql.DB) error {
+		return db.QueryRowContext(ctx, query, shortCode).Scan(
+			&url.ID,
+			&url.ShortCode,
+			&url.OriginalURL,
+			&url.CreatedAt,
+			&url.ClickCount,
+			&url.Status,
+			&url.Tag,
+			&url.Signed,
+			&url.Owner,
+			&url.ExpiresAt,
+			&url.DelaySeconds,
+			&url.WebhookURL,
+		)
+	}
+
+	err := scan(db)
+	// A failing replica shouldn't break redirects; retry once on the
+	// primary while there is still time left
+	if err != nil && err != sql.ErrNoRows && db != r.primary && ctx.Err() == nil {
+		metrics.Degradations.Inc(metrics.ReplicaRead)
+		err = scan(r.primary)
+	}
 
 	if err == sql.ErrNoRows {
 		return nil, ErrNotFound
 	}
-	return &url, err
+	if err != nil {
+		return nil, mapTimeout(err)
+	}
+	return &url, nil
 }
 
-// ============================================================
-// WRITE OPERATIONS (always primary)
-// ============================================================
+// GetRedirect returns the code that oldCode was renamed to
+func (r *URLRepository) GetRedirect(ctx context.Context, oldCode string) (string, error) {
+	db := r.getReadDB(ctx)
+	ctx, cancel := r.readContext(ctx)
+	defer cancel()
 
-// Create inserts a new URL
-func (r *URLRepository) Create(url *model.URL) error {
-	query := `INSERT INTO urls (short_code, original_url) VALUES ($1, $2) RETURNING id`
+	query := `SELECT new_code FROM code_redirects WHERE old_code = $1`
+	if r.driver == "sqlite3" {
+		query = `SELECT new_code FROM code_redirects WHERE old_code = ?`
+	}
+
+	var newCode string
+	err := db.QueryRowContext(ctx, query, oldCode).Scan(&newCode)
+	if err == sql.ErrNoRows {
+		return "", ErrNotFound
+	}
+	return newCode, mapTimeout(err)
+}
 
+// ListURLs returns up to limit URLs with IDs greater than afterID, in ID
+// order, for batch jobs that walk the whole table
+func (r *URLRepository) ListURLs(afterID uint64, limit int) ([]*model.URL, error) {
+	query := `SELECT id, short_code, original_url, created_at, click_count, status, tag, signed, owner, expires_at, delay_seconds, webhook_url
+	          FROM urls WHERE id > $1 ORDER BY id LIMIT $2`
 	if r.driver == "sqlite3" {
-		// SQLite doesn't support RETURNING
-		query = `INSERT INTO urls (short_code, original_url) VALUES (?, ?)`
-		result, err := r.primary.Exec(query, url.ShortCode, url.OriginalURL)
-		if err != nil {
-			return err
+		query = `SELECT id, short_code, original_url, created_at, click_count, status, tag, signed, owner, expires_at, delay_seconds, webhook_url
+		         FROM urls WHERE id > ? ORDER BY id LIMIT ?`
+	}
+
+	ctx, cancel := r.readContext(context.Background())
+	defer cancel()
+
+	// Batch jobs write based on what they read, so skip replicas
+	rows, err := r.primary.QueryContext(ctx, query, afterID, limit)
+	if err != nil {
+		return nil, mapTimeout(err)
+	}
+	defer rows.Close()
+
+	var urls []*model.URL
+	for rows.Next() {
+		var url model.URL
+		if err := rows.Scan(&url.ID, &url.ShortCode, &url.OriginalURL, &url.CreatedAt, &url.ClickCount, &url.Status, &url.Tag, &url.Signed, &url.Owner, &url.ExpiresAt, &url.DelaySeconds, &url.WebhookURL); err != nil {
+			return nil, mapTimeout(err)
 		}
-		id, err := result.LastInsertId()
-		if err != nil {
-			return err
+		urls = append(urls, &url)
+	}
+	return urls, mapTimeout(rows.Err())
+}
+
+// List returns up to limit URLs, newest first, skipping the first offset
+func (r *URLRepository) List(limit, offset int) ([]*model.URL, error) {
+	query := `SELECT id, short_code, original_url, created_at, click_count, status, tag, signed, owner, expires_at, delay_seconds, webhook_url
+	          FROM urls ORDER BY created_at DESC, id DESC LIMIT $1 OFFSET $2`
+	if r.driver == "sqlite3" {
+		query = `SELECT id, short_code, original_url, created_at, click_count, status, tag, signed, owner, expires_at, delay_seconds, webhook_url
+		         FROM urls ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?`
+	}
+
+	ctx := context.Background()
+	db := r.getReadDB(ctx)
+	ctx, cancel := r.readContext(ctx)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, query, limit, offset)
+	if err != nil {
+		return nil, mapTimeout(err)
+	}
+	defer rows.Close()
+
+	var urls []*model.URL
+	for rows.Next() {
+		var url model.URL
+		if err := rows.Scan(&url.ID, &url.ShortCode, &url.OriginalURL, &url.CreatedAt, &url.ClickCount, &url.Status, &url.Tag, &url.Signed, &url.Owner, &url.ExpiresAt, &url.DelaySeconds, &url.WebhookURL); err != nil {
+			return nil, mapTimeout(err)
 		}
-		url.ID = uint64(id)
-		return nil
+		urls = append(urls, &url)
+	}
+	return urls, mapTimeout(rows.Err())
+}
+
+// ListByOwner returns up to limit of owner's URLs, newest first, skipping
+// the first offset
+func (r *URLRepository) ListByOwner(owner string, limit, offset int) ([]*model.URL, error) {
+	query := `SELECT id, short_code, original_url, created_at, click_count, status, tag, signed, owner, expires_at, delay_seconds, webhook_url
+	          FROM urls WHERE owner = $1 ORDER BY id DESC LIMIT $2 OFFSET $3`
+	if r.driver == "sqlite3" {
+		query = `SELECT id, short_code, original_url, created_at, click_count, status, tag, signed, owner, expires_at, delay_seconds, webhook_url
+		         FROM urls WHERE owner = ? ORDER BY id DESC LIMIT ? OFFSET ?`
 	}
 
-	// PostgreSQL with RETURNING
-	err := r.primary.QueryRow(query, url.ShortCode, url.OriginalURL).Scan(&url.ID)
-	return err
+	ctx := context.Background()
+	db := r.getReadDB(ctx)
+	ctx, cancel := r.readContext(ctx)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, query, owner, limit, offset)
+	if err != nil {
+		return nil, mapTimeout(err)
+	}
+	defer rows.Close()
+
+	var urls []*model.URL
+	for rows.Next() {
+		var url model.URL
+		if err := rows.Scan(&url.ID, &url.ShortCode, &url.OriginalURL, &url.CreatedAt, &url.ClickCount, &url.Status, &url.Tag, &url.Signed, &url.Owner, &url.ExpiresAt, &url.DelaySeconds, &url.WebhookURL); err != nil {
+			return nil, mapTimeout(err)
+		}
+		urls = append(urls, &url)
+	}
+	return urls, mapTimeout(rows.Err())
 }
 
-// IncrementClickCount increments click counter
-func (r *URLRepository) IncrementClickCount(shortCode string) error {
-	query := `UPDATE urls SET click_count = click_count + 1 WHERE short_code = $1`
+// GetAllByOriginalURL returns every URL pointing at originalURL, oldest
+// first. Callers decide which of them count as duplicates.
+func (r *URLRepository) GetAllByOriginalURL(ctx context.Context, originalURL string) ([]*model.URL, error) {
+	query := `SELECT id, short_code, original_url, created_at, click_count, status, tag, signed, owner, expires_at, delay_seconds, webhook_url
+	          FROM urls WHERE original_url = $1 ORDER BY id`
+	if r.driver == "sqlite3" {
+		query = `SELECT id, short_code, original_url, created_at, click_count, status, tag, signed, owner, expires_at, delay_seconds, webhook_url
+		         FROM urls WHERE original_url = ? ORDER BY id`
+	}
+
+	ctx, cancel := r.readContext(ctx)
+	defer cancel()
+
+	// Used to decide whether to write, so a lagging replica would let
+	// duplicates through
+	rows, err := r.primary.QueryContext(ctx, query, originalURL)
+	if err != nil {
+		return nil, mapTimeout(err)
+	}
+	defer rows.Close()
+
+	var urls []*model.URL
+	for rows.Next() {
+		var url model.URL
+		if err := rows.Scan(&url.ID, &url.ShortCode, &url.OriginalURL, &url.CreatedAt, &url.ClickCount, &url.Status, &url.Tag, &url.Signed, &url.Owner, &url.ExpiresAt, &url.DelaySeconds, &url.WebhookURL); err != nil {
+			return nil, mapTimeout(err)
+		}
+		urls = append(urls, &url)
+	}
+	return urls, mapTimeout(rows.Err())
+}
+
+// Count returns how many URLs are stored
+func (r *URLRepository) Count() (uint64, error) {
+	db := r.getReadDB(context.Background())
+	ctx, cancel := r.readContext(context.Background())
+	defer cancel()
+
+	var count uint64
+	err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM urls`).Scan(&count)
+	return count, mapTimeout(err)
+}
+
+// CountCreatedSince returns how many URLs were created at or after since
+func (r *URLRepository) CountCreatedSince(since time.Time) (uint64, error) {
+	db := r.getReadDB(context.Background())
+	ctx, cancel := r.readContext(context.Background())
+	defer cancel()
 
+	query := `SELECT COUNT(*) FROM urls WHERE created_at >= $1`
 	if r.driver == "sqlite3" {
-		query = `UPDATE urls SET click_count = click_count + 1 WHERE short_code = ?`
+		query = `SELECT COUNT(*) FROM urls WHERE created_at >= ?`
 	}
 
-	_, err := r.primary.Exec(query, shortCode)
-	return err
+	var count uint64
+	err := db.QueryRowContext(ctx, query, since.UTC()).Scan(&count)
+	return count, mapTimeout(err)
 }
 
-// GetNextID returns next available ID
-func (r *URLRepository) GetNextID() (uint64, error) {
-	var maxID sql.NullInt64
-	query := `SELECT MAX(id) FROM urls`
+// CountClickEvents returns how many detailed click rows exist for a code
+func (r *URLRepository) CountClickEvents(shortCode string) (uint64, error) {
+	db := r.getReadDB(context.Background())
+	ctx, cancel := r.readContext(context.Background())
+	defer cancel()
+
+	query := `SELECT COUNT(*) FROM clicks WHERE short_code = $1`
+	if r.driver == "sqlite3" {
+		query = `SELECT COUNT(*) FROM clicks WHERE short_code = ?`
+	}
+
+	var count uint64
+	err := db.QueryRowContext(ctx, query, shortCode).Scan(&count)
+	return count, mapTimeout(err)
+}
+
+// StatsByTag aggregates link and click counts per tag, busiest tags first.
+// Untagged links are left out.
+func (r *URLRepository) StatsByTag(ctx context.Context, limit int) ([]model.TagStats, error) {
+	db := r.getReadDB(ctx)
+	ctx, cancel := r.readContext(ctx)
+	defer cancel()
 
-	err := r.primary.QueryRow(query).Scan(&maxID)
+	query := `SELECT tag, COUNT(*), COALESCE(SUM(click_count), 0) FROM urls
+	          WHERE tag <> '' GROUP BY tag ORDER BY 3 DESC, tag LIMIT $1`
+	if r.driver == "sqlite3" {
+		query = `SELECT tag, COUNT(*), COALESCE(SUM(click_count), 0) FROM urls
+		         WHERE tag <> '' GROUP BY tag ORDER BY 3 DESC, tag LIMIT ?`
+	}
+
+	rows, err := db.QueryContext(ctx, query, limit)
 	if err != nil {
-		return 0, err
+		return nil, mapTimeout(err)
 	}
+	defer rows.Close()
 
-	if !maxID.Valid {
-		return 1, nil
+	var stats []model.TagStats
+	for rows.Next() {
+		var ts model.TagStats
+		if err := rows.Scan(&ts.Tag, &ts.Links, &ts.Clicks); err != nil {
+			return nil, mapTimeout(err)
+		}
+		stats = append(stats, ts)
 	}
+	return stats, mapTimeout(rows.Err())
+}
 
-	return uint64(maxID.Int64) + 1, nil
+// GetClicksByDay counts a code's click rows per UTC day ("2006-01-02")
+// from since onward. Days without clicks are absent.
+func (r *URLRepository) GetClicksByDay(shortCode string, since time.Time) (map[string]int, error) {
+	db := r.getReadDB(context.Background())
+	ctx, cancel := r.readContext(context.Background())
+	defer cancel()
+
+	query := `SELECT to_char(clicked_at AT TIME ZONE 'UTC', 'YYYY-MM-DD') AS day, COUNT(*) FROM clicks
+	          WHERE short_code = $1 AND clicked_at >= $2 GROUP BY day`
+	if r.driver == "sqlite3" {
+		query = `SELECT date(clicked_at) AS day, COUNT(*) FROM clicks
+		         WHERE short_code = ? AND clicked_at >= ? GROUP BY day`
+	}
+
+	rows, err := db.QueryContext(ctx, query, shortCode, since.UTC())
+	if err != nil {
+		return nil, mapTimeout(err)
+	}
+	defer rows.Close()
+
+	days := make(map[string]int)
+	for rows.Next() {
+		var day string
+		var count int
+		if err := rows.Scan(&day, &count); err != nil {
+			return nil, mapTimeout(err)
+		}
+		days[day] = count
+	}
+	return days, mapTimeout(rows.Err())
 }
 
-// ============================================================
-// LIFECYCLE
-// ============================================================
+// TopByClicks returns up to limit links with at least minClicks clicks,
+// busiest first
+func (r *URLRepository) TopByClicks(ctx context.Context, minClicks uint64, limit int) ([]model.LinkClicks, error) {
+	db := r.getReadDB(ctx)
+	ctx, cancel := r.readContext(ctx)
+	defer cancel()
 
-func (r *URLRepository) Close() error {
-	var errs []error
+	query := `SELECT short_code, click_count FROM urls
+	          WHERE click_count >= $1 ORDER BY click_count DESC, short_code LIMIT $2`
+	if r.driver == "sqlite3" {
+		query = `SELECT short_code, click_count FROM urls
+		         WHERE click_count >= ? ORDER BY click_count DESC, short_code LIMIT ?`
+	}
+
+	rows, err := db.QueryContext(ctx, query, minClicks, limit)
+	if err != nil {
+		return nil, mapTimeout(err)
+	}
+	defer rows.Close()
+
+	var links []model.LinkClicks
+	for rows.Next() {
+		var lc model.LinkClicks
+		if err := rows.Scan(&lc.ShortCode, &lc.Clicks); err != nil {
+			return nil, mapTimeout(err)
+		}
+		links = append(links, lc)
+	}
+	return links, mapTimeout(rows.Err())
+}
+
+// ListRecent returns up to limit links, most recently created first. The
+// created_at index serves the ordering, so this stays cheap on large tables.
+func (r *URLRepository) ListRecent(ctx context.Context, limit int) ([]model.RecentLink, error) {
+	db := r.getReadDB(ctx)
+	ctx, cancel := r.readContext(ctx)
+	defer cancel()
+
+	query := `SELECT short_code, original_url, status, tag, created_at FROM urls
+	          ORDER BY created_at DESC, id DESC LIMIT $1`
+	if r.driver == "sqlite3" {
+		query = `SELECT short_code, original_url, status, tag, created_at FROM urls
+		         ORDER BY created_at DESC, id DESC LIMIT ?`
+	}
+
+	rows, err := db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, mapTimeout(err)
+	}
+	defer rows.Close()
+
+	var links []model.RecentLink
+	for rows.Next() {
+		var link model.RecentLink
+		if err := rows.Scan(&link.ShortCode, &link.OriginalURL, &link.Status, &link.Tag, &link.CreatedAt); err != nil {
+			return nil, mapTimeout(err)
+		}
+		links = append(links, link)
+	}
+	return links, mapTimeout(rows.Err())
+}
+
+// ============================================================
+// WRITE OPERATIONS (always primary)
+// ============================================================
+
+// Create inserts a new URL.
+// The insert is a single conflict-ignoring statement, so concurrent creates of
+// the same short code have exactly one winner; losers get ErrDuplicate.
+func (r *URLRepository) Create(url *model.URL) error {
+	return r.CreateContext(context.Background(), url)
+}
+
+// CreateContext is Create bounded by ctx as well as the write timeout.
+// The link's LanguageURLs are stored in the same transaction.
+func (r *URLRepository) CreateContext(ctx context.Context, url *model.URL) error {
+	ctx, cancel := r.writeContext(ctx)
+	defer cancel()
+	if len(url.LanguageURLs) == 0 {
+		return r.insertURL(ctx, r.primary, url)
+	}
+
+	tx, err := r.primary.BeginTx(ctx, nil)
+	if err != nil {
+		return mapTimeout(err)
+	}
+	defer tx.Rollback()
+
+	if err := r.insertURL(ctx, tx, url); err != nil {
+		return err
+	}
+	if err := r.insertLanguageURLs(ctx, tx, url.ShortCode, url.LanguageURLs); err != nil {
+		url.ID = 0
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		url.ID = 0
+		return mapTimeout(err)
+	}
+	return nil
+}
+
+// CreateGenerated inserts url and sets its ShortCode to codeFor of the ID
+// the database assigns, in one transaction. Unlike reading the next ID
+// first, concurrent creates can't derive the same code. A derived code
+// that is already taken, e.g. by a custom alias, is ErrDuplicate and
+// nothing is stored.
+func (r *URLRepository) CreateGenerated(ctx context.Context, url *model.URL, codeFor CodeFunc) error {
+	ctx, cancel := r.writeContext(ctx)
+	defer cancel()
+
+	tx, err := r.primary.BeginTx(ctx, nil)
+	if err != nil {
+		return mapTimeout(err)
+	}
+	defer tx.Rollback()
+
+	stored, err := r.insertGenerated(ctx, tx, url, codeFor)
+	if err != nil {
+		return err
+	}
+	if stored {
+		if err := r.insertLanguageURLs(ctx, tx, url.ShortCode, url.LanguageURLs); err != nil {
+			url.ID, url.ShortCode = 0, ""
+			return err
+		}
+	}
+	// Committed even when the code was taken: the placeholder row is gone,
+	// and SQLite only moves its ID sequence past the burned ID on commit,
+	// so the next create derives a different code
+	if err := tx.Commit(); err != nil {
+		url.ID, url.ShortCode = 0, ""
+		return mapTimeout(err)
+	}
+	if !stored {
+		return ErrDuplicate
+	}
+	return nil
+}
+
+// CreateBatch inserts urls and their LanguageURLs in one transaction, in
+// order. Rows without a short code get codeFor of their assigned ID, as in
+// CreateGenerated. A row whose code is already taken (by an existing row
+// or an earlier one in the batch) is skipped and keeps ID 0; the others
+// commit. Any other failure rolls back the whole batch.
+func (r *URLRepository) CreateBatch(urls []*model.URL, codeFor CodeFunc) error {
+	ctx, cancel := r.writeContext(context.Background())
+	defer cancel()
+
+	tx, err := r.primary.BeginTx(ctx, nil)
+	if err != nil {
+		return mapTimeout(err)
+	}
+	defer tx.Rollback()
+
+	for _, url := range urls {
+		var err error
+		if url.ShortCode == "" {
+			_, err = r.insertGenerated(ctx, tx, url, codeFor)
+		} else if err = r.insertURL(ctx, tx, url); err == ErrDuplicate {
+			err = nil
+		}
+		if err == nil && url.ID != 0 {
+			err = r.insertLanguageURLs(ctx, tx, url.ShortCode, url.LanguageURLs)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		// Nothing was stored after all
+		for _, url := range urls {
+			url.ID = 0
+		}
+		return mapTimeout(err)
+	}
+	return nil
+}
+
+// insertQuery is the conflict-ignoring insert shared by the create paths
+func (r *URLRepository) insertQuery() string {
+	if r.driver == "sqlite3" {
+		// SQLite doesn't support RETURNING
+		return `INSERT OR IGNORE INTO urls (short_code, original_url, created_at, status, tag, signed, click_count, owner, expires_at, delay_seconds, webhook_url) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	}
+	return `INSERT INTO urls (short_code, original_url, created_at, status, tag, signed, click_count, owner, expires_at, delay_seconds, webhook_url) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	          ON CONFLICT (short_code) DO NOTHING RETURNING id`
+}
+
+// execer is satisfied by *sql.DB and *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// insertURL runs insertQuery for url on db and sets its ID. A taken short
+// code is ErrDuplicate and leaves the ID at 0.
+func (r *URLRepository) insertURL(ctx context.Context, db execer, url *model.URL) error {
+	if url.CreatedAt.IsZero() {
+		url.CreatedAt = time.Now().UTC()
+	}
+	if url.Status == "" {
+		url.Status = model.StatusActive
+	}
+	args := []any{url.ShortCode, url.OriginalURL, url.CreatedAt, url.Status, url.Tag, url.Signed, url.ClickCount, url.Owner, url.ExpiresAt, url.DelaySeconds, url.WebhookURL}
+
+	// PostgreSQL with RETURNING: no row back means the code was taken
+	if r.driver != "sqlite3" {
+		err := db.QueryRowContext(ctx, r.insertQuery(), args...).Scan(&url.ID)
+		if err == sql.ErrNoRows {
+			return ErrDuplicate
+		}
+		return mapWriteError(err)
+	}
+
+	result, err := db.ExecContext(ctx, r.insertQuery(), args...)
+	if err != nil {
+		return mapWriteError(err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrDuplicate
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	url.ID = uint64(id)
+	return nil
+}
+
+// insertGenerated inserts url under a placeholder code within tx, then
+// renames the row to codeFor(id). It reports false, with the row removed
+// and url.ID reset, when that code is already taken.
+func (r *URLRepository) insertGenerated(ctx context.Context, tx *sql.Tx, url *model.URL, codeFor CodeFunc) (bool, error) {
+	placeholder, err := placeholderCode()
+	if err != nil {
+		return false, err
+	}
+	url.ShortCode = placeholder
+	if err := r.insertURL(ctx, tx, url); err != nil {
+		url.ShortCode = ""
+		return false, err
+	}
+
+	code, err := codeFor(url.ID)
+	if err != nil {
+		url.ID, url.ShortCode = 0, ""
+		return false, err
+	}
+
+	// Checked in the same statement so a taken code doesn't abort the
+	// transaction with a constraint error (PostgreSQL would)
+	rename := `UPDATE urls SET short_code = $1 WHERE id = $2 AND NOT EXISTS (SELECT 1 FROM urls WHERE short_code = $1)`
+	remove := `DELETE FROM urls WHERE id = $1`
+	if r.driver == "sqlite3" {
+		rename = `UPDATE urls SET short_code = ?1 WHERE id = ?2 AND NOT EXISTS (SELECT 1 FROM urls WHERE short_code = ?1)`
+		remove = `DELETE FROM urls WHERE id = ?`
+	}
+	result, err := tx.ExecContext(ctx, rename, code, url.ID)
+	if err != nil {
+		return false, mapWriteError(err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if affected == 0 {
+		if _, err := tx.ExecContext(ctx, remove, url.ID); err != nil {
+			return false, mapTimeout(err)
+		}
+		url.ID, url.ShortCode = 0, ""
+		return false, nil
+	}
+	url.ShortCode = code
+	return true, nil
+}
+
+// placeholderCode is a unique stand-in for a row's code until its ID is
+// known. "!" never passes alias validation, so it can't clash with a
+// real code, and 17 characters fit VARCHAR(20).
+func placeholderCode() (string, error) {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return "!" + hex.EncodeToString(b[:]), nil
+}
+
+// Activate sets the destination of a reserved code and marks it active
+func (r *URLRepository) Activate(shortCode, originalURL string) error {
+	return r.ActivateContext(context.Background(), shortCode, originalURL)
+}
+
+// ActivateContext is Activate bounded by ctx as well as the write timeout
+func (r *URLRepository) ActivateContext(ctx context.Context, shortCode, originalURL string) error {
+	query := `UPDATE urls SET original_url = $1, status = $2 WHERE short_code = $3 AND status = $4`
+	if r.driver == "sqlite3" {
+		query = `UPDATE urls SET original_url = ?, status = ? WHERE short_code = ? AND status = ?`
+	}
+
+	ctx, cancel := r.writeContext(ctx)
+	defer cancel()
+
+	result, err := r.primary.ExecContext(ctx, query, originalURL, model.StatusActive, shortCode, model.StatusReserved)
+	if err != nil {
+		return mapTimeout(err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected > 0 {
+		return nil
+	}
+
+	// Nothing updated: either missing or already active
+	if _, err := r.GetByShortCodeContext(WithStrongConsistency(ctx), shortCode); err != nil {
+		return err
+	}
+	return ErrNotReserved
+}
+
+// IncrementClickCount increments click counter
+func (r *URLRepository) IncrementClickCount(shortCode string) error {
+	return r.IncrementClickCountBy(shortCode, 1)
+}
+
+// IncrementClickCountBy adds n to the click counter
+func (r *URLRepository) IncrementClickCountBy(shortCode string, n uint64) error {
+	return r.IncrementClickCountByContext(context.Background(), shortCode, n)
+}
+
+// IncrementClickCountByContext is IncrementClickCountBy bounded by ctx as
+// well as the write timeout
+func (r *URLRepository) IncrementClickCountByContext(ctx context.Context, shortCode string, n uint64) error {
+	query := `UPDATE urls SET click_count = click_count + $1 WHERE short_code = $2`
+
+	if r.driver == "sqlite3" {
+		query = `UPDATE urls SET click_count = click_count + ? WHERE short_code = ?`
+	}
+
+	ctx, cancel := r.writeContext(ctx)
+	defer cancel()
+
+	_, err := r.primary.ExecContext(ctx, query, n, shortCode)
+	return mapTimeout(err)
+}
+
+// RenameShortCode moves a URL and its click rows from oldCode to newCode and
+// records a redirect so oldCode keeps resolving. Existing redirects to
+// oldCode are repointed. Returns ErrDuplicate if newCode is in use or was
+// ever renamed away from, so a printed link never changes destination.
+func (r *URLRepository) RenameShortCode(oldCode, newCode string) error {
+	queries := []string{
+		`SELECT (SELECT COUNT(*) FROM urls WHERE short_code = $1)
+		      + (SELECT COUNT(*) FROM code_redirects WHERE old_code = $1)`,
+		`UPDATE urls SET short_code = $1 WHERE short_code = $2`,
+		`UPDATE clicks SET short_code = $1 WHERE short_code = $2`,
+		`UPDATE code_redirects SET new_code = $1 WHERE new_code = $2`,
+		`INSERT INTO code_redirects (old_code, new_code) VALUES ($1, $2)`,
+		`UPDATE language_urls SET short_code = $1 WHERE short_code = $2`,
+	}
+	if r.driver == "sqlite3" {
+		queries = []string{
+			`SELECT (SELECT COUNT(*) FROM urls WHERE short_code = ?1)
+			      + (SELECT COUNT(*) FROM code_redirects WHERE old_code = ?1)`,
+			`UPDATE urls SET short_code = ? WHERE short_code = ?`,
+			`UPDATE clicks SET short_code = ? WHERE short_code = ?`,
+			`UPDATE code_redirects SET new_code = ? WHERE new_code = ?`,
+			`INSERT INTO code_redirects (old_code, new_code) VALUES (?, ?)`,
+			`UPDATE language_urls SET short_code = ? WHERE short_code = ?`,
+		}
+	}
+
+	ctx, cancel := r.writeContext(context.Background())
+	defer cancel()
+
+	tx, err := r.primary.BeginTx(ctx, nil)
+	if err != nil {
+		return mapTimeout(err)
+	}
+	defer tx.Rollback()
+
+	var taken int
+	if err := tx.QueryRowContext(ctx, queries[0], newCode).Scan(&taken); err != nil {
+		return mapTimeout(err)
+	}
+	if taken > 0 {
+		return ErrDuplicate
+	}
+
+	result, err := tx.ExecContext(ctx, queries[1], newCode, oldCode)
+	if err != nil {
+		return mapWriteError(err)
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return mapTimeout(err)
+	} else if rows == 0 {
+		return ErrNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx, queries[2], newCode, oldCode); err != nil {
+		return mapTimeout(err)
+	}
+	if _, err := tx.ExecContext(ctx, queries[3], newCode, oldCode); err != nil {
+		return mapTimeout(err)
+	}
+	if _, err := tx.ExecContext(ctx, queries[4], oldCode, newCode); err != nil {
+		return mapWriteError(err)
+	}
+	if _, err := tx.ExecContext(ctx, queries[5], newCode, oldCode); err != nil {
+		return mapTimeout(err)
+	}
+
+	return mapTimeout(tx.Commit())
+}
+
+// SetLanguageURLs stores per-language destinations of shortCode, keyed
+// by language tag, replacing any stored for the same tags
+func (r *URLRepository) SetLanguageURLs(ctx context.Context, shortCode string, urls map[string]string) error {
+	if len(urls) == 0 {
+		return nil
+	}
+	ctx, cancel := r.writeContext(ctx)
+	defer cancel()
+
+	tx, err := r.primary.BeginTx(ctx, nil)
+	if err != nil {
+		return mapTimeout(err)
+	}
+	defer tx.Rollback()
+
+	if err := r.insertLanguageURLs(ctx, tx, shortCode, urls); err != nil {
+		return err
+	}
+	return mapTimeout(tx.Commit())
+}
+
+// insertLanguageURLs upserts urls for shortCode within tx
+func (r *URLRepository) insertLanguageURLs(ctx context.Context, tx *sql.Tx, shortCode string, urls map[string]string) error {
+	query := `INSERT INTO language_urls (short_code, lang, original_url) VALUES ($1, $2, $3)
+	          ON CONFLICT (short_code, lang) DO UPDATE SET original_url = EXCLUDED.original_url`
+	if r.driver == "sqlite3" {
+		query = `INSERT OR REPLACE INTO language_urls (short_code, lang, original_url) VALUES (?, ?, ?)`
+	}
+	for lang, originalURL := range urls {
+		if _, err := tx.ExecContext(ctx, query, shortCode, lang, originalURL); err != nil {
+			return mapTimeout(err)
+		}
+	}
+	return nil
+}
+
+// GetLanguageURLs returns the per-language destinations of shortCode,
+// empty if it has none
+func (r *URLRepository) GetLanguageURLs(ctx context.Context, shortCode string) (map[string]string, error) {
+	query := `SELECT lang, original_url FROM language_urls WHERE short_code = $1`
+	if r.driver == "sqlite3" {
+		query = `SELECT lang, original_url FROM language_urls WHERE short_code = ?`
+	}
+
+	db := r.getReadDB(ctx)
+	ctx, cancel := r.readContext(ctx)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, query, shortCode)
+	if err != nil {
+		return nil, mapTimeout(err)
+	}
+	defer rows.Close()
+
+	urls := make(map[string]string)
+	for rows.Next() {
+		var lang, originalURL string
+		if err := rows.Scan(&lang, &originalURL); err != nil {
+			return nil, mapTimeout(err)
+		}
+		urls[lang] = originalURL
+	}
+	return urls, mapTimeout(rows.Err())
+}
+
+// Delete removes a URL and its click rows on the primary. Returns
+// ErrNotFound if the code doesn't exist.
+func (r *URLRepository) Delete(shortCode string) error {
+	return r.DeleteContext(context.Background(), shortCode)
+}
+
+// DeleteContext is Delete bounded by ctx as well as the write timeout
+func (r *URLRepository) DeleteContext(ctx context.Context, shortCode string) error {
+	queries := []string{
+		`DELETE FROM urls WHERE short_code = $1`,
+		`DELETE FROM clicks WHERE short_code = $1`,
+		`DELETE FROM language_urls WHERE short_code = $1`,
+	}
+	if r.driver == "sqlite3" {
+		queries = []string{
+			`DELETE FROM urls WHERE short_code = ?`,
+			`DELETE FROM clicks WHERE short_code = ?`,
+			`DELETE FROM language_urls WHERE short_code = ?`,
+		}
+	}
+
+	ctx, cancel := r.writeContext(ctx)
+	defer cancel()
+
+	tx, err := r.primary.BeginTx(ctx, nil)
+	if err != nil {
+		return mapTimeout(err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, queries[0], shortCode)
+	if err != nil {
+		return mapTimeout(err)
+	}
+	if rows, err := result.RowsAffected(); err != nil {
+		return mapTimeout(err)
+	} else if rows == 0 {
+		return ErrNotFound
+	}
+
+	for _, query := range queries[1:] {
+		if _, err := tx.ExecContext(ctx, query, shortCode); err != nil {
+			return mapTimeout(err)
+		}
+	}
+
+	return mapTimeout(tx.Commit())
+}
+
+// DeleteByShortCodes removes every listed URL and its click rows in one
+// statement per table on the primary. Codes that don't exist are ignored.
+// Returns the number of URLs deleted.
+func (r *URLRepository) DeleteByShortCodes(codes []string) (int64, error) {
+	if len(codes) == 0 {
+		return 0, nil
+	}
+
+	placeholders := make([]string, len(codes))
+	args := make([]any, len(codes))
+	for i, code := range codes {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		if r.driver == "sqlite3" {
+			placeholders[i] = "?"
+		}
+		args[i] = code
+	}
+	in := strings.Join(placeholders, ", ")
+
+	ctx, cancel := r.writeContext(context.Background())
+	defer cancel()
+
+	tx, err := r.primary.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, mapTimeout(err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM urls WHERE short_code IN (`+in+`)`, args...)
+	if err != nil {
+		return 0, mapTimeout(err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, mapTimeout(err)
+	}
+
+	for _, table := range []string{"clicks", "language_urls"} {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM `+table+` WHERE short_code IN (`+in+`)`, args...); err != nil {
+			return 0, mapTimeout(err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, mapTimeout(err)
+	}
+	return deleted, nil
+}
+
+// AddClickCount adds a batch of clicks to the counter in one write
+func (r *URLRepository) AddClickCount(shortCode string, delta uint64) error {
+	return r.IncrementClickCountBy(shortCode, delta)
+}
+
+// AddClickCounts adds each code's delta to its counter in one transaction,
+// so a flush costs one commit instead of one per code. Nothing is written
+// if any update fails.
+func (r *URLRepository) AddClickCounts(counts map[string]uint64) error {
+	query := `UPDATE urls SET click_count = click_count + $1 WHERE short_code = $2`
+	if r.driver == "sqlite3" {
+		query = `UPDATE urls SET click_count = click_count + ? WHERE short_code = ?`
+	}
+
+	ctx, cancel := r.writeContext(context.Background())
+	defer cancel()
+
+	tx, err := r.primary.BeginTx(ctx, nil)
+	if err != nil {
+		return mapTimeout(err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return mapTimeout(err)
+	}
+	defer stmt.Close()
+
+	for shortCode, delta := range counts {
+		if _, err := stmt.ExecContext(ctx, delta, shortCode); err != nil {
+			return mapTimeout(err)
+		}
+	}
+	return mapTimeout(tx.Commit())
+}
+
+// RecordClick stores a detailed click event
+func (r *URLRepository) RecordClick(click *model.Click) error {
+	if click.ClickedAt.IsZero() {
+		click.ClickedAt = time.Now().UTC()
+	}
+
+	query := `INSERT INTO clicks (short_code, clicked_at, referrer, user_agent, client_ip) VALUES ($1, $2, $3, $4, $5)`
+	if r.driver == "sqlite3" {
+		query = `INSERT INTO clicks (short_code, clicked_at, referrer, user_agent, client_ip) VALUES (?, ?, ?, ?, ?)`
+	}
+
+	ctx, cancel := r.writeContext(context.Background())
+	defer cancel()
+
+	_, err := r.primary.ExecContext(ctx, query, click.ShortCode, click.ClickedAt, click.Referrer, click.UserAgent, click.ClientIP)
+	return mapTimeout(err)
+}
+
+// PruneClicks deletes the oldest click rows for a code so at most keep
+// remain. The aggregate click_count is not touched.
+func (r *URLRepository) PruneClicks(shortCode string, keep int) (int64, error) {
+	query := `DELETE FROM clicks WHERE short_code = $1 AND id NOT IN (
+		SELECT id FROM clicks WHERE short_code = $1 ORDER BY clicked_at DESC, id DESC LIMIT $2)`
+	args := []any{shortCode, keep}
+
+	if r.driver == "sqlite3" {
+		query = `DELETE FROM clicks WHERE short_code = ? AND id NOT IN (
+			SELECT id FROM clicks WHERE short_code = ? ORDER BY clicked_at DESC, id DESC LIMIT ?)`
+		args = []any{shortCode, shortCode, keep}
+	}
+
+	ctx, cancel := r.writeContext(context.Background())
+	defer cancel()
+
+	result, err := r.primary.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, mapTimeout(err)
+	}
+	return result.RowsAffected()
+}
+
+// GetNextID returns next available ID. It is a snapshot for reporting;
+// new codes come from CreateGenerated, which can't race.
+func (r *URLRepository) GetNextID() (uint64, error) {
+	return r.GetNextIDContext(context.Background())
+}
+
+// GetNextIDContext is GetNextID bounded by ctx as well as the read timeout.
+// It always reads the primary, which issues the IDs.
+func (r *URLRepository) GetNextIDContext(ctx context.Context) (uint64, error) {
+	var maxID sql.NullInt64
+	query := `SELECT MAX(id) FROM urls`
+
+	ctx, cancel := r.readContext(ctx)
+	defer cancel()
+
+	err := r.primary.QueryRowContext(ctx, query).Scan(&maxID)
+	if err != nil {
+		return 0, mapTimeout(err)
+	}
+
+	if !maxID.Valid {
+		return 1, nil
+	}
+
+	return uint64(maxID.Int64) + 1, nil
+}
+
+// ============================================================
+// LIFECYCLE
+// ============================================================
+
+// Ping checks the primary and every replica still in the read rotation,
+// naming the first that fails. Replicas the health checks took out don't
+// fail it, since reads no longer depend on them; Topology lists them.
+func (r *URLRepository) Ping(ctx context.Context) error {
+	if err := r.primary.PingContext(ctx); err != nil {
+		return fmt.Errorf("primary: %w", mapTimeout(err))
+	}
+	for i, replica := range r.replicas {
+		if !r.replicaUp(i) {
+			continue
+		}
+		if err := replica.PingContext(ctx); err != nil {
+			return fmt.Errorf("replica %d: %w", i, mapTimeout(err))
+		}
+	}
+	return nil
+}
+
+// Topology reports the driver, how many read replicas are configured,
+// and which of them failed their last health check
+func (r *URLRepository) Topology() Topology {
+	topology := Topology{Driver: r.driver, Replicas: len(r.replicas)}
+	for i := range r.replicas {
+		if !r.replicaUp(i) {
+			topology.ReplicasDown = append(topology.ReplicasDown, i)
+		}
+	}
+	return topology
+}
+
+// WarmUp opens conns connections on the primary and every replica and
+// runs a trivial query on each, so the first requests don't pay for
+// connection setup. Connections are returned to the pool, which keeps
+// them if conns is within MaxIdleConns.
+func (r *URLRepository) WarmUp(ctx context.Context, conns int) error {
+	for i, db := range append([]*sql.DB{r.primary}, r.replicas...) {
+		if err := warmPool(ctx, db, conns); err != nil {
+			if i == 0 {
+				return fmt.Errorf("primary: %w", err)
+			}
+			return fmt.Errorf("replica %d: %w", i-1, err)
+		}
+	}
+	return nil
+}
+
+// warmPool holds conns connections of db open at once, which forces the
+// pool to dial each of them, then releases them all
+func warmPool(ctx context.Context, db *sql.DB, conns int) error {
+	if limit := db.Stats().MaxOpenConnections; limit > 0 {
+		conns = min(conns, limit) // more would wait for one of our own
+	}
+	held := make([]*sql.Conn, 0, conns)
+	defer func() {
+		for _, conn := range held {
+			conn.Close()
+		}
+	}()
+	for len(held) < conns {
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			return mapTimeout(err)
+		}
+		held = append(held, conn)
+		if _, err := conn.ExecContext(ctx, "SELECT 1"); err != nil {
+			return mapTimeout(err)
+		}
+	}
+	return nil
+}
+
+func (r *URLRepository) Close() error {
+	var errs []error
+
+	if r.stopChecks != nil {
+		close(r.stopChecks)
+		<-r.checksDone
+	}
 
 	if err := r.primary.Close(); err != nil {
 		errs = append(errs, fmt.Errorf("primary: %w", err))
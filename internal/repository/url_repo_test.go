@@ -0,0 +1,672 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/darkodi/url-shortener/internal/config"
+	"github.com/darkodi/url-shortener/internal/metrics"
+	"github.com/darkodi/url-shortener/internal/model"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := openSQLite(":memory:", 1, 1)
+	if err != nil {
+		t.Fatalf("Failed to open SQLite: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestGetReadDB_StrongConsistencyUsesPrimary(t *testing.T) {
+	primary := openTestDB(t)
+	repo := &URLRepository{
+		primary:  primary,
+		replicas: []*sql.DB{openTestDB(t), openTestDB(t)},
+		driver:   "sqlite3",
+	}
+
+	// Default reads go to replicas
+	for i := 0; i < 4; i++ {
+		if repo.getReadDB(context.Background()) == primary {
+			t.Fatal("Expected default read to use a replica")
+		}
+	}
+
+	// Strong consistency reads always go to the primary
+	ctx := WithStrongConsistency(context.Background())
+	for i := 0; i < 4; i++ {
+		if repo.getReadDB(ctx) != primary {
+			t.Fatal("Expected strong-consistency read to use the primary")
+		}
+	}
+}
+
+func TestCreate_ConcurrentClaimOneWinner(t *testing.T) {
+	// File-backed so several connections race against the same table
+	repo, err := NewURLRepository(&config.DatabaseConfig{
+		Driver:       "sqlite3",
+		Path:         filepath.Join(t.TempDir(), "urls.db") + "?_busy_timeout=5000",
+		MaxOpenConns: 8,
+		MaxIdleConns: 8,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create repo: %v", err)
+	}
+	defer repo.Close()
+
+	const racers = 16
+	var wg sync.WaitGroup
+	var winners, losers atomic.Int32
+
+	for i := 0; i < racers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := repo.Create(&model.URL{ShortCode: "contested", OriginalURL: "https://example.com"})
+			switch err {
+			case nil:
+				winners.Add(1)
+			case ErrDuplicate:
+				losers.Add(1)
+			default:
+				t.Errorf("Unexpected error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if winners.Load() != 1 || losers.Load() != racers-1 {
+		t.Errorf("Expected 1 winner and %d losers, got %d and %d", racers-1, winners.Load(), losers.Load())
+	}
+}
+
+func TestMapWriteError_UniqueViolations(t *testing.T) {
+	repo, err := NewURLRepository(&config.DatabaseConfig{Driver: "sqlite3", Path: ":memory:", MaxOpenConns: 1, MaxIdleConns: 1})
+	if err != nil {
+		t.Fatalf("Failed to create repo: %v", err)
+	}
+	defer repo.Close()
+	if err := repo.Create(&model.URL{ShortCode: "taken", OriginalURL: "https://example.com"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := repo.RenameShortCode("taken", "renamed"); err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+
+	// Plain statements, without the conflict guards, to get the driver's errors
+	_, uniqueErr := repo.primary.Exec(`INSERT INTO urls (short_code, original_url, created_at) VALUES ('renamed', 'https://example.com', CURRENT_TIMESTAMP)`)
+	_, primaryKeyErr := repo.primary.Exec(`INSERT INTO code_redirects (old_code, new_code) VALUES ('taken', 'other')`)
+
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"sqlite unique", uniqueErr, ErrDuplicate},
+		{"sqlite primary key", primaryKeyErr, ErrDuplicate},
+		{"postgres unique", &pq.Error{Code: "23505"}, ErrDuplicate},
+		{"postgres not null", &pq.Error{Code: "23502"}, nil},
+		{"timeout", context.DeadlineExceeded, ErrTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.err == nil {
+				t.Fatal("Expected the statement to fail")
+			}
+			got := mapWriteError(tt.err)
+			if tt.want == nil {
+				if got != tt.err {
+					t.Errorf("Expected the error passed through, got: %v", got)
+				}
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Expected %v, got: %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestWarmUp_OpensIdleConnections(t *testing.T) {
+	repo, err := NewURLRepository(&config.DatabaseConfig{
+		Driver:       "sqlite3",
+		Path:         filepath.Join(t.TempDir(), "urls.db"),
+		MaxOpenConns: 8,
+		MaxIdleConns: 4,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create repo: %v", err)
+	}
+	defer repo.Close()
+
+	if err := repo.WarmUp(context.Background(), 4); err != nil {
+		t.Fatalf("WarmUp failed: %v", err)
+	}
+	if stats := repo.primary.Stats(); stats.Idle != 4 || stats.InUse != 0 {
+		t.Errorf("Expected 4 idle connections and none held, got: %d idle, %d in use", stats.Idle, stats.InUse)
+	}
+}
+
+func TestGetClicksByDay(t *testing.T) {
+	for _, driver := range []string{"sqlite3", "memory"} {
+		t.Run(driver, func(t *testing.T) {
+			repo, err := New(&config.DatabaseConfig{Driver: driver, Path: ":memory:", MaxOpenConns: 1, MaxIdleConns: 1})
+			if err != nil {
+				t.Fatalf("Failed to create repo: %v", err)
+			}
+			t.Cleanup(func() { repo.Close() })
+
+			day := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+			for _, at := range []time.Time{
+				day.Add(-time.Minute), // before since
+				day, day.Add(23 * time.Hour),
+				day.Add(25 * time.Hour),
+			} {
+				click := &model.Click{ShortCode: "daily", ClickedAt: at, Referrer: "https://news.example.com/", UserAgent: "Mozilla/5.0"}
+				if err := repo.RecordClick(click); err != nil {
+					t.Fatalf("RecordClick failed: %v", err)
+				}
+			}
+			_ = repo.RecordClick(&model.Click{ShortCode: "other", ClickedAt: day})
+
+			days, err := repo.GetClicksByDay("daily", day)
+			if err != nil {
+				t.Fatalf("GetClicksByDay failed: %v", err)
+			}
+			if len(days) != 2 || days["2024-03-10"] != 2 || days["2024-03-11"] != 1 {
+				t.Errorf("Expected 2 clicks on 03-10 and 1 on 03-11, got: %v", days)
+			}
+		})
+	}
+}
+
+func TestListRecent_NewestFirst(t *testing.T) {
+	for _, driver := range []string{"sqlite3", "memory"} {
+		t.Run(driver, func(t *testing.T) {
+			repo, err := New(&config.DatabaseConfig{Driver: driver, Path: ":memory:", MaxOpenConns: 1, MaxIdleConns: 1})
+			if err != nil {
+				t.Fatalf("Failed to create repo: %v", err)
+			}
+			t.Cleanup(func() { repo.Close() })
+
+			// Inserted out of order so ID order and creation order differ
+			base := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+			for _, link := range []struct {
+				code string
+				age  time.Duration
+			}{{"middle", 2 * time.Hour}, {"oldest", 3 * time.Hour}, {"newest", 0}, {"second", time.Hour}} {
+				url := &model.URL{ShortCode: link.code, OriginalURL: "https://example.com/" + link.code, CreatedAt: base.Add(-link.age)}
+				if err := repo.Create(url); err != nil {
+					t.Fatalf("Create %s failed: %v", link.code, err)
+				}
+			}
+
+			links, err := repo.ListRecent(context.Background(), 3)
+			if err != nil {
+				t.Fatalf("ListRecent failed: %v", err)
+			}
+			var codes []string
+			for _, link := range links {
+				codes = append(codes, link.ShortCode)
+			}
+			if strings.Join(codes, ",") != "newest,second,middle" {
+				t.Errorf("Expected newest,second,middle, got: %v", codes)
+			}
+			if len(links) > 0 && links[0].OriginalURL != "https://example.com/newest" {
+				t.Errorf("Expected destination in the feed, got: %+v", links[0])
+			}
+		})
+	}
+}
+
+func TestLanguageURLs_FollowRenameAndDelete(t *testing.T) {
+	for _, driver := range []string{"sqlite3", "memory"} {
+		t.Run(driver, func(t *testing.T) {
+			repo, err := New(&config.DatabaseConfig{Driver: driver, Path: ":memory:", MaxOpenConns: 1, MaxIdleConns: 1})
+			if err != nil {
+				t.Fatalf("Failed to create repo: %v", err)
+			}
+			t.Cleanup(func() { repo.Close() })
+			ctx := context.Background()
+
+			if err := repo.Create(&model.URL{ShortCode: "intl", OriginalURL: "https://example.com"}); err != nil {
+				t.Fatalf("Create failed: %v", err)
+			}
+			if err := repo.SetLanguageURLs(ctx, "intl", map[string]string{"fr": "https://example.fr", "de": "https://example.de"}); err != nil {
+				t.Fatalf("SetLanguageURLs failed: %v", err)
+			}
+			if err := repo.SetLanguageURLs(ctx, "intl", map[string]string{"fr": "https://example.fr/neu"}); err != nil {
+				t.Fatalf("SetLanguageURLs update failed: %v", err)
+			}
+
+			if err := repo.RenameShortCode("intl", "global"); err != nil {
+				t.Fatalf("RenameShortCode failed: %v", err)
+			}
+			urls, err := repo.GetLanguageURLs(ctx, "global")
+			if err != nil {
+				t.Fatalf("GetLanguageURLs failed: %v", err)
+			}
+			if len(urls) != 2 || urls["fr"] != "https://example.fr/neu" || urls["de"] != "https://example.de" {
+				t.Errorf("Expected both variants under the new code, got: %v", urls)
+			}
+
+			if err := repo.Delete("global"); err != nil {
+				t.Fatalf("Delete failed: %v", err)
+			}
+			if urls, _ := repo.GetLanguageURLs(ctx, "global"); len(urls) != 0 {
+				t.Errorf("Expected variants deleted with the link, got: %v", urls)
+			}
+		})
+	}
+}
+
+func TestLanguageURLs_StoredWithCreate(t *testing.T) {
+	for _, driver := range []string{"sqlite3", "memory"} {
+		t.Run(driver, func(t *testing.T) {
+			repo, err := New(&config.DatabaseConfig{Driver: driver, Path: ":memory:", MaxOpenConns: 1, MaxIdleConns: 1})
+			if err != nil {
+				t.Fatalf("Failed to create repo: %v", err)
+			}
+			t.Cleanup(func() { repo.Close() })
+			ctx := context.Background()
+			codeFor := func(id uint64) (string, error) { return "gen" + strconv.FormatUint(id, 10), nil }
+
+			if err := repo.CreateContext(ctx, &model.URL{ShortCode: "alias", OriginalURL: "https://example.com", LanguageURLs: map[string]string{"fr": "https://example.fr/alias"}}); err != nil {
+				t.Fatalf("CreateContext failed: %v", err)
+			}
+			generated := &model.URL{OriginalURL: "https://example.com", LanguageURLs: map[string]string{"fr": "https://example.fr/gen"}}
+			if err := repo.CreateGenerated(ctx, generated, codeFor); err != nil {
+				t.Fatalf("CreateGenerated failed: %v", err)
+			}
+			// The taken alias is skipped and must not touch the stored variants
+			batch := []*model.URL{
+				{ShortCode: "batched", OriginalURL: "https://example.com", LanguageURLs: map[string]string{"fr": "https://example.fr/batch"}},
+				{ShortCode: "alias", OriginalURL: "https://example.com", LanguageURLs: map[string]string{"fr": "https://example.fr/stolen"}},
+			}
+			if err := repo.CreateBatch(batch, codeFor); err != nil {
+				t.Fatalf("CreateBatch failed: %v", err)
+			}
+
+			for code, want := range map[string]string{
+				"alias":             "https://example.fr/alias",
+				generated.ShortCode: "https://example.fr/gen",
+				"batched":           "https://example.fr/batch",
+			} {
+				urls, err := repo.GetLanguageURLs(ctx, code)
+				if err != nil {
+					t.Fatalf("GetLanguageURLs failed: %v", err)
+				}
+				if urls["fr"] != want {
+					t.Errorf("Expected %s to have fr %s, got: %v", code, want, urls)
+				}
+			}
+		})
+	}
+}
+
+func TestPruneClicks_KeepsNewest(t *testing.T) {
+	for _, driver := range []string{"sqlite3", "memory"} {
+		t.Run(driver, func(t *testing.T) {
+			repo, err := New(&config.DatabaseConfig{Driver: driver, Path: ":memory:", MaxOpenConns: 1, MaxIdleConns: 1})
+			if err != nil {
+				t.Fatalf("Failed to create repo: %v", err)
+			}
+			t.Cleanup(func() { repo.Close() })
+
+			base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			// Insert out of order so pruning must sort by time
+			for _, minute := range []int{3, 0, 4, 1, 2} {
+				click := &model.Click{ShortCode: "hot", ClickedAt: base.Add(time.Duration(minute) * time.Minute)}
+				if err := repo.RecordClick(click); err != nil {
+					t.Fatalf("RecordClick failed: %v", err)
+				}
+			}
+			_ = repo.RecordClick(&model.Click{ShortCode: "other", ClickedAt: base})
+
+			pruned, err := repo.PruneClicks("hot", 2)
+			if err != nil || pruned != 3 {
+				t.Fatalf("Expected 3 rows pruned, got: %d, %v", pruned, err)
+			}
+			if n, _ := repo.CountClickEvents("hot"); n != 2 {
+				t.Errorf("Expected 2 rows left, got: %d", n)
+			}
+			if n, _ := repo.CountClickEvents("other"); n != 1 {
+				t.Errorf("Expected other code untouched, got: %d", n)
+			}
+			// Pruning to the same cap again is a no-op
+			if pruned, _ := repo.PruneClicks("hot", 2); pruned != 0 {
+				t.Errorf("Expected no further pruning, got: %d", pruned)
+			}
+		})
+	}
+}
+
+// slowDriver is a database/sql driver whose queries block until their
+// context is done, standing in for a stalled database
+type slowDriver struct{}
+
+func (slowDriver) Open(string) (driver.Conn, error) { return slowConn{}, nil }
+
+type slowConn struct{}
+
+func (slowConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (slowConn) Close() error                        { return nil }
+func (slowConn) Begin() (driver.Tx, error)           { return nil, driver.ErrSkip }
+
+func (slowConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (slowConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+var registerSlowDriver sync.Once
+
+func openSlowDB(t *testing.T) *sql.DB {
+	t.Helper()
+	registerSlowDriver.Do(func() { sql.Register("slow", slowDriver{}) })
+	db, err := sql.Open("slow", "")
+	if err != nil {
+		t.Fatalf("Failed to open slow DB: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestQueryTimeouts(t *testing.T) {
+	const readTimeout, writeTimeout = 30 * time.Millisecond, 60 * time.Millisecond
+	repo := &URLRepository{
+		primary:      openSlowDB(t),
+		driver:       "sqlite3",
+		readTimeout:  readTimeout,
+		writeTimeout: writeTimeout,
+	}
+
+	tests := []struct {
+		name  string
+		bound time.Duration
+		call  func() error
+	}{
+		{"read", readTimeout, func() error {
+			_, err := repo.GetByShortCodeContext(context.Background(), "abc")
+			return err
+		}},
+		{"write", writeTimeout, func() error {
+			return repo.IncrementClickCount("abc")
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			err := tt.call()
+			elapsed := time.Since(start)
+
+			if err != ErrTimeout {
+				t.Fatalf("Expected ErrTimeout, got: %v", err)
+			}
+			if elapsed < tt.bound || elapsed > tt.bound+time.Second {
+				t.Errorf("Expected timeout at about %s, took: %s", tt.bound, elapsed)
+			}
+		})
+	}
+}
+
+func TestContextCancellation(t *testing.T) {
+	repo := &URLRepository{primary: openSlowDB(t), driver: "sqlite3"}
+
+	tests := map[string]func(ctx context.Context) error{
+		"GetByShortCodeContext": func(ctx context.Context) error {
+			_, err := repo.GetByShortCodeContext(ctx, "abc")
+			return err
+		},
+		"CreateContext": func(ctx context.Context) error {
+			return repo.CreateContext(ctx, &model.URL{ShortCode: "abc", OriginalURL: "https://example.com"})
+		},
+		"IncrementClickCountByContext": func(ctx context.Context) error {
+			return repo.IncrementClickCountByContext(ctx, "abc", 1)
+		},
+		"GetNextIDContext": func(ctx context.Context) error {
+			_, err := repo.GetNextIDContext(ctx)
+			return err
+		},
+	}
+
+	for name, call := range tests {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(20*time.Millisecond, cancel)
+
+			start := time.Now()
+			err := call(ctx)
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("Expected context.Canceled, got: %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("Expected the query abandoned on cancel, took: %s", elapsed)
+			}
+		})
+	}
+}
+
+func TestReadOnly_RejectsWrites(t *testing.T) {
+	mem := NewMemoryRepository()
+	if err := mem.Create(&model.URL{ShortCode: "abc", OriginalURL: "https://example.com"}); err != nil {
+		t.Fatalf("Failed to seed: %v", err)
+	}
+	repo := ReadOnly(mem)
+
+	writes := map[string]error{
+		"Create":                repo.Create(&model.URL{ShortCode: "new", OriginalURL: "https://example.com"}),
+		"Activate":              repo.Activate("abc", "https://example.com/other"),
+		"RenameShortCode":       repo.RenameShortCode("abc", "xyz"),
+		"Delete":                repo.Delete("abc"),
+		"IncrementClickCount":   repo.IncrementClickCount("abc"),
+		"IncrementClickCountBy": repo.IncrementClickCountBy("abc", 5),
+		"AddClickCount":         repo.AddClickCount("abc", 5),
+		"AddClickCounts":        repo.AddClickCounts(map[string]uint64{"abc": 5}),
+		"RecordClick":           repo.RecordClick(&model.Click{ShortCode: "abc"}),
+	}
+	_, writes["PruneClicks"] = repo.PruneClicks("abc", 0)
+	_, writes["DeleteByShortCodes"] = repo.DeleteByShortCodes([]string{"abc"})
+	for name, err := range writes {
+		if err != ErrReadOnly {
+			t.Errorf("%s: Expected ErrReadOnly, got: %v", name, err)
+		}
+	}
+
+	url, err := repo.GetByShortCode("abc")
+	if err != nil || url.OriginalURL != "https://example.com" || url.ClickCount != 0 {
+		t.Errorf("Expected reads to pass through unchanged, got: %+v, %v", url, err)
+	}
+}
+
+func TestPing_ChecksEveryDatabase(t *testing.T) {
+	primary := openTestDB(t)
+	healthy := openTestDB(t)
+	down := openTestDB(t)
+	down.Close()
+
+	repo := &URLRepository{primary: primary, replicas: []*sql.DB{healthy}, driver: "sqlite3"}
+	if err := repo.Ping(context.Background()); err != nil {
+		t.Errorf("Expected every database to answer, got: %v", err)
+	}
+	if got := repo.Topology(); got.Driver != "sqlite3" || got.Replicas != 1 {
+		t.Errorf("Expected sqlite3 with 1 replica, got: %+v", got)
+	}
+
+	repo.replicas = append(repo.replicas, down)
+	if err := repo.Ping(context.Background()); err == nil || !strings.HasPrefix(err.Error(), "replica 1:") {
+		t.Errorf("Expected the down replica named, got: %v", err)
+	}
+}
+
+func TestCheckReplicas_SkipsDownReplicas(t *testing.T) {
+	primary := openTestDB(t)
+	healthy := openTestDB(t)
+	down := openTestDB(t)
+	down.Close()
+
+	repo := &URLRepository{
+		primary:     primary,
+		replicas:    []*sql.DB{healthy, down},
+		driver:      "sqlite3",
+		replicaDown: make([]atomic.Bool, 2),
+	}
+	repo.checkReplicas(context.Background())
+
+	for i := 0; i < 4; i++ {
+		if db := repo.getReadDB(context.Background()); db != healthy {
+			t.Fatal("Expected reads to skip the down replica")
+		}
+	}
+	if got := repo.Topology().ReplicasDown; len(got) != 1 || got[0] != 1 {
+		t.Errorf("Expected replica 1 reported down, got: %v", got)
+	}
+	if err := repo.Ping(context.Background()); err != nil {
+		t.Errorf("Expected a replica out of rotation not to fail Ping, got: %v", err)
+	}
+
+	// With every replica down, reads fall back to the primary
+	repo.replicas[0] = down
+	repo.checkReplicas(context.Background())
+	before := metrics.Degradations.Get(metrics.ReplicaRead)
+	if repo.getReadDB(context.Background()) != primary {
+		t.Error("Expected the primary when every replica is down")
+	}
+	if n := metrics.Degradations.Get(metrics.ReplicaRead) - before; n != 1 {
+		t.Errorf("Expected 1 replica fallback, got: %d", n)
+	}
+
+	// A replica that answers again rejoins the rotation
+	repo.replicas[0] = healthy
+	repo.checkReplicas(context.Background())
+	if repo.getReadDB(context.Background()) != healthy {
+		t.Error("Expected the recovered replica back in rotation")
+	}
+}
+
+func TestGetByShortCode_ReplicaFailureFallsBackToPrimary(t *testing.T) {
+	primary := openTestDB(t)
+	if err := initSQLiteSchema(primary); err != nil {
+		t.Fatalf("Failed to init schema: %v", err)
+	}
+	replica := openTestDB(t)
+	replica.Close() // a replica that is down
+
+	repo := &URLRepository{primary: primary, replicas: []*sql.DB{replica}, driver: "sqlite3"}
+	if err := repo.Create(&model.URL{ShortCode: "abc", OriginalURL: "https://example.com"}); err != nil {
+		t.Fatalf("Failed to seed: %v", err)
+	}
+
+	before := metrics.Degradations.Get(metrics.ReplicaRead)
+	url, err := repo.GetByShortCodeContext(context.Background(), "abc")
+	if err != nil || url.OriginalURL != "https://example.com" {
+		t.Fatalf("Expected read served by the primary, got: %+v, %v", url, err)
+	}
+	if n := metrics.Degradations.Get(metrics.ReplicaRead) - before; n != 1 {
+		t.Errorf("Expected 1 replica fallback, got: %d", n)
+	}
+
+	// Misses are answers, not failures
+	before = metrics.Degradations.Get(metrics.ReplicaRead)
+	repo.replicas = []*sql.DB{primary}
+	if _, err := repo.GetByShortCodeContext(context.Background(), "missing"); err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound, got: %v", err)
+	}
+	if n := metrics.Degradations.Get(metrics.ReplicaRead) - before; n != 0 {
+		t.Errorf("Expected no fallback for a miss, got: %d", n)
+	}
+}
+
+func TestGetReadDB_WeightedReplicas(t *testing.T) {
+	replicas := []*sql.DB{openTestDB(t), openTestDB(t), openTestDB(t)}
+	weights := []int{5, 3, 2}
+	repo := &URLRepository{primary: openTestDB(t), replicas: replicas, replicaTurns: weightedTurns(weights, len(replicas))}
+
+	const reads = 10000
+	counts := make(map[*sql.DB]int)
+	for i := 0; i < reads; i++ {
+		counts[repo.getReadDB(context.Background())]++
+	}
+	for i, replica := range replicas {
+		want := reads * weights[i] / 10
+		if got := counts[replica]; got < want*95/100 || got > want*105/100 {
+			t.Errorf("replica %d: Expected about %d reads, got: %d", i, want, got)
+		}
+	}
+	if counts[repo.primary] != 0 {
+		t.Errorf("Expected no reads on the primary, got: %d", counts[repo.primary])
+	}
+
+	// The heavy replica's share is interleaved, not served in one burst
+	if turns := weightedTurns([]int{2, 1}, 2); len(turns) != 3 || turns[0] != 0 || turns[1] != 1 || turns[2] != 0 {
+		t.Errorf("Expected an interleaved rotation, got: %v", turns)
+	}
+	if turns := weightedTurns([]int{1}, 2); turns != nil {
+		t.Errorf("Expected no rotation when weights don't match replicas, got: %v", turns)
+	}
+}
+
+func TestVerifySchema(t *testing.T) {
+	db := openTestDB(t)
+	if err := initSQLiteSchema(db); err != nil {
+		t.Fatalf("Failed to init schema: %v", err)
+	}
+	if err := verifySchema(db, "sqlite3"); err != nil {
+		t.Fatalf("Expected current schema to verify, got: %v", err)
+	}
+
+	if _, err := db.Exec(`ALTER TABLE urls DROP COLUMN expires_at`); err != nil {
+		t.Fatalf("Failed to drop column: %v", err)
+	}
+	err := verifySchema(db, "sqlite3")
+	if !errors.Is(err, ErrSchemaMismatch) || !strings.Contains(err.Error(), "urls is missing columns expires_at") {
+		t.Errorf("Expected missing column reported, got: %v", err)
+	}
+}
+
+func TestNewURLRepository_VerifySchemaIssuesNoDDL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "empty.db")
+	_, err := NewURLRepository(&config.DatabaseConfig{
+		Driver:       "sqlite3",
+		Path:         path,
+		MaxOpenConns: 1,
+		MaxIdleConns: 1,
+		VerifySchema: true,
+	})
+	if !errors.Is(err, ErrSchemaMismatch) || !strings.Contains(err.Error(), "table urls does not exist") {
+		t.Fatalf("Expected missing tables reported, got: %v", err)
+	}
+
+	// The failed start must not have created anything
+	db, err := openSQLite(path, 1, 1)
+	if err != nil {
+		t.Fatalf("Failed to reopen database: %v", err)
+	}
+	defer db.Close()
+	var tables int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table'`).Scan(&tables); err != nil {
+		t.Fatalf("Failed to count tables: %v", err)
+	}
+	if tables != 0 {
+		t.Errorf("Expected no tables created, got: %d", tables)
+	}
+}
@@ -0,0 +1,257 @@
+package service
+
+import (
+	"context"
+	"time"
+	"unicode/utf8"
+
+	"github.com/darkodi/url-shortener/internal/model"
+)
+
+// doNotTrackKey marks a request context whose client sent DNT: 1
+type doNotTrackKey struct{}
+
+// WithDoNotTrack marks ctx as coming from a client that opted out of tracking
+func WithDoNotTrack(ctx context.Context) context.Context {
+	return context.WithValue(ctx, doNotTrackKey{}, true)
+}
+
+func isDoNotTrack(ctx context.Context) bool {
+	dnt, _ := ctx.Value(doNotTrackKey{}).(bool)
+	return dnt
+}
+
+// skipClickKey marks a request context whose resolve must not be counted
+type skipClickKey struct{}
+
+// WithoutClick marks ctx as a lookup rather than a visit, so resolving
+// doesn't touch click counts or click rows
+func WithoutClick(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipClickKey{}, true)
+}
+
+func isSkipClick(ctx context.Context) bool {
+	skip, _ := ctx.Value(skipClickKey{}).(bool)
+	return skip
+}
+
+// ifModifiedSinceKey carries the If-Modified-Since time of a redirect request
+type ifModifiedSinceKey struct{}
+
+// WithIfModifiedSince makes a resolve through ctx return ErrNotModified,
+// without counting a click, when the link was created at or before t
+func WithIfModifiedSince(ctx context.Context, t time.Time) context.Context {
+	return context.WithValue(ctx, ifModifiedSinceKey{}, t)
+}
+
+func ifModifiedSince(ctx context.Context) (time.Time, bool) {
+	t, ok := ctx.Value(ifModifiedSinceKey{}).(time.Time)
+	return t, ok
+}
+
+// admissionKey carries the check a resolve runs on the codes it finds
+type admissionKey struct{}
+
+// WithAdmission makes a resolve through ctx call admit with the stored
+// code once the code is known to resolve, before the click is counted.
+// An error from admit is returned by the resolve, which then counts
+// nothing. Codes that don't resolve never reach admit.
+func WithAdmission(ctx context.Context, admit func(ctx context.Context, shortCode string) error) context.Context {
+	return context.WithValue(ctx, admissionKey{}, admit)
+}
+
+func admit(ctx context.Context, shortCode string) error {
+	check, _ := ctx.Value(admissionKey{}).(func(context.Context, string) error)
+	if check == nil {
+		return nil
+	}
+	return check(ctx, shortCode)
+}
+
+// notModified reports whether a conditional resolve may answer "not
+// modified" for record. HTTP dates have whole seconds, so CreatedAt is
+// compared truncated. Delayed links render a fresh countdown every time.
+func notModified(ctx context.Context, record *model.URL) bool {
+	since, ok := ifModifiedSince(ctx)
+	if !ok || record.DelaySeconds > 0 || record.CreatedAt.IsZero() {
+		return false
+	}
+	return !record.CreatedAt.Truncate(time.Second).After(since)
+}
+
+// clickWeightKey carries a non-default click increment for a request
+type clickWeightKey struct{}
+
+// WithClickWeight makes a resolve through ctx add n to the click count
+// instead of 1, e.g. to weigh API-triggered resolves differently.
+// Detailed click rows are still recorded once.
+func WithClickWeight(ctx context.Context, n uint64) context.Context {
+	return context.WithValue(ctx, clickWeightKey{}, n)
+}
+
+func clickWeight(ctx context.Context) uint64 {
+	if n, ok := ctx.Value(clickWeightKey{}).(uint64); ok && n > 0 {
+		return n
+	}
+	return 1
+}
+
+// Longest referrer and User-Agent stored on a click row; longer values are cut
+const (
+	maxClickReferrer  = 2048
+	maxClickUserAgent = 512
+	maxClickClientIP  = 64
+)
+
+// ClickMeta describes the visit behind a resolve, stored on click rows.
+// Fields the request didn't send are stored empty.
+type ClickMeta struct {
+	Referrer  string
+	UserAgent string
+	ClientIP  string
+}
+
+// clickMetaKey carries the ClickMeta of the request being resolved
+type clickMetaKey struct{}
+
+// WithClickMeta attaches the visit's referrer, User-Agent and IP to ctx
+func WithClickMeta(ctx context.Context, meta ClickMeta) context.Context {
+	return context.WithValue(ctx, clickMetaKey{}, meta)
+}
+
+func clickMeta(ctx context.Context) ClickMeta {
+	meta, _ := ctx.Value(clickMetaKey{}).(ClickMeta)
+	return meta
+}
+
+// truncate cuts s to at most n bytes without splitting a rune, since
+// Postgres rejects a row holding invalid UTF-8
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// WithClickEvents enables storing a detailed row per click in addition
+// to the aggregate click count
+func (s *URLService) WithClickEvents(enabled bool) *URLService {
+	s.recordClickEvents = enabled
+	return s
+}
+
+// WithClickClientIP controls whether click rows keep the visitor's IP
+// address; when store is false it is left empty
+func (s *URLService) WithClickClientIP(store bool) *URLService {
+	s.omitClickIP = !store
+	return s
+}
+
+// WithDoNotTrackPolicy controls how DNT requests are recorded.
+// When honor is set, detailed click rows are skipped for DNT requests;
+// countAggregate decides whether they still bump the click count.
+func (s *URLService) WithDoNotTrackPolicy(honor, countAggregate bool) *URLService {
+	s.honorDNT = honor
+	s.dntCountAggregate = countAggregate
+	return s
+}
+
+// recordClick updates analytics for a successful resolve; webhookURL is
+// the link's own webhook, if it has one. Failures are ignored so
+// analytics never break redirects.
+func (s *URLService) recordClick(ctx context.Context, shortCode, webhookURL string) {
+	if s.readOnly || isSkipClick(ctx) {
+		return
+	}
+
+	dnt := s.honorDNT && isDoNotTrack(ctx)
+
+	if !dnt || s.dntCountAggregate {
+		_ = s.incrementClicks(ctx, shortCode, clickWeight(ctx))
+	}
+
+	if s.webhooks != nil && !dnt {
+		s.webhooks.enqueue(WebhookEvent{Event: "click", ShortCode: shortCode, ClickedAt: time.Now().UTC(), target: webhookURL})
+	}
+
+	if s.recordClickEvents && !dnt {
+		meta := clickMeta(ctx)
+		if s.omitClickIP {
+			meta.ClientIP = ""
+		}
+		s.storeClickEvent(&model.Click{
+			ShortCode: shortCode,
+			ClickedAt: time.Now().UTC(),
+			Referrer:  truncate(meta.Referrer, maxClickReferrer),
+			UserAgent: truncate(meta.UserAgent, maxClickUserAgent),
+			ClientIP:  truncate(meta.ClientIP, maxClickClientIP),
+		})
+	}
+}
+
+// GetClicksByDay returns a code's click rows counted per UTC day, one
+// entry for every day from since through today, oldest first. Only clicks
+// stored while click events are enabled (and not yet pruned) are counted.
+func (s *URLService) GetClicksByDay(shortCode string, since time.Time) ([]model.DailyClicks, error) {
+	since = since.UTC().Truncate(24 * time.Hour)
+	counts, err := s.repo.GetClicksByDay(s.normalizeCode(shortCode), since)
+	if err != nil {
+		return nil, err
+	}
+
+	var days []model.DailyClicks
+	for day := since; !day.After(time.Now().UTC()); day = day.AddDate(0, 0, 1) {
+		date := day.Format(time.DateOnly)
+		days = append(days, model.DailyClicks{Date: date, Clicks: counts[date]})
+	}
+	return days, nil
+}
+
+// WithMaxClickRows caps the detailed click rows kept per code. Older rows
+// are deleted oldest-first during FlushClicks; the aggregate count is
+// unaffected. Zero keeps every row.
+func (s *URLService) WithMaxClickRows(max int) *URLService {
+	s.maxClickRows = max
+	return s
+}
+
+// markForPruning remembers a code that gained click rows since the last flush
+func (s *URLService) markForPruning(shortCode string) {
+	if s.maxClickRows <= 0 {
+		return
+	}
+
+	s.pruneMu.Lock()
+	defer s.pruneMu.Unlock()
+	if s.prunePending == nil {
+		s.prunePending = make(map[string]struct{})
+	}
+	s.prunePending[shortCode] = struct{}{}
+}
+
+// pruneClickRows trims click rows for every code touched since the last
+// flush. Codes that fail to prune are retried next time.
+func (s *URLService) pruneClickRows() error {
+	if s.maxClickRows <= 0 {
+		return nil
+	}
+
+	s.pruneMu.Lock()
+	pending := s.prunePending
+	s.prunePending = nil
+	s.pruneMu.Unlock()
+
+	var firstErr error
+	for shortCode := range pending {
+		if _, err := s.repo.PruneClicks(shortCode, s.maxClickRows); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			s.markForPruning(shortCode)
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+
+	"github.com/darkodi/url-shortener/internal/model"
+)
+
+// BatchResult is the outcome of one item of CreateShortURLBatch: Response
+// on success, otherwise Err, which is any error CreateShortURL returns
+type BatchResult struct {
+	Response *model.CreateURLResponse
+	Err      error
+}
+
+// CreateShortURLBatch creates many links in one database transaction.
+// Results line up with reqs; more than MaxBatchSize items is
+// ErrBatchTooLarge. An item that fails validation or whose alias
+// is taken fails alone; the rest are still created.
+func (s *URLService) CreateShortURLBatch(reqs []model.CreateURLRequest) ([]BatchResult, error) {
+	if len(reqs) > MaxBatchSize {
+		return nil, ErrBatchTooLarge
+	}
+
+	results := make([]BatchResult, len(reqs))
+	if s.readOnly {
+		for i := range results {
+			results[i].Err = ErrReadOnly
+		}
+		return results, nil
+	}
+
+	records := make([]*model.URL, len(reqs))
+	generated := make([]bool, len(reqs)) // no alias asked for; the code comes from the ID
+	var pending []*model.URL
+	for i, req := range reqs {
+		record, existing, err := s.newURLRecord(context.Background(), req)
+		switch {
+		case err != nil:
+			results[i].Err = err
+		case existing != nil:
+			results[i].Response = existing
+		default:
+			records[i] = record
+			generated[i] = record.ShortCode == ""
+			pending = append(pending, record)
+		}
+	}
+	if len(pending) == 0 {
+		return results, nil
+	}
+
+	// Rows without an alias get codes from their assigned IDs
+	if err := s.repo.CreateBatch(pending, s.encodeID); err != nil {
+		return nil, err
+	}
+
+	for i, record := range records {
+		if record == nil {
+			continue
+		}
+		if record.ID == 0 && generated[i] {
+			// The ID's code was already an alias; that collision is this
+			// row's, not the alias owner's, so retry it with fresh IDs
+			if err := s.createGenerated(context.Background(), record, generatedCodeAttempts-1); err != nil {
+				results[i].Err = err
+				continue
+			}
+		}
+		if record.ID == 0 {
+			results[i].Err = ErrAliasExists // taken, possibly earlier in the batch
+			continue
+		}
+		results[i].Response = s.finishCreate(record, reqs[i].Region)
+	}
+	return results, nil
+}
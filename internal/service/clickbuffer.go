@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/darkodi/url-shortener/internal/metrics"
+)
+
+// defaultClickFlushBatch bounds each flush transaction unless configured
+const defaultClickFlushBatch = 500
+
+// ClickBuffer holds click counts outside the database until they are
+// flushed. *cache.RedisCache satisfies it.
+type ClickBuffer interface {
+	IncrClicks(ctx context.Context, shortCode string, delta uint64) error
+	PendingClicks(ctx context.Context, shortCode string) (uint64, error)
+	DrainClicks(ctx context.Context) (map[string]uint64, error)
+}
+
+// WithClickBuffer moves click counting off the database hot path.
+// Counts accumulate in buf and are written by FlushClicks.
+func (s *URLService) WithClickBuffer(buf ClickBuffer) *URLService {
+	s.clickBuffer = buf
+	return s
+}
+
+// WithClickFlushBatchSize caps how many codes one flush transaction
+// updates; larger backlogs are written in several transactions
+func (s *URLService) WithClickFlushBatchSize(n int) *URLService {
+	if n > 0 {
+		s.clickFlushBatch = n
+	}
+	return s
+}
+
+// incrementClicks adds n to the click count, through the buffer when one is
+// configured. Falls back to a direct write if the buffer is unavailable,
+// and queues the increment for retry if that fails too.
+func (s *URLService) incrementClicks(ctx context.Context, shortCode string, n uint64) error {
+	if s.clickBuffer != nil {
+		if err := s.clickBuffer.IncrClicks(ctx, shortCode, n); err == nil {
+			return nil
+		}
+		metrics.Degradations.Inc(metrics.ClickBuffer)
+	}
+	// The redirect has already been decided; a client hanging up must not
+	// cancel the count
+	err := s.repo.IncrementClickCountByContext(context.WithoutCancel(ctx), shortCode, n)
+	if err != nil && s.queueClickRetry(clickRetry{shortCode: shortCode, delta: n, attempts: 1}) {
+		return nil
+	}
+	return err
+}
+
+// pendingClicks returns buffered clicks not yet written to the database
+func (s *URLService) pendingClicks(ctx context.Context, shortCode string) uint64 {
+	if s.clickBuffer == nil {
+		return 0
+	}
+	pending, err := s.clickBuffer.PendingClicks(ctx, shortCode)
+	if err != nil {
+		return 0
+	}
+	return pending
+}
+
+// FlushClicks writes buffered click counts to the database in transactions
+// of up to the flush batch size, then prunes click rows beyond the
+// configured cap. Counts in a batch that fails to write are put back for
+// the next flush.
+// Returns the number of codes whose counts were flushed.
+func (s *URLService) FlushClicks(ctx context.Context) (int, error) {
+	flushed, err := s.flushClickCounts(ctx)
+	if pruneErr := s.pruneClickRows(); err == nil {
+		err = pruneErr
+	}
+	return flushed, err
+}
+
+func (s *URLService) flushClickCounts(ctx context.Context) (int, error) {
+	if s.clickBuffer == nil {
+		return 0, nil
+	}
+
+	drained, err := s.clickBuffer.DrainClicks(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var firstErr error
+	flushed := 0
+	batch := make(map[string]uint64, min(len(drained), s.clickFlushBatch))
+	write := func() {
+		if err := s.repo.AddClickCounts(batch); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			for shortCode, count := range batch {
+				_ = s.clickBuffer.IncrClicks(ctx, shortCode, count)
+			}
+		} else {
+			flushed += len(batch)
+		}
+		clear(batch)
+	}
+	for shortCode, count := range drained {
+		batch[shortCode] = count
+		if len(batch) == s.clickFlushBatch {
+			write()
+		}
+	}
+	if len(batch) > 0 {
+		write()
+	}
+	return flushed, firstErr
+}
+
+// RunClickFlusher flushes buffered clicks and prunes click rows every
+// interval until ctx is cancelled, then flushes once more so nothing is
+// left behind on shutdown.
+func (s *URLService) RunClickFlusher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := s.FlushClicks(ctx); err != nil {
+				fmt.Printf("Warning: failed to flush click counts: %v\n", err)
+			}
+		case <-ctx.Done():
+			if _, err := s.FlushClicks(context.Background()); err != nil {
+				fmt.Printf("Warning: failed to flush click counts on shutdown: %v\n", err)
+			}
+			return
+		}
+	}
+}
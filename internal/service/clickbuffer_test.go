@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+	"unicode"
+
+	"github.com/darkodi/url-shortener/internal/metrics"
+	"github.com/darkodi/url-shortener/internal/model"
+	"github.com/darkodi/url-shortener/internal/repository"
+)
+
+// fakeClickBuffer stands in for Redis in write-behind tests
+type fakeClickBuffer struct {
+	mu      sync.Mutex
+	pending map[string]uint64
+	err     error
+}
+
+func newFakeClickBuffer() *fakeClickBuffer {
+	return &fakeClickBuffer{pending: make(map[string]uint64)}
+}
+
+func (f *fakeClickBuffer) IncrClicks(ctx context.Context, shortCode string, delta uint64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return f.err
+	}
+	f.pending[shortCode] += delta
+	return nil
+}
+
+func (f *fakeClickBuffer) PendingClicks(ctx context.Context, shortCode string) (uint64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.pending[shortCode], f.err
+}
+
+func (f *fakeClickBuffer) DrainClicks(ctx context.Context) (map[string]uint64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return nil, f.err
+	}
+	drained := f.pending
+	f.pending = make(map[string]uint64)
+	return drained, nil
+}
+
+func TestClickBuffer_StatsPendingUnderNormalizedCode(t *testing.T) {
+	buf := newFakeClickBuffer()
+	table := &unicode.RangeTable{R16: []unicode.Range16{{Lo: 0x00C0, Hi: 0x024F, Stride: 1}}}
+	svc := setupTestService(t).WithClickBuffer(buf).WithUnicodeAliases(table)
+
+	_, _ = svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com", CustomAlias: "caf\u00e9"})
+	if _, err := svc.Resolve("cafe\u0301"); err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+
+	// Buffered under the stored NFC code, whichever spelling asks
+	stats, err := svc.GetURLStats("cafe\u0301")
+	if err != nil {
+		t.Fatalf("GetURLStats failed: %v", err)
+	}
+	if stats.ClickCount != 1 {
+		t.Errorf("Expected the pending click counted, got: %d", stats.ClickCount)
+	}
+}
+
+func TestClickBuffer_StatsIncludePending(t *testing.T) {
+	buf := newFakeClickBuffer()
+	svc := setupTestService(t).WithClickBuffer(buf)
+
+	_, _ = svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com", CustomAlias: "buffered"})
+	for i := 0; i < 3; i++ {
+		if _, err := svc.Resolve("buffered"); err != nil {
+			t.Fatalf("Resolve failed: %v", err)
+		}
+	}
+
+	stored, _ := svc.repo.GetByShortCode("buffered")
+	if stored.ClickCount != 0 {
+		t.Errorf("Expected no clicks written to DB yet, got: %d", stored.ClickCount)
+	}
+
+	stats, err := svc.GetURLStats("buffered")
+	if err != nil {
+		t.Fatalf("GetURLStats failed: %v", err)
+	}
+	if stats.ClickCount != 3 {
+		t.Errorf("Expected click count 3 including pending, got: %d", stats.ClickCount)
+	}
+
+	flushed, err := svc.FlushClicks(context.Background())
+	if err != nil || flushed != 1 {
+		t.Fatalf("Expected 1 code flushed, got: %d, %v", flushed, err)
+	}
+
+	stored, _ = svc.repo.GetByShortCode("buffered")
+	if stored.ClickCount != 3 {
+		t.Errorf("Expected 3 clicks in DB after flush, got: %d", stored.ClickCount)
+	}
+	stats, _ = svc.GetURLStats("buffered")
+	if stats.ClickCount != 3 {
+		t.Errorf("Expected click count 3 after flush, got: %d", stats.ClickCount)
+	}
+}
+
+func TestClickBuffer_FlushOnInterval(t *testing.T) {
+	buf := newFakeClickBuffer()
+	svc := setupTestService(t).WithClickBuffer(buf)
+
+	_, _ = svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com", CustomAlias: "ticker"})
+	_, _ = svc.Resolve("ticker")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		svc.RunClickFlusher(ctx, 10*time.Millisecond)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		stored, _ := svc.repo.GetByShortCode("ticker")
+		if stored.ClickCount == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected flusher to write 1 click, got: %d", stored.ClickCount)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Clicks recorded after the last tick are flushed on shutdown
+	_, _ = svc.Resolve("ticker")
+	cancel()
+	<-done
+
+	stored, _ := svc.repo.GetByShortCode("ticker")
+	if stored.ClickCount != 2 {
+		t.Errorf("Expected 2 clicks after shutdown flush, got: %d", stored.ClickCount)
+	}
+}
+
+func TestClickBuffer_FallsBackToDB(t *testing.T) {
+	buf := newFakeClickBuffer()
+	buf.err = context.DeadlineExceeded
+	svc := setupTestService(t).WithClickBuffer(buf)
+
+	_, _ = svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com", CustomAlias: "fallback"})
+	before := metrics.Degradations.Get(metrics.ClickBuffer)
+	_, _ = svc.Resolve("fallback")
+
+	if got := metrics.Degradations.Get(metrics.ClickBuffer) - before; got != 1 {
+		t.Errorf("Expected click buffer fallback counted once, got: %d", got)
+	}
+
+	stored, _ := svc.repo.GetByShortCode("fallback")
+	if stored.ClickCount != 1 {
+		t.Errorf("Expected direct DB write when buffer fails, got: %d", stored.ClickCount)
+	}
+}
+
+// batchRecordingRepo records the size of every AddClickCounts call
+type batchRecordingRepo struct {
+	repository.Repository
+	mu      sync.Mutex
+	batches []int
+}
+
+func (r *batchRecordingRepo) AddClickCounts(counts map[string]uint64) error {
+	r.mu.Lock()
+	r.batches = append(r.batches, len(counts))
+	r.mu.Unlock()
+	return r.Repository.AddClickCounts(counts)
+}
+
+func TestClickBuffer_FlushesInBoundedBatches(t *testing.T) {
+	base := setupTestService(t)
+	repo := &batchRecordingRepo{Repository: base.repo}
+	buf := newFakeClickBuffer()
+	svc := NewURLService(repo, "http://localhost:8080", nil).WithClickBuffer(buf).WithClickFlushBatchSize(10)
+
+	const codes = 25
+	for i := 0; i < codes; i++ {
+		code := fmt.Sprintf("code%02d", i)
+		if _, err := svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com", CustomAlias: code}); err != nil {
+			t.Fatalf("Failed to create %s: %v", code, err)
+		}
+		_ = buf.IncrClicks(context.Background(), code, uint64(i+1))
+	}
+
+	flushed, err := svc.FlushClicks(context.Background())
+	if err != nil || flushed != codes {
+		t.Fatalf("Expected %d codes flushed, got: %d, %v", codes, flushed, err)
+	}
+	if len(repo.batches) != 3 {
+		t.Fatalf("Expected 3 batches for %d codes, got: %v", codes, repo.batches)
+	}
+	total := 0
+	for _, size := range repo.batches {
+		if size > 10 {
+			t.Errorf("Expected batches of at most 10 codes, got: %v", repo.batches)
+		}
+		total += size
+	}
+	if total != codes {
+		t.Errorf("Expected every code written once, got: %v", repo.batches)
+	}
+
+	stored, _ := svc.repo.GetByShortCode("code24")
+	if stored.ClickCount != 25 {
+		t.Errorf("Expected 25 clicks for code24, got: %d", stored.ClickCount)
+	}
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/darkodi/url-shortener/internal/metrics"
+	"github.com/darkodi/url-shortener/internal/model"
+)
+
+// WithClickEventQueue writes detailed click rows in the background so the
+// insert stays off the redirect path. At most queueSize rows wait at once;
+// when the queue is full a row is dropped and counted rather than written
+// on the request path.
+// RunClickEventWriter must be running for queued rows to be written.
+func (s *URLService) WithClickEventQueue(queueSize int) *URLService {
+	if queueSize > 0 {
+		s.clickEvents = make(chan *model.Click, queueSize)
+	}
+	return s
+}
+
+// storeClickEvent queues a click row, or writes it now without a queue
+func (s *URLService) storeClickEvent(click *model.Click) {
+	if s.clickEvents == nil {
+		s.writeClickEvent(click)
+		return
+	}
+	select {
+	case s.clickEvents <- click:
+	default:
+		metrics.Drops.Inc(metrics.ClickEventQueue)
+	}
+}
+
+func (s *URLService) writeClickEvent(click *model.Click) {
+	if err := s.repo.RecordClick(click); err != nil {
+		fmt.Printf("Warning: failed to record click for %s: %v\n", click.ShortCode, err)
+		return
+	}
+	s.markForPruning(click.ShortCode)
+}
+
+// RunClickEventWriter writes queued click rows until ctx is cancelled,
+// then writes whatever is still queued
+func (s *URLService) RunClickEventWriter(ctx context.Context) {
+	for {
+		select {
+		case click := <-s.clickEvents:
+			s.writeClickEvent(click)
+		case <-ctx.Done():
+			for {
+				select {
+				case click := <-s.clickEvents:
+					s.writeClickEvent(click)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
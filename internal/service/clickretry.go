@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/darkodi/url-shortener/internal/metrics"
+)
+
+// clickRetry is a click increment that failed and is waiting to be retried
+type clickRetry struct {
+	shortCode string
+	delta     uint64
+	attempts  int
+	notBefore time.Time // backoff ends; queued increments are retried after it
+}
+
+// WithClickRetry queues failed click increments for a background retry
+// instead of dropping them. At most queueSize increments wait at once;
+// each is retried up to maxAttempts times, at least backoff apart.
+// RunClickRetrier must be running for queued increments to be written.
+func (s *URLService) WithClickRetry(queueSize, maxAttempts int, backoff time.Duration) *URLService {
+	s.clickRetries = make(chan clickRetry, queueSize)
+	s.clickRetryMax = maxAttempts
+	s.clickRetryBackoff = backoff
+	return s
+}
+
+// queueClickRetry hands a failed increment to the retrier without blocking
+// the redirect, due once the backoff has passed. Reports false if retries
+// are off or the queue is full; drops are counted, not logged, since they
+// come in bursts during the outages that fill the queue.
+func (s *URLService) queueClickRetry(retry clickRetry) bool {
+	if s.clickRetries == nil {
+		return false
+	}
+	retry.notBefore = time.Now().Add(s.clickRetryBackoff)
+	select {
+	case s.clickRetries <- retry:
+		return true
+	default:
+		metrics.Drops.Inc(metrics.ClickRetryQueue)
+		return false
+	}
+}
+
+// RunClickRetrier retries queued click increments until ctx is cancelled,
+// then makes one last attempt at whatever is still queued. The queue is in
+// due order, so the retrier only waits for increments whose backoff hasn't
+// passed yet.
+func (s *URLService) RunClickRetrier(ctx context.Context) {
+	for {
+		select {
+		case retry := <-s.clickRetries:
+			if wait := time.Until(retry.notBefore); wait > 0 {
+				select {
+				case <-time.After(wait):
+				case <-ctx.Done():
+					s.retryOnShutdown(retry)
+					s.drainClickRetries()
+					return
+				}
+			}
+			s.retryClick(retry)
+		case <-ctx.Done():
+			s.drainClickRetries()
+			return
+		}
+	}
+}
+
+// retryClick makes one attempt and requeues the increment if it fails again
+func (s *URLService) retryClick(retry clickRetry) {
+	if err := s.repo.IncrementClickCountByContext(context.Background(), retry.shortCode, retry.delta); err == nil {
+		return
+	}
+	retry.attempts++
+	if retry.attempts >= s.clickRetryMax {
+		fmt.Printf("Warning: giving up on click for %s after %d attempts\n", retry.shortCode, retry.attempts)
+		return
+	}
+	s.queueClickRetry(retry)
+}
+
+// drainClickRetries makes a single attempt at every queued increment
+func (s *URLService) drainClickRetries() {
+	for {
+		select {
+		case retry := <-s.clickRetries:
+			s.retryOnShutdown(retry)
+		default:
+			return
+		}
+	}
+}
+
+// retryOnShutdown makes a final attempt at an increment
+func (s *URLService) retryOnShutdown(retry clickRetry) {
+	if err := s.repo.IncrementClickCountByContext(context.Background(), retry.shortCode, retry.delta); err != nil {
+		fmt.Printf("Warning: dropping click for %s on shutdown: %v\n", retry.shortCode, err)
+	}
+}
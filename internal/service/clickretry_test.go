@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/darkodi/url-shortener/internal/metrics"
+	"github.com/darkodi/url-shortener/internal/model"
+	"github.com/darkodi/url-shortener/internal/repository"
+)
+
+// flakyRepo fails the first failures click increments, then recovers
+type flakyRepo struct {
+	repository.Repository
+	failures int32
+}
+
+func (f *flakyRepo) IncrementClickCountByContext(ctx context.Context, shortCode string, n uint64) error {
+	if atomic.AddInt32(&f.failures, -1) >= 0 {
+		return errors.New("connection reset")
+	}
+	return f.Repository.IncrementClickCountByContext(ctx, shortCode, n)
+}
+
+func TestClickRetry_EventuallyPersists(t *testing.T) {
+	base := setupTestService(t)
+	_, _ = base.CreateShortURL(model.CreateURLRequest{URL: "https://example.com", CustomAlias: "blip"})
+
+	repo := &flakyRepo{Repository: base.repo, failures: 3}
+	svc := NewURLService(repo, "http://localhost:8080", nil).WithClickRetry(10, 5, time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		svc.RunClickRetrier(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// Two clicks fail on the hot path; one retry fails again
+	for i := 0; i < 2; i++ {
+		if _, err := svc.Resolve("blip"); err != nil {
+			t.Fatalf("Resolve failed: %v", err)
+		}
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		stored, _ := base.repo.GetByShortCode("blip")
+		if stored.ClickCount == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected both clicks to persist after retry, got: %d", stored.ClickCount)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestClickRetry_GivesUpAfterMaxAttempts(t *testing.T) {
+	base := setupTestService(t)
+	_, _ = base.CreateShortURL(model.CreateURLRequest{URL: "https://example.com", CustomAlias: "down"})
+
+	repo := &flakyRepo{Repository: base.repo, failures: 1000}
+	svc := NewURLService(repo, "http://localhost:8080", nil).WithClickRetry(10, 3, time.Millisecond)
+
+	_, _ = svc.Resolve("down")
+	retry := <-svc.clickRetries
+	svc.retryClick(retry)
+	retry = <-svc.clickRetries
+	if retry.attempts != 2 {
+		t.Fatalf("Expected 2 attempts so far, got: %d", retry.attempts)
+	}
+	svc.retryClick(retry)
+	if len(svc.clickRetries) != 0 {
+		t.Errorf("Expected increment dropped after 3 attempts, queue has: %d", len(svc.clickRetries))
+	}
+}
+
+func TestClickRetry_BoundedQueue(t *testing.T) {
+	base := setupTestService(t)
+	_, _ = base.CreateShortURL(model.CreateURLRequest{URL: "https://example.com", CustomAlias: "full"})
+
+	repo := &flakyRepo{Repository: base.repo, failures: 1000}
+	svc := NewURLService(repo, "http://localhost:8080", nil).WithClickRetry(2, 5, time.Millisecond)
+
+	before := metrics.Drops.Get(metrics.ClickRetryQueue)
+	for i := 0; i < 5; i++ {
+		_, _ = svc.Resolve("full")
+	}
+	if len(svc.clickRetries) != 2 {
+		t.Errorf("Expected queue capped at 2, got: %d", len(svc.clickRetries))
+	}
+	if n := metrics.Drops.Get(metrics.ClickRetryQueue) - before; n != 3 {
+		t.Errorf("Expected 3 drops counted, got: %d", n)
+	}
+}
+
+func TestClickRetry_DueIncrementsRetriedTogether(t *testing.T) {
+	base := setupTestService(t)
+	_, _ = base.CreateShortURL(model.CreateURLRequest{URL: "https://example.com", CustomAlias: "burst"})
+
+	const clicks = 10
+	backoff := 100 * time.Millisecond
+	repo := &flakyRepo{Repository: base.repo, failures: clicks}
+	svc := NewURLService(repo, "http://localhost:8080", nil).WithClickRetry(clicks, 5, backoff)
+	for i := 0; i < clicks; i++ {
+		_, _ = svc.Resolve("burst")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		svc.RunClickRetrier(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// Waiting a backoff per increment would take clicks*backoff
+	deadline := time.Now().Add(clicks * backoff / 2)
+	for {
+		stored, _ := base.repo.GetByShortCode("burst")
+		if stored.ClickCount == clicks {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected every due increment retried within one backoff, got: %d", stored.ClickCount)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
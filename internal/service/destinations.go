@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// defaultDestinationQueue bounds how many destinations wait for a probe
+const defaultDestinationQueue = 1000
+
+// defaultDestinationResults bounds how many probe results are remembered
+const defaultDestinationResults = 10000
+
+// Prober reports whether a destination currently answers
+type Prober interface {
+	Probe(ctx context.Context, rawURL string) bool
+}
+
+// HTTPProber probes destinations over HTTP. A destination is healthy if
+// HEAD, or GET for servers that reject HEAD, answers below 400. Redirects
+// aren't followed; a redirect counts as an answer.
+type HTTPProber struct {
+	client *http.Client
+}
+
+// NewHTTPProber probes with a copy of client; pass outbound.NewPublicClient
+// so probes share the outbound TLS and timeout settings and can't be
+// pointed at internal addresses
+func NewHTTPProber(client *http.Client) *HTTPProber {
+	probeClient := *client
+	probeClient.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return &HTTPProber{client: &probeClient}
+}
+
+// Probe requests rawURL without following it into the body
+func (p *HTTPProber) Probe(ctx context.Context, rawURL string) bool {
+	for _, method := range []string{http.MethodHead, http.MethodGet} {
+		req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+		if err != nil {
+			return false
+		}
+		resp, err := p.client.Do(req)
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 400 {
+			return true
+		}
+	}
+	return false
+}
+
+// DestinationChecker remembers which destinations failed their last probe.
+// Lookups only read the cache; unknown and stale destinations are queued
+// for Run to probe in the background, so resolves never wait on the network.
+// At most maxResults results are kept. Safe for concurrent use.
+type DestinationChecker struct {
+	prober     Prober
+	ttl        time.Duration
+	maxResults int
+
+	mu      sync.Mutex
+	results map[string]destinationResult
+	queued  map[string]struct{}
+	queue   chan string
+}
+
+type destinationResult struct {
+	broken    bool
+	checkedAt time.Time
+}
+
+// NewDestinationChecker caches each probe result for ttl
+func NewDestinationChecker(prober Prober, ttl time.Duration) *DestinationChecker {
+	return &DestinationChecker{
+		prober:     prober,
+		ttl:        ttl,
+		maxResults: defaultDestinationResults,
+		results:    make(map[string]destinationResult),
+		queued:     make(map[string]struct{}),
+		queue:      make(chan string, defaultDestinationQueue),
+	}
+}
+
+// Broken reports whether rawURL failed its last probe. Destinations not
+// yet probed count as healthy until the background probe says otherwise.
+func (c *DestinationChecker) Broken(rawURL string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	result, ok := c.results[rawURL]
+	if !ok || time.Since(result.checkedAt) > c.ttl {
+		c.schedule(rawURL)
+	}
+	return result.broken
+}
+
+// schedule queues rawURL for probing unless it is already waiting or the
+// queue is full; either way a later lookup asks again. Callers hold c.mu.
+func (c *DestinationChecker) schedule(rawURL string) {
+	if _, waiting := c.queued[rawURL]; waiting {
+		return
+	}
+	select {
+	case c.queue <- rawURL:
+		c.queued[rawURL] = struct{}{}
+	default:
+	}
+}
+
+// Check probes rawURL now and caches the result
+func (c *DestinationChecker) Check(ctx context.Context, rawURL string) bool {
+	broken := !c.prober.Probe(ctx, rawURL)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.queued, rawURL)
+	// A probe cut short by shutdown says nothing about the destination
+	if ctx.Err() == nil {
+		if _, ok := c.results[rawURL]; !ok && len(c.results) >= c.maxResults {
+			c.evict()
+		}
+		c.results[rawURL] = destinationResult{broken: broken, checkedAt: time.Now()}
+	}
+	return broken
+}
+
+// evict makes room for a result: expired results go first, and if none
+// have expired an arbitrary one does, to be probed again on its next
+// lookup. Callers hold c.mu.
+func (c *DestinationChecker) evict() {
+	for rawURL, result := range c.results {
+		if time.Since(result.checkedAt) > c.ttl {
+			delete(c.results, rawURL)
+		}
+	}
+	for rawURL := range c.results {
+		if len(c.results) < c.maxResults {
+			return
+		}
+		delete(c.results, rawURL)
+	}
+}
+
+// Run probes queued destinations until ctx is cancelled
+func (c *DestinationChecker) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case rawURL := <-c.queue:
+			c.Check(ctx, rawURL)
+		}
+	}
+}
+
+// WithDestinationChecker flags resolved links whose destination failed its
+// last probe, so the redirect can warn instead of sending users to a dead page
+func (s *URLService) WithDestinationChecker(c *DestinationChecker) *URLService {
+	s.destinations = c
+	return s
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/darkodi/url-shortener/internal/model"
+)
+
+// stubProber reports every destination in dead as unreachable
+type stubProber struct {
+	mu     sync.Mutex
+	dead   map[string]bool
+	probes int
+}
+
+func (p *stubProber) Probe(ctx context.Context, rawURL string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.probes++
+	return !p.dead[rawURL]
+}
+
+func TestDestinationChecker_CachesInBackground(t *testing.T) {
+	prober := &stubProber{dead: map[string]bool{"https://gone.example.com": true}}
+	checker := NewDestinationChecker(prober, time.Hour)
+	svc := setupTestService(t).WithDestinationChecker(checker)
+	_, _ = svc.CreateShortURL(model.CreateURLRequest{URL: "https://gone.example.com", CustomAlias: "gone"})
+	_, _ = svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com", CustomAlias: "alive"})
+
+	// Nothing is probed on the resolve path, so the first visit redirects
+	link, err := svc.ResolveLinkContext(context.Background(), "gone")
+	if err != nil || link.Broken {
+		t.Fatalf("Expected an unprobed destination to count as healthy, got: %+v, %v", link, err)
+	}
+	if prober.probes != 0 {
+		t.Fatalf("Expected no probe on the resolve path, got: %d", prober.probes)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		checker.Run(ctx)
+		close(done)
+	}()
+	_, _ = svc.ResolveLinkContext(context.Background(), "alive")
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		gone, _ := svc.ResolveLinkContext(context.Background(), "gone")
+		alive, _ := svc.ResolveLinkContext(context.Background(), "alive")
+		prober.mu.Lock()
+		probes := prober.probes
+		prober.mu.Unlock()
+		if gone.Broken && !alive.Broken && probes == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected gone flagged broken after one probe each, got: %v %v, %d probes", gone.Broken, alive.Broken, probes)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	<-done
+}
+
+func TestDestinationChecker_ReprobesAfterTTL(t *testing.T) {
+	prober := &stubProber{dead: map[string]bool{"https://flaky.example.com": true}}
+	checker := NewDestinationChecker(prober, time.Millisecond)
+
+	if !checker.Check(context.Background(), "https://flaky.example.com") {
+		t.Fatal("Expected the destination to be broken")
+	}
+	prober.dead = nil
+	time.Sleep(2 * time.Millisecond)
+
+	// Stale results are served until the queued probe replaces them
+	if !checker.Broken("https://flaky.example.com") {
+		t.Error("Expected the stale result until reprobed")
+	}
+	checker.Check(context.Background(), <-checker.queue)
+	if checker.Broken("https://flaky.example.com") {
+		t.Error("Expected the recovered destination to be healthy")
+	}
+}
+
+func TestHTTPProber(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+		case "/moved":
+			// Followed, this would land on /missing
+			http.Redirect(w, r, "/missing", http.StatusFound)
+		case "/no-head":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	prober := NewHTTPProber(srv.Client())
+	for path, want := range map[string]bool{"/ok": true, "/moved": true, "/no-head": true, "/missing": false} {
+		if got := prober.Probe(context.Background(), srv.URL+path); got != want {
+			t.Errorf("Probe(%s) = %v; want %v", path, got, want)
+		}
+	}
+	srv.Close()
+	if prober.Probe(context.Background(), srv.URL+"/ok") {
+		t.Error("Expected an unreachable server to fail the probe")
+	}
+}
+
+func TestDestinationChecker_BoundsResults(t *testing.T) {
+	checker := NewDestinationChecker(&stubProber{}, time.Hour)
+	checker.maxResults = 3
+
+	for i := 0; i < 10; i++ {
+		checker.Check(context.Background(), fmt.Sprintf("https://example.com/%d", i))
+	}
+	if n := len(checker.results); n != 3 {
+		t.Errorf("Expected 3 results kept, got: %d", n)
+	}
+	if _, ok := checker.results["https://example.com/9"]; !ok {
+		t.Error("Expected the latest result kept")
+	}
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/darkodi/url-shortener/internal/cache"
+	"github.com/darkodi/url-shortener/internal/model"
+)
+
+// Readiness pings the database (primary and replicas in the read rotation)
+// and Redis, if configured. The status is unavailable when any of them
+// doesn't answer. Besides each dependency's status it reports the driver
+// and replica counts, but not error text: the endpoint is unauthenticated,
+// so why a dependency failed is logged instead.
+func (s *URLService) Readiness(ctx context.Context) *model.ReadinessResponse {
+	topology := s.repo.Topology()
+	resp := &model.ReadinessResponse{
+		Status:       model.StatusReady,
+		Driver:       topology.Driver,
+		Replicas:     topology.Replicas,
+		ReplicasDown: topology.ReplicasDown,
+		Dependencies: map[string]model.DependencyHealth{"database": dependencyHealth("database", s.repo.Ping(ctx))},
+	}
+	if s.cache != nil {
+		resp.Dependencies["cache"] = dependencyHealth("cache", s.cache.Ping(ctx))
+	}
+	for _, dep := range resp.Dependencies {
+		if dep.Status == "down" {
+			resp.Status = model.StatusUnavailable
+		}
+	}
+	return resp
+}
+
+func dependencyHealth(name string, err error) model.DependencyHealth {
+	if errors.Is(err, cache.ErrDisabled) {
+		// Serving without the cache is slower, not broken, and was already
+		// logged at startup; probes every few seconds needn't repeat it
+		return model.DependencyHealth{Status: "degraded"}
+	}
+	if err != nil {
+		fmt.Printf("Warning: readiness check for %s failed: %v\n", name, err)
+		return model.DependencyHealth{Status: "down"}
+	}
+	return model.DependencyHealth{Status: "up"}
+}
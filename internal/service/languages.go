@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"strings"
+)
+
+// MaxLanguageURLs bounds the language variants of one link
+const MaxLanguageURLs = 20
+
+// languageKey carries the language suffix of a /{code}.{lang} request
+type languageKey struct{}
+
+// WithLanguage makes a resolve through ctx prefer the link's destination
+// for lang, falling back to the default when it has none
+func WithLanguage(ctx context.Context, lang string) context.Context {
+	return context.WithValue(ctx, languageKey{}, strings.ToLower(lang))
+}
+
+func language(ctx context.Context) string {
+	lang, _ := ctx.Value(languageKey{}).(string)
+	return lang
+}
+
+// ValidLanguageTag reports whether tag is a lowercase language tag: a
+// 2-3 letter language, optionally with one subtag, e.g. "fr" or "pt-br"
+func ValidLanguageTag(tag string) bool {
+	lang, sub, hasSub := strings.Cut(tag, "-")
+	if len(lang) < 2 || len(lang) > 3 || !isLower(lang, false) {
+		return false
+	}
+	return !hasSub || (len(sub) >= 2 && len(sub) <= 8 && isLower(sub, true))
+}
+
+func isLower(s string, digits bool) bool {
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (!digits || r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// validateLanguageURLs checks the language_urls of a create request.
+// Tags are matched case-insensitively.
+func (s *URLService) validateLanguageURLs(urls map[string]string) error {
+	if len(urls) > MaxLanguageURLs {
+		return ErrInvalidLang
+	}
+	for lang, originalURL := range urls {
+		if !ValidLanguageTag(strings.ToLower(lang)) {
+			return ErrInvalidLang
+		}
+		if err := s.validateURL(originalURL); err != nil {
+			return ErrInvalidLang
+		}
+	}
+	return nil
+}
+
+// normalizeLanguageURLs lowercases the tags of validated language_urls
+func normalizeLanguageURLs(urls map[string]string) map[string]string {
+	normalized := make(map[string]string, len(urls))
+	for lang, originalURL := range urls {
+		normalized[strings.ToLower(lang)] = originalURL
+	}
+	return normalized
+}
+
+// applyLanguage swaps in the link's destination for the requested
+// language. A missing variant, or a failed lookup, keeps the default:
+// the visitor still gets a working redirect.
+func (s *URLService) applyLanguage(ctx context.Context, link *Link) {
+	lang := language(ctx)
+	if lang == "" {
+		return
+	}
+	urls, err := s.repo.GetLanguageURLs(ctx, link.ShortCode)
+	if err != nil {
+		return
+	}
+	if originalURL, ok := urls[lang]; ok {
+		link.OriginalURL = originalURL
+	}
+}
@@ -0,0 +1,23 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// LoadLegacyCodes reads a JSON object of legacy code → current code pairs,
+// e.g. {"Xk9Lq2": "abc", "old-promo": "summer"}
+func LoadLegacyCodes(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read legacy codes file: %w", err)
+	}
+
+	mapping := make(map[string]string)
+	if err := json.Unmarshal(data, &mapping); err != nil {
+		return nil, fmt.Errorf("failed to parse legacy codes file: %w", err)
+	}
+
+	return mapping, nil
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/darkodi/url-shortener/internal/encoder"
+	"github.com/darkodi/url-shortener/internal/model"
+	"github.com/darkodi/url-shortener/internal/repository"
+)
+
+// migrateBatchSize is how many rows MigrateCodes reads per query
+const migrateBatchSize = 500
+
+// CodeScheme is everything that decides which code an ID generates.
+// Changing any part of it makes existing generated codes inconsistent
+// with new ones until they are migrated.
+type CodeScheme struct {
+	Encoder   *encoder.Encoder // nil means encoder.Default
+	Sequence  encoder.Sequence
+	Checksum  bool
+	MinLength int // generated codes are left-padded to this length
+}
+
+// Encode returns the generated code for a record ID
+func (c CodeScheme) Encode(id uint64) (string, error) {
+	num, err := c.Sequence.Apply(id)
+	if err != nil {
+		return "", err
+	}
+	if c.Checksum {
+		return c.encoder().EncodePaddedWithChecksum(num, c.MinLength), nil
+	}
+	return c.encoder().EncodePadded(num, c.MinLength), nil
+}
+
+// Decode recovers the record ID from a generated code, padded or not.
+// Errors are the encoder's, so callers can tell typos from codes that were
+// never generated.
+func (c CodeScheme) Decode(code string) (uint64, error) {
+	decode := c.encoder().DecodeStrict
+	if c.Checksum {
+		decode = c.encoder().DecodeWithChecksum
+	}
+
+	num, err := decode(code)
+	if err != nil {
+		return 0, err
+	}
+	return c.Sequence.Invert(num)
+}
+
+func (c CodeScheme) encoder() *encoder.Encoder {
+	if c.Encoder == nil {
+		return encoder.Default
+	}
+	return c.Encoder
+}
+
+// codeScheme is the scheme new codes are generated with
+func (s *URLService) codeScheme() CodeScheme {
+	return CodeScheme{Encoder: s.encoder, Sequence: s.sequence, Checksum: s.codeChecksum, MinLength: s.codeMinLength}
+}
+
+// MigrateCodes re-encodes generated codes that were issued under from so
+// they match the current scheme. Each renamed code keeps a redirect from
+// its old form. Custom aliases, which don't decode to their own ID under
+// from, are left alone. A code whose new form is already in use, or is
+// some other link's old form, is reported as a conflict and not renamed,
+// so no existing link ever changes destination. With dryRun nothing is
+// written.
+func (s *URLService) MigrateCodes(ctx context.Context, from CodeScheme, dryRun bool) (*model.MigrationReport, error) {
+	if s.readOnly && !dryRun {
+		return nil, ErrReadOnly
+	}
+
+	to := s.codeScheme()
+	report := &model.MigrationReport{DryRun: dryRun, Conflicts: []string{}}
+
+	var afterID uint64
+	for {
+		batch, err := s.repo.ListURLs(afterID, migrateBatchSize)
+		if err != nil {
+			return report, err
+		}
+		if len(batch) == 0 {
+			return report, nil
+		}
+
+		for _, record := range batch {
+			afterID = record.ID
+			report.Scanned++
+
+			if id, err := from.Decode(record.ShortCode); err != nil || id != record.ID {
+				report.Skipped++
+				continue
+			}
+
+			newCode, err := to.Encode(record.ID)
+			if err != nil {
+				return report, err
+			}
+			if newCode == record.ShortCode {
+				report.Unchanged++
+				continue
+			}
+
+			if dryRun {
+				err = s.checkCodeFree(ctx, newCode)
+			} else {
+				err = s.repo.RenameShortCode(record.ShortCode, newCode)
+			}
+			if err == repository.ErrDuplicate {
+				report.Conflicts = append(report.Conflicts, record.ShortCode)
+				continue
+			}
+			if err != nil {
+				return report, err
+			}
+			if !dryRun {
+				s.evictCached(ctx, record.ShortCode)
+				s.evictCached(ctx, newCode) // may be cached as not found
+			}
+			report.Migrated++
+		}
+	}
+}
+
+// checkCodeFree mirrors RenameShortCode's conflict rule without writing
+func (s *URLService) checkCodeFree(ctx context.Context, code string) error {
+	if _, err := s.repo.GetByShortCodeContext(ctx, code); err != repository.ErrNotFound {
+		if err == nil {
+			return repository.ErrDuplicate
+		}
+		return err
+	}
+	if _, err := s.repo.GetRedirect(ctx, code); err != repository.ErrNotFound {
+		if err == nil {
+			return repository.ErrDuplicate
+		}
+		return err
+	}
+	return nil
+}
+
+// evictCached drops a code from Redis so it resolves through the database
+func (s *URLService) evictCached(ctx context.Context, shortCode string) {
+	if s.cache == nil {
+		return
+	}
+	if err := s.cache.Delete(ctx, fmt.Sprintf("url:%s", shortCode)); err != nil {
+		fmt.Printf("Warning: failed to evict cached URL: %v\n", err)
+	}
+}
@@ -0,0 +1,317 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/darkodi/url-shortener/internal/model"
+	"github.com/darkodi/url-shortener/internal/repository"
+)
+
+// mockRepo is a hand-written repository.Repository for testing service
+// logic without a database. Set err to make every call fail.
+type mockRepo struct {
+	urls   map[string]*model.URL
+	nextID uint64
+	err    error
+
+	created []string // short codes passed to Create
+	clicked []string // short codes passed to IncrementClickCount
+}
+
+func newMockRepo() *mockRepo {
+	return &mockRepo{urls: make(map[string]*model.URL), nextID: 1}
+}
+
+func (m *mockRepo) GetByShortCode(shortCode string) (*model.URL, error) {
+	return m.GetByShortCodeContext(context.Background(), shortCode)
+}
+
+func (m *mockRepo) GetByShortCodeContext(ctx context.Context, shortCode string) (*model.URL, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	url, ok := m.urls[shortCode]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return url, nil
+}
+
+func (m *mockRepo) Count() (uint64, error) {
+	return uint64(len(m.urls)), m.err
+}
+
+func (m *mockRepo) List(limit, offset int) ([]*model.URL, error) {
+	return nil, m.err
+}
+
+func (m *mockRepo) CountCreatedSince(since time.Time) (uint64, error) {
+	return uint64(len(m.urls)), m.err
+}
+
+func (m *mockRepo) CountClickEvents(shortCode string) (uint64, error) {
+	return 0, m.err
+}
+
+func (m *mockRepo) RecordClick(click *model.Click) error {
+	return m.err
+}
+
+func (m *mockRepo) Create(url *model.URL) error {
+	return m.CreateContext(context.Background(), url)
+}
+
+func (m *mockRepo) CreateContext(ctx context.Context, url *model.URL) error {
+	if m.err != nil {
+		return m.err
+	}
+	if _, exists := m.urls[url.ShortCode]; exists {
+		return repository.ErrDuplicate
+	}
+	url.ID = m.nextID
+	m.nextID++
+	if url.Status == "" {
+		url.Status = model.StatusActive
+	}
+	m.urls[url.ShortCode] = url
+	m.created = append(m.created, url.ShortCode)
+	return nil
+}
+
+func (m *mockRepo) CreateGenerated(ctx context.Context, url *model.URL, codeFor repository.CodeFunc) error {
+	if m.err != nil {
+		return m.err
+	}
+	code, err := codeFor(m.nextID)
+	if err != nil {
+		return err
+	}
+	url.ShortCode = code
+	if err := m.CreateContext(ctx, url); err != nil {
+		url.ShortCode = ""
+		return err
+	}
+	return nil
+}
+
+func (m *mockRepo) CreateBatch(urls []*model.URL, codeFor repository.CodeFunc) error {
+	for _, url := range urls {
+		var err error
+		if url.ShortCode == "" {
+			err = m.CreateGenerated(context.Background(), url, codeFor)
+		} else {
+			err = m.Create(url)
+		}
+		if err != nil && err != repository.ErrDuplicate {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *mockRepo) Activate(shortCode, originalURL string) error {
+	return m.ActivateContext(context.Background(), shortCode, originalURL)
+}
+
+func (m *mockRepo) ActivateContext(ctx context.Context, shortCode, originalURL string) error {
+	return m.err
+}
+
+func (m *mockRepo) IncrementClickCount(shortCode string) error {
+	return m.IncrementClickCountBy(shortCode, 1)
+}
+
+func (m *mockRepo) IncrementClickCountBy(shortCode string, n uint64) error {
+	return m.IncrementClickCountByContext(context.Background(), shortCode, n)
+}
+
+func (m *mockRepo) IncrementClickCountByContext(ctx context.Context, shortCode string, n uint64) error {
+	for i := uint64(0); i < n; i++ {
+		m.clicked = append(m.clicked, shortCode)
+	}
+	return m.err
+}
+
+func (m *mockRepo) AddClickCount(shortCode string, delta uint64) error {
+	return m.IncrementClickCountBy(shortCode, delta)
+}
+
+func (m *mockRepo) AddClickCounts(counts map[string]uint64) error {
+	for shortCode, delta := range counts {
+		_ = m.AddClickCount(shortCode, delta)
+	}
+	return m.err
+}
+
+func (m *mockRepo) PruneClicks(shortCode string, keep int) (int64, error) {
+	return 0, m.err
+}
+
+func (m *mockRepo) GetRedirect(ctx context.Context, oldCode string) (string, error) {
+	return "", repository.ErrNotFound
+}
+
+func (m *mockRepo) ListURLs(afterID uint64, limit int) ([]*model.URL, error) {
+	return nil, m.err
+}
+
+func (m *mockRepo) ListByOwner(owner string, limit, offset int) ([]*model.URL, error) {
+	return nil, m.err
+}
+
+func (m *mockRepo) GetAllByOriginalURL(ctx context.Context, originalURL string) ([]*model.URL, error) {
+	return nil, m.err
+}
+
+func (m *mockRepo) StatsByTag(ctx context.Context, limit int) ([]model.TagStats, error) {
+	return nil, m.err
+}
+
+func (m *mockRepo) GetClicksByDay(shortCode string, since time.Time) (map[string]int, error) {
+	return nil, m.err
+}
+
+func (m *mockRepo) TopByClicks(ctx context.Context, minClicks uint64, limit int) ([]model.LinkClicks, error) {
+	return nil, m.err
+}
+
+func (m *mockRepo) ListRecent(ctx context.Context, limit int) ([]model.RecentLink, error) {
+	return nil, m.err
+}
+
+func (m *mockRepo) GetLanguageURLs(ctx context.Context, shortCode string) (map[string]string, error) {
+	return nil, m.err
+}
+
+func (m *mockRepo) SetLanguageURLs(ctx context.Context, shortCode string, urls map[string]string) error {
+	return m.err
+}
+
+func (m *mockRepo) RenameShortCode(oldCode, newCode string) error {
+	return m.err
+}
+
+func (m *mockRepo) Delete(shortCode string) error {
+	return m.DeleteContext(context.Background(), shortCode)
+}
+
+func (m *mockRepo) DeleteContext(ctx context.Context, shortCode string) error {
+	if m.err != nil {
+		return m.err
+	}
+	if _, ok := m.urls[shortCode]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(m.urls, shortCode)
+	return nil
+}
+
+func (m *mockRepo) DeleteByShortCodes(codes []string) (int64, error) {
+	if m.err != nil {
+		return 0, m.err
+	}
+	var deleted int64
+	for _, code := range codes {
+		if _, ok := m.urls[code]; ok {
+			delete(m.urls, code)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (m *mockRepo) GetNextID() (uint64, error) {
+	return m.GetNextIDContext(context.Background())
+}
+
+func (m *mockRepo) GetNextIDContext(ctx context.Context) (uint64, error) {
+	return m.nextID, m.err
+}
+
+func (m *mockRepo) WarmUp(ctx context.Context, conns int) error {
+	return nil
+}
+
+func (m *mockRepo) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (m *mockRepo) Topology() repository.Topology {
+	return repository.Topology{Driver: "mock"}
+}
+
+func (m *mockRepo) Close() error {
+	return nil
+}
+
+func TestMockRepo_CreateUsesNextID(t *testing.T) {
+	repo := newMockRepo()
+	repo.nextID = 62 // encodes to "10"
+	svc := NewURLService(repo, "http://localhost:8080", nil)
+
+	resp, err := svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if resp.ShortURL != "http://localhost:8080/10" {
+		t.Errorf("Expected code derived from next ID, got: %s", resp.ShortURL)
+	}
+	if len(repo.created) != 1 || repo.created[0] != "10" {
+		t.Errorf("Expected Create called with 10, got: %v", repo.created)
+	}
+}
+
+func TestMockRepo_ResolveCountsClick(t *testing.T) {
+	repo := newMockRepo()
+	repo.urls["abc"] = &model.URL{ShortCode: "abc", OriginalURL: "https://example.com", Status: model.StatusActive}
+	svc := NewURLService(repo, "http://localhost:8080", nil)
+
+	original, err := svc.Resolve("abc")
+	if err != nil || original != "https://example.com" {
+		t.Fatalf("Expected https://example.com, got: %s (%v)", original, err)
+	}
+	if len(repo.clicked) != 1 {
+		t.Errorf("Expected one click recorded, got: %v", repo.clicked)
+	}
+
+	// Misses are not counted
+	if _, err := svc.Resolve("missing"); err != ErrURLNotFound {
+		t.Errorf("Expected ErrURLNotFound, got: %v", err)
+	}
+	if len(repo.clicked) != 1 {
+		t.Errorf("Expected miss not to record a click, got: %v", repo.clicked)
+	}
+}
+
+func TestMockRepo_DatabaseErrorsPropagate(t *testing.T) {
+	dbErr := errors.New("connection refused")
+	repo := newMockRepo()
+	repo.err = dbErr
+	svc := NewURLService(repo, "http://localhost:8080", nil)
+
+	if _, err := svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com"}); err != dbErr {
+		t.Errorf("CreateShortURL: expected db error, got: %v", err)
+	}
+	if _, err := svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com", CustomAlias: "mine"}); err != dbErr {
+		t.Errorf("CreateShortURL with alias: expected db error, got: %v", err)
+	}
+	if _, err := svc.Resolve("abc"); err != dbErr {
+		t.Errorf("Resolve: expected db error, got: %v", err)
+	}
+}
+
+func TestMockRepo_MalformedCodeSkipsDatabase(t *testing.T) {
+	repo := newMockRepo()
+	repo.err = errors.New("connection refused")
+	svc := NewURLService(repo, "http://localhost:8080", nil)
+
+	// The repo fails every call, so ErrURLNotFound means it was never asked
+	for _, code := range []string{"", "a.b", "ab%20c", "caf\u00e9"} {
+		if _, err := svc.Resolve(code); err != ErrURLNotFound {
+			t.Errorf("Resolve(%q): expected ErrURLNotFound, got: %v", code, err)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/darkodi/url-shortener/internal/model"
+)
+
+// createSigned makes a signed link and returns its code and exp/sig query
+func createSigned(t *testing.T, svc *URLService, alias string) (string, url.Values) {
+	t.Helper()
+	resp, err := svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com/secret", CustomAlias: alias, Signed: true})
+	if err != nil {
+		t.Fatalf("Failed to create signed link: %v", err)
+	}
+	parsed, err := url.Parse(resp.ShortURL)
+	if err != nil {
+		t.Fatalf("Failed to parse short URL %q: %v", resp.ShortURL, err)
+	}
+	return strings.TrimPrefix(parsed.Path, "/"), parsed.Query()
+}
+
+func TestSignedLinks(t *testing.T) {
+	svc := setupTestService(t).WithSignedLinks("test-secret", time.Hour)
+	code, query := createSigned(t, svc, "oneoff")
+
+	record, err := svc.repo.GetByShortCode(code)
+	if err != nil {
+		t.Fatalf("Failed to load record: %v", err)
+	}
+	expired := strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10)
+
+	tests := []struct {
+		name    string
+		expires string
+		sig     string
+		wantErr error
+	}{
+		{"valid", query.Get(SignatureExpiresParam), query.Get(SignatureParam), nil},
+		{"expired", expired, svc.signLink(record.ID, mustParseInt(t, expired)), ErrSignatureExpired},
+		{"tampered signature", query.Get(SignatureExpiresParam), strings.Repeat("0", 64), ErrSignatureInvalid},
+		{"extended expiry", strconv.FormatInt(time.Now().Add(24*time.Hour).Unix(), 10), query.Get(SignatureParam), ErrSignatureInvalid},
+		{"missing", "", "", ErrSignatureInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.sig != "" {
+				ctx = WithSignature(ctx, tt.expires, tt.sig)
+			}
+			got, err := svc.ResolveContext(ctx, code)
+			if err != tt.wantErr {
+				t.Fatalf("Expected error %v, got: %v", tt.wantErr, err)
+			}
+			if err == nil && got != "https://example.com/secret" {
+				t.Errorf("Expected destination, got: %s", got)
+			}
+		})
+	}
+}
+
+func TestSignedLinks_StatsHideDestination(t *testing.T) {
+	svc := setupTestService(t).WithSignedLinks("test-secret", time.Hour)
+	code, query := createSigned(t, svc, "private")
+	if err := svc.repo.SetLanguageURLs(context.Background(), code, map[string]string{"fr": "https://example.fr/secret"}); err != nil {
+		t.Fatalf("SetLanguageURLs failed: %v", err)
+	}
+
+	stats, err := svc.GetURLStatsContext(context.Background(), code)
+	if err != nil {
+		t.Fatalf("GetURLStats failed: %v", err)
+	}
+	if stats.OriginalURL != "" || stats.LanguageURLs != nil {
+		t.Errorf("Expected destinations hidden without a signature, got: %q, %v", stats.OriginalURL, stats.LanguageURLs)
+	}
+	if !stats.Signed || stats.ShortCode != code {
+		t.Errorf("Expected the rest of the stats, got: %+v", stats)
+	}
+
+	ctx := WithSignature(context.Background(), query.Get(SignatureExpiresParam), query.Get(SignatureParam))
+	stats, err = svc.GetURLStatsContext(ctx, code)
+	if err != nil {
+		t.Fatalf("GetURLStats failed: %v", err)
+	}
+	if stats.OriginalURL != "https://example.com/secret" || stats.LanguageURLs["fr"] != "https://example.fr/secret" {
+		t.Errorf("Expected destinations with a valid signature, got: %q, %v", stats.OriginalURL, stats.LanguageURLs)
+	}
+}
+
+func TestSignedLinks_UnsignedUnaffected(t *testing.T) {
+	svc := setupTestService(t).WithSignedLinks("test-secret", time.Hour)
+
+	_, _ = svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com", CustomAlias: "public"})
+	if _, err := svc.Resolve("public"); err != nil {
+		t.Errorf("Expected unsigned link to resolve without a signature, got: %v", err)
+	}
+}
+
+func TestSignedLinks_Disabled(t *testing.T) {
+	svc := setupTestService(t)
+
+	_, err := svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com", Signed: true})
+	if err != ErrSigningDisabled {
+		t.Errorf("Expected ErrSigningDisabled without a secret, got: %v", err)
+	}
+}
+
+func mustParseInt(t *testing.T, s string) int64 {
+	t.Helper()
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		t.Fatalf("Failed to parse %q: %v", s, err)
+	}
+	return n
+}
@@ -4,151 +4,1148 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math/rand/v2"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
 
 	"github.com/darkodi/url-shortener/internal/cache"
 	"github.com/darkodi/url-shortener/internal/encoder"
+	"github.com/darkodi/url-shortener/internal/metrics"
 	"github.com/darkodi/url-shortener/internal/model"
 	"github.com/darkodi/url-shortener/internal/repository"
 )
 
 // Custom errors for the service layer
 var (
-	ErrInvalidURL   = errors.New("invalid URL format")
-	ErrEmptyURL     = errors.New("URL cannot be empty")
-	ErrAliasExists  = errors.New("custom alias already taken")
-	ErrInvalidAlias = errors.New("alias contains invalid characters")
-	ErrURLNotFound  = errors.New("short URL not found")
+	ErrInvalidURL    = errors.New("invalid URL format")
+	ErrEmptyURL      = errors.New("URL cannot be empty")
+	ErrAliasExists   = errors.New("custom alias already taken")
+	ErrInvalidAlias  = errors.New("alias contains invalid characters")
+	ErrAliasSpace    = errors.New("alias must not contain whitespace")
+	ErrURLNotFound   = errors.New("short URL not found")
+	ErrInvalidWindow = errors.New("window must be positive")
+	ErrURLReserved   = errors.New("short code is reserved but not yet active")
+	ErrNotReserved   = errors.New("short code is not reserved")
+	ErrCodeTooLong   = errors.New("short code cannot be a generated code")
+	ErrCodeChecksum  = errors.New("short code checksum mismatch, likely a typo")
+	ErrInvalidTag    = errors.New("tag contains invalid characters")
+	ErrInvalidOwner  = errors.New("owner is longer than 64 characters")
+	ErrInvalidExpiry = errors.New("expiry must be a positive duration or a future time, not both")
+	ErrInvalidDelay  = fmt.Errorf("delay must be between 0 and %d seconds", MaxRedirectDelay)
+	ErrURLExpired    = errors.New("short URL has expired")
+	ErrNotModified   = errors.New("short URL not modified since the given time")
+	ErrInvalidLang   = fmt.Errorf("language_urls takes at most %d language tags like \"fr\" or \"pt-br\", each with a valid URL", MaxLanguageURLs)
+	ErrNoCodes       = errors.New("no short codes given")
+	ErrBatchTooLarge = fmt.Errorf("at most %d items per batch", MaxBatchSize)
+	ErrNotOwner      = errors.New("short URL belongs to another owner")
+	ErrNoFreeCode    = errors.New("every generated short code tried was already taken")
+	ErrOwnerHasURL   = errors.New("owner already has a short URL for this destination under another code")
+
+	ErrSigningDisabled  = errors.New("signed links are not enabled")
+	ErrWebhooksDisabled = errors.New("click webhooks are not enabled")
+	ErrInvalidWebhook   = fmt.Errorf("webhook_url must be a public http/https URL of at most %d bytes", maxWebhookURL)
+	ErrSignatureInvalid = errors.New("link signature missing or invalid")
+	ErrSignatureExpired = errors.New("link signature has expired")
+
+	// ErrTimeout is returned when a query exceeds DB_READ_TIMEOUT or DB_WRITE_TIMEOUT
+	ErrTimeout = repository.ErrTimeout
+
+	// ErrReadOnly is returned by every write while READ_ONLY is set
+	ErrReadOnly = repository.ErrReadOnly
 )
 
+// MaxRedirectDelay caps a link's countdown page, in seconds
+const MaxRedirectDelay = 30
+
+// generatedCodeAttempts bounds creates of a generated code that keep
+// landing on codes taken by custom aliases
+const generatedCodeAttempts = 3
+
+// notFoundSentinel is cached for codes known not to exist. It can't be
+// mistaken for a destination, which is always an http(s) URL.
+const notFoundSentinel = "!notfound"
+
 // URLService handles business logic for URL operations
 type URLService struct {
-	repo    *repository.URLRepository
+	repo    repository.Repository
 	baseURL string // e.g., "http://localhost:8080"
-	cache   *cache.RedisCache
+
+	// Short domain per region; defaultRegion applies to requests without one
+	regionBaseURLs map[string]string
+	defaultRegion  string
+	cache          cache.Cache
+	cacheTTL       time.Duration // upper bound on how long a link stays cached
+	cacheJitter    float64       // fraction cacheTTL is randomly moved by per entry
+	negativeTTL    time.Duration // how long unknown codes are cached as such; zero disables
+
+	// legacyCodes maps codes from the pre-migration system to current codes.
+	// Consulted only when a code is not found directly.
+	legacyCodes map[string]string
+
+	// Append a check character to generated codes to catch typos
+	codeChecksum bool
+
+	// Maps record IDs onto the numbers that get encoded (offset + id*stride)
+	sequence encoder.Sequence
+
+	// Alphabet generated codes are written in; nil means encoder.Default
+	encoder *encoder.Encoder
+
+	// Generated codes are left-padded to at least this many characters
+	codeMinLength int
+
+	// On a miss, retry all-lower and all-upper variants of generated codes
+	caseFallback bool
+
+	// Non-ASCII characters custom aliases may contain; nil allows none
+	unicodeAliases *unicode.RangeTable
+
+	// Reject creates and updates, and stop counting clicks
+	readOnly bool
+
+	// Hand an owner back their existing link instead of creating a second
+	// code for the same URL
+	uniquePerOwner bool
+
+	// Signed links (see signing.go); empty secret disables them
+	signingSecret []byte
+	signedLinkTTL time.Duration
+
+	// Click analytics (see analytics.go)
+	recordClickEvents bool
+	honorDNT          bool
+	dntCountAggregate bool
+	omitClickIP       bool
+
+	// Write-behind click counting (see clickbuffer.go)
+	clickBuffer     ClickBuffer
+	clickFlushBatch int // codes written per flush transaction
+
+	// Retry queue for failed click increments (see clickretry.go)
+	clickRetries      chan clickRetry
+	clickRetryMax     int
+	clickRetryBackoff time.Duration
+
+	// Detailed click rows waiting to be written (see clickevents.go);
+	// nil writes them inline
+	clickEvents chan *model.Click
+
+	// Retained click rows per code; codes over the cap are pruned on flush
+	maxClickRows int
+	pruneMu      sync.Mutex
+	prunePending map[string]struct{}
+
+	// Cached destination health (see destinations.go); nil skips the check
+	destinations *DestinationChecker
+
+	// Click webhooks (see webhooks.go); nil sends none
+	webhooks *WebhookSender
 }
 
-// NewURLService creates a new service instance
-func NewURLService(repo *repository.URLRepository, baseURL string, cache *cache.RedisCache) *URLService {
+// NewURLService creates a new service instance. cache may be nil, which
+// skips caching altogether.
+func NewURLService(repo repository.Repository, baseURL string, cache cache.Cache) *URLService {
 	return &URLService{
 		repo:    repo,
 		baseURL: strings.TrimRight(baseURL, "/"),
 		cache:   cache,
+
+		cacheTTL:          24 * time.Hour,
+		dntCountAggregate: true,
+		clickFlushBatch:   defaultClickFlushBatch,
+	}
+}
+
+// WithCacheTTL sets how long resolved links stay in Redis. Links that
+// expire sooner are cached only until they expire.
+func (s *URLService) WithCacheTTL(ttl time.Duration) *URLService {
+	if ttl > 0 {
+		s.cacheTTL = ttl
 	}
+	return s
+}
+
+// WithCacheJitter moves each cached link's TTL randomly by up to fraction
+// of it either way (0.1 is ±10%), so links cached at the same moment don't
+// all expire and miss at once. Zero keeps every TTL exact.
+func (s *URLService) WithCacheJitter(fraction float64) *URLService {
+	if fraction >= 0 && fraction < 1 {
+		s.cacheJitter = fraction
+	}
+	return s
+}
+
+// WithNegativeCacheTTL caches lookups of unknown codes for ttl, so
+// scanning the same missing code repeatedly doesn't reach the database.
+// Zero disables negative caching.
+func (s *URLService) WithNegativeCacheTTL(ttl time.Duration) *URLService {
+	s.negativeTTL = ttl
+	return s
+}
+
+// WithLegacyCodes sets the legacy code → current code mapping used as a
+// fallback when a short code is not found
+func (s *URLService) WithLegacyCodes(mapping map[string]string) *URLService {
+	s.legacyCodes = mapping
+	return s
+}
+
+// WithCodeChecksum appends a check character to generated codes so
+// mistyped codes can be told apart from unknown ones
+func (s *URLService) WithCodeChecksum(enabled bool) *URLService {
+	s.codeChecksum = enabled
+	return s
+}
+
+// WithIDSequence spreads generated codes out so they don't start at "0"
+// and consecutive creates aren't adjacent
+func (s *URLService) WithIDSequence(seq encoder.Sequence) *URLService {
+	s.sequence = seq
+	return s
+}
+
+// WithEncoder generates codes in a custom alphabet
+func (s *URLService) WithEncoder(enc *encoder.Encoder) *URLService {
+	s.encoder = enc
+	return s
+}
+
+// WithCodeMinLength pads generated codes to at least n characters, so the
+// first links don't get codes like "1" that reveal how few exist
+func (s *URLService) WithCodeMinLength(n int) *URLService {
+	s.codeMinLength = n
+	return s
+}
+
+// WithCaseFallback lets a miss retry the all-lower and all-upper forms of
+// the code. Only records whose code was generated are accepted, so custom
+// aliases stay case-sensitive.
+func (s *URLService) WithCaseFallback(enabled bool) *URLService {
+	s.caseFallback = enabled
+	return s
+}
+
+// WithUnicodeAliases lets custom aliases also use characters from table,
+// e.g. emoji. Aliases are stored and looked up in NFC form, so composed
+// and decomposed spellings reach the same link.
+func (s *URLService) WithUnicodeAliases(table *unicode.RangeTable) *URLService {
+	s.unicodeAliases = table
+	return s
+}
+
+// WithUniqueURLPerOwner makes creates return the owner's existing link for
+// a URL they already shortened. Requests without an owner are unaffected.
+// The check runs before the insert, so two concurrent creates of the same
+// URL can still both succeed.
+func (s *URLService) WithUniqueURLPerOwner(enabled bool) *URLService {
+	s.uniquePerOwner = enabled
+	return s
 }
 
 // CreateShortURL handles the core business logic of shortening a URL
 func (s *URLService) CreateShortURL(req model.CreateURLRequest) (*model.CreateURLResponse, error) {
+	return s.CreateShortURLContext(context.Background(), req)
+}
+
+// CreateShortURLContext is CreateShortURL with a request context, so the
+// lookups and insert stop when the client goes away
+func (s *URLService) CreateShortURLContext(ctx context.Context, req model.CreateURLRequest) (*model.CreateURLResponse, error) {
+	if s.readOnly {
+		return nil, ErrReadOnly
+	}
+
 	// ============ STEP 1: Validation ============
-	if err := s.validateURL(req.URL); err != nil {
+	urlRecord, existing, err := s.newURLRecord(ctx, req)
+	if err != nil {
 		return nil, err
 	}
+	if existing != nil {
+		return existing, nil
+	}
 
-	// ============ STEP 2: Determine Short Code ============
-	var shortCode string
+	// ============ STEP 2: Create the record ============
+	if urlRecord.ShortCode == "" {
+		err = s.createGenerated(ctx, urlRecord, generatedCodeAttempts)
+	} else if err = s.repo.CreateContext(ctx, urlRecord); err == repository.ErrDuplicate {
+		err = ErrAliasExists // Someone else holds this code
+	}
+	if err != nil {
+		return nil, err
+	}
 
+	// ============ STEP 3: Build response ============
+	return s.finishCreate(urlRecord, req.Region), nil
+}
+
+// createGenerated stores urlRecord under a code derived from its ID,
+// trying up to attempts IDs. The code comes from the ID the insert is
+// assigned, inside the same transaction, so parallel creates never derive
+// the same one. Running out of attempts is ErrNoFreeCode: the caller asked
+// for no particular code, so it is not a conflict of theirs.
+func (s *URLService) createGenerated(ctx context.Context, urlRecord *model.URL, attempts int) error {
+	err := s.repo.CreateGenerated(ctx, urlRecord, s.encodeID)
+	for attempt := 1; err == repository.ErrDuplicate && attempt < attempts; attempt++ {
+		// A custom alias already spells that ID's code; the ID is used
+		// up, so the next attempt derives a different one
+		err = s.repo.CreateGenerated(ctx, urlRecord, s.encodeID)
+	}
+	if err == repository.ErrDuplicate {
+		return ErrNoFreeCode
+	}
+	return err
+}
+
+// newURLRecord validates a create request and builds the record to insert.
+// ShortCode is left empty when one should be generated. If the owner
+// already has a link to the URL, that link's response comes back instead.
+func (s *URLService) newURLRecord(ctx context.Context, req model.CreateURLRequest) (*model.URL, *model.CreateURLResponse, error) {
+	if err := s.validateURL(req.URL); err != nil {
+		return nil, nil, err
+	}
+
+	if err := validateTag(req.Tag); err != nil {
+		return nil, nil, err
+	}
+	if req.Signed && len(s.signingSecret) == 0 {
+		return nil, nil, ErrSigningDisabled
+	}
+
+	if len(req.Owner) > 64 {
+		return nil, nil, ErrInvalidOwner
+	}
+	if req.DelaySeconds < 0 || req.DelaySeconds > MaxRedirectDelay {
+		return nil, nil, ErrInvalidDelay
+	}
+	if err := s.validateLanguageURLs(req.LanguageURLs); err != nil {
+		return nil, nil, err
+	}
+	if req.WebhookURL != "" {
+		if s.webhooks == nil {
+			return nil, nil, ErrWebhooksDisabled
+		}
+		if err := validateWebhookURL(req.WebhookURL); err != nil {
+			return nil, nil, err
+		}
+	}
+	expiresAt, err := expiryFor(req, time.Now())
+	if err != nil {
+		return nil, nil, err
+	}
+	// Padding is forgiven, so an alias of only spaces means "generate"
+	req.CustomAlias = strings.TrimSpace(req.CustomAlias)
 	if req.CustomAlias != "" {
-		// User wants a custom alias
+		req.CustomAlias = s.normalizeCode(req.CustomAlias)
+		// No pre-check: the insert itself claims the alias atomically
 		if err := s.validateAlias(req.CustomAlias); err != nil {
-			return nil, err
+			return nil, nil, err
 		}
+	}
 
-		// Check if alias is already taken
-		_, err := s.repo.GetByShortCode(req.CustomAlias)
-		if err == nil {
-			return nil, ErrAliasExists // Found existing = taken!
+	if s.uniquePerOwner && req.Owner != "" {
+		existing, err := s.findOwnedURL(ctx, req.Owner, req.URL)
+		if err != nil {
+			return nil, nil, err
 		}
-		if err != repository.ErrNotFound {
-			return nil, err // Some other database error
+		// Handing back another code than the one asked for would look
+		// like the alias was granted
+		if existing != nil && req.CustomAlias != "" && req.CustomAlias != existing.ShortCode {
+			return nil, nil, ErrOwnerHasURL
 		}
-
-		shortCode = req.CustomAlias
-	} else {
-		// Generate code from next ID
-		nextID, err := s.repo.GetNextID()
-		if err != nil {
-			return nil, err
+		if existing != nil {
+			return nil, s.existingURLResponse(existing, req.Region), nil
 		}
-		shortCode = encoder.Encode(nextID)
 	}
 
-	// ============ STEP 3: Create the record ============
-	urlRecord := &model.URL{
-		ShortCode:   shortCode,
-		OriginalURL: req.URL,
-	}
+	return &model.URL{
+		ShortCode:    req.CustomAlias,
+		OriginalURL:  req.URL,
+		Tag:          req.Tag,
+		Signed:       req.Signed,
+		Owner:        req.Owner,
+		ExpiresAt:    expiresAt,
+		DelaySeconds: req.DelaySeconds,
+		WebhookURL:   req.WebhookURL,
+		LanguageURLs: normalizeLanguageURLs(req.LanguageURLs),
+		ClickCount:   req.InitialClicks, // authorization is the handler's job
+	}, nil, nil
+}
 
-	if err := s.repo.Create(urlRecord); err != nil {
-		return nil, err
-	}
+// finishCreate caches a newly stored link and builds its response
+func (s *URLService) finishCreate(urlRecord *model.URL, region string) *model.CreateURLResponse {
 	// ============ REDIS: Write-Through Cache ============
-	if s.cache != nil {
+	// Signed and delayed links stay out of the cache (see cacheable),
+	// but a cached "not found" for the code must still go
+	if !cacheable(urlRecord) {
+		s.evictCached(context.Background(), urlRecord.ShortCode)
+	}
+	if s.cache != nil && cacheable(urlRecord) {
 		ctx := context.Background()
-		cacheKey := fmt.Sprintf("url:%s", shortCode)
-		ttl := 24 * time.Hour
-		if err := s.cache.Set(ctx, cacheKey, req.URL, ttl); err != nil {
+		cacheKey := fmt.Sprintf("url:%s", urlRecord.ShortCode)
+		ttl := s.cacheTTLFor(urlRecord, time.Now())
+		if err := s.cache.Set(ctx, cacheKey, urlRecord.OriginalURL, ttl); err != nil {
 			// Log warning but don't fail the request
 			fmt.Printf("Warning: failed to cache URL on create: %v\n", err)
+			metrics.Degradations.Inc(metrics.CacheWrite)
 		}
 	}
 
-	// ============ STEP 4: Build response ============
+	shortURL := s.baseURLFor(region) + "/" + urlRecord.ShortCode
+	if urlRecord.Signed {
+		shortURL += "?" + s.signedQuery(urlRecord.ID)
+	}
 	return &model.CreateURLResponse{
-		ShortURL:    s.baseURL + "/" + shortCode,
-		OriginalURL: req.URL,
-	}, nil
+		ShortURL:    shortURL,
+		OriginalURL: urlRecord.OriginalURL,
+	}
+}
+
+// expiryFor turns a create request's expires_in or expires_at into an
+// absolute time, or nil when the link never expires
+func expiryFor(req model.CreateURLRequest, now time.Time) (*time.Time, error) {
+	switch {
+	case req.ExpiresIn != "" && req.ExpiresAt != nil:
+		return nil, ErrInvalidExpiry
+	case req.ExpiresIn != "":
+		ttl, err := time.ParseDuration(req.ExpiresIn)
+		if err != nil || ttl <= 0 {
+			return nil, ErrInvalidExpiry
+		}
+		expiresAt := now.Add(ttl).UTC()
+		return &expiresAt, nil
+	case req.ExpiresAt != nil:
+		if !req.ExpiresAt.After(now) {
+			return nil, ErrInvalidExpiry
+		}
+		expiresAt := req.ExpiresAt.UTC()
+		return &expiresAt, nil
+	}
+	return nil, nil
+}
+
+func isExpired(url *model.URL, now time.Time) bool {
+	return url.ExpiresAt != nil && !now.Before(*url.ExpiresAt)
+}
+
+// cacheNotFound remembers that shortCode doesn't exist for negativeTTL.
+// Creating the code overwrites or evicts the entry.
+func (s *URLService) cacheNotFound(ctx context.Context, shortCode string) {
+	if s.cache == nil || s.negativeTTL <= 0 {
+		return
+	}
+	if err := s.cache.Set(ctx, fmt.Sprintf("url:%s", shortCode), notFoundSentinel, s.negativeTTL); err != nil {
+		metrics.Degradations.Inc(metrics.CacheWrite)
+	}
+}
+
+// jitteredTTL is cacheTTL moved by a random amount within cacheJitter
+func (s *URLService) jitteredTTL() time.Duration {
+	if s.cacheJitter == 0 {
+		return s.cacheTTL
+	}
+	spread := time.Duration(float64(s.cacheTTL) * s.cacheJitter)
+	return s.cacheTTL - spread + rand.N(2*spread+1)
+}
+
+// cacheTTLFor keeps a cached link from outliving its expiry
+func (s *URLService) cacheTTLFor(url *model.URL, now time.Time) time.Duration {
+	ttl := s.jitteredTTL()
+	if url.ExpiresAt != nil {
+		// Redis reads a zero TTL as "never expire"
+		ttl = max(min(ttl, url.ExpiresAt.Sub(now)), time.Millisecond)
+	}
+	return ttl
+}
+
+// findOwnedURL returns the owner's oldest active link to originalURL, or
+// nil if they have none
+func (s *URLService) findOwnedURL(ctx context.Context, owner, originalURL string) (*model.URL, error) {
+	urls, err := s.repo.GetAllByOriginalURL(ctx, originalURL)
+	if err != nil {
+		return nil, err
+	}
+	for _, url := range urls {
+		if url.Owner == owner && url.Status == model.StatusActive && !isExpired(url, time.Now()) {
+			return url, nil
+		}
+	}
+	return nil, nil
+}
+
+// existingURLResponse describes a link that was reused rather than created
+func (s *URLService) existingURLResponse(url *model.URL, region string) *model.CreateURLResponse {
+	shortURL := s.baseURLFor(region) + "/" + url.ShortCode
+	if url.Signed {
+		shortURL += "?" + s.signedQuery(url.ID)
+	}
+	return &model.CreateURLResponse{
+		ShortURL:    shortURL,
+		OriginalURL: url.OriginalURL,
+		Existing:    true,
+	}
+}
+
+// Where a resolved link was read from
+const (
+	SourceCache    = "cache"
+	SourceDatabase = "database"
+)
+
+// Link is where a resolved code sends the visitor
+type Link struct {
+	ShortCode    string // the stored code, after any legacy or case fallback
+	OriginalURL  string
+	CreatedAt    time.Time // zero when served from the cache
+	DelaySeconds int       // show a countdown page first when positive
+	Source       string    // SourceCache or SourceDatabase
+	Broken       bool      // the destination failed its last health probe
+}
+
+// cacheable reports whether a link may be served from the cache, which
+// holds only the destination. Signed links must be verified on every
+// resolve, delayed links need their delay from the record, and links
+// with their own webhook need its URL for each click.
+func cacheable(record *model.URL) bool {
+	return !record.Signed && record.DelaySeconds == 0 && record.WebhookURL == ""
 }
 
 // Resolve finds the original URL and increments click count
 func (s *URLService) Resolve(shortCode string) (string, error) {
+	return s.ResolveWithMeta(shortCode, ClickMeta{})
+}
+
+// ResolveWithMeta is Resolve, storing meta on the click row when click
+// events are recorded
+func (s *URLService) ResolveWithMeta(shortCode string, meta ClickMeta) (string, error) {
+	return s.ResolveContext(WithClickMeta(context.Background(), meta), shortCode)
+}
+
+// ResolveContext is Resolve with a request context (read routing, cancellation)
+func (s *URLService) ResolveContext(ctx context.Context, shortCode string) (string, error) {
+	link, err := s.ResolveLinkContext(ctx, shortCode)
+	return link.OriginalURL, err
+}
+
+// ResolveLinkContext is ResolveContext, also returning the link's redirect
+// delay and whether its destination failed the last health probe
+func (s *URLService) ResolveLinkContext(ctx context.Context, shortCode string) (Link, error) {
+	link, err := s.resolve(ctx, s.normalizeCode(shortCode), true)
+	if err == nil {
+		s.applyLanguage(ctx, &link)
+	}
+	if err == nil && s.destinations != nil {
+		link.Broken = s.destinations.Broken(link.OriginalURL)
+	}
+	return link, err
+}
+
+// resolve looks up a code; followLegacy allows one hop through the legacy mapping
+func (s *URLService) resolve(ctx context.Context, shortCode string, followLegacy bool) (Link, error) {
+	// Nothing stored could match, so spare the cache and database
+	if _, legacy := s.legacyCodes[shortCode]; !legacy && !s.wellFormedCode(shortCode) {
+		return Link{}, ErrURLNotFound
+	}
+
 	// ============ REDIS: Try cache first (Cache-Aside) ============
-	if s.cache != nil {
-		ctx := context.Background()
+	// The cache holds only the destination, so conditional requests need
+	// the record's creation time from the database
+	_, conditional := ifModifiedSince(ctx)
+	if s.cache != nil && !conditional {
 		cacheKey := fmt.Sprintf("url:%s", shortCode)
 
 		cachedURL, err := s.cache.Get(ctx, cacheKey)
-		if err == nil && cachedURL != "" {
-			// Cache hit! Increment count and return
-			_ = s.repo.IncrementClickCount(shortCode)
-			return cachedURL, nil
+		switch {
+		case err != nil:
+			// Redis is unreachable; the database still answers
+			metrics.Degradations.Inc(metrics.CacheRead)
+		case cachedURL == notFoundSentinel:
+			metrics.CacheLookups.Inc(metrics.CacheHit)
+			return Link{}, ErrURLNotFound
+		case cachedURL != "":
+			// Cache hit! Record the click and return
+			metrics.CacheLookups.Inc(metrics.CacheHit)
+			if err := admit(ctx, shortCode); err != nil {
+				return Link{}, err
+			}
+			s.recordClick(ctx, shortCode, "")
+			return Link{ShortCode: shortCode, OriginalURL: cachedURL, Source: SourceCache}, nil
+		default:
+			metrics.CacheLookups.Inc(metrics.CacheMiss)
 		}
 	}
 
 	// ============ REDIS: Cache miss - Get from database ============
 	// Find the URL
-	urlRecord, err := s.repo.GetByShortCode(shortCode)
+	urlRecord, err := s.repo.GetByShortCodeContext(ctx, shortCode)
 	if err == repository.ErrNotFound {
-		return "", ErrURLNotFound
+		if current, ok := s.legacyCodes[shortCode]; ok && followLegacy {
+			return s.resolve(ctx, current, false)
+		}
+		// Codes renamed by MigrateCodes keep working through one hop
+		if followLegacy {
+			if current, err := s.repo.GetRedirect(ctx, shortCode); err == nil {
+				return s.resolve(ctx, current, false)
+			}
+		}
+		if variant := s.findCaseVariant(ctx, shortCode); variant != nil {
+			urlRecord, shortCode, err = variant, variant.ShortCode, nil
+		}
+	}
+	if err == repository.ErrNotFound {
+		if s.isMistypedCode(shortCode) {
+			return Link{}, ErrCodeChecksum
+		}
+		s.cacheNotFound(ctx, shortCode)
+		return Link{}, ErrURLNotFound
 	}
 	if err != nil {
-		return "", err
+		return Link{}, err
+	}
+
+	// Reserved codes have no destination yet; never cache or count them
+	if urlRecord.Status == model.StatusReserved {
+		return Link{}, ErrURLReserved
+	}
+
+	now := time.Now()
+	if isExpired(urlRecord, now) {
+		return Link{}, ErrURLExpired
+	}
+
+	if err := s.verifySignature(ctx, urlRecord); err != nil {
+		return Link{}, err
 	}
 
 	// ============ REDIS: Populate cache for next time ============
-	if s.cache != nil {
-		ctx := context.Background()
+	if s.cache != nil && cacheable(urlRecord) {
 		cacheKey := fmt.Sprintf("url:%s", shortCode)
-		ttl := 24 * time.Hour
+		ttl := s.cacheTTLFor(urlRecord, now)
 		if err := s.cache.Set(ctx, cacheKey, urlRecord.OriginalURL, ttl); err != nil {
 			fmt.Printf("Warning: failed to cache URL on read: %v\n", err)
+			metrics.Degradations.Inc(metrics.CacheWrite)
+		}
+	}
+
+	link := Link{
+		ShortCode:    urlRecord.ShortCode,
+		OriginalURL:  urlRecord.OriginalURL,
+		CreatedAt:    urlRecord.CreatedAt,
+		DelaySeconds: urlRecord.DelaySeconds,
+		Source:       SourceDatabase,
+	}
+
+	// The client still has this redirect; answering it is not a visit
+	if notModified(ctx, urlRecord) {
+		return link, ErrNotModified
+	}
+
+	if err := admit(ctx, shortCode); err != nil {
+		return Link{}, err
+	}
+
+	// Record the click (fire and forget - don't fail if this errors)
+	s.recordClick(ctx, shortCode, urlRecord.WebhookURL)
+
+	return link, nil
+}
+
+// ReserveShortCode holds a custom alias without a destination.
+// The code resolves to a placeholder until ActivateShortCode is called.
+func (s *URLService) ReserveShortCode(req model.ReserveRequest) (*model.CreateURLResponse, error) {
+	return s.ReserveShortCodeContext(context.Background(), req)
+}
+
+// ReserveShortCodeContext is ReserveShortCode with a request context
+func (s *URLService) ReserveShortCodeContext(ctx context.Context, req model.ReserveRequest) (*model.CreateURLResponse, error) {
+	if s.readOnly {
+		return nil, ErrReadOnly
+	}
+	req.CustomAlias = s.normalizeCode(strings.TrimSpace(req.CustomAlias))
+	if err := s.validateAlias(req.CustomAlias); err != nil {
+		return nil, err
+	}
+
+	urlRecord := &model.URL{
+		ShortCode: req.CustomAlias,
+		Status:    model.StatusReserved,
+		Owner:     req.Owner,
+	}
+	if err := s.repo.CreateContext(ctx, urlRecord); err != nil {
+		if err == repository.ErrDuplicate {
+			return nil, ErrAliasExists
+		}
+		return nil, err
+	}
+
+	// The placeholder must show instead of a cached "not found", even if
+	// the caller has gone by now
+	s.evictCached(context.WithoutCancel(ctx), req.CustomAlias)
+
+	return &model.CreateURLResponse{
+		ShortURL: s.baseURLFor(req.Region) + "/" + req.CustomAlias,
+	}, nil
+}
+
+// ActivateShortCode sets the destination of a reserved code. Unless admin
+// is set, owner must match the reservation's owner, or ErrNotOwner is
+// returned.
+func (s *URLService) ActivateShortCode(shortCode, owner string, admin bool, req model.ActivateRequest) (*model.CreateURLResponse, error) {
+	return s.ActivateShortCodeContext(context.Background(), shortCode, owner, admin, req)
+}
+
+// ActivateShortCodeContext is ActivateShortCode with a request context.
+// The owner check reads the primary, so a replica that hasn't caught up
+// with the reservation can't report it missing.
+func (s *URLService) ActivateShortCodeContext(ctx context.Context, shortCode, owner string, admin bool, req model.ActivateRequest) (*model.CreateURLResponse, error) {
+	if s.readOnly {
+		return nil, ErrReadOnly
+	}
+	if err := s.validateURL(req.URL); err != nil {
+		return nil, err
+	}
+	shortCode = s.normalizeCode(shortCode)
+
+	if !admin {
+		record, err := s.repo.GetByShortCodeContext(repository.WithStrongConsistency(ctx), shortCode)
+		if err == repository.ErrNotFound {
+			return nil, ErrURLNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		if record.Owner != owner {
+			return nil, ErrNotOwner
 		}
 	}
 
-	// Increment click count (fire and forget - don't fail if this errors)
-	_ = s.repo.IncrementClickCount(shortCode)
+	err := s.repo.ActivateContext(ctx, shortCode, req.URL)
+	switch err {
+	case nil:
+	case repository.ErrNotFound:
+		return nil, ErrURLNotFound
+	case repository.ErrNotReserved:
+		return nil, ErrNotReserved
+	default:
+		return nil, err
+	}
+
+	// ============ REDIS: Write-Through Cache ============
+	if s.cache != nil {
+		cacheKey := fmt.Sprintf("url:%s", shortCode)
+		if err := s.cache.Set(context.WithoutCancel(ctx), cacheKey, req.URL, s.jitteredTTL()); err != nil {
+			fmt.Printf("Warning: failed to cache URL on activate: %v\n", err)
+			metrics.Degradations.Inc(metrics.CacheWrite)
+		}
+	}
 
-	return urlRecord.OriginalURL, nil
+	return &model.CreateURLResponse{
+		ShortURL:    s.baseURLFor(req.Region) + "/" + shortCode,
+		OriginalURL: req.URL,
+	}, nil
+}
+
+// DeleteURL removes a short code and its click history, and evicts it from
+// Redis so it stops resolving immediately. Unless admin is set, owner must
+// be non-empty and match the link's owner, or ErrNotOwner is returned.
+func (s *URLService) DeleteURL(shortCode, owner string, admin bool) error {
+	return s.DeleteURLContext(context.Background(), shortCode, owner, admin)
+}
+
+// DeleteURLContext is DeleteURL with a request context; like activation,
+// the owner check reads the primary
+func (s *URLService) DeleteURLContext(ctx context.Context, shortCode, owner string, admin bool) error {
+	if s.readOnly {
+		return ErrReadOnly
+	}
+	shortCode = s.normalizeCode(shortCode)
+
+	if !admin {
+		record, err := s.repo.GetByShortCodeContext(repository.WithStrongConsistency(ctx), shortCode)
+		if err == repository.ErrNotFound {
+			return ErrURLNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if owner == "" || record.Owner != owner {
+			return ErrNotOwner
+		}
+	}
+
+	err := s.repo.DeleteContext(ctx, shortCode)
+	if err == repository.ErrNotFound {
+		return ErrURLNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	s.evictCached(context.WithoutCancel(ctx), shortCode)
+	return nil
+}
+
+// MaxBatchSize bounds batch creates and deletes so one request can't hold
+// a transaction for long, and IN lists stay well under parameter limits
+const MaxBatchSize = 500
+
+// DeleteURLs removes several short codes at once. Duplicates are ignored,
+// as are codes that don't exist; the response counts only real deletions.
+func (s *URLService) DeleteURLs(codes []string) (*model.DeleteCodesResponse, error) {
+	if s.readOnly {
+		return nil, ErrReadOnly
+	}
+
+	unique := make([]string, 0, len(codes))
+	seen := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		if code != "" && !seen[code] {
+			seen[code] = true
+			unique = append(unique, code)
+		}
+	}
+	if len(unique) == 0 {
+		return nil, ErrNoCodes
+	}
+	if len(unique) > MaxBatchSize {
+		return nil, ErrBatchTooLarge
+	}
+
+	deleted, err := s.repo.DeleteByShortCodes(unique)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	for _, code := range unique {
+		s.evictCached(ctx, code)
+	}
+
+	return &model.DeleteCodesResponse{Requested: len(unique), Deleted: deleted}, nil
 }
 
 // GetURLStats returns statistics for a short URL
-func (s *URLService) GetURLStats(shortCode string) (*model.URL, error) {
-	urlRecord, err := s.repo.GetByShortCode(shortCode)
+func (s *URLService) GetURLStats(shortCode string) (*model.StatsResponse, error) {
+	return s.GetURLStatsContext(context.Background(), shortCode)
+}
+
+// GetURLStatsContext is GetURLStats with a request context (read routing, cancellation)
+func (s *URLService) GetURLStatsContext(ctx context.Context, shortCode string) (*model.StatsResponse, error) {
+	urlRecord, err := s.repo.GetByShortCodeContext(ctx, s.normalizeCode(shortCode))
 	if err == repository.ErrNotFound {
 		return nil, ErrURLNotFound
 	}
-	return urlRecord, err
+	if err != nil {
+		return nil, err
+	}
+
+	// A signed link's destination is only for holders of a valid
+	// signature, so stats without one leave it out
+	originalURL := urlRecord.OriginalURL
+	var languageURLs map[string]string
+	if s.verifySignature(ctx, urlRecord) == nil {
+		languageURLs, err = s.repo.GetLanguageURLs(ctx, urlRecord.ShortCode)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		originalURL = ""
+	}
+
+	return &model.StatsResponse{
+		ShortCode:   urlRecord.ShortCode,
+		ShortURL:    s.baseURLFor(regionFrom(ctx)) + "/" + urlRecord.ShortCode,
+		OriginalURL: originalURL,
+		// Include clicks still sitting in the write-behind buffer
+		ClickCount:   urlRecord.ClickCount + s.pendingClicks(ctx, urlRecord.ShortCode),
+		CreatedAt:    urlRecord.CreatedAt,
+		AgeSeconds:   int64(max(time.Since(urlRecord.CreatedAt), 0) / time.Second),
+		Status:       urlRecord.Status,
+		Tag:          urlRecord.Tag,
+		Signed:       urlRecord.Signed,
+		ExpiresAt:    urlRecord.ExpiresAt,
+		DelaySeconds: urlRecord.DelaySeconds,
+		LanguageURLs: languageURLs,
+	}, nil
+}
+
+// WithRegionalBaseURLs returns short links on the caller's regional domain.
+// Requests without a known region use defaultRegion, then the base URL.
+func (s *URLService) WithRegionalBaseURLs(baseURLs map[string]string, defaultRegion string) *URLService {
+	s.regionBaseURLs = make(map[string]string, len(baseURLs))
+	for region, base := range baseURLs {
+		s.regionBaseURLs[strings.ToLower(region)] = strings.TrimRight(base, "/")
+	}
+	s.defaultRegion = strings.ToLower(defaultRegion)
+	return s
+}
+
+// regionKey carries the caller's region for responses that aren't a create
+type regionKey struct{}
+
+// WithRegion attaches the caller's region to ctx, so short URLs built
+// for the request use that region's domain like the create response does
+func WithRegion(ctx context.Context, region string) context.Context {
+	return context.WithValue(ctx, regionKey{}, region)
+}
+
+func regionFrom(ctx context.Context) string {
+	region, _ := ctx.Value(regionKey{}).(string)
+	return region
+}
+
+// baseURLFor picks the short domain for a region
+func (s *URLService) baseURLFor(region string) string {
+	if base, ok := s.regionBaseURLs[strings.ToLower(region)]; ok && region != "" {
+		return base
+	}
+	if base, ok := s.regionBaseURLs[s.defaultRegion]; ok && s.defaultRegion != "" {
+		return base
+	}
+	return s.baseURL
+}
+
+// WithReadOnly rejects every operation that would write to the database.
+// Redirects still resolve but clicks are not counted.
+func (s *URLService) WithReadOnly(enabled bool) *URLService {
+	s.readOnly = enabled
+	return s
+}
+
+// GetTagStats returns link and click totals for up to limit tags, busiest
+// first. Reads may be served by a replica, and buffered clicks not yet
+// flushed are not included.
+func (s *URLService) GetTagStats(ctx context.Context, limit int) ([]model.TagStats, error) {
+	return s.repo.StatsByTag(ctx, limit)
+}
+
+// GetTopLinks returns the click counts of up to limit links with at least
+// minClicks clicks, busiest first. Like GetTagStats it reads the database
+// only, so buffered clicks appear after the next flush.
+func (s *URLService) GetTopLinks(ctx context.Context, minClicks uint64, limit int) ([]model.LinkClicks, error) {
+	return s.repo.TopByClicks(ctx, minClicks, limit)
+}
+
+// GetRecentLinks returns up to limit links, most recently created first.
+// Reads may be served by a replica, so a link created a moment ago may not
+// appear yet.
+func (s *URLService) GetRecentLinks(ctx context.Context, limit int) ([]model.RecentLink, error) {
+	return s.repo.ListRecent(ctx, limit)
+}
+
+// ListURLs returns up to limit links, newest first, skipping the first
+// offset, along with the total number stored. Reads may be served by a replica.
+func (s *URLService) ListURLs(limit, offset int) ([]*model.URL, uint64, error) {
+	total, err := s.repo.Count()
+	if err != nil {
+		return nil, 0, err
+	}
+	urls, err := s.repo.List(limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	return urls, total, nil
+}
+
+// ListURLsByOwner returns up to limit of owner's links, newest first,
+// skipping the first offset. Reads may be served by a replica.
+func (s *URLService) ListURLsByOwner(owner string, limit, offset int) ([]*model.URL, error) {
+	return s.repo.ListByOwner(owner, limit, offset)
+}
+
+// ListTakenAliases returns up to limit codes with IDs greater than afterID,
+// in ID order, and the afterID of the next page (zero if this is the last)
+func (s *URLService) ListTakenAliases(afterID uint64, limit int) ([]model.TakenAlias, uint64, error) {
+	// One extra row tells whether another page follows
+	records, err := s.repo.ListURLs(afterID, limit+1)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var next uint64
+	if len(records) > limit {
+		records = records[:limit]
+		next = records[limit-1].ID
+	}
+	taken := make([]model.TakenAlias, 0, len(records))
+	for _, record := range records {
+		taken = append(taken, model.TakenAlias{ShortCode: record.ShortCode, Status: record.Status, CreatedAt: record.CreatedAt})
+	}
+	return taken, next, nil
+}
+
+// GetCapacityStats reports creation throughput over the given window and the
+// remaining code space before generated codes grow by one character
+func (s *URLService) GetCapacityStats(window time.Duration) (*model.CapacityStats, error) {
+	if window <= 0 {
+		return nil, ErrInvalidWindow
+	}
+
+	created, err := s.repo.CountCreatedSince(time.Now().Add(-window))
+	if err != nil {
+		return nil, err
+	}
+
+	nextID, err := s.repo.GetNextID()
+	if err != nil {
+		return nil, err
+	}
+	maxID := nextID - 1
+
+	// Code length follows the encoded number, which the sequence may have
+	// pushed well past the raw ID
+	maxEncoded, err := s.sequence.Apply(maxID)
+	if err != nil {
+		return nil, err
+	}
+	codeLength := max(encoder.MinLength(maxEncoded), min(s.codeMinLength, encoder.MaxLength))
+	codeSpace := encoder.Capacity(codeLength)
+
+	return &model.CapacityStats{
+		Window:            window.String(),
+		CreatedInWindow:   created,
+		LinksPerMinute:    float64(created) / window.Minutes(),
+		MaxID:             maxID,
+		CodeLength:        codeLength,
+		CodeSpace:         codeSpace,
+		RemainingCapacity: (encoder.MaxID(codeLength) - maxEncoded) / s.sequence.Step(),
+		Utilization:       float64(maxEncoded+1) / float64(codeSpace),
+	}, nil
+}
+
+// DecodeGeneratedCode returns the ID a generated code maps to.
+// Oversized or out-of-range codes are rejected rather than wrapped onto
+// another ID, which could otherwise expose someone else's link.
+func (s *URLService) DecodeGeneratedCode(shortCode string) (uint64, error) {
+	id, err := s.codeScheme().Decode(shortCode)
+	switch err {
+	case nil:
+		return id, nil
+	case encoder.ErrCodeTooLong, encoder.ErrOverflow:
+		return 0, ErrCodeTooLong
+	case encoder.ErrChecksum:
+		return 0, ErrCodeChecksum
+	default:
+		return 0, ErrInvalidAlias
+	}
+}
+
+// DecodeCode reports whether a code is generated, and if so which record
+// ID it stands for. A code that decodes but is stored under a different ID
+// is a custom alias that happens to use only alphabet characters.
+func (s *URLService) DecodeCode(ctx context.Context, shortCode string) (*model.CodeDecode, error) {
+	result := &model.CodeDecode{ShortCode: shortCode, Kind: model.CodeKindCustom}
+
+	record, err := s.repo.GetByShortCodeContext(ctx, shortCode)
+	switch err {
+	case nil:
+		result.Exists = true
+	case repository.ErrNotFound:
+	default:
+		return nil, err
+	}
+
+	id, err := s.DecodeGeneratedCode(shortCode)
+	switch err {
+	case nil:
+	case ErrCodeTooLong:
+		result.Reason = "too long to be a generated code"
+		return result, nil
+	case ErrCodeChecksum:
+		result.Reason = "check character does not match"
+		return result, nil
+	default:
+		result.Reason = "contains characters outside the code alphabet"
+		return result, nil
+	}
+	if record != nil && record.ID != id {
+		result.Reason = "stored under a different ID"
+		return result, nil
+	}
+
+	result.Kind = model.CodeKindGenerated
+	result.ID = &id
+	return result, nil
+}
+
+// wellFormedCode reports whether shortCode could be stored at all: it is
+// non-empty and every character is allowed in custom aliases or is part of
+// the code alphabet
+func (s *URLService) wellFormedCode(shortCode string) bool {
+	if shortCode == "" {
+		return false
+	}
+	alphabet := s.codeScheme().encoder().Alphabet()
+	for _, char := range shortCode {
+		if char >= utf8.RuneSelf {
+			if s.unicodeAliases == nil || !unicode.Is(s.unicodeAliases, char) {
+				return false
+			}
+			continue
+		}
+		if !isValidAliasChar(char) && strings.IndexRune(alphabet, char) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeCode returns the NFC form aliases are stored in when Unicode
+// aliases are enabled, and code unchanged otherwise
+func (s *URLService) normalizeCode(code string) string {
+	if s.unicodeAliases == nil {
+		return code
+	}
+	return norm.NFC.String(code)
+}
+
+// encodeID turns a new record ID into its generated short code
+func (s *URLService) encodeID(id uint64) (string, error) {
+	return s.codeScheme().Encode(id)
+}
+
+// findCaseVariant looks up at most two case variants of an unknown code and
+// returns the first that belongs to a generated code, or nil
+func (s *URLService) findCaseVariant(ctx context.Context, shortCode string) *model.URL {
+	if !s.caseFallback {
+		return nil
+	}
+
+	for _, variant := range []string{strings.ToLower(shortCode), strings.ToUpper(shortCode)} {
+		if variant == shortCode {
+			continue
+		}
+		record, err := s.repo.GetByShortCodeContext(ctx, variant)
+		if err != nil {
+			continue
+		}
+		if s.isGeneratedRecord(record) {
+			return record
+		}
+	}
+	return nil
+}
+
+// isGeneratedRecord reports whether a record's code is the one its ID
+// would generate, as opposed to a custom alias
+func (s *URLService) isGeneratedRecord(record *model.URL) bool {
+	id, err := s.DecodeGeneratedCode(record.ShortCode)
+	return err == nil && id == record.ID
+}
+
+// isMistypedCode reports whether an unknown code looks like a generated code
+// whose check character is wrong. Codes with characters outside the
+// alphabet can only be custom aliases and are never flagged.
+func (s *URLService) isMistypedCode(shortCode string) bool {
+	if !s.codeChecksum {
+		return false
+	}
+	_, err := s.codeScheme().encoder().DecodeWithChecksum(shortCode)
+	return err == encoder.ErrChecksum
 }
 
 // ============ VALIDATION HELPERS ============
@@ -177,13 +1174,18 @@ func (s *URLService) validateURL(rawURL string) error {
 }
 
 func (s *URLService) validateAlias(alias string) error {
-	if len(alias) < 3 || len(alias) > 20 {
+	if strings.ContainsFunc(alias, unicode.IsSpace) {
+		return ErrAliasSpace
+	}
+
+	// Count characters, not bytes: an emoji is one of the 20
+	if n := utf8.RuneCountInString(alias); n < 3 || n > 20 {
 		return ErrInvalidAlias
 	}
 
-	// Only allow alphanumeric, hyphens, underscores
+	// Only allow alphanumeric, hyphens, underscores, and configured Unicode
 	for _, char := range alias {
-		if !isValidAliasChar(char) {
+		if !isValidAliasChar(char) && (s.unicodeAliases == nil || !unicode.Is(s.unicodeAliases, char)) {
 			return ErrInvalidAlias
 		}
 	}
@@ -191,6 +1193,19 @@ func (s *URLService) validateAlias(alias string) error {
 	return nil
 }
 
+// validateTag allows an empty tag or up to 64 alias characters
+func validateTag(tag string) error {
+	if len(tag) > 64 {
+		return ErrInvalidTag
+	}
+	for _, char := range tag {
+		if !isValidAliasChar(char) {
+			return ErrInvalidTag
+		}
+	}
+	return nil
+}
+
 func isValidAliasChar(char rune) bool {
 	return (char >= 'a' && char <= 'z') ||
 		(char >= 'A' && char <= 'Z') ||
@@ -1,20 +1,58 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
+	"github.com/darkodi/url-shortener/internal/cache"
+	"github.com/darkodi/url-shortener/internal/cache/redistest"
+	"github.com/darkodi/url-shortener/internal/config"
+	"github.com/darkodi/url-shortener/internal/encoder"
+	"github.com/darkodi/url-shortener/internal/metrics"
 	"github.com/darkodi/url-shortener/internal/model"
 	"github.com/darkodi/url-shortener/internal/repository"
 	_ "github.com/mattn/go-sqlite3"
+	"github.com/redis/go-redis/v9"
 )
 
+// testDriver selects the backend for setupTestService; TestMain runs the
+// whole suite once per backend
+var testDriver = "sqlite3"
+
+func TestMain(m *testing.M) {
+	code := 0
+	for _, driver := range []string{"sqlite3", "memory"} {
+		testDriver = driver
+		if c := m.Run(); c != 0 {
+			fmt.Printf("FAIL: service tests against %s backend\n", driver)
+			code = c
+		}
+	}
+	os.Exit(code)
+}
+
 func setupTestService(t *testing.T) *URLService {
-	// Use in-memory SQLite for tests
-	repo, err := repository.NewURLRepository(":memory:")
+	// SQLite runs in memory on a single connection so every query sees the same DB
+	repo, err := repository.New(&config.DatabaseConfig{
+		Driver:       testDriver,
+		Path:         ":memory:",
+		MaxOpenConns: 1,
+		MaxIdleConns: 1,
+	})
 	if err != nil {
 		t.Fatalf("Failed to create repo: %v", err)
 	}
-	return NewURLService(repo, "http://localhost:8080")
+	t.Cleanup(func() { repo.Close() })
+	return NewURLService(repo, "http://localhost:8080", nil)
 }
 
 func TestCreateShortURL_Valid(t *testing.T) {
@@ -77,6 +115,99 @@ func TestCreateShortURL_CustomAlias(t *testing.T) {
 	}
 }
 
+func TestCreateShortURL_WhitespaceAlias(t *testing.T) {
+	svc := setupTestService(t)
+
+	// Only whitespace is the same as no alias
+	resp, err := svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com", CustomAlias: "   "})
+	if err != nil {
+		t.Fatalf("Expected a generated code for a blank alias, got: %v", err)
+	}
+	if code := strings.TrimPrefix(resp.ShortURL, "http://localhost:8080/"); code == "" || strings.ContainsFunc(code, unicode.IsSpace) {
+		t.Errorf("Expected a generated code, got: %q", resp.ShortURL)
+	}
+
+	// Surrounding whitespace is dropped
+	resp, err = svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com", CustomAlias: " padded\t"})
+	if err != nil || resp.ShortURL != "http://localhost:8080/padded" {
+		t.Errorf("Expected alias padded, got: %+v, %v", resp, err)
+	}
+
+	// Whitespace inside is rejected with its own error
+	for _, alias := range []string{"my link", "my\tlink", " a b "} {
+		if _, err := svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com", CustomAlias: alias}); err != ErrAliasSpace {
+			t.Errorf("%q: expected ErrAliasSpace, got: %v", alias, err)
+		}
+	}
+}
+
+func TestNoopCache_RunsWithoutRedis(t *testing.T) {
+	base := setupTestService(t)
+	svc := NewURLService(base.repo, "http://localhost:8080", cache.Noop{})
+
+	if _, err := svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com", CustomAlias: "nocache"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		link, err := svc.ResolveLinkContext(context.Background(), "nocache")
+		if err != nil || link.OriginalURL != "https://example.com" || link.Source != SourceDatabase {
+			t.Fatalf("Expected every resolve served by the database, got: %+v, %v", link, err)
+		}
+	}
+
+	// Running without the cache is degraded, not unready
+	ready := svc.Readiness(context.Background())
+	if ready.Status != model.StatusReady || ready.Dependencies["cache"].Status != "degraded" {
+		t.Errorf("Expected ready with the cache degraded, got: %+v", ready)
+	}
+}
+
+func TestMemoryCache_CachesWithoutRedis(t *testing.T) {
+	base := setupTestService(t)
+	svc := NewURLService(base.repo, "http://localhost:8080", cache.NewMemoryCache(1))
+	ctx := context.Background()
+
+	for _, alias := range []string{"lru-a", "lru-b"} {
+		if _, err := svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com/" + alias, CustomAlias: alias}); err != nil {
+			t.Fatalf("Create %s failed: %v", alias, err)
+		}
+	}
+
+	resolve := func(code string) string {
+		t.Helper()
+		link, err := svc.ResolveLinkContext(ctx, code)
+		if err != nil || link.OriginalURL != "https://example.com/"+code {
+			t.Fatalf("Resolve %s failed: %+v, %v", code, link, err)
+		}
+		return link.Source
+	}
+
+	if src := resolve("lru-a"); src != SourceDatabase {
+		t.Errorf("Expected first resolve from the database, got: %s", src)
+	}
+	if src := resolve("lru-a"); src != SourceCache {
+		t.Errorf("Expected second resolve from the cache, got: %s", src)
+	}
+
+	// A size of one evicts lru-a as soon as lru-b is cached
+	resolve("lru-b")
+	if src := resolve("lru-a"); src != SourceDatabase {
+		t.Errorf("Expected evicted link read from the database, got: %s", src)
+	}
+
+	// Deleting the link clears it from the cache too
+	if err := svc.DeleteURL("lru-a", "", true); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := svc.ResolveLinkContext(ctx, "lru-a"); err != ErrURLNotFound {
+		t.Errorf("Expected ErrURLNotFound after delete, got: %v", err)
+	}
+
+	if ready := svc.Readiness(ctx); ready.Dependencies["cache"].Status != "up" {
+		t.Errorf("Expected the memory cache reported up, got: %+v", ready.Dependencies["cache"])
+	}
+}
+
 func TestCreateShortURL_DuplicateAlias(t *testing.T) {
 	svc := setupTestService(t)
 
@@ -124,3 +255,1618 @@ func TestResolve(t *testing.T) {
 		t.Errorf("Expected click count 1, got: %d", stats.ClickCount)
 	}
 }
+
+func TestGetCapacityStats(t *testing.T) {
+	svc := setupTestService(t)
+
+	now := time.Now().UTC()
+	seeded := []time.Duration{
+		10 * time.Minute, // inside the 1h window
+		20 * time.Minute,
+		30 * time.Minute,
+		2 * time.Hour, // outside
+		3 * time.Hour,
+	}
+	for i, age := range seeded {
+		err := svc.repo.Create(&model.URL{
+			ShortCode:   fmt.Sprintf("seed%d", i),
+			OriginalURL: "https://example.com",
+			CreatedAt:   now.Add(-age),
+		})
+		if err != nil {
+			t.Fatalf("Seed %d failed: %v", i, err)
+		}
+	}
+
+	stats, err := svc.GetCapacityStats(time.Hour)
+	if err != nil {
+		t.Fatalf("GetCapacityStats failed: %v", err)
+	}
+
+	if stats.CreatedInWindow != 3 {
+		t.Errorf("Expected 3 links in window, got: %d", stats.CreatedInWindow)
+	}
+	if stats.LinksPerMinute != 3.0/60 {
+		t.Errorf("Expected %.4f links/min, got: %.4f", 3.0/60, stats.LinksPerMinute)
+	}
+
+	// IDs 1-5 encode to a single character: 62 values, 0-5 used
+	if stats.MaxID != 5 || stats.CodeLength != 1 {
+		t.Errorf("Expected max ID 5 at length 1, got: %d at length %d", stats.MaxID, stats.CodeLength)
+	}
+	if stats.RemainingCapacity != 56 {
+		t.Errorf("Expected 56 remaining, got: %d", stats.RemainingCapacity)
+	}
+}
+
+func TestGetCapacityStats_InvalidWindow(t *testing.T) {
+	svc := setupTestService(t)
+
+	if _, err := svc.GetCapacityStats(0); err != ErrInvalidWindow {
+		t.Errorf("Expected ErrInvalidWindow, got: %v", err)
+	}
+}
+
+func TestCacheTTLFor(t *testing.T) {
+	svc := setupTestService(t).WithCacheTTL(10 * time.Minute)
+	now := time.Now()
+	soon := now.Add(time.Minute)
+	past := now.Add(-time.Minute)
+
+	tests := []struct {
+		name string
+		url  *model.URL
+		want time.Duration
+	}{
+		{"no expiry uses configured TTL", &model.URL{}, 10 * time.Minute},
+		{"expires sooner", &model.URL{ExpiresAt: &soon}, time.Minute},
+		{"already expired stays positive", &model.URL{ExpiresAt: &past}, time.Millisecond},
+	}
+	for _, tt := range tests {
+		if got := svc.cacheTTLFor(tt.url, now); got != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestResolve_Expired(t *testing.T) {
+	svc := setupTestService(t)
+
+	_, err := svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com/soon", CustomAlias: "soon", ExpiresIn: "50ms"})
+	if err != nil {
+		t.Fatalf("CreateShortURL failed: %v", err)
+	}
+	if _, err := svc.Resolve("soon"); err != nil {
+		t.Fatalf("Expected link to resolve before expiry, got: %v", err)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if _, err := svc.Resolve("soon"); err != ErrURLExpired {
+		t.Errorf("Expected ErrURLExpired, got: %v", err)
+	}
+
+	// Stats still explain why the link stopped working
+	stats, err := svc.GetURLStats("soon")
+	if err != nil {
+		t.Fatalf("Expected stats for an expired link, got: %v", err)
+	}
+	if stats.ExpiresAt == nil || time.Since(*stats.ExpiresAt) < 0 || time.Since(*stats.ExpiresAt) > time.Minute {
+		t.Errorf("Expected a recent expires_at in stats, got: %v", stats.ExpiresAt)
+	}
+	if stats.ClickCount != 1 {
+		t.Errorf("Expected only the pre-expiry click counted, got: %d", stats.ClickCount)
+	}
+}
+
+func TestCreateShortURL_Expiry(t *testing.T) {
+	svc := setupTestService(t)
+	future := time.Now().Add(time.Hour).Truncate(time.Second)
+	past := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name string
+		req  model.CreateURLRequest
+		err  error
+	}{
+		{"no expiry", model.CreateURLRequest{}, nil},
+		{"expires_in", model.CreateURLRequest{ExpiresIn: "72h"}, nil},
+		{"expires_at", model.CreateURLRequest{ExpiresAt: &future}, nil},
+		{"both", model.CreateURLRequest{ExpiresIn: "1h", ExpiresAt: &future}, ErrInvalidExpiry},
+		{"bad duration", model.CreateURLRequest{ExpiresIn: "soon"}, ErrInvalidExpiry},
+		{"negative duration", model.CreateURLRequest{ExpiresIn: "-1h"}, ErrInvalidExpiry},
+		{"past time", model.CreateURLRequest{ExpiresAt: &past}, ErrInvalidExpiry},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.URL = "https://example.com/expiry"
+			resp, err := svc.CreateShortURL(tt.req)
+			if err != tt.err {
+				t.Fatalf("Expected %v, got: %v", tt.err, err)
+			}
+			if err != nil {
+				return
+			}
+			if _, err := svc.Resolve(strings.TrimPrefix(resp.ShortURL, "http://localhost:8080/")); err != nil {
+				t.Errorf("Expected unexpired link to resolve, got: %v", err)
+			}
+		})
+	}
+
+	resp, _ := svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com/expiry", ExpiresAt: &future})
+	stats, _ := svc.GetURLStats(strings.TrimPrefix(resp.ShortURL, "http://localhost:8080/"))
+	if stats.ExpiresAt == nil || !stats.ExpiresAt.Equal(future) {
+		t.Errorf("Expected expires_at %v stored, got: %v", future, stats.ExpiresAt)
+	}
+}
+
+func TestResolve_LegacyCode(t *testing.T) {
+	svc := setupTestService(t).WithLegacyCodes(map[string]string{
+		"Xk9Lq2": "current",
+		"loop1":  "loop2", // neither side exists
+		"loop2":  "loop1",
+	})
+
+	_, _ = svc.CreateShortURL(model.CreateURLRequest{
+		URL:         "https://example.com/migrated",
+		CustomAlias: "current",
+	})
+
+	// Legacy code resolves through the mapping
+	original, err := svc.Resolve("Xk9Lq2")
+	if err != nil {
+		t.Fatalf("Resolve legacy failed: %v", err)
+	}
+	if original != "https://example.com/migrated" {
+		t.Errorf("Expected migrated URL, got: %s", original)
+	}
+
+	// Current code resolves directly
+	original, err = svc.Resolve("current")
+	if err != nil {
+		t.Fatalf("Resolve current failed: %v", err)
+	}
+	if original != "https://example.com/migrated" {
+		t.Errorf("Expected migrated URL, got: %s", original)
+	}
+
+	// Mapping cycles stop after one hop
+	if _, err := svc.Resolve("loop1"); err != ErrURLNotFound {
+		t.Errorf("Expected ErrURLNotFound for cyclic mapping, got: %v", err)
+	}
+}
+
+func TestLoadLegacyCodes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "legacy.json")
+	if err := os.WriteFile(path, []byte(`{"Xk9Lq2": "abc"}`), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	mapping, err := LoadLegacyCodes(path)
+	if err != nil {
+		t.Fatalf("LoadLegacyCodes failed: %v", err)
+	}
+	if mapping["Xk9Lq2"] != "abc" {
+		t.Errorf("Expected Xk9Lq2 -> abc, got: %v", mapping)
+	}
+
+	if _, err := LoadLegacyCodes(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("Expected error for missing file")
+	}
+}
+
+func TestCreateShortURLBatch_PartialFailure(t *testing.T) {
+	svc := setupTestService(t)
+	_, _ = svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com/taken", CustomAlias: "taken"})
+
+	results, err := svc.CreateShortURLBatch([]model.CreateURLRequest{
+		{URL: "https://example.com/a"},
+		{URL: "https://example.com/b", CustomAlias: "taken"},
+		{URL: "not a url"},
+		{URL: "https://example.com/c", CustomAlias: "fresh"},
+		{URL: "https://example.com/d", CustomAlias: "fresh"},
+		{URL: "https://example.com/e"},
+	})
+	if err != nil {
+		t.Fatalf("CreateShortURLBatch failed: %v", err)
+	}
+
+	wantErr := []error{nil, ErrAliasExists, ErrInvalidURL, nil, ErrAliasExists, nil}
+	for i, want := range wantErr {
+		if results[i].Err != want {
+			t.Errorf("item %d: expected error %v, got: %v", i, want, results[i].Err)
+		}
+		if (results[i].Response != nil) == (want != nil) {
+			t.Errorf("item %d: expected exactly one of response and error, got: %+v", i, results[i])
+		}
+	}
+
+	// Created items resolve, and the first item with an alias won it
+	for i, want := range map[int]string{0: "https://example.com/a", 3: "https://example.com/c", 5: "https://example.com/e"} {
+		code := strings.TrimPrefix(results[i].Response.ShortURL, "http://localhost:8080/")
+		if got, err := svc.Resolve(code); err != nil || got != want {
+			t.Errorf("item %d: expected %s to resolve to %s, got: %q, %v", i, code, want, got, err)
+		}
+	}
+
+	// Generated codes still decode to their own record
+	for _, i := range []int{0, 5} {
+		code := strings.TrimPrefix(results[i].Response.ShortURL, "http://localhost:8080/")
+		stored, _ := svc.repo.GetByShortCode(code)
+		if id, err := svc.codeScheme().Decode(code); err != nil || id != stored.ID {
+			t.Errorf("item %d: expected %s to decode to ID %d, got: %d, %v", i, code, stored.ID, id, err)
+		}
+	}
+
+	if _, err := svc.CreateShortURLBatch(make([]model.CreateURLRequest, MaxBatchSize+1)); err != ErrBatchTooLarge {
+		t.Errorf("Expected ErrBatchTooLarge, got: %v", err)
+	}
+}
+
+func TestCreateShortURLBatch_GeneratedCollisionRetried(t *testing.T) {
+	// Padded so generated codes are also valid aliases
+	svc := setupTestService(t).WithCodeMinLength(6)
+
+	// The first batch row takes ID 1; the second asks for ID 2's code
+	taken, err := svc.encodeID(2)
+	if err != nil {
+		t.Fatalf("encodeID failed: %v", err)
+	}
+	results, err := svc.CreateShortURLBatch([]model.CreateURLRequest{
+		{URL: "https://example.com/alias", CustomAlias: taken},
+		{URL: "https://example.com/generated"},
+	})
+	if err != nil {
+		t.Fatalf("CreateShortURLBatch failed: %v", err)
+	}
+
+	// Neither row asked for a code anyone else holds, so both succeed
+	for i, result := range results {
+		if result.Err != nil {
+			t.Fatalf("item %d: expected success, got: %v", i, result.Err)
+		}
+	}
+	if results[0].Response.ShortURL != "http://localhost:8080/"+taken {
+		t.Errorf("Expected the alias row to get %s, got: %s", taken, results[0].Response.ShortURL)
+	}
+	code := strings.TrimPrefix(results[1].Response.ShortURL, "http://localhost:8080/")
+	if got, err := svc.Resolve(code); err != nil || got != "https://example.com/generated" {
+		t.Errorf("Expected %s to resolve to the generated row, got: %q, %v", code, got, err)
+	}
+
+	if _, err := svc.CreateShortURLBatch(make([]model.CreateURLRequest, MaxBatchSize+1)); err != ErrBatchTooLarge {
+		t.Errorf("Expected ErrBatchTooLarge, got: %v", err)
+	}
+}
+
+func TestDeleteURL_ChecksOwner(t *testing.T) {
+	svc := setupTestService(t)
+	_, _ = svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com", CustomAlias: "mine", Owner: "alice"})
+
+	if err := svc.DeleteURL("mine", "bob", false); err != ErrNotOwner {
+		t.Fatalf("Expected ErrNotOwner for another owner, got: %v", err)
+	}
+	if _, err := svc.Resolve("mine"); err != nil {
+		t.Errorf("Expected the link kept after a refused delete, got: %v", err)
+	}
+	if err := svc.DeleteURL("mine", "", false); err != ErrNotOwner {
+		t.Errorf("Expected ErrNotOwner without an owner, got: %v", err)
+	}
+	if err := svc.DeleteURL("mine", "alice", false); err != nil {
+		t.Errorf("Expected the owner's delete to succeed, got: %v", err)
+	}
+}
+
+func TestListURLsByOwner(t *testing.T) {
+	svc := setupTestService(t)
+	for i, owner := range []string{"alice", "bob", "alice", "alice"} {
+		alias := fmt.Sprintf("owned%d", i)
+		if _, err := svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com", CustomAlias: alias, Owner: owner}); err != nil {
+			t.Fatalf("Failed to create %s: %v", alias, err)
+		}
+	}
+
+	page, err := svc.ListURLsByOwner("alice", 2, 0)
+	if err != nil {
+		t.Fatalf("ListURLsByOwner failed: %v", err)
+	}
+	if len(page) != 2 || page[0].ShortCode != "owned3" || page[1].ShortCode != "owned2" {
+		t.Errorf("Expected alice's newest two links, got: %v", codesOf(page))
+	}
+	page, _ = svc.ListURLsByOwner("alice", 2, 2)
+	if len(page) != 1 || page[0].ShortCode != "owned0" {
+		t.Errorf("Expected alice's oldest link on page two, got: %v", codesOf(page))
+	}
+}
+
+func TestListURLs(t *testing.T) {
+	svc := setupTestService(t)
+	for i := 0; i < 3; i++ {
+		alias := fmt.Sprintf("listed%d", i)
+		if _, err := svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com", CustomAlias: alias}); err != nil {
+			t.Fatalf("Failed to create %s: %v", alias, err)
+		}
+	}
+
+	page, total, err := svc.ListURLs(2, 0)
+	if err != nil {
+		t.Fatalf("ListURLs failed: %v", err)
+	}
+	if total != 3 {
+		t.Errorf("Expected total 3, got: %d", total)
+	}
+	if len(page) != 2 || page[0].ShortCode != "listed2" || page[1].ShortCode != "listed1" {
+		t.Errorf("Expected the newest two links, got: %v", codesOf(page))
+	}
+	page, _, _ = svc.ListURLs(2, 2)
+	if len(page) != 1 || page[0].ShortCode != "listed0" {
+		t.Errorf("Expected the oldest link on page two, got: %v", codesOf(page))
+	}
+	if page, _, _ = svc.ListURLs(2, 10); len(page) != 0 {
+		t.Errorf("Expected no links past the end, got: %v", codesOf(page))
+	}
+}
+
+func codesOf(urls []*model.URL) []string {
+	codes := make([]string, len(urls))
+	for i, url := range urls {
+		codes[i] = url.ShortCode
+	}
+	return codes
+}
+
+func TestDeleteURL(t *testing.T) {
+	svc := setupTestService(t)
+
+	_, _ = svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com", CustomAlias: "doomed"})
+	_, _ = svc.Resolve("doomed")
+
+	if err := svc.DeleteURL("doomed", "", true); err != nil {
+		t.Fatalf("DeleteURL failed: %v", err)
+	}
+	if _, err := svc.Resolve("doomed"); err != ErrURLNotFound {
+		t.Errorf("Expected deleted code to be gone, got: %v", err)
+	}
+	if err := svc.DeleteURL("doomed", "", true); err != ErrURLNotFound {
+		t.Errorf("Expected ErrURLNotFound deleting twice, got: %v", err)
+	}
+
+	// Click history goes with the link, so a re-created alias starts clean
+	if clicks, _ := svc.repo.CountClickEvents("doomed"); clicks != 0 {
+		t.Errorf("Expected click rows removed, got: %d", clicks)
+	}
+}
+
+func TestDeleteURLs_MixedCodes(t *testing.T) {
+	svc := setupTestService(t)
+
+	for _, alias := range []string{"keep", "drop1", "drop2"} {
+		_, _ = svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com/" + alias, CustomAlias: alias})
+	}
+
+	resp, err := svc.DeleteURLs([]string{"drop1", "missing", "drop2", "drop1"})
+	if err != nil {
+		t.Fatalf("DeleteURLs failed: %v", err)
+	}
+	if resp.Requested != 3 || resp.Deleted != 2 {
+		t.Errorf("Expected 3 requested and 2 deleted, got: %+v", resp)
+	}
+
+	for _, code := range []string{"drop1", "drop2"} {
+		if _, err := svc.Resolve(code); err != ErrURLNotFound {
+			t.Errorf("Expected %s deleted, got: %v", code, err)
+		}
+	}
+	if _, err := svc.Resolve("keep"); err != nil {
+		t.Errorf("Expected unlisted code untouched, got: %v", err)
+	}
+
+	if _, err := svc.DeleteURLs(nil); err != ErrNoCodes {
+		t.Errorf("Expected ErrNoCodes for an empty batch, got: %v", err)
+	}
+	tooMany := make([]string, MaxBatchSize+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("c%d", i)
+	}
+	if _, err := svc.DeleteURLs(tooMany); err != ErrBatchTooLarge {
+		t.Errorf("Expected ErrBatchTooLarge, got: %v", err)
+	}
+}
+
+func TestReserveAndActivate(t *testing.T) {
+	svc := setupTestService(t)
+
+	// Reserve
+	resp, err := svc.ReserveShortCode(model.ReserveRequest{CustomAlias: "launch"})
+	if err != nil {
+		t.Fatalf("Reserve failed: %v", err)
+	}
+	if resp.ShortURL != "http://localhost:8080/launch" {
+		t.Errorf("Expected reserved short URL, got: %s", resp.ShortURL)
+	}
+
+	stats, _ := svc.GetURLStats("launch")
+	if stats.Status != model.StatusReserved {
+		t.Errorf("Expected status reserved, got: %s", stats.Status)
+	}
+
+	// Alias is taken while reserved
+	_, err = svc.CreateShortURL(model.CreateURLRequest{URL: "https://other.com", CustomAlias: "launch"})
+	if err != ErrAliasExists {
+		t.Errorf("Expected ErrAliasExists, got: %v", err)
+	}
+
+	// Resolving while reserved is a placeholder, not a 404
+	if _, err := svc.Resolve("launch"); err != ErrURLReserved {
+		t.Errorf("Expected ErrURLReserved, got: %v", err)
+	}
+
+	// Activate
+	_, err = svc.ActivateShortCode("launch", "", false, model.ActivateRequest{URL: "https://example.com/launch"})
+	if err != nil {
+		t.Fatalf("Activate failed: %v", err)
+	}
+
+	original, err := svc.Resolve("launch")
+	if err != nil {
+		t.Fatalf("Resolve after activate failed: %v", err)
+	}
+	if original != "https://example.com/launch" {
+		t.Errorf("Expected activated URL, got: %s", original)
+	}
+
+	// Only reserved codes can be activated
+	_, err = svc.ActivateShortCode("launch", "", false, model.ActivateRequest{URL: "https://example.com/again"})
+	if err != ErrNotReserved {
+		t.Errorf("Expected ErrNotReserved, got: %v", err)
+	}
+	_, err = svc.ActivateShortCode("missing", "", false, model.ActivateRequest{URL: "https://example.com"})
+	if err != ErrURLNotFound {
+		t.Errorf("Expected ErrURLNotFound, got: %v", err)
+	}
+}
+
+// requestKey marks the context a test passes in
+type requestKey struct{}
+
+// ctxRecordingRepo notes which writes and lookups got the caller's context
+type ctxRecordingRepo struct {
+	repository.Repository
+	mu   sync.Mutex
+	seen []string
+}
+
+func (r *ctxRecordingRepo) record(op string, ctx context.Context) {
+	if ctx.Value(requestKey{}) != nil {
+		r.mu.Lock()
+		r.seen = append(r.seen, op)
+		r.mu.Unlock()
+	}
+}
+
+func (r *ctxRecordingRepo) GetByShortCodeContext(ctx context.Context, shortCode string) (*model.URL, error) {
+	r.record("get", ctx)
+	return r.Repository.GetByShortCodeContext(ctx, shortCode)
+}
+
+func (r *ctxRecordingRepo) CreateContext(ctx context.Context, url *model.URL) error {
+	r.record("create", ctx)
+	return r.Repository.CreateContext(ctx, url)
+}
+
+func (r *ctxRecordingRepo) ActivateContext(ctx context.Context, shortCode, originalURL string) error {
+	r.record("activate", ctx)
+	return r.Repository.ActivateContext(ctx, shortCode, originalURL)
+}
+
+func (r *ctxRecordingRepo) DeleteContext(ctx context.Context, shortCode string) error {
+	r.record("delete", ctx)
+	return r.Repository.DeleteContext(ctx, shortCode)
+}
+
+func TestReserveActivateDelete_UseRequestContext(t *testing.T) {
+	repo := &ctxRecordingRepo{Repository: setupTestService(t).repo}
+	svc := NewURLService(repo, "http://localhost:8080", nil)
+	ctx := context.WithValue(context.Background(), requestKey{}, true)
+
+	if _, err := svc.ReserveShortCodeContext(ctx, model.ReserveRequest{CustomAlias: "held", Owner: "acme"}); err != nil {
+		t.Fatalf("Reserve failed: %v", err)
+	}
+	if _, err := svc.ActivateShortCodeContext(ctx, "held", "acme", false, model.ActivateRequest{URL: "https://example.com"}); err != nil {
+		t.Fatalf("Activate failed: %v", err)
+	}
+	if err := svc.DeleteURLContext(ctx, "held", "acme", false); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+
+	want := []string{"create", "get", "activate", "get", "delete"}
+	if strings.Join(repo.seen, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected %v to get the request context, got: %v", want, repo.seen)
+	}
+}
+
+func TestDecodeGeneratedCode_Oversized(t *testing.T) {
+	svc := setupTestService(t)
+
+	if id, err := svc.DecodeGeneratedCode("8m0Kx"); err != nil || id != 123456789 {
+		t.Errorf("Expected 123456789, got: %d (%v)", id, err)
+	}
+
+	// 12+ chars would wrap in uint64; must be rejected instead
+	for _, code := range []string{"100000000000", "aaaaaaaaaaaaaaaaaaaa"} {
+		if _, err := svc.DecodeGeneratedCode(code); err != ErrCodeTooLong {
+			t.Errorf("DecodeGeneratedCode(%s): expected ErrCodeTooLong, got: %v", code, err)
+		}
+	}
+}
+
+func TestCreateShortURL_ConcurrentAliasOneWinner(t *testing.T) {
+	svc := setupTestService(t)
+
+	const racers = 20
+	var wg sync.WaitGroup
+	results := make(chan error, racers)
+
+	for i := 0; i < racers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := svc.CreateShortURL(model.CreateURLRequest{
+				URL:         fmt.Sprintf("https://example.com/%d", i),
+				CustomAlias: "contested",
+			})
+			results <- err
+		}(i)
+	}
+	wg.Wait()
+	close(results)
+
+	winners := 0
+	for err := range results {
+		switch err {
+		case nil:
+			winners++
+		case ErrAliasExists:
+		default:
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+	if winners != 1 {
+		t.Errorf("Expected exactly one winner, got: %d", winners)
+	}
+}
+
+func TestResolve_DoNotTrack(t *testing.T) {
+	tests := []struct {
+		name           string
+		honorDNT       bool
+		countAggregate bool
+		wantCount      uint64
+		wantEvents     uint64
+	}{
+		{"honored, aggregate counted", true, true, 1, 0},
+		{"honored, aggregate skipped", true, false, 0, 0},
+		{"ignored", false, true, 1, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := setupTestService(t).
+				WithClickEvents(true).
+				WithDoNotTrackPolicy(tt.honorDNT, tt.countAggregate)
+
+			_, _ = svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com", CustomAlias: "dnt"})
+
+			if _, err := svc.ResolveContext(WithDoNotTrack(context.Background()), "dnt"); err != nil {
+				t.Fatalf("Resolve failed: %v", err)
+			}
+
+			stats, _ := svc.GetURLStats("dnt")
+			if stats.ClickCount != tt.wantCount {
+				t.Errorf("Expected click count %d, got: %d", tt.wantCount, stats.ClickCount)
+			}
+			events, _ := svc.repo.CountClickEvents("dnt")
+			if events != tt.wantEvents {
+				t.Errorf("Expected %d click rows, got: %d", tt.wantEvents, events)
+			}
+		})
+	}
+}
+
+func TestResolve_ClickEventsWithoutDNT(t *testing.T) {
+	svc := setupTestService(t).WithClickEvents(true).WithDoNotTrackPolicy(true, true)
+
+	_, _ = svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com", CustomAlias: "tracked"})
+	_, _ = svc.Resolve("tracked")
+	_, _ = svc.Resolve("tracked")
+
+	events, _ := svc.repo.CountClickEvents("tracked")
+	if events != 2 {
+		t.Errorf("Expected 2 click rows, got: %d", events)
+	}
+}
+
+func TestResolve_ClickWeight(t *testing.T) {
+	svc := setupTestService(t).WithClickEvents(true)
+
+	_, _ = svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com", CustomAlias: "weighted"})
+	_, _ = svc.Resolve("weighted")
+	if _, err := svc.ResolveContext(WithClickWeight(context.Background(), 5), "weighted"); err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+
+	stats, _ := svc.GetURLStats("weighted")
+	if stats.ClickCount != 6 {
+		t.Errorf("Expected click count 6 (1 + 5), got: %d", stats.ClickCount)
+	}
+	events, _ := svc.repo.CountClickEvents("weighted")
+	if events != 2 {
+		t.Errorf("Expected one click row per resolve, got: %d", events)
+	}
+}
+
+func TestGetClicksByDay(t *testing.T) {
+	svc := setupTestService(t).WithClickEvents(true)
+	_, _ = svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com", CustomAlias: "series"})
+
+	ctx := WithClickMeta(context.Background(), ClickMeta{Referrer: "https://news.example.com/", UserAgent: strings.Repeat("x", 1000)})
+	for i := 0; i < 3; i++ {
+		if _, err := svc.ResolveContext(ctx, "series"); err != nil {
+			t.Fatalf("Resolve failed: %v", err)
+		}
+	}
+
+	days, err := svc.GetClicksByDay("series", time.Now().AddDate(0, 0, -6))
+	if err != nil {
+		t.Fatalf("GetClicksByDay failed: %v", err)
+	}
+	if len(days) != 7 {
+		t.Fatalf("Expected 7 days including today, got: %v", days)
+	}
+	today := days[len(days)-1]
+	if today.Date != time.Now().UTC().Format(time.DateOnly) || today.Clicks != 3 {
+		t.Errorf("Expected 3 clicks today, got: %+v", today)
+	}
+	for _, day := range days[:len(days)-1] {
+		if day.Clicks != 0 {
+			t.Errorf("Expected earlier days filled with zero, got: %+v", day)
+		}
+	}
+}
+
+// clickRecordingRepo keeps every click row written through it
+type clickRecordingRepo struct {
+	repository.Repository
+	mu     sync.Mutex
+	clicks []model.Click
+}
+
+func (r *clickRecordingRepo) RecordClick(click *model.Click) error {
+	r.mu.Lock()
+	r.clicks = append(r.clicks, *click)
+	r.mu.Unlock()
+	return r.Repository.RecordClick(click)
+}
+
+func TestResolveWithMeta(t *testing.T) {
+	repo := &clickRecordingRepo{Repository: setupTestService(t).repo}
+	svc := NewURLService(repo, "http://localhost:8080", nil).WithClickEvents(true)
+	_, _ = svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com", CustomAlias: "meta"})
+
+	meta := ClickMeta{Referrer: "https://news.example.com/", UserAgent: "Mozilla/5.0", ClientIP: "203.0.113.7"}
+	if _, err := svc.ResolveWithMeta("meta", meta); err != nil {
+		t.Fatalf("ResolveWithMeta failed: %v", err)
+	}
+	// A request without those headers is still a click
+	if _, err := svc.Resolve("meta"); err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+
+	if len(repo.clicks) != 2 {
+		t.Fatalf("Expected 2 click rows, got: %d", len(repo.clicks))
+	}
+	got := repo.clicks[0]
+	if got.Referrer != meta.Referrer || got.UserAgent != meta.UserAgent || got.ClientIP != meta.ClientIP {
+		t.Errorf("Expected the visit's metadata stored, got: %+v", got)
+	}
+	if empty := repo.clicks[1]; empty.Referrer != "" || empty.UserAgent != "" || empty.ClientIP != "" {
+		t.Errorf("Expected empty metadata for a bare resolve, got: %+v", empty)
+	}
+}
+
+func TestResolveWithMeta_ClientIPNotStored(t *testing.T) {
+	repo := &clickRecordingRepo{Repository: setupTestService(t).repo}
+	svc := NewURLService(repo, "http://localhost:8080", nil).WithClickEvents(true).WithClickClientIP(false)
+	_, _ = svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com", CustomAlias: "noip"})
+
+	meta := ClickMeta{Referrer: "https://news.example.com/", UserAgent: "Mozilla/5.0", ClientIP: "203.0.113.7"}
+	if _, err := svc.ResolveWithMeta("noip", meta); err != nil {
+		t.Fatalf("ResolveWithMeta failed: %v", err)
+	}
+
+	if len(repo.clicks) != 1 {
+		t.Fatalf("Expected 1 click row, got: %d", len(repo.clicks))
+	}
+	if got := repo.clicks[0]; got.ClientIP != "" || got.UserAgent != meta.UserAgent {
+		t.Errorf("Expected the row kept without its client IP, got: %+v", got)
+	}
+}
+
+func TestTruncate_KeepsRunesWhole(t *testing.T) {
+	tests := []struct {
+		in   string
+		n    int
+		want string
+	}{
+		{"short", 10, "short"},
+		{"abcdef", 3, "abc"},
+		{"caf\u00e9", 4, "caf"}, // é is two bytes; half of it is dropped
+		{"caf\u00e9", 5, "caf\u00e9"},
+		{"🎉🎉", 5, "🎉"},
+		{"🎉", 2, ""},
+	}
+	for _, tt := range tests {
+		got := truncate(tt.in, tt.n)
+		if got != tt.want || !utf8.ValidString(got) {
+			t.Errorf("truncate(%q, %d): Expected %q, got: %q", tt.in, tt.n, tt.want, got)
+		}
+	}
+}
+
+func TestClickEventQueue_WritesInBackground(t *testing.T) {
+	svc := setupTestService(t).WithClickEvents(true).WithClickEventQueue(10)
+	_, _ = svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com", CustomAlias: "queued"})
+
+	for i := 0; i < 3; i++ {
+		_, _ = svc.Resolve("queued")
+	}
+	if n, _ := svc.repo.CountClickEvents("queued"); n != 0 {
+		t.Fatalf("Expected rows to wait for the writer, got: %d", n)
+	}
+	stats, _ := svc.GetURLStats("queued")
+	if stats.ClickCount != 3 {
+		t.Errorf("Expected the click count updated inline, got: %d", stats.ClickCount)
+	}
+
+	// Shutdown writes whatever is still queued
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	svc.RunClickEventWriter(ctx)
+	if n, _ := svc.repo.CountClickEvents("queued"); n != 3 {
+		t.Errorf("Expected 3 rows after the writer drained, got: %d", n)
+	}
+}
+
+func TestClickEventQueue_DropsWhenFull(t *testing.T) {
+	svc := setupTestService(t).WithClickEvents(true).WithClickEventQueue(1)
+	_, _ = svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com", CustomAlias: "full"})
+
+	before := metrics.Drops.Get(metrics.ClickEventQueue)
+	for i := 0; i < 3; i++ {
+		_, _ = svc.Resolve("full")
+	}
+	// Rows that didn't fit are dropped, not written on the request path
+	if n, _ := svc.repo.CountClickEvents("full"); n != 0 {
+		t.Fatalf("Expected no rows written inline, got: %d", n)
+	}
+	if n := metrics.Drops.Get(metrics.ClickEventQueue) - before; n != 2 {
+		t.Errorf("Expected 2 drops counted, got: %d", n)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	svc.RunClickEventWriter(ctx)
+	if n, _ := svc.repo.CountClickEvents("full"); n != 1 {
+		t.Errorf("Expected the queued row written, got: %d", n)
+	}
+}
+
+func TestCodeChecksum(t *testing.T) {
+	svc := setupTestService(t).WithCodeChecksum(true)
+
+	resp, err := svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com"})
+	if err != nil {
+		t.Fatalf("CreateShortURL failed: %v", err)
+	}
+	code := strings.TrimPrefix(resp.ShortURL, "http://localhost:8080/")
+	if !encoder.ValidChecksum(code) {
+		t.Fatalf("Expected generated code with valid checksum, got: %s", code)
+	}
+
+	if _, err := svc.Resolve(code); err != nil {
+		t.Errorf("Expected valid code to resolve, got: %v", err)
+	}
+
+	id, err := svc.DecodeGeneratedCode(code)
+	if err != nil || id != 1 {
+		t.Errorf("Expected decoded ID 1, got: %d, %v", id, err)
+	}
+
+	typo := code[:len(code)-1] + "Z"
+	if code == typo {
+		typo = code[:len(code)-1] + "Y"
+	}
+	if _, err := svc.Resolve(typo); err != ErrCodeChecksum {
+		t.Errorf("Expected ErrCodeChecksum, got: %v", err)
+	}
+	if _, err := svc.DecodeGeneratedCode(typo); err != ErrCodeChecksum {
+		t.Errorf("Expected ErrCodeChecksum from decode, got: %v", err)
+	}
+
+	if _, err := svc.Resolve("not-a-code"); err != ErrURLNotFound {
+		t.Errorf("Expected ErrURLNotFound for alias miss, got: %v", err)
+	}
+}
+
+func TestIDSequence(t *testing.T) {
+	seq, err := encoder.NewSequence(916132832, 7919)
+	if err != nil {
+		t.Fatalf("NewSequence failed: %v", err)
+	}
+	svc := setupTestService(t).WithIDSequence(seq)
+
+	var codes []string
+	for i := 0; i < 3; i++ {
+		resp, err := svc.CreateShortURL(model.CreateURLRequest{URL: fmt.Sprintf("https://example.com/%d", i)})
+		if err != nil {
+			t.Fatalf("CreateShortURL failed: %v", err)
+		}
+		codes = append(codes, strings.TrimPrefix(resp.ShortURL, "http://localhost:8080/"))
+	}
+
+	for i, code := range codes {
+		if code == "1" || len(code) < 5 {
+			t.Errorf("Expected code offset into the space, got: %s", code)
+		}
+
+		id, err := svc.DecodeGeneratedCode(code)
+		if err != nil || id != uint64(i+1) {
+			t.Errorf("Expected %s to decode to %d, got: %d, %v", code, i+1, id, err)
+		}
+		if _, err := svc.Resolve(code); err != nil {
+			t.Errorf("Expected %s to resolve, got: %v", code, err)
+		}
+	}
+
+	// Consecutive creates must not land on adjacent codes
+	for i := 1; i < len(codes); i++ {
+		prev, _ := encoder.DecodeSafe(codes[i-1])
+		cur, _ := encoder.DecodeSafe(codes[i])
+		if cur-prev == 1 {
+			t.Errorf("Expected non-adjacent codes, got %s then %s", codes[i-1], codes[i])
+		}
+	}
+
+	// Numbers between strides aren't generated codes
+	between, _ := encoder.DecodeSafe(codes[0])
+	if _, err := svc.DecodeGeneratedCode(encoder.Encode(between + 1)); err != ErrInvalidAlias {
+		t.Errorf("Expected ErrInvalidAlias for off-sequence code, got: %v", err)
+	}
+}
+
+func TestCreateShortURL_CodeMinLength(t *testing.T) {
+	svc := setupTestService(t).WithCodeMinLength(6)
+
+	resp, err := svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com/padded"})
+	if err != nil {
+		t.Fatalf("CreateShortURL failed: %v", err)
+	}
+	code := strings.TrimPrefix(resp.ShortURL, "http://localhost:8080/")
+	if code != "000001" {
+		t.Errorf("Expected padded code 000001, got: %s", code)
+	}
+
+	id, err := svc.DecodeGeneratedCode(code)
+	if err != nil || id != 1 {
+		t.Errorf("Expected %s to decode to 1, got: %d, %v", code, id, err)
+	}
+	if _, err := svc.Resolve(code); err != nil {
+		t.Errorf("Expected %s to resolve, got: %v", code, err)
+	}
+
+	stats, err := svc.GetCapacityStats(time.Hour)
+	if err != nil {
+		t.Fatalf("GetCapacityStats failed: %v", err)
+	}
+	if stats.CodeLength != 6 {
+		t.Errorf("Expected code length 6 while padding applies, got: %d", stats.CodeLength)
+	}
+}
+
+func TestCreateShortURL_CustomAlphabet(t *testing.T) {
+	enc, err := encoder.NewEncoder("ZYXWVUTSRQPONMLKJIHGFEDCBAzyxwvutsrqponmlkjihgfedcba9876543210")
+	if err != nil {
+		t.Fatalf("NewEncoder failed: %v", err)
+	}
+	svc := setupTestService(t).WithEncoder(enc)
+
+	resp, err := svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com/alphabet"})
+	if err != nil {
+		t.Fatalf("CreateShortURL failed: %v", err)
+	}
+	code := strings.TrimPrefix(resp.ShortURL, "http://localhost:8080/")
+	if code != enc.Encode(1) {
+		t.Errorf("Expected code %q from the custom alphabet, got: %s", enc.Encode(1), code)
+	}
+
+	id, err := svc.DecodeGeneratedCode(code)
+	if err != nil || id != 1 {
+		t.Errorf("Expected %s to decode to 1, got: %d, %v", code, id, err)
+	}
+	if _, err := svc.Resolve(code); err != nil {
+		t.Errorf("Expected %s to resolve, got: %v", code, err)
+	}
+}
+
+func TestResolve_CaseFallback(t *testing.T) {
+	// Offset so the generated code contains letters
+	seq, _ := encoder.NewSequence(40, 1)
+	svc := setupTestService(t).WithIDSequence(seq).WithCaseFallback(true)
+
+	resp, err := svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com/generated"})
+	if err != nil {
+		t.Fatalf("CreateShortURL failed: %v", err)
+	}
+	code := strings.TrimPrefix(resp.ShortURL, "http://localhost:8080/")
+	if code != "F" {
+		t.Fatalf("Expected generated code F, got: %s", code)
+	}
+	_, _ = svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com/alias", CustomAlias: "MyLink"})
+
+	tests := []struct {
+		name    string
+		code    string
+		want    string
+		wantErr error
+	}{
+		{"lowercased generated code", "f", "https://example.com/generated", nil},
+		{"custom alias stays case-sensitive", "mylink", "", ErrURLNotFound},
+		{"unknown code", "zzz", "", ErrURLNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := svc.Resolve(tt.code)
+			if err != tt.wantErr || got != tt.want {
+				t.Errorf("Expected %q, %v, got: %q, %v", tt.want, tt.wantErr, got, err)
+			}
+		})
+	}
+
+	stats, _ := svc.GetURLStats("F")
+	if stats.ClickCount != 1 {
+		t.Errorf("Expected variant hit counted on the canonical code, got: %d", stats.ClickCount)
+	}
+}
+
+func TestResolve_CaseFallbackDisabled(t *testing.T) {
+	seq, _ := encoder.NewSequence(40, 1)
+	svc := setupTestService(t).WithIDSequence(seq)
+	_, _ = svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com/generated"})
+
+	if _, err := svc.Resolve("f"); err != ErrURLNotFound {
+		t.Errorf("Expected ErrURLNotFound without fallback, got: %v", err)
+	}
+}
+
+func TestFlushClicks_PrunesRowsBeyondCap(t *testing.T) {
+	svc := setupTestService(t).WithClickEvents(true).WithMaxClickRows(2)
+
+	_, _ = svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com", CustomAlias: "hot"})
+	_, _ = svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com", CustomAlias: "cold"})
+	for i := 0; i < 5; i++ {
+		_, _ = svc.Resolve("hot")
+	}
+	_, _ = svc.Resolve("cold")
+
+	if _, err := svc.FlushClicks(context.Background()); err != nil {
+		t.Fatalf("FlushClicks failed: %v", err)
+	}
+
+	if rows, _ := svc.repo.CountClickEvents("hot"); rows != 2 {
+		t.Errorf("Expected 2 click rows kept, got: %d", rows)
+	}
+	if rows, _ := svc.repo.CountClickEvents("cold"); rows != 1 {
+		t.Errorf("Expected untouched code to keep 1 row, got: %d", rows)
+	}
+
+	stats, _ := svc.GetURLStats("hot")
+	if stats.ClickCount != 5 {
+		t.Errorf("Expected aggregate count 5 preserved, got: %d", stats.ClickCount)
+	}
+}
+
+func TestMigrateCodes(t *testing.T) {
+	svc := setupTestService(t)
+
+	urls := []string{"https://example.com/a", "https://example.com/b", "https://example.com/c"}
+	var oldCodes []string
+	for _, u := range urls {
+		resp, err := svc.CreateShortURL(model.CreateURLRequest{URL: u})
+		if err != nil {
+			t.Fatalf("CreateShortURL failed: %v", err)
+		}
+		oldCodes = append(oldCodes, strings.TrimPrefix(resp.ShortURL, "http://localhost:8080/"))
+	}
+	_, _ = svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com/custom", CustomAlias: "keep"})
+
+	// Switch the scheme, then migrate codes issued under the default one
+	seq, _ := encoder.NewSequence(100000, 1)
+	svc.WithIDSequence(seq)
+
+	dry, err := svc.MigrateCodes(context.Background(), CodeScheme{}, true)
+	if err != nil || dry.Migrated != 3 {
+		t.Fatalf("Expected dry run to report 3 migrations, got: %+v, %v", dry, err)
+	}
+	if _, err := svc.repo.GetByShortCode(oldCodes[0]); err != nil {
+		t.Fatalf("Expected dry run to leave codes in place, got: %v", err)
+	}
+
+	report, err := svc.MigrateCodes(context.Background(), CodeScheme{}, false)
+	if err != nil {
+		t.Fatalf("MigrateCodes failed: %v", err)
+	}
+	if report.Scanned != 4 || report.Migrated != 3 || report.Skipped != 1 || len(report.Conflicts) != 0 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+
+	for i, old := range oldCodes {
+		newCode, _ := svc.encodeID(uint64(i + 1))
+		if newCode == old {
+			t.Fatalf("Expected code %s to change", old)
+		}
+
+		for _, code := range []string{old, newCode} {
+			got, err := svc.Resolve(code)
+			if err != nil || got != urls[i] {
+				t.Errorf("Expected %s to resolve to %s, got: %q, %v", code, urls[i], got, err)
+			}
+		}
+		if id, err := svc.DecodeGeneratedCode(newCode); err != nil || id != uint64(i+1) {
+			t.Errorf("Expected %s to decode to %d, got: %d, %v", newCode, i+1, id, err)
+		}
+	}
+
+	if got, _ := svc.Resolve("keep"); got != "https://example.com/custom" {
+		t.Errorf("Expected custom alias untouched, got: %q", got)
+	}
+
+	// Re-running is a no-op
+	again, _ := svc.MigrateCodes(context.Background(), CodeScheme{}, false)
+	if again.Migrated != 0 {
+		t.Errorf("Expected nothing left to migrate, got: %+v", again)
+	}
+}
+
+func TestMigrateCodes_OverlapIsConflict(t *testing.T) {
+	svc := setupTestService(t)
+	for i := 0; i < 3; i++ {
+		_, _ = svc.CreateShortURL(model.CreateURLRequest{URL: fmt.Sprintf("https://example.com/%d", i)})
+	}
+
+	// Shifting by one would hand codes 2 and 3 to different links
+	seq, _ := encoder.NewSequence(1, 1)
+	svc.WithIDSequence(seq)
+
+	report, err := svc.MigrateCodes(context.Background(), CodeScheme{}, false)
+	if err != nil {
+		t.Fatalf("MigrateCodes failed: %v", err)
+	}
+	if report.Migrated != 1 || len(report.Conflicts) != 2 {
+		t.Errorf("Expected 1 migration and 2 conflicts, got: %+v", report)
+	}
+
+	for i, code := range []string{"1", "2", "3"} {
+		got, err := svc.Resolve(code)
+		if want := fmt.Sprintf("https://example.com/%d", i); err != nil || got != want {
+			t.Errorf("Expected %s to keep resolving to %s, got: %q, %v", code, want, got, err)
+		}
+	}
+}
+
+func TestGetTagStats(t *testing.T) {
+	svc := setupTestService(t)
+
+	seeded := []struct {
+		alias  string
+		tag    string
+		clicks int
+	}{
+		{"spring1", "spring", 3},
+		{"spring2", "spring", 2},
+		{"news1", "newsletter", 7},
+		{"plain1", "", 4}, // untagged links are excluded
+	}
+	for _, s := range seeded {
+		if _, err := svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com", CustomAlias: s.alias, Tag: s.tag}); err != nil {
+			t.Fatalf("Failed to create %s: %v", s.alias, err)
+		}
+		for i := 0; i < s.clicks; i++ {
+			if _, err := svc.Resolve(s.alias); err != nil {
+				t.Fatalf("Resolve failed: %v", err)
+			}
+		}
+	}
+
+	stats, err := svc.GetTagStats(context.Background(), 10)
+	if err != nil {
+		t.Fatalf("GetTagStats failed: %v", err)
+	}
+
+	want := []model.TagStats{
+		{Tag: "newsletter", Links: 1, Clicks: 7},
+		{Tag: "spring", Links: 2, Clicks: 5},
+	}
+	if len(stats) != len(want) {
+		t.Fatalf("Expected %d tags, got: %+v", len(want), stats)
+	}
+	for i := range want {
+		if stats[i] != want[i] {
+			t.Errorf("Expected %+v at position %d, got: %+v", want[i], i, stats[i])
+		}
+	}
+
+	capped, err := svc.GetTagStats(context.Background(), 1)
+	if err != nil || len(capped) != 1 || capped[0].Tag != "newsletter" {
+		t.Errorf("Expected only the busiest tag with limit 1, got: %+v, %v", capped, err)
+	}
+}
+
+func TestCreateShortURL_InvalidTag(t *testing.T) {
+	svc := setupTestService(t)
+
+	for _, tag := range []string{"has space", "emoji🎉", strings.Repeat("a", 65)} {
+		_, err := svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com", Tag: tag})
+		if err != ErrInvalidTag {
+			t.Errorf("Expected ErrInvalidTag for %q, got: %v", tag, err)
+		}
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	seed := setupTestService(t)
+	if _, err := seed.CreateShortURL(model.CreateURLRequest{URL: "https://example.com", CustomAlias: "existing"}); err != nil {
+		t.Fatalf("Failed to seed: %v", err)
+	}
+	if _, err := seed.ReserveShortCode(model.ReserveRequest{CustomAlias: "held"}); err != nil {
+		t.Fatalf("Failed to seed reservation: %v", err)
+	}
+
+	svc := NewURLService(repository.ReadOnly(seed.repo), "http://localhost:8080", nil).WithReadOnly(true)
+
+	writes := map[string]func() error{
+		"create": func() error {
+			_, err := svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com/new"})
+			return err
+		},
+		"reserve": func() error {
+			_, err := svc.ReserveShortCode(model.ReserveRequest{CustomAlias: "another"})
+			return err
+		},
+		"activate": func() error {
+			_, err := svc.ActivateShortCode("held", "", false, model.ActivateRequest{URL: "https://example.com/held"})
+			return err
+		},
+		"migrate": func() error {
+			_, err := svc.MigrateCodes(context.Background(), CodeScheme{}, false)
+			return err
+		},
+	}
+	for name, write := range writes {
+		if err := write(); err != ErrReadOnly {
+			t.Errorf("%s: Expected ErrReadOnly, got: %v", name, err)
+		}
+	}
+
+	got, err := svc.Resolve("existing")
+	if err != nil || got != "https://example.com" {
+		t.Fatalf("Expected redirect to keep working, got: %q, %v", got, err)
+	}
+	stats, err := svc.GetURLStats("existing")
+	if err != nil {
+		t.Fatalf("GetURLStats failed: %v", err)
+	}
+	if stats.ClickCount != 0 {
+		t.Errorf("Expected clicks not to be counted in read-only mode, got: %d", stats.ClickCount)
+	}
+}
+
+func TestCreateShortURL_RegionalBaseURL(t *testing.T) {
+	regions := map[string]string{
+		"eu": "https://eu.sho.rt/",
+		"US": "https://us.sho.rt",
+	}
+
+	tests := []struct {
+		name          string
+		defaultRegion string
+		region        string
+		wantPrefix    string
+	}{
+		{"region from header", "", "eu", "https://eu.sho.rt/"},
+		{"region is case-insensitive", "", "us", "https://us.sho.rt/"},
+		{"unknown region falls back to base", "", "apac", "http://localhost:8080/"},
+		{"no region falls back to base", "", "", "http://localhost:8080/"},
+		{"no region uses configured default", "us", "", "https://us.sho.rt/"},
+		{"unknown region uses configured default", "us", "apac", "https://us.sho.rt/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := setupTestService(t).WithRegionalBaseURLs(regions, tt.defaultRegion)
+			resp, err := svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com", Region: tt.region})
+			if err != nil {
+				t.Fatalf("Failed to create: %v", err)
+			}
+			if !strings.HasPrefix(resp.ShortURL, tt.wantPrefix) {
+				t.Errorf("Expected short URL on %s, got: %s", tt.wantPrefix, resp.ShortURL)
+			}
+
+			// Reserving and activating hand back the same domain
+			reserved, err := svc.ReserveShortCode(model.ReserveRequest{CustomAlias: "held", Region: tt.region})
+			if err != nil {
+				t.Fatalf("Failed to reserve: %v", err)
+			}
+			if !strings.HasPrefix(reserved.ShortURL, tt.wantPrefix) {
+				t.Errorf("Expected reserved short URL on %s, got: %s", tt.wantPrefix, reserved.ShortURL)
+			}
+			activated, err := svc.ActivateShortCode("held", "", false, model.ActivateRequest{URL: "https://example.com/held", Region: tt.region})
+			if err != nil {
+				t.Fatalf("Failed to activate: %v", err)
+			}
+			if !strings.HasPrefix(activated.ShortURL, tt.wantPrefix) {
+				t.Errorf("Expected activated short URL on %s, got: %s", tt.wantPrefix, activated.ShortURL)
+			}
+		})
+	}
+}
+
+func TestCreateShortURL_UniquePerOwner(t *testing.T) {
+	svc := setupTestService(t).WithUniqueURLPerOwner(true)
+	const target = "https://example.com/owned"
+
+	first, err := svc.CreateShortURL(model.CreateURLRequest{URL: target, Owner: "acme", CustomAlias: "acme-link"})
+	if err != nil {
+		t.Fatalf("Failed to create: %v", err)
+	}
+	if first.Existing {
+		t.Error("Expected the first create to be new")
+	}
+
+	// Same owner gets the existing code, asking for no alias or that code
+	for _, alias := range []string{"", "acme-link"} {
+		again, err := svc.CreateShortURL(model.CreateURLRequest{URL: target, Owner: "acme", CustomAlias: alias})
+		if err != nil {
+			t.Fatalf("Failed to create again: %v", err)
+		}
+		if again.ShortURL != first.ShortURL || !again.Existing {
+			t.Errorf("alias %q: Expected existing %s, got: %s (existing=%v)", alias, first.ShortURL, again.ShortURL, again.Existing)
+		}
+	}
+	// Another alias is refused rather than silently swapped for the code
+	if _, err := svc.CreateShortURL(model.CreateURLRequest{URL: target, Owner: "acme", CustomAlias: "acme-again"}); err != ErrOwnerHasURL {
+		t.Errorf("Expected ErrOwnerHasURL, got: %v", err)
+	}
+	if _, err := svc.repo.GetByShortCode("acme-again"); err == nil {
+		t.Error("Expected no record created for the requested alias")
+	}
+
+	// A different owner, or no owner, gets a new code
+	for _, owner := range []string{"globex", ""} {
+		other, err := svc.CreateShortURL(model.CreateURLRequest{URL: target, Owner: owner})
+		if err != nil {
+			t.Fatalf("Failed to create for %q: %v", owner, err)
+		}
+		if other.ShortURL == first.ShortURL || other.Existing {
+			t.Errorf("owner %q: Expected a new code, got: %s", owner, other.ShortURL)
+		}
+	}
+
+	stored, _ := svc.repo.GetByShortCode("acme-link")
+	if stored == nil || stored.Owner != "acme" {
+		t.Errorf("Expected owner stored on the link, got: %+v", stored)
+	}
+}
+
+func TestCreateShortURL_DuplicatesAllowedByDefault(t *testing.T) {
+	svc := setupTestService(t)
+
+	first, _ := svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com/twice", Owner: "acme"})
+	second, err := svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com/twice", Owner: "acme"})
+	if err != nil {
+		t.Fatalf("Failed to create: %v", err)
+	}
+	if second.ShortURL == first.ShortURL || second.Existing {
+		t.Errorf("Expected a second code without UNIQUE_URL_PER_OWNER, got: %s", second.ShortURL)
+	}
+}
+
+func TestResolve_CacheDownCountsFallback(t *testing.T) {
+	base := setupTestService(t)
+	_, _ = base.CreateShortURL(model.CreateURLRequest{URL: "https://example.com/degraded", CustomAlias: "degraded"})
+
+	// Nothing listens on port 1, so every Redis call fails fast
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", DialTimeout: 50 * time.Millisecond, MaxRetries: -1})
+	t.Cleanup(func() { client.Close() })
+	svc := NewURLService(base.repo, "http://localhost:8080", cache.NewRedisCacheFromClient(client))
+
+	readsBefore := metrics.Degradations.Get(metrics.CacheRead)
+	writesBefore := metrics.Degradations.Get(metrics.CacheWrite)
+
+	got, err := svc.Resolve("degraded")
+	if err != nil || got != "https://example.com/degraded" {
+		t.Fatalf("Expected resolve to fall back to the database, got: %q, %v", got, err)
+	}
+
+	if n := metrics.Degradations.Get(metrics.CacheRead) - readsBefore; n != 1 {
+		t.Errorf("Expected 1 cache read fallback, got: %d", n)
+	}
+	if n := metrics.Degradations.Get(metrics.CacheWrite) - writesBefore; n != 1 {
+		t.Errorf("Expected 1 failed cache write, got: %d", n)
+	}
+}
+
+func TestCacheTTL_JitterWithinBounds(t *testing.T) {
+	svc := setupTestService(t).WithCacheTTL(time.Hour).WithCacheJitter(0.1)
+	now := time.Now()
+
+	lo, hi := 54*time.Minute, 66*time.Minute
+	seen := make(map[time.Duration]bool)
+	for i := 0; i < 1000; i++ {
+		ttl := svc.cacheTTLFor(&model.URL{}, now)
+		if ttl < lo || ttl > hi {
+			t.Fatalf("Expected TTL within ±10%% of 1h, got: %s", ttl)
+		}
+		seen[ttl] = true
+	}
+	if len(seen) < 2 {
+		t.Error("Expected TTLs to vary between entries")
+	}
+
+	// Jitter never pushes an entry past the link's expiry
+	expires := now.Add(time.Hour)
+	for i := 0; i < 100; i++ {
+		if ttl := svc.cacheTTLFor(&model.URL{ExpiresAt: &expires}, now); ttl > time.Hour {
+			t.Fatalf("Expected TTL capped at expiry, got: %s", ttl)
+		}
+	}
+
+	exact := setupTestService(t).WithCacheTTL(time.Hour).WithCacheJitter(0)
+	if ttl := exact.cacheTTLFor(&model.URL{}, now); ttl != time.Hour {
+		t.Errorf("Expected exact TTL without jitter, got: %s", ttl)
+	}
+}
+
+func TestResolve_NegativeCache(t *testing.T) {
+	base := setupTestService(t)
+	redisCache, fake := redistest.NewCache(t)
+	svc := NewURLService(base.repo, "http://localhost:8080", redisCache).WithNegativeCacheTTL(30 * time.Second)
+
+	if _, err := svc.Resolve("later"); err != ErrURLNotFound {
+		t.Fatalf("Expected ErrURLNotFound, got: %v", err)
+	}
+	if v, _ := fake.Get("url:later"); v != notFoundSentinel {
+		t.Fatalf("Expected not-found sentinel cached, got: %q", v)
+	}
+
+	// Served from Redis: the repo is never asked
+	failing := newMockRepo()
+	failing.err = errors.New("database should not be queried")
+	cached := NewURLService(failing, "http://localhost:8080", redisCache).WithNegativeCacheTTL(30 * time.Second)
+	hitsBefore := metrics.CacheLookups.Get(metrics.CacheHit)
+	if _, err := cached.Resolve("later"); err != ErrURLNotFound {
+		t.Errorf("Expected cached ErrURLNotFound, got: %v", err)
+	}
+	if n := metrics.CacheLookups.Get(metrics.CacheHit) - hitsBefore; n != 1 {
+		t.Errorf("Expected 1 cache hit, got: %d", n)
+	}
+
+	// Creating the code replaces the sentinel
+	if _, err := svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com/later", CustomAlias: "later"}); err != nil {
+		t.Fatalf("Failed to create: %v", err)
+	}
+	got, err := svc.Resolve("later")
+	if err != nil || got != "https://example.com/later" {
+		t.Errorf("Expected created code to resolve, got: %q, %v", got, err)
+	}
+}
+
+func TestResolve_NegativeCacheClearedByReserve(t *testing.T) {
+	base := setupTestService(t)
+	redisCache, fake := redistest.NewCache(t)
+	svc := NewURLService(base.repo, "http://localhost:8080", redisCache).WithNegativeCacheTTL(30 * time.Second)
+
+	_, _ = svc.Resolve("soon")
+	if _, err := svc.ReserveShortCode(model.ReserveRequest{CustomAlias: "soon"}); err != nil {
+		t.Fatalf("Failed to reserve: %v", err)
+	}
+	if _, ok := fake.Get("url:soon"); ok {
+		t.Error("Expected reserve to evict the not-found entry")
+	}
+	if _, err := svc.Resolve("soon"); err != ErrURLReserved {
+		t.Errorf("Expected ErrURLReserved, got: %v", err)
+	}
+}
+
+func TestResolve_NegativeCacheDisabled(t *testing.T) {
+	base := setupTestService(t)
+	redisCache, fake := redistest.NewCache(t)
+	svc := NewURLService(base.repo, "http://localhost:8080", redisCache)
+
+	_, _ = svc.Resolve("missing")
+	if _, ok := fake.Get("url:missing"); ok {
+		t.Error("Expected nothing cached without a negative TTL")
+	}
+}
+
+func TestResolveLink_DelayedLinksSkipCache(t *testing.T) {
+	base := setupTestService(t)
+	redisCache, fake := redistest.NewCache(t)
+	svc := NewURLService(base.repo, "http://localhost:8080", redisCache)
+
+	if _, err := svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com", CustomAlias: "wait", DelaySeconds: 30}); err != nil {
+		t.Fatalf("Failed to create: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		link, err := svc.ResolveLinkContext(context.Background(), "wait")
+		if err != nil || link.DelaySeconds != 30 {
+			t.Fatalf("Expected a 30 second delay, got: %+v, %v", link, err)
+		}
+	}
+	if _, ok := fake.Get("url:wait"); ok {
+		t.Error("Expected delayed link kept out of the cache")
+	}
+
+	if _, err := svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com", DelaySeconds: MaxRedirectDelay + 1}); err != ErrInvalidDelay {
+		t.Errorf("Expected ErrInvalidDelay, got: %v", err)
+	}
+}
+
+func TestCreateShortURL_InitialClicks(t *testing.T) {
+	svc := setupTestService(t)
+
+	_, err := svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com", CustomAlias: "imported", InitialClicks: 1500})
+	if err != nil {
+		t.Fatalf("Failed to create: %v", err)
+	}
+	_, _ = svc.Resolve("imported")
+
+	stats, err := svc.GetURLStats("imported")
+	if err != nil {
+		t.Fatalf("GetURLStats failed: %v", err)
+	}
+	if stats.ClickCount != 1501 {
+		t.Errorf("Expected count to start at 1500 and grow to 1501, got: %d", stats.ClickCount)
+	}
+}
+
+func TestCreateShortURL_UnicodeAlias(t *testing.T) {
+	table := &unicode.RangeTable{
+		R16: []unicode.Range16{{Lo: 0x00C0, Hi: 0x024F, Stride: 1}},
+		R32: []unicode.Range32{{Lo: 0x1F300, Hi: 0x1FAFF, Stride: 1}},
+	}
+	svc := setupTestService(t).WithUnicodeAliases(table)
+
+	if _, err := svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com/party", CustomAlias: "🎉🎉🎉"}); err != nil {
+		t.Fatalf("Failed to create emoji alias: %v", err)
+	}
+	got, err := svc.Resolve("🎉🎉🎉")
+	if err != nil || got != "https://example.com/party" {
+		t.Errorf("Expected emoji alias to resolve, got: %q, %v", got, err)
+	}
+
+	// Stored in NFC, so the decomposed spelling finds the same link
+	if _, err := svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com/cafe", CustomAlias: "caf\u00e9"}); err != nil {
+		t.Fatalf("Failed to create: %v", err)
+	}
+	got, err = svc.Resolve("cafe\u0301")
+	if err != nil || got != "https://example.com/cafe" {
+		t.Errorf("Expected decomposed spelling to resolve, got: %q, %v", got, err)
+	}
+
+	// Activating and deleting accept it too
+	if _, err := svc.ReserveShortCode(model.ReserveRequest{CustomAlias: "r\u00e9serv\u00e9"}); err != nil {
+		t.Fatalf("Failed to reserve: %v", err)
+	}
+	if _, err := svc.ActivateShortCode("re\u0301serve\u0301", "", false, model.ActivateRequest{URL: "https://example.com/held"}); err != nil {
+		t.Errorf("Expected decomposed spelling to activate, got: %v", err)
+	}
+	if err := svc.DeleteURL("cafe\u0301", "", true); err != nil {
+		t.Errorf("Expected decomposed spelling to delete, got: %v", err)
+	}
+
+	// 20 emoji are 80 bytes but within the 20 character limit
+	if _, err := svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com", CustomAlias: strings.Repeat("🎈", 20)}); err != nil {
+		t.Errorf("Expected 20 emoji accepted, got: %v", err)
+	}
+	if _, err := svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com", CustomAlias: strings.Repeat("🎈", 21)}); err != ErrInvalidAlias {
+		t.Errorf("Expected ErrInvalidAlias for 21 emoji, got: %v", err)
+	}
+
+	plain := setupTestService(t)
+	if _, err := plain.CreateShortURL(model.CreateURLRequest{URL: "https://example.com", CustomAlias: "🎉🎉🎉"}); err != ErrInvalidAlias {
+		t.Errorf("Expected ErrInvalidAlias without Unicode aliases, got: %v", err)
+	}
+}
+
+func TestCreateShortURL_ConcurrentCodesAreUnique(t *testing.T) {
+	svc := setupTestService(t)
+
+	const creates = 50
+	codes := make([]string, creates)
+	errs := make([]error, creates)
+	var wg sync.WaitGroup
+	for i := 0; i < creates; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resp, err := svc.CreateShortURL(model.CreateURLRequest{URL: fmt.Sprintf("https://example.com/%d", i)})
+			errs[i] = err
+			if err == nil {
+				codes[i] = strings.TrimPrefix(resp.ShortURL, "http://localhost:8080/")
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	seen := make(map[string]int)
+	for i, code := range codes {
+		if errs[i] != nil {
+			t.Fatalf("Create %d failed: %v", i, errs[i])
+		}
+		if prev, dup := seen[code]; dup {
+			t.Fatalf("Creates %d and %d both got code %q", prev, i, code)
+		}
+		seen[code] = i
+
+		got, err := svc.Resolve(code)
+		if err != nil || got != fmt.Sprintf("https://example.com/%d", i) {
+			t.Errorf("Expected %q to resolve to its own URL, got: %q, %v", code, got, err)
+		}
+	}
+}
+
+func TestCreateShortURL_GeneratedSkipsTakenCode(t *testing.T) {
+	svc := setupTestService(t)
+
+	// Row 1 holds the code ID 2 would be given
+	taken, err := svc.encodeID(2)
+	if err != nil {
+		t.Fatalf("encodeID failed: %v", err)
+	}
+	if err := svc.repo.Create(&model.URL{ShortCode: taken, OriginalURL: "https://example.com/alias"}); err != nil {
+		t.Fatalf("Failed to create: %v", err)
+	}
+
+	resp, err := svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com/generated"})
+	if err != nil {
+		t.Fatalf("Expected the taken code to be skipped, got: %v", err)
+	}
+	if resp.ShortURL == "http://localhost:8080/"+taken {
+		t.Fatalf("Expected a different code than %q", taken)
+	}
+	got, err := svc.Resolve(taken)
+	if err != nil || got != "https://example.com/alias" {
+		t.Errorf("Expected the alias untouched, got: %q, %v", got, err)
+	}
+}
+
+func TestCreateShortURL_GeneratedCodesExhausted(t *testing.T) {
+	svc := setupTestService(t)
+
+	// Rows 1-3 hold the codes IDs 4-6 would be given, so every attempt collides
+	for id := uint64(4); id < 4+generatedCodeAttempts; id++ {
+		taken, err := svc.encodeID(id)
+		if err != nil {
+			t.Fatalf("encodeID failed: %v", err)
+		}
+		if err := svc.repo.Create(&model.URL{ShortCode: taken, OriginalURL: "https://example.com/alias"}); err != nil {
+			t.Fatalf("Failed to create: %v", err)
+		}
+	}
+
+	// The caller asked for no alias, so this isn't ErrAliasExists
+	if _, err := svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com/generated"}); err != ErrNoFreeCode {
+		t.Errorf("Expected ErrNoFreeCode, got: %v", err)
+	}
+}
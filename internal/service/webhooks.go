@@ -0,0 +1,169 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/darkodi/url-shortener/internal/metrics"
+	"github.com/darkodi/url-shortener/internal/outbound"
+)
+
+// maxWebhookURL caps a link's webhook_url, in bytes
+const maxWebhookURL = 2048
+
+// Headers carried by every webhook delivery
+const (
+	WebhookSignatureHeader = "X-Signature"
+	WebhookTimestampHeader = "X-Signature-Timestamp"
+)
+
+// WebhookEvent is the JSON body of a webhook delivery
+type WebhookEvent struct {
+	Event     string    `json:"event"` // "click"
+	ShortCode string    `json:"short_code"`
+	ClickedAt time.Time `json:"clicked_at"`
+
+	target string // the link's own endpoint; empty uses WEBHOOK_URL
+}
+
+// SignWebhook returns the X-Signature value for body sent at timestamp
+// (Unix seconds): "sha256=" and the hex HMAC-SHA256 of "timestamp.body".
+// Signing the timestamp lets receivers reject replayed deliveries.
+func SignWebhook(secret []byte, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhook is the receiver's check: signature must match body and
+// timestamp, and timestamp must be within tolerance of now
+func VerifyWebhook(secret []byte, timestamp, signature string, body []byte, tolerance time.Duration, now time.Time) bool {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if age := now.Sub(time.Unix(ts, 0)); age > tolerance || age < -tolerance {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(SignWebhook(secret, ts, body)))
+}
+
+// WebhookSender POSTs signed click events from a background queue, so a
+// slow receiver never delays a redirect. Links with a webhook_url get
+// their events there; the rest go to the configured URL, if any. Events
+// that don't fit in the queue are dropped and counted in metrics.Drops.
+// Safe for concurrent use.
+type WebhookSender struct {
+	client     *http.Client // delivers to url
+	linkClient *http.Client // delivers to per-link URLs
+	url        string
+	secret     []byte
+	queue      chan WebhookEvent
+}
+
+// NewWebhookSender delivers to url, signing with secret, with up to
+// queueSize events waiting. An empty url sends only per-link events.
+// Pass outbound.NewClient as client and outbound.NewPublicClient as
+// linkClient: per-link URLs are chosen by whoever creates the link, so
+// they must not reach internal addresses.
+func NewWebhookSender(client, linkClient *http.Client, url, secret string, queueSize int) *WebhookSender {
+	return &WebhookSender{
+		client:     client,
+		linkClient: linkClient,
+		url:        url,
+		secret:     []byte(secret),
+		queue:      make(chan WebhookEvent, max(queueSize, 1)),
+	}
+}
+
+// enqueue queues event for delivery, dropping it when the queue is full
+// or there is nowhere to send it
+func (ws *WebhookSender) enqueue(event WebhookEvent) {
+	if event.target == "" && ws.url == "" {
+		return
+	}
+	select {
+	case ws.queue <- event:
+	default:
+		metrics.Drops.Inc(metrics.WebhookQueue)
+	}
+}
+
+// Deliver sends one event now. Any status below 300 counts as delivered.
+func (ws *WebhookSender) Deliver(ctx context.Context, event WebhookEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	client, target := ws.client, ws.url
+	if event.target != "" {
+		client, target = ws.linkClient, event.target
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(ws.secret, timestamp, body))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook answered %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Run delivers queued events until ctx is cancelled
+func (ws *WebhookSender) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-ws.queue:
+			if err := ws.Deliver(ctx, event); err != nil && ctx.Err() == nil {
+				fmt.Printf("Warning: webhook delivery for %s failed: %v\n", event.ShortCode, err)
+			}
+		}
+	}
+}
+
+// validateWebhookURL checks a link's webhook_url. Addresses that can't be
+// public are refused here; hostnames are checked again when delivering.
+func validateWebhookURL(raw string) error {
+	if len(raw) > maxWebhookURL {
+		return ErrInvalidWebhook
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" || u.User != nil {
+		return ErrInvalidWebhook
+	}
+	if ip := net.ParseIP(u.Hostname()); ip != nil && !outbound.IsPublicIP(ip) {
+		return ErrInvalidWebhook
+	}
+	return nil
+}
+
+// WithWebhooks sends a click event through ws for every counted click,
+// and lets links set their own webhook_url. Do-not-track clicks, when
+// honored, send none.
+func (s *URLService) WithWebhooks(ws *WebhookSender) *URLService {
+	s.webhooks = ws
+	return s
+}
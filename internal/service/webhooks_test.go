@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/darkodi/url-shortener/internal/config"
+	"github.com/darkodi/url-shortener/internal/metrics"
+	"github.com/darkodi/url-shortener/internal/model"
+	"github.com/darkodi/url-shortener/internal/outbound"
+)
+
+func TestWebhook_SignatureVerifies(t *testing.T) {
+	secret := []byte("webhook-secret")
+	type delivery struct {
+		signature, timestamp string
+		body                 []byte
+	}
+	received := make(chan delivery, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- delivery{r.Header.Get(WebhookSignatureHeader), r.Header.Get(WebhookTimestampHeader), body}
+	}))
+	defer srv.Close()
+
+	sender := NewWebhookSender(srv.Client(), srv.Client(), srv.URL, string(secret), 10)
+	svc := setupTestService(t).WithWebhooks(sender)
+	_, _ = svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com", CustomAlias: "hooked"})
+	if _, err := svc.Resolve("hooked"); err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go sender.Run(ctx)
+
+	var got delivery
+	select {
+	case got = <-received:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected a webhook delivery for the click")
+	}
+
+	var event WebhookEvent
+	if err := json.Unmarshal(got.body, &event); err != nil || event.Event != "click" || event.ShortCode != "hooked" {
+		t.Errorf("Expected a click event for hooked, got: %s, %v", got.body, err)
+	}
+	if !VerifyWebhook(secret, got.timestamp, got.signature, got.body, 5*time.Minute, time.Now()) {
+		t.Errorf("Expected the signature to verify, got: %s at %s", got.signature, got.timestamp)
+	}
+
+	// Tampering, the wrong secret, or a stale timestamp all fail
+	tampered := append([]byte(nil), got.body...)
+	tampered[len(tampered)-2] ^= 1
+	if VerifyWebhook(secret, got.timestamp, got.signature, tampered, 5*time.Minute, time.Now()) {
+		t.Error("Expected a tampered body to fail verification")
+	}
+	if VerifyWebhook([]byte("other"), got.timestamp, got.signature, got.body, 5*time.Minute, time.Now()) {
+		t.Error("Expected the wrong secret to fail verification")
+	}
+	if VerifyWebhook(secret, got.timestamp, got.signature, got.body, 5*time.Minute, time.Now().Add(time.Hour)) {
+		t.Error("Expected a replayed delivery to fail verification")
+	}
+}
+
+func TestWebhook_PerLinkURL(t *testing.T) {
+	received := make(chan string, 2)
+	endpoint := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var event WebhookEvent
+			_ = json.NewDecoder(r.Body).Decode(&event)
+			received <- name + ":" + event.ShortCode
+		}))
+	}
+	shared, own := endpoint("shared"), endpoint("own")
+	defer shared.Close()
+	defer own.Close()
+
+	sender := NewWebhookSender(shared.Client(), own.Client(), shared.URL, "secret", 10)
+	svc := setupTestService(t).WithWebhooks(sender)
+	// A hostname, since literal loopback addresses are refused up front
+	ownURL := strings.Replace(own.URL, "127.0.0.1", "localhost", 1)
+	if _, err := svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com/a", CustomAlias: "ownhook", WebhookURL: ownURL}); err != nil {
+		t.Fatalf("CreateShortURL failed: %v", err)
+	}
+	if _, err := svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com/b", CustomAlias: "sharedhook"}); err != nil {
+		t.Fatalf("CreateShortURL failed: %v", err)
+	}
+	for _, code := range []string{"ownhook", "sharedhook"} {
+		if _, err := svc.Resolve(code); err != nil {
+			t.Fatalf("Resolve %s failed: %v", code, err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go sender.Run(ctx)
+
+	got := map[string]bool{}
+	for range 2 {
+		select {
+		case delivery := <-received:
+			got[delivery] = true
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Expected two deliveries, got: %v", got)
+		}
+	}
+	if !got["own:ownhook"] || !got["shared:sharedhook"] {
+		t.Errorf("Expected each link's event at its own endpoint, got: %v", got)
+	}
+}
+
+func TestWebhook_LinkURLValidation(t *testing.T) {
+	svc := setupTestService(t)
+	if _, err := svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com", WebhookURL: "https://hooks.example.com/"}); err != ErrWebhooksDisabled {
+		t.Errorf("Expected ErrWebhooksDisabled without a sender, got: %v", err)
+	}
+
+	svc.WithWebhooks(NewWebhookSender(http.DefaultClient, http.DefaultClient, "", "secret", 10))
+	for _, raw := range []string{"ftp://hooks.example.com/", "https://127.0.0.1/hook", "http://[::1]:8080/", "http://10.0.0.7/", "https://user:pw@hooks.example.com/"} {
+		if _, err := svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com", WebhookURL: raw}); err != ErrInvalidWebhook {
+			t.Errorf("Expected ErrInvalidWebhook for %s, got: %v", raw, err)
+		}
+	}
+	if _, err := svc.CreateShortURL(model.CreateURLRequest{URL: "https://example.com", WebhookURL: "https://hooks.example.com/click"}); err != nil {
+		t.Errorf("Expected a public webhook URL to be accepted, got: %v", err)
+	}
+}
+
+func TestWebhook_LinkDeliveriesStayPublic(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Expected no delivery to a loopback address")
+	}))
+	defer srv.Close()
+
+	// The hostname passes validation; the dialer still refuses it
+	link := strings.Replace(srv.URL, "127.0.0.1", "localhost", 1)
+	sender := NewWebhookSender(srv.Client(), outbound.NewPublicClient(config.OutboundConfig{Timeout: time.Second, TLSMinVersion: "1.2"}), "", "secret", 10)
+	err := sender.Deliver(context.Background(), WebhookEvent{Event: "click", ShortCode: "inside", target: link})
+	if !errors.Is(err, outbound.ErrNonPublicAddress) {
+		t.Errorf("Expected ErrNonPublicAddress, got: %v", err)
+	}
+}
+
+func TestWebhook_FullQueueCountsDrops(t *testing.T) {
+	sender := NewWebhookSender(http.DefaultClient, http.DefaultClient, "https://hooks.example.com/", "secret", 1)
+	before := metrics.Drops.Get(metrics.WebhookQueue)
+	sender.enqueue(WebhookEvent{Event: "click", ShortCode: "first"})
+	sender.enqueue(WebhookEvent{Event: "click", ShortCode: "second"})
+	if got := metrics.Drops.Get(metrics.WebhookQueue) - before; got != 1 {
+		t.Errorf("Expected 1 dropped event, got: %d", got)
+	}
+}
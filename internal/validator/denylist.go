@@ -0,0 +1,74 @@
+package validator
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// leetReplacer undoes common digit-for-letter substitutions. "1" is read
+// as "i" here and as "l" by leetReplacerL, and both forms are checked.
+var (
+	leetReplacer  = strings.NewReplacer("0", "o", "1", "i", "3", "e", "4", "a", "5", "s", "7", "t", "8", "b", "9", "g", "-", "", "_", "")
+	leetReplacerL = strings.NewReplacer("0", "o", "1", "l", "3", "e", "4", "a", "5", "s", "7", "t", "8", "b", "9", "g", "-", "", "_", "")
+)
+
+// LoadWordList reads one word per line, skipping blanks and # comments
+func LoadWordList(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open word list: %w", err)
+	}
+	defer f.Close()
+
+	var words []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		word := strings.TrimSpace(scanner.Text())
+		if word == "" || strings.HasPrefix(word, "#") {
+			continue
+		}
+		words = append(words, word)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read word list: %w", err)
+	}
+	return words, nil
+}
+
+// WithDeniedWords rejects custom aliases containing any of words, after
+// lowercasing, dropping separators, and undoing simple leetspeak
+func (v *URLValidator) WithDeniedWords(words ...string) *URLValidator {
+	for _, word := range words {
+		if normalized := normalizeAlias(word, leetReplacer); normalized != "" {
+			v.deniedWords = append(v.deniedWords, normalized)
+		}
+	}
+	return v
+}
+
+// isDeniedAlias reports whether code contains a denied word in any of its
+// normalized forms
+func (v *URLValidator) isDeniedAlias(code string) bool {
+	if len(v.deniedWords) == 0 {
+		return false
+	}
+
+	forms := []string{
+		normalizeAlias(code, leetReplacer),
+		normalizeAlias(code, leetReplacerL),
+	}
+	for _, word := range v.deniedWords {
+		for _, form := range forms {
+			if strings.Contains(form, word) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func normalizeAlias(s string, replacer *strings.Replacer) string {
+	return replacer.Replace(strings.ToLower(s))
+}
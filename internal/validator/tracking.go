@@ -0,0 +1,62 @@
+package validator
+
+import (
+	"net/url"
+	"strings"
+)
+
+// DefaultTrackingParams are the campaign and click identifiers stripped
+// when tracking parameter stripping is enabled without an explicit list
+var DefaultTrackingParams = []string{"utm_*", "fbclid", "gclid"}
+
+// WithTrackingParamStripping removes the given query parameters from URLs
+// in NormalizeURL. Names match case-insensitively, and a trailing "*"
+// matches any parameter with that prefix.
+func (v *URLValidator) WithTrackingParamStripping(params ...string) *URLValidator {
+	v.trackingParams = v.trackingParams[:0]
+	for _, param := range params {
+		if param = strings.ToLower(strings.TrimSpace(param)); param != "" {
+			v.trackingParams = append(v.trackingParams, param)
+		}
+	}
+	return v
+}
+
+// stripTrackingParams drops matching parameters from u's query, keeping the
+// rest in their original order and encoding. It reports whether any were removed.
+func (v *URLValidator) stripTrackingParams(u *url.URL) bool {
+	if len(v.trackingParams) == 0 || u.RawQuery == "" {
+		return false
+	}
+
+	pairs := strings.Split(u.RawQuery, "&")
+	kept := pairs[:0]
+	for _, pair := range pairs {
+		key, _, _ := strings.Cut(pair, "=")
+		if name, err := url.QueryUnescape(key); err == nil && v.isTrackingParam(name) {
+			continue
+		}
+		kept = append(kept, pair)
+	}
+	if len(kept) == len(pairs) {
+		return false
+	}
+
+	u.RawQuery = strings.Join(kept, "&")
+	u.ForceQuery = false
+	return true
+}
+
+func (v *URLValidator) isTrackingParam(name string) bool {
+	name = strings.ToLower(name)
+	for _, param := range v.trackingParams {
+		if prefix, ok := strings.CutSuffix(param, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == param {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package validator
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// ParseRuneRanges parses comma-separated hex code points and inclusive
+// ranges, e.g. "1F300-1FAFF,00C0-024F,FE0F", into a table for unicode.Is
+func ParseRuneRanges(spec string) (*unicode.RangeTable, error) {
+	type span struct{ lo, hi rune }
+	var spans []span
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		loText, hiText, isRange := strings.Cut(part, "-")
+		if !isRange {
+			hiText = loText
+		}
+		lo, err := parseCodePoint(loText)
+		if err != nil {
+			return nil, err
+		}
+		hi, err := parseCodePoint(hiText)
+		if err != nil {
+			return nil, err
+		}
+		if hi < lo {
+			return nil, fmt.Errorf("invalid range %q: end is before start", part)
+		}
+		if lo < 0x80 {
+			return nil, fmt.Errorf("invalid range %q: ASCII is governed by the alias rules", part)
+		}
+		spans = append(spans, span{lo, hi})
+	}
+	if len(spans) == 0 {
+		return nil, fmt.Errorf("no ranges given")
+	}
+
+	// unicode.Is expects sorted, non-overlapping ranges
+	sort.Slice(spans, func(i, j int) bool { return spans[i].lo < spans[j].lo })
+	merged := spans[:1]
+	for _, s := range spans[1:] {
+		last := &merged[len(merged)-1]
+		if s.lo <= last.hi+1 {
+			last.hi = max(last.hi, s.hi)
+			continue
+		}
+		merged = append(merged, s)
+	}
+
+	table := &unicode.RangeTable{}
+	for _, s := range merged {
+		if s.lo <= 0xFFFF {
+			hi16 := min(s.hi, 0xFFFF)
+			table.R16 = append(table.R16, unicode.Range16{Lo: uint16(s.lo), Hi: uint16(hi16), Stride: 1})
+			if s.hi <= 0xFFFF {
+				continue
+			}
+			s.lo = 0x10000
+		}
+		table.R32 = append(table.R32, unicode.Range32{Lo: uint32(s.lo), Hi: uint32(s.hi), Stride: 1})
+	}
+	return table, nil
+}
+
+func parseCodePoint(s string) (rune, error) {
+	s = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(s), "U+"), "u+")
+	n, err := strconv.ParseUint(s, 16, 32)
+	if err != nil || n > unicode.MaxRune {
+		return 0, fmt.Errorf("invalid code point %q", s)
+	}
+	return rune(n), nil
+}
+
+// WithUnicodeAliases also accepts characters from table in short codes.
+// Codes are checked in NFC form, and lengths count characters, not bytes.
+func (v *URLValidator) WithUnicodeAliases(table *unicode.RangeTable) *URLValidator {
+	v.unicodeAliases = table
+	return v
+}
+
+// validUnicodeCode reports whether every character of the NFC form of code
+// is an ASCII alias character or in the configured table
+func (v *URLValidator) validUnicodeCode(code string) bool {
+	for _, r := range norm.NFC.String(code) {
+		isASCII := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_'
+		if !isASCII && !unicode.Is(v.unicodeAliases, r) {
+			return false
+		}
+	}
+	return true
+}
@@ -1,19 +1,49 @@
 package validator
 
 import (
+	"net"
 	"net/url"
 	"regexp"
+	"slices"
 	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/text/unicode/norm"
 
 	"github.com/darkodi/url-shortener/internal/errors"
+	"github.com/darkodi/url-shortener/internal/logger"
+)
+
+// Rejection reasons reported when a URL fails validation
+const (
+	ReasonEmpty         = "empty"
+	ReasonLength        = "length"
+	ReasonUnparseable   = "unparseable"
+	ReasonScheme        = "scheme"
+	ReasonMissingHost   = "missing_host"
+	ReasonInvalidHost   = "invalid_host"
+	ReasonBlockedDomain = "blocked_domain"
+	ReasonPrivateIP     = "private_ip"
 )
 
+// reservedWords are route names custom aliases may not take
+var reservedWords = []string{"api", "admin", "errors", "health", "reserve", "shorten", "stats", "static", "urls"}
+
 // URLValidator validates URL inputs
 type URLValidator struct {
 	maxLength       int
 	allowedSchemes  []string
 	blockedDomains  []string
 	blockPrivateIPs bool
+	normalizeHosts  bool           // punycode IDN hosts and strip trailing dots before checks
+	rejectionLog    *logger.Logger // logs the reason for each rejected URL when set
+	deniedWords     []string       // normalized words custom aliases may not contain
+	trackingParams  []string       // lowercased query params stripped on storage; "utm_*" matches a prefix
+
+	// Non-ASCII characters short codes may contain; nil allows none
+	unicodeAliases *unicode.RangeTable
 }
 
 // NewURLValidator creates a validator with default settings
@@ -23,48 +53,103 @@ func NewURLValidator() *URLValidator {
 		allowedSchemes:  []string{"http", "https"},
 		blockedDomains:  []string{},
 		blockPrivateIPs: true,
+		normalizeHosts:  true,
 	}
 }
 
 // ValidateURL validates a URL string
 func (v *URLValidator) ValidateURL(rawURL string) *errors.AppError {
+	reason, parsedURL, appErr := v.validateURL(rawURL)
+	if appErr != nil && v.rejectionLog != nil {
+		// Only the scheme and host are logged; paths and queries can carry secrets
+		args := []any{"reason", reason, "length", len(rawURL)}
+		if parsedURL != nil {
+			args = append(args, "scheme", parsedURL.Scheme, "host", parsedURL.Host)
+		}
+		v.rejectionLog.Info("url rejected", args...)
+	}
+	return appErr
+}
+
+// validateURL runs the checks and reports which one failed. The parsed URL
+// is returned when parsing got that far.
+func (v *URLValidator) validateURL(rawURL string) (string, *url.URL, *errors.AppError) {
 	// Check if empty
 	if strings.TrimSpace(rawURL) == "" {
-		return errors.MissingField("url")
+		return ReasonEmpty, nil, errors.MissingField("url")
 	}
 
 	// Check length
 	if len(rawURL) > v.maxLength {
-		return errors.InvalidURL("URL exceeds maximum length of 2048 characters")
+		return ReasonLength, nil, errors.InvalidURL("URL exceeds maximum length of 2048 characters")
 	}
 
 	// Parse URL
 	parsedURL, err := url.Parse(rawURL)
 	if err != nil {
-		return errors.InvalidURL("URL could not be parsed")
+		return ReasonUnparseable, nil, errors.InvalidURL("URL could not be parsed")
 	}
 
 	// Check scheme
 	if !v.isAllowedScheme(parsedURL.Scheme) {
-		return errors.InvalidURL("URL must use http or https scheme")
+		return ReasonScheme, parsedURL, errors.InvalidURL("URL must use http or https scheme")
 	}
 
 	// Check host exists
 	if parsedURL.Host == "" {
-		return errors.InvalidURL("URL must have a valid host")
+		return ReasonMissingHost, parsedURL, errors.InvalidURL("URL must have a valid host")
+	}
+
+	// Canonicalize so "exämple.com" and "example.com." match their plain forms
+	host := parsedURL.Host
+	if v.normalizeHosts {
+		host, err = canonicalHost(parsedURL)
+		if err != nil {
+			return ReasonInvalidHost, parsedURL, errors.InvalidURL("URL host is not a valid domain name")
+		}
 	}
 
 	// Check for blocked domains
-	if v.isBlockedDomain(parsedURL.Host) {
-		return errors.InvalidURL("This domain is not allowed")
+	if v.isBlockedDomain(host) {
+		return ReasonBlockedDomain, parsedURL, errors.InvalidURL("This domain is not allowed")
 	}
 
 	// Check for private/local IPs
-	if v.blockPrivateIPs && v.isPrivateIP(parsedURL.Host) {
-		return errors.InvalidURL("URLs pointing to private IPs are not allowed")
+	if v.blockPrivateIPs && v.isPrivateIP(host) {
+		return ReasonPrivateIP, parsedURL, errors.InvalidURL("URLs pointing to private IPs are not allowed")
 	}
 
-	return nil
+	return "", parsedURL, nil
+}
+
+// NormalizeURL rewrites the host of a valid URL to its canonical form
+// (punycode, no trailing dot) and drops configured tracking parameters so
+// equivalent URLs are stored identically. URLs that are unchanged or can't
+// be normalized are returned as-is.
+func (v *URLValidator) NormalizeURL(rawURL string) string {
+	if !v.normalizeHosts && len(v.trackingParams) == 0 {
+		return rawURL
+	}
+
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil || parsedURL.Host == "" {
+		return rawURL
+	}
+
+	changed := false
+	if v.normalizeHosts {
+		if host, err := canonicalHost(parsedURL); err == nil && host != parsedURL.Host {
+			parsedURL.Host = host
+			changed = true
+		}
+	}
+	if v.stripTrackingParams(parsedURL) {
+		changed = true
+	}
+	if !changed {
+		return rawURL
+	}
+	return parsedURL.String()
 }
 
 // ValidateShortCode validates a short code format
@@ -73,6 +158,17 @@ func (v *URLValidator) ValidateShortCode(code string) *errors.AppError {
 		return errors.MissingField("code")
 	}
 
+	if v.unicodeAliases != nil {
+		// Multi-byte characters count once, as the database does
+		if n := utf8.RuneCountInString(norm.NFC.String(code)); n > 20 {
+			return errors.BadRequest("Short code must be between 1 and 20 characters")
+		}
+		if !v.validUnicodeCode(code) {
+			return errors.BadRequest("Short code can only contain letters, numbers, hyphens, underscores, and allowed symbols")
+		}
+		return nil
+	}
+
 	// Check length (typically 6-10 characters)
 	if len(code) < 1 || len(code) > 20 {
 		return errors.BadRequest("Short code must be between 1 and 20 characters")
@@ -92,16 +188,32 @@ func (v *URLValidator) ValidateCustomCode(code string) *errors.AppError {
 	if code == "" {
 		return nil // Custom code is optional
 	}
+	if strings.ContainsFunc(code, unicode.IsSpace) {
+		return errors.BadRequest("Alias must not contain spaces")
+	}
 
 	// Check reserved words
-	reserved := []string{"api", "admin", "health", "shorten", "stats", "static"}
-	for _, r := range reserved {
+	for _, r := range reservedWords {
 		if strings.EqualFold(code, r) {
 			return errors.BadRequest("This short code is reserved and cannot be used")
 		}
 	}
 
-	return v.ValidateShortCode(code)
+	if appErr := v.ValidateShortCode(code); appErr != nil {
+		return appErr
+	}
+
+	if v.isDeniedAlias(code) {
+		return errors.BadRequest("This short code is not allowed")
+	}
+
+	return nil
+}
+
+// ReservedWords returns the aliases ValidateCustomCode always rejects,
+// compared case-insensitively
+func (v *URLValidator) ReservedWords() []string {
+	return slices.Clone(reservedWords)
 }
 
 // ============================================================
@@ -118,6 +230,27 @@ func (v *URLValidator) isAllowedScheme(scheme string) bool {
 	return false
 }
 
+// canonicalHost returns the URL's host in ASCII (punycode) form, lowercased,
+// without a trailing dot, and with any port preserved. IP literals are only lowercased.
+func canonicalHost(u *url.URL) (string, error) {
+	hostname := strings.TrimSuffix(u.Hostname(), ".")
+	port := u.Port()
+
+	if ip := net.ParseIP(hostname); ip != nil {
+		return strings.ToLower(u.Host), nil
+	}
+
+	ascii, err := idna.Lookup.ToASCII(hostname)
+	if err != nil {
+		return "", err
+	}
+
+	if port != "" {
+		return ascii + ":" + port, nil
+	}
+	return ascii, nil
+}
+
 func (v *URLValidator) isBlockedDomain(host string) bool {
 	host = strings.ToLower(host)
 	for _, blocked := range v.blockedDomains {
@@ -174,6 +307,19 @@ func (v *URLValidator) WithBlockedDomains(domains ...string) *URLValidator {
 	return v
 }
 
+// WithoutHostNormalization checks hosts exactly as given
+func (v *URLValidator) WithoutHostNormalization() *URLValidator {
+	v.normalizeHosts = false
+	return v
+}
+
+// WithRejectionLogger logs a reason-tagged entry for every rejected URL,
+// with only its scheme and host
+func (v *URLValidator) WithRejectionLogger(log *logger.Logger) *URLValidator {
+	v.rejectionLog = log
+	return v
+}
+
 // WithAllowPrivateIPs allows private IP addresses
 func (v *URLValidator) WithAllowPrivateIPs() *URLValidator {
 	v.blockPrivateIPs = false
@@ -0,0 +1,243 @@
+package validator
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/darkodi/url-shortener/internal/logger"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	v := NewURLValidator()
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"canonical unchanged", "https://example.com/path?q=1", "https://example.com/path?q=1"},
+		{"trailing dot", "https://example.com./path", "https://example.com/path"},
+		{"IDN host", "https://exämple.com/path", "https://xn--exmple-cua.com/path"},
+		{"IDN with port and dot", "https://Exämple.com.:8443/", "https://xn--exmple-cua.com:8443/"},
+		{"IP literal", "http://93.184.216.34/", "http://93.184.216.34/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := v.NormalizeURL(tt.input); got != tt.expected {
+				t.Errorf("NormalizeURL(%s) = %s; want %s", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNormalizeURL_TrackingParams(t *testing.T) {
+	v := NewURLValidator().WithTrackingParamStripping("utm_*", "FBCLID", "gclid")
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"no query", "https://example.com/path", "https://example.com/path"},
+		{"utm prefix", "https://example.com/?utm_source=a&utm_medium=b", "https://example.com/"},
+		{"others kept in order", "https://example.com/p?b=2&gclid=x&a=1#top", "https://example.com/p?b=2&a=1#top"},
+		{"case-insensitive", "https://example.com/?FbClid=x&q=go", "https://example.com/?q=go"},
+		{"encoding preserved", "https://example.com/?q=a%20b&utm_id=1", "https://example.com/?q=a%20b"},
+		{"similar name kept", "https://example.com/?utm=1&fbclid_extra=2", "https://example.com/?utm=1&fbclid_extra=2"},
+		{"with host normalization", "https://exämple.com./?utm_campaign=x", "https://xn--exmple-cua.com/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := v.NormalizeURL(tt.input); got != tt.expected {
+				t.Errorf("NormalizeURL(%s) = %s; want %s", tt.input, got, tt.expected)
+			}
+		})
+	}
+
+	// Disabled by default
+	raw := "https://example.com/?utm_source=a&gclid=b"
+	if got := NewURLValidator().NormalizeURL(raw); got != raw {
+		t.Errorf("Expected tracking params preserved by default, got: %s", got)
+	}
+	// Stripping works without host normalization
+	if got := NewURLValidator().WithoutHostNormalization().WithTrackingParamStripping(DefaultTrackingParams...).NormalizeURL(raw); got != "https://example.com/" {
+		t.Errorf("Expected tracking params stripped without host normalization, got: %s", got)
+	}
+}
+
+func TestValidateURL_NormalizedHostChecks(t *testing.T) {
+	v := NewURLValidator().WithBlockedDomains("evil.com", "xn--exmple-cua.com")
+
+	tests := []struct {
+		name    string
+		url     string
+		blocked bool
+	}{
+		{"blocked canonical", "https://evil.com/", true},
+		{"blocked trailing dot", "https://evil.com./", true},
+		{"blocked IDN by punycode entry", "https://exämple.com/", true},
+		{"localhost trailing dot", "http://localhost./admin", true},
+		{"allowed", "https://example.com/", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.ValidateURL(tt.url)
+			if (err != nil) != tt.blocked {
+				t.Errorf("ValidateURL(%s) = %v; want blocked=%v", tt.url, err, tt.blocked)
+			}
+		})
+	}
+}
+
+func TestValidateURL_WithoutHostNormalization(t *testing.T) {
+	v := NewURLValidator().WithoutHostNormalization().WithBlockedDomains("evil.com")
+
+	// Exact matching only: the IDN host is left as typed
+	if got := v.NormalizeURL("https://exämple.com./"); got != "https://exämple.com./" {
+		t.Errorf("Expected URL unchanged, got: %s", got)
+	}
+	// Substring blocklist still catches the trailing-dot form
+	if err := v.ValidateURL("https://evil.com./"); err == nil {
+		t.Error("Expected evil.com. to be blocked")
+	}
+}
+
+func TestValidateURL_LogsRejectionReason(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.New(logger.Config{Level: "info", Format: "json", Output: &buf})
+	v := NewURLValidator().WithBlockedDomains("evil.com").WithRejectionLogger(log)
+
+	tests := []struct {
+		url    string
+		reason string
+	}{
+		{"", ReasonEmpty},
+		{"https://example.com/" + strings.Repeat("a", 2100), ReasonLength},
+		{"ftp://example.com/secret-path", ReasonScheme},
+		{"https:///secret-path", ReasonMissingHost},
+		{"https://evil.com/secret-path?token=abc", ReasonBlockedDomain},
+		{"http://192.168.1.1/secret-path", ReasonPrivateIP},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.reason, func(t *testing.T) {
+			buf.Reset()
+			if appErr := v.ValidateURL(tt.url); appErr == nil {
+				t.Fatalf("Expected %q to be rejected", tt.url)
+			}
+
+			var entry map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+				t.Fatalf("Expected one JSON log line, got: %s", buf.String())
+			}
+			if entry["reason"] != tt.reason {
+				t.Errorf("Expected reason %s, got: %v", tt.reason, entry["reason"])
+			}
+			if strings.Contains(buf.String(), "secret-path") || strings.Contains(buf.String(), "token") {
+				t.Errorf("Expected path and query kept out of the log, got: %s", buf.String())
+			}
+		})
+	}
+}
+
+func TestValidateURL_NoLogWhenAccepted(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.New(logger.Config{Level: "info", Format: "json", Output: &buf})
+	v := NewURLValidator().WithRejectionLogger(log)
+
+	if appErr := v.ValidateURL("https://example.com/ok"); appErr != nil {
+		t.Fatalf("Expected URL to be accepted, got: %v", appErr)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Expected no log for an accepted URL, got: %s", buf.String())
+	}
+}
+
+func TestValidateCustomCode_Denylist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "denylist.txt")
+	if err := os.WriteFile(path, []byte("# offensive words\nbadword\n\nslur\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write word list: %v", err)
+	}
+	words, err := LoadWordList(path)
+	if err != nil || len(words) != 2 {
+		t.Fatalf("Expected 2 words, got: %v, %v", words, err)
+	}
+	v := NewURLValidator().WithDeniedWords(words...)
+
+	tests := []struct {
+		alias   string
+		allowed bool
+	}{
+		{"summer-sale", true},
+		{"badword", false},
+		{"BadWord2024", false},
+		{"b4dw0rd", false},
+		{"bad-w_o-rd", false},
+		{"5lur", false},
+		{"s1ur", false}, // 1 read as l
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.alias, func(t *testing.T) {
+			appErr := v.ValidateCustomCode(tt.alias)
+			if tt.allowed && appErr != nil {
+				t.Errorf("Expected %q to pass, got: %v", tt.alias, appErr)
+			}
+			if !tt.allowed && appErr == nil {
+				t.Errorf("Expected %q to be rejected", tt.alias)
+			}
+		})
+	}
+}
+
+func TestValidateCustomCode_UnicodeAliases(t *testing.T) {
+	table, err := ParseRuneRanges("1F300-1FAFF, 2600-27BF, FE0F, 00C0-024F")
+	if err != nil {
+		t.Fatalf("ParseRuneRanges failed: %v", err)
+	}
+	v := NewURLValidator().WithUnicodeAliases(table)
+
+	tests := []struct {
+		alias   string
+		allowed bool
+	}{
+		{"party🎉", true},
+		{"🎉", true},
+		{"caf\u00e9", true},
+		{"cafe\u0301", true},             // decomposed, checked in NFC form
+		{strings.Repeat("🎉", 20), true},  // 80 bytes, 20 characters
+		{strings.Repeat("🎉", 21), false}, // too many characters
+		{"日本", false},                    // outside the configured ranges
+		{"sp ace", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.alias, func(t *testing.T) {
+			appErr := v.ValidateCustomCode(tt.alias)
+			if tt.allowed && appErr != nil {
+				t.Errorf("Expected %q to pass, got: %v", tt.alias, appErr)
+			}
+			if !tt.allowed && appErr == nil {
+				t.Errorf("Expected %q to be rejected", tt.alias)
+			}
+		})
+	}
+
+	if appErr := NewURLValidator().ValidateCustomCode("party🎉"); appErr == nil {
+		t.Error("Expected emoji rejected without Unicode aliases")
+	}
+}
+
+func TestParseRuneRanges_Invalid(t *testing.T) {
+	for _, spec := range []string{"", "zz", "1F600-1F300", "41-5A", "110000"} {
+		if _, err := ParseRuneRanges(spec); err == nil {
+			t.Errorf("Expected %q to be rejected", spec)
+		}
+	}
+}